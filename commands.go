@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"project/config"
+	"project/database"
+	"project/migrations"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// runCommand runs a one-off maintenance command instead of the HTTP server and returns
+// the process exit code.
+func runCommand(db *gorm.DB, args []string) int {
+	switch args[0] {
+	case "backfill-referral-closure":
+		fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+		rebuild := fs.Bool("rebuild", false, "empty referral_closure before rebuilding it")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		n, err := utils.BackfillReferralClosure(db, *rebuild)
+		if err != nil {
+			log.Printf("backfill-referral-closure: %v", err)
+			return 1
+		}
+		log.Printf("backfill-referral-closure: %d rows written", n)
+		return 0
+	case "migrate":
+		return runMigrate(db, args[1:])
+	case "schema-drift":
+		return runSchemaDrift(db, args)
+	case "backup":
+		return runBackup(db, args[1:])
+	}
+	log.Printf("unknown command %q (available: backfill-referral-closure, migrate, schema-drift, backup)", args[0])
+	return 2
+}
+
+const migrateUsage = "usage: migrate up [VERSION] | down [STEPS] | status | verify | baseline VERSION | force VERSION"
+
+// runMigrate handles ./app migrate <subcommand>.
+func runMigrate(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		log.Print(migrateUsage)
+		return 2
+	}
+	m, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Printf("migrate: %v", err)
+		return 1
+	}
+	m.Logf = log.Printf
+
+	// Optional or required numeric argument after the subcommand
+	number := func(def int, required bool) (int, bool) {
+		if len(args) < 2 {
+			if required {
+				log.Print(migrateUsage)
+			}
+			return def, !required
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			log.Printf("migrate %s: invalid number %q", args[0], args[1])
+			return 0, false
+		}
+		return n, true
+	}
+
+	switch args[0] {
+	case "up":
+		target, ok := number(0, false)
+		if !ok {
+			return 2
+		}
+		n, err := m.Up(target)
+		log.Printf("migrate up: %d migration(s) applied", n)
+		if err != nil {
+			log.Printf("migrate up: %v", err)
+			return 1
+		}
+		return 0
+	case "down":
+		steps, ok := number(1, false)
+		if !ok {
+			return 2
+		}
+		n, err := m.Down(steps)
+		log.Printf("migrate down: %d migration(s) reverted", n)
+		if err != nil {
+			log.Printf("migrate down: %v", err)
+			return 1
+		}
+		return 0
+	case "status":
+		states, unknown, err := m.Status()
+		if err != nil {
+			log.Printf("migrate status: %v", err)
+			return 1
+		}
+		for _, st := range states {
+			state := "pending"
+			if st.Applied != nil {
+				state = "applied " + st.Applied.AppliedAt.Format("2006-01-02 15:04:05")
+				if st.Applied.Dirty {
+					state = "DIRTY"
+				} else if st.ChecksumMismatch {
+					state += " (EDITED)"
+				}
+			}
+			fmt.Printf("%04d  %-40s  %s\n", st.Version, st.Name, state)
+		}
+		for _, r := range unknown {
+			fmt.Printf("%04d  %-40s  applied, no file in this build\n", r.Version, r.Name)
+		}
+		return 0
+	case "verify":
+		problems, err := m.Verify()
+		if err != nil {
+			log.Printf("migrate verify: %v", err)
+			return 1
+		}
+		for _, p := range problems {
+			log.Printf("migrate verify: %s", p)
+		}
+		if len(problems) > 0 {
+			return 1
+		}
+		log.Printf("migrate verify: schema is up to date (%d migrations)", len(m.Migrations))
+		return 0
+	case "baseline":
+		version, ok := number(0, true)
+		if !ok {
+			return 2
+		}
+		n, err := m.Baseline(version)
+		if err != nil {
+			log.Printf("migrate baseline: %v", err)
+			return 1
+		}
+		log.Printf("migrate baseline: %d migration(s) recorded as applied", n)
+		return 0
+	case "force":
+		version, ok := number(0, true)
+		if !ok {
+			return 2
+		}
+		if err := m.Force(version); err != nil {
+			log.Printf("migrate force: %v", err)
+			return 1
+		}
+		log.Printf("migrate force: %04d marked clean", version)
+		return 0
+	}
+	log.Print(migrateUsage)
+	return 2
+}
+
+// runSchemaDrift compares the models with the live schema and exits non-zero on drift,
+// so it can gate a deploy: ./app schema-drift [-strict] [-json]
+func runSchemaDrift(db *gorm.DB, args []string) int {
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	asJSON := fs.Bool("json", false, "print issues as a JSON array")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	issues, err := database.DetectSchemaDrift(db, models.All())
+	if err != nil {
+		log.Printf("schema-drift: %v", err)
+		return 1
+	}
+
+	errorsFound := 0
+	for _, i := range issues {
+		if i.Severity == database.DriftError {
+			errorsFound++
+		}
+	}
+	if *asJSON {
+		if issues == nil {
+			issues = []database.DriftIssue{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			log.Printf("schema-drift: %v", err)
+			return 1
+		}
+	} else {
+		for _, i := range issues {
+			fmt.Println(i)
+		}
+	}
+	log.Printf("schema-drift: %d error(s), %d warning(s)", errorsFound, len(issues)-errorsFound)
+
+	if database.HasDriftErrors(issues, *strict) {
+		return 1
+	}
+	return 0
+}
+
+const backupUsage = "usage: backup create | list | prune [-dry-run] | verify NAME|latest | restore [-force] [-until TIME] -database NAME NAME|latest"
+
+// runBackup handles ./app backup <subcommand>. Backups go to the R2 bucket, or to
+// DB_BACKUP_LOCAL_DIR when it is set.
+func runBackup(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		log.Print(backupUsage)
+		return 2
+	}
+	cfg, err := database.LoadBackupConfig()
+	if err != nil {
+		log.Printf("backup: %v", err)
+		return 1
+	}
+	var store database.BackupStore = utils.R2BackupStore{}
+	if dir := config.Get().Backup.LocalDir; dir != "" {
+		store = database.DirBackupStore{Dir: dir}
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		m, err := database.CreateBackup(ctx, cfg, store, time.Now())
+		if err != nil {
+			log.Printf("backup create: %v", err)
+			return 1
+		}
+		log.Printf("backup create: %s (%d bytes, %dms)", m.DumpKey, m.Size, m.DurationMs)
+		for _, t := range database.MoneyTables {
+			if sum, ok := m.Tables[t.Table]; ok {
+				log.Printf("backup create: %-14s %8d rows  sha256 %.16s", t.Table, sum.Rows, sum.SHA256)
+			}
+		}
+		// Retention runs after every successful backup
+		expired, err := database.PruneBackups(cfg, store, time.Now(), false)
+		if err != nil {
+			log.Printf("backup prune: %v", err)
+			return 1
+		}
+		log.Printf("backup prune: %d old backup(s) removed", len(expired))
+		return 0
+	case "list":
+		backups, err := database.ListBackups(store, cfg.Prefix)
+		if err != nil {
+			log.Printf("backup list: %v", err)
+			return 1
+		}
+		for _, b := range backups {
+			state := "complete"
+			if !b.Complete() {
+				state = "INCOMPLETE"
+			}
+			fmt.Printf("%s  %-60s  %12d  %s\n", b.CreatedAt.Format("2006-01-02 15:04:05"), b.Base, b.Size, state)
+		}
+		return 0
+	case "prune":
+		fs := flag.NewFlagSet("backup prune", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "only list what would be removed")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		expired, err := database.PruneBackups(cfg, store, time.Now(), *dryRun)
+		for _, b := range expired {
+			log.Printf("backup prune: %s", b.Base)
+		}
+		if err != nil {
+			log.Printf("backup prune: %v", err)
+			return 1
+		}
+		log.Printf("backup prune: %d backup(s) expired (keep %d days, at least %d)", len(expired), cfg.KeepDays, cfg.KeepMin)
+		return 0
+	case "verify":
+		if len(args) < 2 {
+			log.Print(backupUsage)
+			return 2
+		}
+		m, ok := loadBackup(cfg, store, args[1])
+		if !ok {
+			return 1
+		}
+		if err := database.VerifyBackup(cfg, store, m); err != nil {
+			log.Printf("backup verify: %v", err)
+			return 1
+		}
+		log.Printf("backup verify: %s is intact (%d money tables match)", m.DumpKey, len(m.Tables))
+		return 0
+	case "restore":
+		fs := flag.NewFlagSet("backup restore", flag.ContinueOnError)
+		target := fs.String("database", "", "database to restore into (created if missing)")
+		force := fs.Bool("force", false, "allow restoring over the live database")
+		until := fs.String("until", "", "point in time to roll forward to with the binlog, e.g. \"2006-01-02 15:04:05\"")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if *target == "" || fs.NArg() != 1 {
+			log.Print(backupUsage)
+			return 2
+		}
+		var untilAt time.Time
+		if *until != "" {
+			if untilAt, err = time.ParseInLocation("2006-01-02 15:04:05", *until, time.Local); err != nil {
+				log.Printf("backup restore: invalid -until %q", *until)
+				return 2
+			}
+		}
+		m, ok := loadBackup(cfg, store, fs.Arg(0))
+		if !ok {
+			return 1
+		}
+		if err := database.RestoreBackup(ctx, db, cfg, store, m, *target, *force); err != nil {
+			log.Printf("backup restore: %v", err)
+			return 1
+		}
+		log.Printf("backup restore: %s restored into %s; %d money tables match the manifest", m.DumpKey, *target, len(m.Tables))
+		if !untilAt.IsZero() {
+			log.Printf("backup restore: %s", database.PointInTimeHint(m, *target, untilAt))
+		}
+		return 0
+	}
+	log.Print(backupUsage)
+	return 2
+}
+
+func loadBackup(cfg database.BackupConfig, store database.BackupStore, name string) (*database.BackupManifest, bool) {
+	b, err := database.FindBackup(cfg, store, name)
+	if err != nil {
+		log.Printf("backup %s: %v", name, err)
+		return nil, false
+	}
+	m, err := database.LoadBackupManifest(store, b)
+	if err != nil {
+		log.Printf("backup %s: %v", name, err)
+		return nil, false
+	}
+	return m, true
+}
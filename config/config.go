@@ -0,0 +1,219 @@
+// Package config holds the process configuration: one typed struct loaded from
+// defaults, a config file, the environment and secret files, validated once at boot.
+//
+// Business settings edited from the admin panel (the settings and payment_settings
+// tables) stay in the database; this package only covers what the process needs to
+// start and to talk to other systems.
+package config
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config is the full process configuration. Field tags:
+//
+//	env:"NAME"       variable name, also the key in config files and the admin dump
+//	default:"value"  used when no source sets the key
+//	secret:"true"    redacted in Dump
+//	reload:"true"    picked up by Reload without a restart
+//	sep:";"          list separator (default ",")
+type Config struct {
+	Env  string `env:"ENV" default:"development"`
+	Port string `env:"PORT" default:"8080"`
+
+	DB         DB
+	Replica    Replica
+	Backup     Backup
+	Redis      Redis
+	JWT        JWT
+	HTTP       HTTP
+	RateLimit  RateLimit
+	Cron       Cron
+	Withdrawal Withdrawal
+	Pakailink  Pakailink
+	R2         R2
+	Groq       Groq
+	Fazpass    Fazpass
+	Telegram   Telegram
+	Partner    Partner
+
+	sources        map[string]Source
+	loadedAt       time.Time
+	pendingRestart []string
+}
+
+type DB struct {
+	Host     string `env:"DB_HOST" default:"127.0.0.1"`
+	Port     string `env:"DB_PORT" default:"3306"`
+	User     string `env:"DB_USER" default:"root"`
+	Pass     string `env:"DB_PASS" secret:"true"`
+	Name     string `env:"DB_NAME" default:"v1"`
+	Params   string `env:"DB_PARAMS" default:"charset=utf8mb4&parseTime=True&loc=Local"`
+	DSN      string `env:"DB_DSN" secret:"true"` // overrides the fields above
+	Role     string `env:"DB_ROLE" default:"write"`
+	ReadUser string `env:"DB_READ_USER"`
+	ReadPass string `env:"DB_READ_PASS" secret:"true"`
+
+	TLS           string `env:"DB_TLS" default:"true"` // true | preferred | false | skip
+	TLSVerify     bool   `env:"DB_TLS_VERIFY"`
+	TLSCAPath     string `env:"DB_TLS_CA_PATH"`
+	TLSClientCert string `env:"DB_TLS_CLIENT_CERT"`
+	TLSClientKey  string `env:"DB_TLS_CLIENT_KEY"`
+
+	ConnectRetries  int  `env:"DB_CONNECT_RETRIES" default:"5"`
+	MaxOpenConns    int  `env:"DB_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int  `env:"DB_MAX_IDLE_CONNS" default:"25"`
+	ConnMaxLifetime int  `env:"DB_CONN_MAX_LIFETIME" default:"3600"` // seconds
+	PingOnConnect   bool `env:"DB_PING_ON_CONNECT" default:"true"`
+}
+
+type Replica struct {
+	DSNs          []string `env:"DB_REPLICA_DSNS" sep:";" secret:"true"`
+	Hosts         []string `env:"DB_REPLICA_HOSTS"`
+	MaxLag        int      `env:"DB_REPLICA_MAX_LAG" default:"5"`        // seconds
+	CheckInterval int      `env:"DB_REPLICA_CHECK_INTERVAL" default:"5"` // seconds
+}
+
+type Backup struct {
+	Prefix         string `env:"DB_BACKUP_PREFIX" default:"backups/db/"`
+	Dir            string `env:"DB_BACKUP_DIR"` // scratch space, os.TempDir() when empty
+	LocalDir       string `env:"DB_BACKUP_LOCAL_DIR"`
+	KeepDays       int    `env:"DB_BACKUP_KEEP_DAYS" default:"14"`
+	KeepMin        int    `env:"DB_BACKUP_KEEP_MIN" default:"7"`
+	BinlogPosition bool   `env:"DB_BACKUP_BINLOG_POSITION"`
+	Flags          string `env:"DB_BACKUP_FLAGS"`
+	EncryptionKey  string `env:"DB_BACKUP_ENCRYPTION_KEY" secret:"true"`
+	PreMigratePath string `env:"DB_BACKUP_PATH"` // dump taken before migrate up
+}
+
+type Redis struct {
+	Addr string `env:"REDIS_ADDR"`
+	Pass string `env:"REDIS_PASS" secret:"true"`
+	DB   int    `env:"REDIS_DB"`
+}
+
+type JWT struct {
+	Secret   string `env:"JWT_SECRET" secret:"true"`
+	Audience string `env:"JWT_AUD"`
+	Issuer   string `env:"JWT_ISS"`
+}
+
+type HTTP struct {
+	// Extra origins on top of the built-in list; empty lets the security headers
+	// middleware answer any origin
+	CORSAllowedOrigins  []string `env:"CORS_ALLOWED_ORIGINS"`
+	TrustedProxies      []string `env:"TRUSTED_PROXIES"`
+	HSTS                bool     `env:"SEC_HSTS"`
+	CSP                 string   `env:"SEC_CSP" default:"default-src 'none'; frame-ancestors 'none'; base-uri 'self';"`
+	MaxBodyBytes        int64    `env:"MAX_BODY_BYTES" default:"1048576"`
+	RequestTimeout      int      `env:"REQ_TIMEOUT_SEC" default:"10"`
+	MetricSlowMs        int      `env:"METRIC_SLOW_MS" default:"800"`
+	SuspiciousThreshold int      `env:"SUSPICIOUS_THRESHOLD" default:"10"`
+	// Bearer token for GET /metrics; the endpoint answers 404 while it is empty
+	MetricsToken string `env:"METRICS_TOKEN" secret:"true" reload:"true"`
+}
+
+// RateLimit values are requests per minute. Zero keeps the limiter's built-in
+// default for that category.
+type RateLimit struct {
+	CleanupSeconds int `env:"RATE_CLEANUP_SECONDS" default:"60"`
+	IPDefault      int `env:"RATE_IP_DEFAULT" default:"200" reload:"true"`
+	IPAuth         int `env:"RATE_IP_AUTH" reload:"true"`
+	UserAuth       int `env:"RATE_USER_AUTH" default:"50" reload:"true"`
+	UserUpload     int `env:"RATE_USER_UPLOAD" default:"10" reload:"true"`
+	UserAdmin      int `env:"RATE_USER_ADMIN" reload:"true"` // 500 for admins, 50 for others when zero
+	UserAPI        int `env:"RATE_USER_API" default:"100" reload:"true"`
+}
+
+type Cron struct {
+	Key string `env:"CRON_KEY" secret:"true" reload:"true"`
+}
+
+type Withdrawal struct {
+	ChargePercent float64 `env:"WITHDRAWAL_CHARGE_PERCENT" default:"10" reload:"true"`
+}
+
+type Pakailink struct {
+	BaseURL            string `env:"PAKAILINK_BASE_URL" default:"https://api.pakailink.id" reload:"true"`
+	ClientKey          string `env:"PAKAILINK_CLIENT_KEY" reload:"true"`
+	ClientSecret       string `env:"PAKAILINK_CLIENT_SECRET" secret:"true" reload:"true"`
+	PartnerID          string `env:"PAKAILINK_PARTNER_ID" reload:"true"`
+	PrivateKeyPath     string `env:"PAKAILINK_PRIVATE_KEY_PATH" reload:"true"`
+	PaymentCallbackURL string `env:"PAKAILINK_PAYMENT_CALLBACK_URL" reload:"true"`
+	PayoutCallbackURL  string `env:"PAKAILINK_PAYOUT_CALLBACK_URL" reload:"true"` // payment callback when empty
+	MerchantID         string `env:"PAKAILINK_MERCHANT_ID" reload:"true"`
+	StoreID            string `env:"PAKAILINK_STORE_ID" reload:"true"`
+	TerminalID         string `env:"PAKAILINK_TERMINAL_ID" reload:"true"`
+}
+
+type R2 struct {
+	AccountID       string `env:"R2_ACCOUNT_ID" reload:"true"`
+	AccessKeyID     string `env:"R2_ACCESS_KEY_ID" reload:"true"`
+	SecretAccessKey string `env:"R2_SECRET_ACCESS_KEY" secret:"true" reload:"true"`
+	BucketName      string `env:"R2_BUCKET_NAME" reload:"true"`
+}
+
+type Groq struct {
+	APIKey string `env:"GROQ_API_KEY" secret:"true" reload:"true"`
+	Model  string `env:"GROQ_MODEL" default:"llama-3.1-70b-versatile" reload:"true"`
+}
+
+type Fazpass struct {
+	MerchantKey string `env:"FAZPASS_MERCHANT_KEY" secret:"true" reload:"true"`
+	GatewayKey  string `env:"FAZPASS_GATEWAY_KEY" secret:"true" reload:"true"`
+}
+
+type Telegram struct {
+	BotToken        string  `env:"TELEGRAM_BOT_TOKEN" secret:"true" reload:"true"`
+	BotUsername     string  `env:"TELEGRAM_BOT_USERNAME" reload:"true"`
+	AllowedGroupIDs []int64 `env:"TELEGRAM_ALLOWED_GROUP_IDS" reload:"true"`
+}
+
+type Partner struct {
+	// base64 AES-256 key encrypting partner HMAC signing secrets at rest
+	SecretEncryptionKey string `env:"PARTNER_SECRET_ENCRYPTION_KEY" secret:"true"`
+}
+
+// IsDevelopment reports whether ENV is development.
+func (c *Config) IsDevelopment() bool {
+	return strings.EqualFold(c.Env, "development")
+}
+
+// LoadedAt is when the values were read.
+func (c *Config) LoadedAt() time.Time { return c.loadedAt }
+
+var (
+	current  atomic.Pointer[Config]
+	reloadMu sync.Mutex
+)
+
+// Load reads every source and validates the result. All problems are reported
+// together, one per line.
+func Load() (*Config, error) {
+	c, errs := read(fileSources())
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c, nil
+}
+
+// Set installs c as the configuration returned by Get.
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// Get returns the installed configuration. Until Set is called (tests, tools that do
+// not go through main) it reads the environment on every call without validating,
+// so t.Setenv keeps working.
+func Get() *Config {
+	if c := current.Load(); c != nil {
+		return c
+	}
+	c, _ := read(nil)
+	return c
+}
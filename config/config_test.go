@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setBase sets the minimum for Load to succeed and clears the keys the tests use.
+func setBase(t *testing.T) {
+	t.Helper()
+	for k, v := range map[string]string{
+		"DB_HOST": "db", "DB_USER": "app", "DB_PASS": "pw", "DB_NAME": "vla",
+		"JWT_SECRET": "test-secret", "CONFIG_FILE": "", "PORT": "", "CRON_KEY": "",
+		"CRON_KEY_FILE": "", "GROQ_MODEL": "", "RATE_USER_API": "", "DB_DSN": "",
+	} {
+		t.Setenv(k, v)
+	}
+	t.Cleanup(func() { current.Store(nil) })
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func entry(c *Config, key string) Entry {
+	for _, e := range c.Dump() {
+		if e.Key == key {
+			return e
+		}
+	}
+	return Entry{}
+}
+
+func TestLoadSourcePrecedence(t *testing.T) {
+	setBase(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "app.json", `{
+		"PORT": 9090,
+		"GROQ_MODEL": "from-file",
+		"RATE_USER_API": 300,
+		"TELEGRAM_ALLOWED_GROUP_IDS": [-100123, 42],
+		"DB_REPLICA_DSNS": ["u:p@tcp(r1:3306)/vla", "u:p@tcp(r2:3306)/vla"]
+	}`))
+	t.Setenv("GROQ_MODEL", "from-env")
+	t.Setenv("CRON_KEY", "from-env")
+	t.Setenv("CRON_KEY_FILE", writeFile(t, "cron_key", "from-secret\n"))
+
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Port != "9090" || c.RateLimit.UserAPI != 300 || c.Groq.Model != "from-env" || c.Cron.Key != "from-secret" {
+		t.Fatalf("port %s, rate %d, model %s, cron %s", c.Port, c.RateLimit.UserAPI, c.Groq.Model, c.Cron.Key)
+	}
+	if got := c.Telegram.AllowedGroupIDs; len(got) != 2 || got[0] != -100123 || got[1] != 42 {
+		t.Fatalf("group ids = %v", got)
+	}
+	if len(c.Replica.DSNs) != 2 {
+		t.Fatalf("replica dsns = %v", c.Replica.DSNs)
+	}
+	if c.Pakailink.BaseURL != "https://api.pakailink.id" || c.Withdrawal.ChargePercent != 10 {
+		t.Fatalf("defaults not applied: %+v %+v", c.Pakailink, c.Withdrawal)
+	}
+	for key, want := range map[string]Source{
+		"PORT": SourceFile, "GROQ_MODEL": SourceEnv, "CRON_KEY": SourceSecretFile, "DB_PARAMS": SourceDefault,
+	} {
+		if got := entry(c, key).Source; got != want {
+			t.Errorf("%s source = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	setBase(t)
+	t.Setenv("DB_HOST", "")
+	t.Setenv("JWT_SECRET", insecureJWTSecret)
+	t.Setenv("PORT", "http")
+	t.Setenv("RATE_USER_API", "lots")
+	t.Setenv("PAKAILINK_CLIENT_KEY", "ck")
+	t.Setenv("R2_BUCKET_NAME", "bucket")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"DB_HOST: is required unless DB_DSN is set",
+		"JWT_SECRET: is the example placeholder",
+		"PORT: must be a port number",
+		`RATE_USER_API: "lots" is not a whole number`,
+		"PAKAILINK_CLIENT_SECRET: is required together with PAKAILINK_CLIENT_KEY",
+		"R2_ACCOUNT_ID: is required together with R2_BUCKET_NAME",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+
+	// A DSN replaces the individual database settings
+	t.Setenv("DB_DSN", "app:pw@tcp(db:3306)/vla")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("PORT", "")
+	t.Setenv("RATE_USER_API", "")
+	t.Setenv("PAKAILINK_CLIENT_KEY", "")
+	t.Setenv("R2_BUCKET_NAME", "")
+	if _, err := Load(); err != nil {
+		t.Fatalf("with DB_DSN: %v", err)
+	}
+}
+
+func TestReloadAppliesOnlyReloadableKeys(t *testing.T) {
+	setBase(t)
+	t.Setenv("CRON_KEY", "old-key")
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(c)
+
+	t.Setenv("CRON_KEY", "new-key")
+	t.Setenv("PORT", "9999")
+	res, err := Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Get(); got.Cron.Key != "new-key" || got.Port != "8080" {
+		t.Fatalf("after reload cron %q port %q", got.Cron.Key, got.Port)
+	}
+	if strings.Join(res.Changed, ",") != "CRON_KEY" || strings.Join(res.RestartRequired, ",") != "PORT" {
+		t.Fatalf("result = %+v", res)
+	}
+	if p := Get().PendingRestart(); len(p) != 1 || p[0] != "PORT" {
+		t.Fatalf("pending restart = %v", p)
+	}
+
+	// An invalid configuration is rejected as a whole
+	t.Setenv("CRON_KEY", "newer-key")
+	t.Setenv("WITHDRAWAL_CHARGE_PERCENT", "150")
+	if _, err := Reload(); err == nil || !strings.Contains(err.Error(), "WITHDRAWAL_CHARGE_PERCENT") {
+		t.Fatalf("invalid reload gave %v", err)
+	}
+	if Get().Cron.Key != "new-key" {
+		t.Fatalf("rejected reload changed cron key to %q", Get().Cron.Key)
+	}
+}
+
+func TestReloadValidatesMergedConfig(t *testing.T) {
+	setBase(t)
+	// Installed without validation, as Get does for tools: JWT_SECRET is not
+	// reloadable, so a reload cannot fix it and must not install it either
+	t.Setenv("JWT_SECRET", "")
+	c, _ := read(nil)
+	c.Cron.Key = "old-key"
+	Set(c)
+
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("CRON_KEY", "new-key")
+	if _, err := Reload(); err == nil || !strings.Contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("reload over an invalid running config gave %v", err)
+	}
+	if Get().Cron.Key != "old-key" {
+		t.Fatalf("rejected reload changed cron key to %q", Get().Cron.Key)
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	setBase(t)
+	t.Setenv("GROQ_API_KEY", "gsk_live_123")
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range c.Dump() {
+		if strings.Contains(e.Value, "gsk_live_123") || strings.Contains(e.Value, "test-secret") || e.Value == "pw" {
+			t.Fatalf("%s leaks its value", e.Key)
+		}
+	}
+	if e := entry(c, "GROQ_API_KEY"); e.Value != redacted || !e.Secret || !e.Reloadable {
+		t.Fatalf("GROQ_API_KEY entry = %+v", e)
+	}
+	if e := entry(c, "DB_HOST"); e.Value != "db" || e.Secret {
+		t.Fatalf("DB_HOST entry = %+v", e)
+	}
+	if e := entry(c, "DB_READ_PASS"); e.Value != "" {
+		t.Fatalf("unset secret shows %q", e.Value)
+	}
+}
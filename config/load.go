@@ -0,0 +1,257 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Source says where a value came from. Later sources win:
+// default < file < env < secret_file.
+type Source string
+
+const (
+	SourceDefault    Source = "default"
+	SourceFile       Source = "file"
+	SourceEnv        Source = "env"
+	SourceSecretFile Source = "secret_file"
+)
+
+// field is one env-tagged leaf of Config.
+type field struct {
+	key    string
+	def    string
+	sep    string
+	secret bool
+	reload bool
+	index  []int
+}
+
+var (
+	schemaOnce sync.Once
+	schema     []field
+)
+
+func fields() []field {
+	schemaOnce.Do(func() {
+		schema = collectFields(reflect.TypeOf(Config{}), nil)
+	})
+	return schema
+}
+
+func collectFields(t reflect.Type, prefix []int) []field {
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		index := append(append([]int{}, prefix...), i)
+		key := sf.Tag.Get("env")
+		if key == "" {
+			if sf.Type.Kind() == reflect.Struct {
+				out = append(out, collectFields(sf.Type, index)...)
+			}
+			continue
+		}
+		sep := sf.Tag.Get("sep")
+		if sep == "" {
+			sep = ","
+		}
+		out = append(out, field{
+			key:    key,
+			def:    sf.Tag.Get("default"),
+			sep:    sep,
+			secret: sf.Tag.Get("secret") == "true",
+			reload: sf.Tag.Get("reload") == "true",
+			index:  index,
+		})
+	}
+	return out
+}
+
+// fileSources lists the config files to read: .env in the working directory when
+// present, then CONFIG_FILE.
+func fileSources() []string {
+	var files []string
+	if _, err := os.Stat(".env"); err == nil {
+		files = append(files, ".env")
+	}
+	if p := strings.TrimSpace(os.Getenv("CONFIG_FILE")); p != "" {
+		files = append(files, p)
+	}
+	return files
+}
+
+// readFile reads a JSON object or a dotenv file into key/value pairs. JSON values may
+// be strings, numbers, booleans or arrays.
+func readFile(path string) (map[string]any, error) {
+	out := map[string]any{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &out); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return out, nil
+	}
+	m, err := godotenv.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// fileString renders a config file value the way it would be written in the
+// environment; arrays are joined with the field's separator.
+func fileString(v any, sep string) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case []any:
+		parts := make([]string, 0, len(x))
+		for _, e := range x {
+			parts = append(parts, fileString(e, sep))
+		}
+		return strings.Join(parts, sep)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// read builds a Config from the defaults, the given files, the environment and
+// NAME_FILE secret files. It returns the parse errors instead of stopping at the
+// first one; fields that fail to parse keep their default.
+func read(files []string) (*Config, []error) {
+	var errs []error
+	fileValues := map[string]any{}
+	for _, path := range files {
+		m, err := readFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file: %w", err))
+			continue
+		}
+		for k, v := range m {
+			fileValues[k] = v
+		}
+	}
+
+	c := &Config{sources: map[string]Source{}, loadedAt: time.Now()}
+	root := reflect.ValueOf(c).Elem()
+	for _, f := range fields() {
+		raw, src := f.def, SourceDefault
+		if v, ok := fileValues[f.key]; ok {
+			if s := strings.TrimSpace(fileString(v, f.sep)); s != "" {
+				raw, src = s, SourceFile
+			}
+		}
+		if s := strings.TrimSpace(os.Getenv(f.key)); s != "" {
+			raw, src = s, SourceEnv
+		}
+		secretPath := strings.TrimSpace(os.Getenv(f.key + "_FILE"))
+		if secretPath == "" {
+			secretPath = strings.TrimSpace(fileString(fileValues[f.key+"_FILE"], ""))
+		}
+		if secretPath != "" {
+			b, err := os.ReadFile(secretPath)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.key, err))
+			} else {
+				raw, src = strings.TrimSpace(string(b)), SourceSecretFile
+			}
+		}
+
+		if err := setField(root.FieldByIndex(f.index), raw, f.sep); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.key, err))
+			if f.def != "" {
+				_ = setField(root.FieldByIndex(f.index), f.def, f.sep)
+			}
+			continue
+		}
+		c.sources[f.key] = src
+	}
+	return c, errs
+}
+
+func setField(v reflect.Value, raw, sep string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		if raw == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var parts []string
+		for _, p := range strings.Split(raw, sep) {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		switch v.Type().Elem().Kind() {
+		case reflect.String:
+			v.Set(reflect.ValueOf(parts))
+		case reflect.Int64:
+			ids := make([]int64, 0, len(parts))
+			for _, p := range parts {
+				n, err := strconv.ParseInt(p, 10, 64)
+				if err != nil {
+					return fmt.Errorf("%q is not a whole number", p)
+				}
+				ids = append(ids, n)
+			}
+			v.Set(reflect.ValueOf(ids))
+		default:
+			return errors.New("unsupported list type " + v.Type().String())
+		}
+	default:
+		return errors.New("unsupported type " + v.Type().String())
+	}
+	return nil
+}
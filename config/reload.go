@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ReloadResult lists the keys whose values differ from the running configuration.
+type ReloadResult struct {
+	Changed         []string `json:"changed"`          // applied now
+	RestartRequired []string `json:"restart_required"` // kept until the next restart
+}
+
+// Reload re-reads every source and applies the fields tagged reload:"true". Other
+// changes are left alone and reported in RestartRequired. The new sources and the
+// merged result (running values plus the reloaded fields) are both validated; if
+// either fails, nothing changes and the error says why.
+func Reload() (*ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	old := current.Load()
+	if old == nil {
+		Set(next)
+		return &ReloadResult{}, nil
+	}
+
+	merged := *old
+	merged.sources = make(map[string]Source, len(old.sources))
+	for k, v := range old.sources {
+		merged.sources[k] = v
+	}
+	merged.loadedAt = next.loadedAt
+
+	res := &ReloadResult{}
+	mv := reflect.ValueOf(&merged).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	for _, f := range fields() {
+		o, n := mv.FieldByIndex(f.index), nv.FieldByIndex(f.index)
+		if reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+		if !f.reload {
+			res.RestartRequired = append(res.RestartRequired, f.key)
+			continue
+		}
+		o.Set(n)
+		merged.sources[f.key] = next.sources[f.key]
+		res.Changed = append(res.Changed, f.key)
+	}
+	merged.pendingRestart = res.RestartRequired
+	if errs := merged.validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	Set(&merged)
+	return res, nil
+}
+
+// PendingRestart lists the keys changed in the sources since boot that Reload could
+// not apply.
+func (c *Config) PendingRestart() []string { return c.pendingRestart }
+
+// Entry is one key of the redacted dump.
+type Entry struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Source     Source `json:"source"`
+	Secret     bool   `json:"secret"`
+	Reloadable bool   `json:"reloadable"`
+}
+
+const redacted = "******"
+
+// Dump lists every key with its value and source. Secrets that are set show as
+// ******, so the output is safe to return from the admin API.
+func (c *Config) Dump() []Entry {
+	root := reflect.ValueOf(c).Elem()
+	out := make([]Entry, 0, len(fields()))
+	for _, f := range fields() {
+		value := formatValue(root.FieldByIndex(f.index), f.sep)
+		if f.secret && value != "" {
+			value = redacted
+		}
+		src := c.sources[f.key]
+		if src == "" {
+			src = SourceDefault
+		}
+		out = append(out, Entry{Key: f.key, Value: value, Source: src, Secret: f.secret, Reloadable: f.reload})
+	}
+	return out
+}
+
+func formatValue(v reflect.Value, sep string) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i), sep)
+		}
+		return strings.Join(parts, sep)
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// insecureJWTSecret is the placeholder from old example files; refusing it keeps a
+// copied .env from going to production with a public signing key.
+const insecureJWTSecret = "supersecretjwtkey"
+
+// validate checks the loaded values and returns every problem found.
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	required := func(key, value string) {
+		if value == "" {
+			fail(key, "is required")
+		}
+	}
+	atLeast := func(key string, value, min int) {
+		if value < min {
+			fail(key, "must be at least %d, got %d", min, value)
+		}
+	}
+
+	if p, err := strconv.Atoi(c.Port); err != nil || p < 1 || p > 65535 {
+		fail("PORT", "must be a port number, got %q", c.Port)
+	}
+
+	// The defaults for host, user and name only suit local tools; the server must be
+	// told where its database is
+	if c.DB.DSN == "" {
+		for _, key := range []string{"DB_HOST", "DB_USER", "DB_PASS", "DB_NAME"} {
+			if c.sources[key] == SourceDefault {
+				fail(key, "is required unless DB_DSN is set")
+			}
+		}
+	}
+	if role := strings.ToLower(c.DB.Role); role != "read" && role != "write" {
+		fail("DB_ROLE", "must be read or write, got %q", c.DB.Role)
+	}
+	switch c.DB.TLS {
+	case "true", "preferred", "false", "skip":
+	default:
+		fail("DB_TLS", "must be true, preferred, false or skip, got %q", c.DB.TLS)
+	}
+	if (c.DB.TLSClientCert == "") != (c.DB.TLSClientKey == "") {
+		fail("DB_TLS_CLIENT_CERT", "and DB_TLS_CLIENT_KEY must be set together")
+	}
+	atLeast("DB_CONNECT_RETRIES", c.DB.ConnectRetries, 1)
+	atLeast("DB_MAX_OPEN_CONNS", c.DB.MaxOpenConns, 0)
+	atLeast("DB_MAX_IDLE_CONNS", c.DB.MaxIdleConns, 0)
+	atLeast("DB_CONN_MAX_LIFETIME", c.DB.ConnMaxLifetime, 0)
+	atLeast("DB_REPLICA_MAX_LAG", c.Replica.MaxLag, 0)
+	atLeast("DB_REPLICA_CHECK_INTERVAL", c.Replica.CheckInterval, 1)
+	atLeast("DB_BACKUP_KEEP_DAYS", c.Backup.KeepDays, 0)
+	atLeast("DB_BACKUP_KEEP_MIN", c.Backup.KeepMin, 0)
+	atLeast("REDIS_DB", c.Redis.DB, 0)
+
+	required("JWT_SECRET", c.JWT.Secret)
+	if c.JWT.Secret == insecureJWTSecret {
+		fail("JWT_SECRET", "is the example placeholder, generate a random secret")
+	}
+
+	if c.HTTP.MaxBodyBytes < 1 {
+		fail("MAX_BODY_BYTES", "must be positive, got %d", c.HTTP.MaxBodyBytes)
+	}
+	atLeast("REQ_TIMEOUT_SEC", c.HTTP.RequestTimeout, 1)
+	atLeast("METRIC_SLOW_MS", c.HTTP.MetricSlowMs, 1)
+	atLeast("SUSPICIOUS_THRESHOLD", c.HTTP.SuspiciousThreshold, 1)
+
+	atLeast("RATE_CLEANUP_SECONDS", c.RateLimit.CleanupSeconds, 1)
+	atLeast("RATE_IP_DEFAULT", c.RateLimit.IPDefault, 0)
+	atLeast("RATE_IP_AUTH", c.RateLimit.IPAuth, 0)
+	atLeast("RATE_USER_AUTH", c.RateLimit.UserAuth, 0)
+	atLeast("RATE_USER_UPLOAD", c.RateLimit.UserUpload, 0)
+	atLeast("RATE_USER_ADMIN", c.RateLimit.UserAdmin, 0)
+	atLeast("RATE_USER_API", c.RateLimit.UserAPI, 0)
+
+	if p := c.Withdrawal.ChargePercent; p < 0 || p > 100 {
+		fail("WITHDRAWAL_CHARGE_PERCENT", "must be between 0 and 100, got %v", p)
+	}
+
+	// Payment gateways are optional, but a half-configured one fails at the first
+	// payment instead of at boot
+	allOrNone(&errs, map[string]string{
+		"PAKAILINK_CLIENT_KEY":           c.Pakailink.ClientKey,
+		"PAKAILINK_CLIENT_SECRET":        c.Pakailink.ClientSecret,
+		"PAKAILINK_PARTNER_ID":           c.Pakailink.PartnerID,
+		"PAKAILINK_PRIVATE_KEY_PATH":     c.Pakailink.PrivateKeyPath,
+		"PAKAILINK_PAYMENT_CALLBACK_URL": c.Pakailink.PaymentCallbackURL,
+	})
+	allOrNone(&errs, map[string]string{
+		"PAKAILINK_MERCHANT_ID": c.Pakailink.MerchantID,
+		"PAKAILINK_STORE_ID":    c.Pakailink.StoreID,
+		"PAKAILINK_TERMINAL_ID": c.Pakailink.TerminalID,
+	})
+	for _, kv := range [][2]string{
+		{"PAKAILINK_BASE_URL", c.Pakailink.BaseURL},
+		{"PAKAILINK_PAYMENT_CALLBACK_URL", c.Pakailink.PaymentCallbackURL},
+		{"PAKAILINK_PAYOUT_CALLBACK_URL", c.Pakailink.PayoutCallbackURL},
+	} {
+		if kv[1] == "" {
+			continue
+		}
+		if u, err := url.Parse(kv[1]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail(kv[0], "must be an http(s) URL, got %q", kv[1])
+		}
+	}
+	allOrNone(&errs, map[string]string{
+		"R2_ACCOUNT_ID":        c.R2.AccountID,
+		"R2_ACCESS_KEY_ID":     c.R2.AccessKeyID,
+		"R2_SECRET_ACCESS_KEY": c.R2.SecretAccessKey,
+		"R2_BUCKET_NAME":       c.R2.BucketName,
+	})
+	if c.Fazpass.GatewayKey != "" && c.Fazpass.MerchantKey == "" {
+		fail("FAZPASS_MERCHANT_KEY", "is required when FAZPASS_GATEWAY_KEY is set")
+	}
+	if k := c.Partner.SecretEncryptionKey; k != "" {
+		if b, err := base64.StdEncoding.DecodeString(k); err != nil || len(b) != 32 {
+			fail("PARTNER_SECRET_ENCRYPTION_KEY", "must be 32 bytes, base64 encoded (openssl rand -base64 32)")
+		}
+	}
+
+	return errs
+}
+
+// allOrNone reports the missing keys of a group where only some keys are set.
+func allOrNone(errs *[]error, group map[string]string) {
+	var set, missing []string
+	for key, value := range group {
+		if value == "" {
+			missing = append(missing, key)
+		} else {
+			set = append(set, key)
+		}
+	}
+	if len(set) == 0 || len(missing) == 0 {
+		return
+	}
+	sort.Strings(missing)
+	sort.Strings(set)
+	for _, key := range missing {
+		*errs = append(*errs, fmt.Errorf("%s: is required together with %s", key, strings.Join(set, ", ")))
+	}
+}
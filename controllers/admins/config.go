@@ -0,0 +1,48 @@
+package admins
+
+import (
+	"log"
+	"net/http"
+
+	"project/config"
+	"project/utils"
+)
+
+// GET /api/admin/config
+// Lists every configuration key with its source; secrets are redacted.
+func GetConfigHandler(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"env":             cfg.Env,
+			"loaded_at":       cfg.LoadedAt(),
+			"pending_restart": cfg.PendingRestart(),
+			"entries":         cfg.Dump(),
+		},
+	})
+}
+
+// POST /api/admin/config/reload
+// Re-reads the configuration sources and applies the reloadable keys. An invalid
+// configuration is rejected and the running one is kept.
+func ReloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := utils.GetAdminID(r)
+	res, err := config.Reload()
+	if err != nil {
+		log.Printf("[admin/config] reload by admin %d rejected: %v", adminID, err)
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, utils.APIResponse{
+			Success: false,
+			Message: "Konfigurasi tidak valid, konfigurasi lama tetap dipakai",
+			Data:    map[string]interface{}{"errors": err.Error()},
+		})
+		return
+	}
+	log.Printf("[admin/config] reload by admin %d: changed %v, restart required for %v", adminID, res.Changed, res.RestartRequired)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Konfigurasi berhasil dimuat ulang",
+		Data:    res,
+	})
+}
@@ -53,7 +53,7 @@ type DashboardStats struct {
 
 func GetDashboardStats(w http.ResponseWriter, r *http.Request) {
 	var stats DashboardStats
-	db := database.DB
+	db := database.ReadDB()
 
 	// initialize slices to ensure empty arrays are returned (not null)
 	stats.GrowthUsers = make([]DailyGrowth, 0)
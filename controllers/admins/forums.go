@@ -153,36 +153,20 @@ func ApproveForumHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Update forum status and reward
+		acceptedAt := utils.SystemClock.Now()
 		forum.Status = "Accepted"
 		forum.Reward = req.Reward
+		forum.AcceptedAt = &acceptedAt
 		if err := tx.Save(&forum).Error; err != nil {
 			return err
 		}
 
-		// Add reward to user balance
-		if err := tx.Model(&models.User{}).
-			Where("id = ?", forum.UserID).
-			UpdateColumn("balance", gorm.Expr("balance + ?", req.Reward)).Error; err != nil {
+		// Held as Pending while the user's bonuses are frozen for review
+		if _, err := utils.CreditBonus(tx, forum.UserID, req.Reward, "bonus", "Hadiah Forum Post"); err != nil {
 			return err
 		}
 
-		// Create bonus transaction
-		msg := "Hadiah Forum Post"
-		trx := models.Transaction{
-			UserID:          forum.UserID,
-			Amount:          req.Reward,
-			Charge:          0,
-			OrderID:         utils.GenerateOrderID(forum.UserID),
-			TransactionFlow: "debit",
-			TransactionType: "bonus",
-			Message:         &msg,
-			Status:          "Success",
-		}
-		if err := tx.Create(&trx).Error; err != nil {
-			return err
-		}
-
-		return nil
+		return utils.InvalidateTaskProgress(tx, []uint{forum.UserID}, utils.TaskCriterionForumAccepted)
 	})
 
 	if err != nil {
@@ -1,6 +1,7 @@
 package admins
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -29,6 +30,8 @@ type GiftResponse struct {
 	RecipientType    string  `json:"recipient_type"`
 	Status           string  `json:"status"`
 	TotalDeducted    float64 `json:"total_deducted"`
+	RefundedAmount   float64 `json:"refunded_amount"`
+	ExpiresAt        *string `json:"expires_at"`
 	CreatedAt        string  `json:"created_at"`
 }
 
@@ -126,6 +129,8 @@ func GetGifts(w http.ResponseWriter, r *http.Request) {
 			RecipientType:    g.RecipientType,
 			Status:           g.Status,
 			TotalDeducted:    g.TotalDeducted,
+			RefundedAmount:   g.RefundedAmount,
+			ExpiresAt:        formatOptionalTime(g.ExpiresAt),
 			CreatedAt:        g.CreatedAt.Format(time.RFC3339),
 		})
 	}
@@ -294,6 +299,14 @@ func GetGiftWinners(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
 // CancelGift PUT /admin/gifts/{id}/cancel - cancel active gift
 func CancelGift(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -326,40 +339,22 @@ func CancelGift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var claimedCount int64
-	database.DB.Model(&models.GiftClaim{}).Where("gift_id = ?", gift.ID).Count(&claimedCount)
-	if claimedCount > 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
-			Success: false,
-			Message: "Hadiah yang sudah ada klaim tidak dapat dibatalkan",
-		})
-		return
-	}
-
-	// Refund balance to sender
+	// Refund whatever has not been claimed yet, same path as the expiry cron
+	var refund *utils.GiftRefund
 	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&gift).Update("status", "cancelled").Error; err != nil {
-			return err
-		}
-		if err := tx.Model(&models.User{}).Where("id = ?", gift.UserID).
-			Update("balance", gorm.Expr("balance + ?", gift.TotalDeducted)).Error; err != nil {
-			return err
-		}
-		msg := "Refund hadiah dibatalkan - " + gift.Code
-		trx := models.Transaction{
-			UserID:          gift.UserID,
-			Amount:          gift.TotalDeducted,
-			Charge:          0,
-			OrderID:         utils.GenerateOrderID(gift.UserID),
-			TransactionFlow: "debit",
-			TransactionType: "refund",
-			Message:         &msg,
-			Status:          "Success",
-		}
-		return tx.Create(&trx).Error
+		var err error
+		refund, err = utils.RefundGift(tx, gift.ID, "cancelled")
+		return err
 	})
 
 	if err != nil {
+		if errors.Is(err, utils.ErrGiftNotActive) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+				Success: false,
+				Message: "Hadiah hanya dapat dibatalkan jika status active",
+			})
+			return
+		}
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal membatalkan hadiah",
@@ -371,9 +366,11 @@ func CancelGift(w http.ResponseWriter, r *http.Request) {
 		Success: true,
 		Message: "Hadiah berhasil dibatalkan dan saldo telah dikembalikan",
 		Data: map[string]interface{}{
-			"id":     gift.ID,
-			"code":   gift.Code,
-			"status": "cancelled",
+			"id":              gift.ID,
+			"code":            gift.Code,
+			"status":          "cancelled",
+			"refunded_amount": refund.Amount,
+			"refund_order_id": refund.OrderID,
 		},
 	})
 }
@@ -0,0 +1,242 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HolidayRequest struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+type HolidayResponse struct {
+	ID     uint   `json:"id"`
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+func toHolidayResponse(h models.Holiday) HolidayResponse {
+	return HolidayResponse{ID: h.ID, Date: utils.DateKey(h.Date), Name: h.Name, Source: h.Source}
+}
+
+// GET /api/admin/holidays?year=2026
+func GetHolidays(w http.ResponseWriter, r *http.Request) {
+	query := database.DB.Model(&models.Holiday{})
+	if yearStr := strings.TrimSpace(r.URL.Query().Get("year")); yearStr != "" {
+		year, err := strconv.Atoi(yearStr)
+		if err != nil || year < 2000 || year > 2100 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tahun tidak valid"})
+			return
+		}
+		query = query.Where("YEAR(date) = ?", year)
+	}
+
+	var holidays []models.Holiday
+	if err := query.Order("date ASC").Find(&holidays).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data hari libur"})
+		return
+	}
+
+	response := make([]HolidayResponse, 0, len(holidays))
+	for _, h := range holidays {
+		response = append(response, toHolidayResponse(h))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    response,
+	})
+}
+
+// POST /api/admin/holidays
+func CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var req HolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	date, err := utils.ParseDate(req.Date)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tanggal harus berformat YYYY-MM-DD"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nama hari libur wajib diisi"})
+		return
+	}
+
+	db := database.DB
+	var count int64
+	if err := db.Model(&models.Holiday{}).Where("date = ?", utils.DateKey(date)).Count(&count).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if count > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tanggal tersebut sudah terdaftar sebagai hari libur"})
+		return
+	}
+
+	holiday := models.Holiday{Date: date, Name: name, Source: "manual"}
+	if err := db.Create(&holiday).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menambahkan hari libur"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Hari libur berhasil ditambahkan",
+		Data:    toHolidayResponse(holiday),
+	})
+}
+
+// PUT /api/admin/holidays/{id}
+func UpdateHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req HolidayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	db := database.DB
+	var holiday models.Holiday
+	if err := db.First(&holiday, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Hari libur tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	if strings.TrimSpace(req.Date) != "" {
+		date, err := utils.ParseDate(req.Date)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tanggal harus berformat YYYY-MM-DD"})
+			return
+		}
+		holiday.Date = date
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		holiday.Name = name
+	}
+
+	if err := db.Save(&holiday).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui hari libur"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Hari libur berhasil diperbarui",
+		Data:    toHolidayResponse(holiday),
+	})
+}
+
+// DELETE /api/admin/holidays/{id}
+func DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Delete(&models.Holiday{}, id)
+	if res.Error != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus hari libur"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Hari libur tidak ditemukan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Hari libur berhasil dihapus"})
+}
+
+// POST /api/admin/holidays/import (multipart: file=.ics|.csv)
+// Existing dates are overwritten with the imported name.
+func ImportHolidays(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "File wajib diunggah"})
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(strings.TrimSpace(r.FormValue("format")))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	var parsed []utils.ParsedHoliday
+	var source string
+	switch format {
+	case "ics", "ical":
+		parsed, err = utils.ParseHolidayICal(file)
+		source = "ical"
+	case "csv":
+		parsed, err = utils.ParseHolidayCSV(file)
+		source = "csv"
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format file harus .ics atau .csv"})
+		return
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Gagal membaca file: " + err.Error()})
+		return
+	}
+	if len(parsed) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak ada tanggal yang dapat diimpor"})
+		return
+	}
+
+	// Deduplicate by date, the last occurrence in the file wins
+	byDate := make(map[string]models.Holiday, len(parsed))
+	for _, p := range parsed {
+		byDate[utils.DateKey(p.Date)] = models.Holiday{Date: p.Date, Name: p.Name, Source: source}
+	}
+	rows := make([]models.Holiday, 0, len(byDate))
+	for _, h := range byDate {
+		rows = append(rows, h)
+	}
+
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "source", "updated_at"}),
+	}).Create(&rows).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan hari libur"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Hari libur berhasil diimpor",
+		Data:    map[string]interface{}{"imported": len(rows)},
+	})
+}
@@ -0,0 +1,179 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type KYCSubmissionResponse struct {
+	models.KYCSubmission
+	UserName   string `json:"user_name"`
+	UserNumber string `json:"user_number"`
+}
+
+type KYCReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GET /api/admin/kyc?status=Pending&user_id=1&page=1&limit=20
+func GetKYCSubmissions(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.DB.Model(&models.KYCSubmission{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data KYC"})
+		return
+	}
+	var subs []models.KYCSubmission
+	if err := query.Order("created_at ASC").Offset((page - 1) * limit).Limit(limit).Find(&subs).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data KYC"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(subs))
+	for _, s := range subs {
+		userIDs = append(userIDs, s.UserID)
+	}
+	usersByID := make(map[uint]models.User)
+	if len(userIDs) > 0 {
+		var userRows []models.User
+		database.DB.Select("id", "name", "number").Where("id IN ?", userIDs).Find(&userRows)
+		for _, u := range userRows {
+			usersByID[u.ID] = u
+		}
+	}
+
+	items := make([]KYCSubmissionResponse, 0, len(subs))
+	for _, s := range subs {
+		u := usersByID[s.UserID]
+		items = append(items, KYCSubmissionResponse{KYCSubmission: s, UserName: u.Name, UserNumber: u.Number})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"items": items,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}})
+}
+
+// GET /api/admin/kyc/{id}
+// Includes signed document URLs that expire after a few minutes.
+func GetKYCSubmission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var sub models.KYCSubmission
+	if err := database.DB.First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Data KYC tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	var user models.User
+	database.DB.Select("id", "name", "number", "kyc_status").First(&user, sub.UserID)
+
+	documents, err := utils.KYCDocumentURLs(sub)
+	if err != nil {
+		log.Printf("[admin/kyc] sign documents for submission %d: %v", sub.ID, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat tautan dokumen"})
+		return
+	}
+
+	// Earlier attempts help spot recycled documents
+	var history []models.KYCSubmission
+	database.DB.Where("user_id = ? AND id <> ?", sub.UserID, sub.ID).Order("id DESC").Limit(10).Find(&history)
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"submission":          KYCSubmissionResponse{KYCSubmission: sub, UserName: user.Name, UserNumber: user.Number},
+		"user_kyc_status":     user.KYCStatus,
+		"documents":           documents,
+		"documents_expire_in": utils.KYCSignedURLExpiry,
+		"history":             history,
+	}})
+}
+
+// PUT /api/admin/kyc/{id}/approve
+func ApproveKYCSubmission(w http.ResponseWriter, r *http.Request) {
+	reviewKYCSubmission(w, r, true)
+}
+
+// PUT /api/admin/kyc/{id}/reject
+// Body: {"reason": "Foto KTP buram"}; the reason is shown to the user.
+func RejectKYCSubmission(w http.ResponseWriter, r *http.Request) {
+	reviewKYCSubmission(w, r, false)
+}
+
+func reviewKYCSubmission(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req KYCReviewRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	if !approve && len(req.Reason) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Alasan penolakan maksimal 255 karakter"})
+		return
+	}
+	adminID, _ := utils.GetAdminID(r)
+
+	var sub *models.KYCSubmission
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		sub, err = utils.ReviewKYC(tx, uint(id), approve, adminID, req.Reason, time.Now())
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Data KYC tidak ditemukan"})
+		case errors.Is(err, utils.ErrKYCNotPending):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hanya pengajuan dengan status Pending yang dapat diproses"})
+		case errors.Is(err, utils.ErrKYCReasonRequired):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Alasan penolakan wajib diisi"})
+		case errors.Is(err, utils.ErrKYCIDNumberUsed):
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "NIK ini sudah terverifikasi di akun lain"})
+		default:
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		}
+		return
+	}
+
+	message := "Verifikasi KYC disetujui"
+	if !approve {
+		message = "Verifikasi KYC ditolak"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: sub})
+}
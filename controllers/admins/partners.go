@@ -0,0 +1,369 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type PartnerRequest struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type PartnerKeyRequest struct {
+	Scopes           []string   `json:"scopes"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RequireSignature bool       `json:"require_signature"`
+}
+
+type RotatePartnerKeyRequest struct {
+	GraceHours int        `json:"grace_hours"` // how long the old key keeps working, default 24
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type PartnerKeyResponse struct {
+	ID               uint       `json:"id"`
+	PartnerID        uint       `json:"partner_id"`
+	Prefix           string     `json:"prefix"`
+	Scopes           []string   `json:"scopes"`
+	RequireSignature bool       `json:"require_signature"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RotatedFromID    *uint      `json:"rotated_from_id"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type PartnerResponse struct {
+	ID        uint                 `json:"id"`
+	Name      string               `json:"name"`
+	Status    string               `json:"status"`
+	Keys      []PartnerKeyResponse `json:"keys"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// IssuedPartnerKeyResponse carries the plaintext credentials; they are only returned here.
+type IssuedPartnerKeyResponse struct {
+	PartnerKeyResponse
+	APIKey        string `json:"api_key"`
+	SigningSecret string `json:"signing_secret,omitempty"`
+}
+
+func toPartnerKeyResponse(k models.PartnerKey) PartnerKeyResponse {
+	return PartnerKeyResponse{
+		ID:               k.ID,
+		PartnerID:        k.PartnerID,
+		Prefix:           k.Prefix,
+		Scopes:           k.ScopeList(),
+		RequireSignature: k.RequireSignature,
+		ExpiresAt:        k.ExpiresAt,
+		RevokedAt:        k.RevokedAt,
+		RotatedFromID:    k.RotatedFromID,
+		LastUsedAt:       k.LastUsedAt,
+		CreatedAt:        k.CreatedAt,
+	}
+}
+
+func toIssuedPartnerKeyResponse(issued *utils.IssuedPartnerKey) IssuedPartnerKeyResponse {
+	return IssuedPartnerKeyResponse{
+		PartnerKeyResponse: toPartnerKeyResponse(*issued.Key),
+		APIKey:             issued.APIKey,
+		SigningSecret:      issued.SigningSecret,
+	}
+}
+
+func writePartnerKeyError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, utils.ErrPartnerScopeUnknown):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+	case errors.Is(err, utils.ErrPartnerNoEncryption):
+		utils.WriteJSON(w, http.StatusServiceUnavailable, utils.APIResponse{Success: false, Message: "Kunci dengan tanda tangan membutuhkan PARTNER_SECRET_ENCRYPTION_KEY"})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: fallback})
+	}
+}
+
+func findPartnerKey(w http.ResponseWriter, r *http.Request) (*models.PartnerKey, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return nil, false
+	}
+	var key models.PartnerKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Kunci partner tidak ditemukan"})
+			return nil, false
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return nil, false
+	}
+	return &key, true
+}
+
+// GET /api/admin/partners
+func GetPartners(w http.ResponseWriter, r *http.Request) {
+	var partners []models.Partner
+	if err := database.DB.Preload("Keys", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id DESC")
+	}).Order("name ASC").Find(&partners).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data partner"})
+		return
+	}
+
+	response := make([]PartnerResponse, 0, len(partners))
+	for _, p := range partners {
+		keys := make([]PartnerKeyResponse, 0, len(p.Keys))
+		for _, k := range p.Keys {
+			keys = append(keys, toPartnerKeyResponse(k))
+		}
+		response = append(response, PartnerResponse{ID: p.ID, Name: p.Name, Status: p.Status, Keys: keys, CreatedAt: p.CreatedAt})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: response})
+}
+
+// POST /api/admin/partners
+func CreatePartner(w http.ResponseWriter, r *http.Request) {
+	var req PartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nama partner wajib diisi (maks. 100 karakter)"})
+		return
+	}
+
+	db := database.DB
+	var count int64
+	if err := db.Model(&models.Partner{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if count > 0 {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Partner dengan nama tersebut sudah ada"})
+		return
+	}
+
+	partner := models.Partner{Name: name, Status: "Active"}
+	if err := db.Create(&partner).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menambahkan partner"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Partner berhasil ditambahkan",
+		Data:    PartnerResponse{ID: partner.ID, Name: partner.Name, Status: partner.Status, Keys: []PartnerKeyResponse{}, CreatedAt: partner.CreatedAt},
+	})
+}
+
+// PUT /api/admin/partners/{id}/status
+// An inactive partner's keys stop working immediately without being revoked.
+func UpdatePartnerStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req PartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	if req.Status != "Active" && req.Status != "Inactive" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Status harus Active atau Inactive"})
+		return
+	}
+
+	res := database.DB.Model(&models.Partner{}).Where("id = ?", id).Update("status", req.Status)
+	if res.Error != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui partner"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		var count int64
+		database.DB.Model(&models.Partner{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Partner tidak ditemukan"})
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Status partner berhasil diperbarui"})
+}
+
+// POST /api/admin/partners/{id}/keys
+// The API key and signing secret are only shown in this response.
+func CreatePartnerKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req PartnerKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	now := utils.SystemClock.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "expires_at harus di masa depan"})
+		return
+	}
+
+	var partner models.Partner
+	if err := database.DB.First(&partner, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Partner tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	opts := utils.PartnerKeyOptions{Scopes: req.Scopes, ExpiresAt: req.ExpiresAt, RequireSignature: req.RequireSignature}
+	if adminID, ok := utils.GetAdminID(r); ok {
+		opts.CreatedBy = &adminID
+	}
+	issued, err := utils.IssuePartnerKey(database.DB, partner.ID, opts)
+	if err != nil {
+		writePartnerKeyError(w, err, "Gagal membuat kunci partner")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Kunci partner berhasil dibuat. Simpan api_key sekarang, kunci tidak dapat ditampilkan lagi",
+		Data:    toIssuedPartnerKeyResponse(issued),
+	})
+}
+
+// POST /api/admin/partner-keys/{id}/rotate
+func RotatePartnerKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := findPartnerKey(w, r)
+	if !ok {
+		return
+	}
+	var req RotatePartnerKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+			return
+		}
+	}
+	if req.GraceHours < 0 || req.GraceHours > 24*30 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "grace_hours harus antara 0 dan 720"})
+		return
+	}
+	if req.GraceHours == 0 {
+		req.GraceHours = 24
+	}
+	now := utils.SystemClock.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Kunci partner sudah tidak aktif"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "expires_at harus di masa depan"})
+		return
+	}
+
+	var createdBy *uint
+	if adminID, ok := utils.GetAdminID(r); ok {
+		createdBy = &adminID
+	}
+	issued, err := utils.RotatePartnerKey(database.DB, key, time.Duration(req.GraceHours)*time.Hour, req.ExpiresAt, createdBy, now)
+	if err != nil {
+		writePartnerKeyError(w, err, "Gagal merotasi kunci partner")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Kunci partner berhasil dirotasi. Kunci lama berlaku hingga " + key.ExpiresAt.Format(time.RFC3339),
+		Data:    toIssuedPartnerKeyResponse(issued),
+	})
+}
+
+// PUT /api/admin/partner-keys/{id}/revoke
+func RevokePartnerKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := findPartnerKey(w, r)
+	if !ok {
+		return
+	}
+	if key.RevokedAt != nil {
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Kunci partner sudah dicabut"})
+		return
+	}
+	now := utils.SystemClock.Now()
+	if err := database.DB.Model(key).Update("revoked_at", now).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mencabut kunci partner"})
+		return
+	}
+	key.RevokedAt = &now
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Kunci partner berhasil dicabut", Data: toPartnerKeyResponse(*key)})
+}
+
+// GET /api/admin/partner-audit-logs?partner_id=&order_id=&action=&page=&limit=
+func GetPartnerAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := database.DB.Model(&models.PartnerAuditLog{})
+	if partnerID := strings.TrimSpace(q.Get("partner_id")); partnerID != "" {
+		query = query.Where("partner_id = ?", partnerID)
+	}
+	if orderID := strings.TrimSpace(q.Get("order_id")); orderID != "" {
+		query = query.Where("order_id = ?", orderID)
+	}
+	if action := strings.TrimSpace(q.Get("action")); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if outcome := strings.TrimSpace(q.Get("outcome")); outcome != "" {
+		query = query.Where("outcome = ?", outcome)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil log partner"})
+		return
+	}
+	var logs []models.PartnerAuditLog
+	if err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil log partner"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"items": logs,
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
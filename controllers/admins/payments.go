@@ -43,7 +43,7 @@ func GetPayments(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	// Start query
-	db := database.DB
+	db := database.ReadDB()
 	query := db.Model(&models.Payment{})
 
 	// Apply filters
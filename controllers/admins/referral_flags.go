@@ -0,0 +1,246 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type ReferralFlagResponse struct {
+	models.ReferralFlag
+	UserName          string                     `json:"user_name"`
+	UserNumber        string                     `json:"user_number"`
+	UserBonusFrozen   bool                       `json:"user_bonus_frozen"`
+	RelatedUserName   string                     `json:"related_user_name,omitempty"`
+	RelatedUserNumber string                     `json:"related_user_number,omitempty"`
+	Signal            *models.RegistrationSignal `json:"signal,omitempty"`
+	HeldBonus         float64                    `json:"held_bonus"`
+}
+
+type ReferralFlagReviewRequest struct {
+	Note string `json:"note"`
+}
+
+type BonusFreezeRequest struct {
+	Frozen bool `json:"frozen"`
+	// When unfreezing, void the held bonuses instead of releasing them
+	Void bool `json:"void"`
+}
+
+// GET /api/admin/referral-flags?status=Pending&kind=device_cluster&user_id=1&page=1&limit=20
+func GetReferralFlags(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.DB.Model(&models.ReferralFlag{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("user_id = ? OR related_user_id = ?", userID, userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data peninjauan referral"})
+		return
+	}
+	var flags []models.ReferralFlag
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&flags).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data peninjauan referral"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(flags)*2)
+	flaggedIDs := make([]uint, 0, len(flags))
+	for _, f := range flags {
+		userIDs = append(userIDs, f.UserID)
+		flaggedIDs = append(flaggedIDs, f.UserID)
+		if f.RelatedUserID != nil {
+			userIDs = append(userIDs, *f.RelatedUserID)
+		}
+	}
+	usersByID := make(map[uint]models.User)
+	signalsByUser := make(map[uint]models.RegistrationSignal)
+	heldByUser := make(map[uint]float64)
+	if len(userIDs) > 0 {
+		var userRows []models.User
+		database.DB.Select("id", "name", "number", "bonus_frozen").Where("id IN ?", userIDs).Find(&userRows)
+		for _, u := range userRows {
+			usersByID[u.ID] = u
+		}
+		var signals []models.RegistrationSignal
+		database.DB.Where("user_id IN ?", flaggedIDs).Find(&signals)
+		for _, s := range signals {
+			signalsByUser[s.UserID] = s
+		}
+		var held []struct {
+			UserID uint
+			Total  float64
+		}
+		database.DB.Model(&models.Transaction{}).
+			Select("user_id, SUM(amount) AS total").
+			Where("user_id IN ? AND status = ? AND transaction_flow = ? AND transaction_type IN ?", flaggedIDs, "Pending", "debit", utils.HeldBonusTypes).
+			Group("user_id").
+			Scan(&held)
+		for _, h := range held {
+			heldByUser[h.UserID] = h.Total
+		}
+	}
+
+	response := make([]ReferralFlagResponse, 0, len(flags))
+	for _, f := range flags {
+		user := usersByID[f.UserID]
+		item := ReferralFlagResponse{
+			ReferralFlag:    f,
+			UserName:        user.Name,
+			UserNumber:      user.Number,
+			UserBonusFrozen: user.BonusFrozen,
+			HeldBonus:       heldByUser[f.UserID],
+		}
+		if f.RelatedUserID != nil {
+			related := usersByID[*f.RelatedUserID]
+			item.RelatedUserName = related.Name
+			item.RelatedUserNumber = related.Number
+		}
+		if s, ok := signalsByUser[f.UserID]; ok {
+			item.Signal = &s
+		}
+		response = append(response, item)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: map[string]interface{}{
+		"items": response,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	}})
+}
+
+// PUT /api/admin/referral-flags/{id}/clear
+// Marks the flag as a false positive; accounts with no other open flag are unfrozen and
+// their held bonuses are credited.
+func ClearReferralFlag(w http.ResponseWriter, r *http.Request) {
+	resolveReferralFlag(w, r, false)
+}
+
+// PUT /api/admin/referral-flags/{id}/confirm
+// Confirms the fraud; the flagged account's held bonuses are voided and it stays frozen.
+func ConfirmReferralFlag(w http.ResponseWriter, r *http.Request) {
+	resolveReferralFlag(w, r, true)
+}
+
+func resolveReferralFlag(w http.ResponseWriter, r *http.Request, confirm bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req ReferralFlagReviewRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	adminID, _ := utils.GetAdminID(r)
+
+	var flag *models.ReferralFlag
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		flag, err = utils.ResolveReferralFlag(tx, uint(id), confirm, adminID, strings.TrimSpace(req.Note), time.Now())
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Data peninjauan tidak ditemukan"})
+		case errors.Is(err, utils.ErrReferralFlagNotPending):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hanya peninjauan dengan status Pending yang dapat diproses"})
+		default:
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		}
+		return
+	}
+
+	message := "Peninjauan ditandai aman"
+	if confirm {
+		message = "Kecurangan referral dikonfirmasi"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: flag})
+}
+
+// PUT /api/admin/users/{id}/bonus-freeze
+// Freezes or unfreezes a user's bonuses by hand. Unfreezing releases the held bonuses,
+// or voids them when void is set.
+func SetUserBonusFreeze(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req BonusFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
+		return
+	}
+
+	var amount float64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Frozen {
+			res := tx.Model(&models.User{}).Where("id = ?", id).Update("bonus_frozen", true)
+			if res.Error == nil && res.RowsAffected == 0 {
+				var count int64
+				tx.Model(&models.User{}).Where("id = ?", id).Count(&count)
+				if count == 0 {
+					return gorm.ErrRecordNotFound
+				}
+			}
+			return res.Error
+		}
+		var err error
+		if req.Void {
+			if amount, err = utils.VoidHeldBonuses(tx, uint(id)); err != nil {
+				return err
+			}
+			return tx.Model(&models.User{}).Where("id = ?", id).Update("bonus_frozen", false).Error
+		}
+		amount, err = utils.ReleaseHeldBonuses(tx, uint(id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	message := "Bonus pengguna dibekukan"
+	data := map[string]interface{}{"user_id": id, "bonus_frozen": req.Frozen}
+	if !req.Frozen {
+		message = "Bonus pengguna dicairkan"
+		data["released"] = amount
+		if req.Void {
+			message = "Bonus tertahan dibatalkan"
+			delete(data, "released")
+			data["voided"] = amount
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: message, Data: data})
+}
@@ -21,6 +21,18 @@ type SettingRequest struct {
 	LinkCS         string  `json:"link_cs"`
 	LinkGroup      string  `json:"link_group"`
 	LinkApp        string  `json:"link_app"`
+
+	GiftDefaultExpiryHours int `json:"gift_default_expiry_hours"`
+	GiftMaxExpiryHours     int `json:"gift_max_expiry_hours"`
+
+	// nil keeps the current budget, 0 removes it
+	SpinDailyBudget *float64 `json:"spin_daily_budget"`
+	SpinTotalBudget *float64 `json:"spin_total_budget"`
+	// nil keeps the current value, 0 disables ticket expiry
+	SpinTicketExpiryDays *int `json:"spin_ticket_expiry_days"`
+	// nil keeps the current threshold, 0 disables the KYC requirement
+	KYCWithdrawalThreshold      *float64 `json:"kyc_withdrawal_threshold"`
+	KYCDailyWithdrawalThreshold *float64 `json:"kyc_daily_withdrawal_threshold"`
 }
 
 // GET /api/admin/settings
@@ -50,6 +62,17 @@ func GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		"link_cs":         setting.LinkCS,
 		"link_group":      setting.LinkGroup,
 		"link_app":        setting.LinkApp,
+
+		"gift_default_expiry_hours": setting.GiftDefaultExpiryHours,
+		"gift_max_expiry_hours":     setting.GiftMaxExpiryHours,
+
+		"spin_daily_budget": setting.SpinDailyBudget,
+		"spin_total_budget": setting.SpinTotalBudget,
+
+		"spin_ticket_expiry_days": setting.SpinTicketExpiryDays,
+
+		"kyc_withdrawal_threshold":       setting.KYCWithdrawalThreshold,
+		"kyc_daily_withdrawal_threshold": setting.KYCDailyWithdrawalThreshold,
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
@@ -95,6 +118,57 @@ func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	setting.LinkCS = req.LinkCS
 	setting.LinkGroup = req.LinkGroup
 	setting.LinkApp = req.LinkApp
+	// Zero keeps the current gift expiry values so older admin clients do not reset them
+	if req.GiftDefaultExpiryHours > 0 {
+		setting.GiftDefaultExpiryHours = req.GiftDefaultExpiryHours
+	}
+	if req.GiftMaxExpiryHours > 0 {
+		setting.GiftMaxExpiryHours = req.GiftMaxExpiryHours
+	}
+	if setting.GiftDefaultExpiryHours > setting.GiftMaxExpiryHours {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Masa berlaku default hadiah tidak boleh melebihi batas maksimal",
+		})
+		return
+	}
+
+	if (req.SpinDailyBudget != nil && *req.SpinDailyBudget < 0) || (req.SpinTotalBudget != nil && *req.SpinTotalBudget < 0) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Anggaran spin tidak boleh negatif",
+		})
+		return
+	}
+	if req.SpinTicketExpiryDays != nil {
+		if *req.SpinTicketExpiryDays < 0 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+				Success: false,
+				Message: "Masa berlaku tiket spin tidak boleh negatif",
+			})
+			return
+		}
+		setting.SpinTicketExpiryDays = *req.SpinTicketExpiryDays
+	}
+	if (req.KYCWithdrawalThreshold != nil && *req.KYCWithdrawalThreshold < 0) || (req.KYCDailyWithdrawalThreshold != nil && *req.KYCDailyWithdrawalThreshold < 0) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Batas penarikan KYC tidak boleh negatif",
+		})
+		return
+	}
+	if req.KYCWithdrawalThreshold != nil {
+		setting.KYCWithdrawalThreshold = *req.KYCWithdrawalThreshold
+	}
+	if req.KYCDailyWithdrawalThreshold != nil {
+		setting.KYCDailyWithdrawalThreshold = *req.KYCDailyWithdrawalThreshold
+	}
+	if req.SpinDailyBudget != nil {
+		setting.SpinDailyBudget = *req.SpinDailyBudget
+	}
+	if req.SpinTotalBudget != nil {
+		setting.SpinTotalBudget = *req.SpinTotalBudget
+	}
 
 	if err := db.Save(&setting).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
@@ -118,6 +192,17 @@ func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		"link_cs":         setting.LinkCS,
 		"link_group":      setting.LinkGroup,
 		"link_app":        setting.LinkApp,
+
+		"gift_default_expiry_hours": setting.GiftDefaultExpiryHours,
+		"gift_max_expiry_hours":     setting.GiftMaxExpiryHours,
+
+		"spin_daily_budget": setting.SpinDailyBudget,
+		"spin_total_budget": setting.SpinTotalBudget,
+
+		"spin_ticket_expiry_days": setting.SpinTicketExpiryDays,
+
+		"kyc_withdrawal_threshold":       setting.KYCWithdrawalThreshold,
+		"kyc_daily_withdrawal_threshold": setting.KYCDailyWithdrawalThreshold,
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
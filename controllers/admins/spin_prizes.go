@@ -22,6 +22,9 @@ type SpinPrizeResponse struct {
 	Status       string  `json:"status"`
 	TotalWins    int64   `json:"total_wins"`
 	TotalPaid    float64 `json:"total_paid"`
+
+	DailyQuantityLimit int `json:"daily_quantity_limit"`
+	TotalQuantityLimit int `json:"total_quantity_limit"`
 }
 
 type UpdateSpinPrizeRequest struct {
@@ -29,6 +32,10 @@ type UpdateSpinPrizeRequest struct {
 	Code         string  `json:"code"`
 	ChanceWeight int     `json:"chance_weight"`
 	Status       string  `json:"status"`
+
+	// nil keeps the current limit, 0 removes it
+	DailyQuantityLimit *int `json:"daily_quantity_limit"`
+	TotalQuantityLimit *int `json:"total_quantity_limit"`
 }
 
 func calculateChances(prizes []models.SpinPrize) []SpinPrizeResponse {
@@ -52,6 +59,9 @@ func calculateChances(prizes []models.SpinPrize) []SpinPrizeResponse {
 			ChanceWeight: prize.ChanceWeight,
 			Chance:       utils.RoundFloat(chance, 2), // Round to 2 decimal places
 			Status:       prize.Status,
+
+			DailyQuantityLimit: prize.DailyQuantityLimit,
+			TotalQuantityLimit: prize.TotalQuantityLimit,
 		})
 	}
 	return response
@@ -137,13 +147,30 @@ func UpdateSpinPrize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Amount <= 0 || req.ChanceWeight < 0 ||
+		(req.DailyQuantityLimit != nil && *req.DailyQuantityLimit < 0) ||
+		(req.TotalQuantityLimit != nil && *req.TotalQuantityLimit < 0) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: "Nominal, bobot dan batas kuantitas hadiah tidak valid",
+		})
+		return
+	}
+
 	// Update prize details
-	if err := database.DB.Model(&prize).Updates(map[string]interface{}{
+	updates := map[string]interface{}{
 		"amount":        req.Amount,
 		"code":          req.Code,
 		"chance_weight": req.ChanceWeight,
 		"status":        req.Status,
-	}).Error; err != nil {
+	}
+	if req.DailyQuantityLimit != nil {
+		updates["daily_quantity_limit"] = *req.DailyQuantityLimit
+	}
+	if req.TotalQuantityLimit != nil {
+		updates["total_quantity_limit"] = *req.TotalQuantityLimit
+	}
+	if err := database.DB.Model(&prize).Updates(updates).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal memperbarui hadiah",
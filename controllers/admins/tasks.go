@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // GET /api/admin/tasks
@@ -112,6 +113,28 @@ type TaskRequest struct {
 	RequiredLevel         int     `json:"required_level"`
 	RequiredActiveMembers int     `json:"required_active_members"`
 	Status                string  `json:"status"`
+	Type                  string  `json:"type"`
+	Criterion             string  `json:"criterion"`
+	TargetValue           float64 `json:"target_value"`
+}
+
+// applyTaskRequest copies req onto task; an empty type or criterion keeps the original
+// one-time team-size behaviour.
+func applyTaskRequest(task *models.Task, req TaskRequest) {
+	task.Name = req.Name
+	task.Reward = req.Reward
+	task.RequiredLevel = req.RequiredLevel
+	task.RequiredActiveMembers = int64(req.RequiredActiveMembers)
+	task.Status = req.Status
+	task.Type = req.Type
+	if task.Type == "" {
+		task.Type = utils.TaskTypeOneTime
+	}
+	task.Criterion = req.Criterion
+	if task.Criterion == "" {
+		task.Criterion = utils.TaskCriterionActiveMembers
+	}
+	task.TargetValue = req.TargetValue
 }
 
 // POST /api/admin/tasks
@@ -125,12 +148,14 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task := models.Task{
-		Name:                  req.Name,
-		Reward:                req.Reward,
-		RequiredLevel:         req.RequiredLevel,
-		RequiredActiveMembers: int64(req.RequiredActiveMembers),
-		Status:                req.Status,
+	var task models.Task
+	applyTaskRequest(&task, req)
+	if err := utils.ValidateTask(task); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
 	}
 
 	db := database.DB
@@ -174,13 +199,23 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task.Name = req.Name
-	task.Reward = req.Reward
-	task.RequiredLevel = req.RequiredLevel
-	task.RequiredActiveMembers = int64(req.RequiredActiveMembers)
-	task.Status = req.Status
+	applyTaskRequest(&task, req)
+	if err := utils.ValidateTask(task); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
 
-	if err := db.Save(&task).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&task).Error; err != nil {
+			return err
+		}
+		// Progress cached against the old criterion or target is no longer meaningful
+		return tx.Where("task_id = ?", task.ID).Delete(&models.UserTaskProgress{}).Error
+	})
+	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan sistem, silakan coba lagi",
@@ -195,6 +230,57 @@ func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// DELETE /api/admin/tasks/:id
+// Tasks that were already claimed are deactivated instead so the claim history stays intact.
+func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+	db := database.DB
+
+	var task models.Task
+	if err := db.First(&task, taskID).Error; err != nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+			Success: false,
+			Message: "Tugas tidak ditemukan",
+		})
+		return
+	}
+
+	var claims int64
+	if err := db.Model(&models.UserTask{}).Where("task_id = ?", task.ID).Count(&claims).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
+		})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", task.ID).Delete(&models.UserTaskProgress{}).Error; err != nil {
+			return err
+		}
+		if claims > 0 {
+			return tx.Model(&task).Update("status", "Inactive").Error
+		}
+		return tx.Delete(&task).Error
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
+		})
+		return
+	}
+
+	message := "Tugas berhasil dihapus"
+	if claims > 0 {
+		message = "Tugas sudah pernah diklaim sehingga dinonaktifkan"
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: message,
+	})
+}
+
 // GET /api/admin/user-tasks
 func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 	db := database.DB
@@ -249,6 +335,7 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 		TaskName  string
 		Reward    float64
 		ClaimedAt time.Time
+		PeriodKey string
 	}
 
 	var rows []rowScan
@@ -261,7 +348,8 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			ut.task_id,
 			t.name AS task_name,
 			t.reward AS reward,
-			ut.claimed_at
+			ut.claimed_at,
+			ut.period_key
 		`).
 		Order("ut.claimed_at DESC").
 		Offset(offset).
@@ -284,6 +372,7 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 		TaskName  string  `json:"task_name"`
 		Reward    float64 `json:"reward"`
 		ClaimedAt string  `json:"claimed_at"`
+		PeriodKey string  `json:"period_key"`
 	}
 
 	items := make([]UserTaskResponse, 0, len(rows))
@@ -297,6 +386,7 @@ func UserTasksHandler(w http.ResponseWriter, r *http.Request) {
 			TaskName:  r.TaskName,
 			Reward:    r.Reward,
 			ClaimedAt: r.ClaimedAt.Format(time.RFC3339),
+			PeriodKey: r.PeriodKey,
 		})
 	}
 
@@ -46,7 +46,7 @@ func GetTransactions(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	// Start query
-	db := database.DB
+	db := database.ReadDB()
 	query := db.Model(&models.Transaction{}).
 		Joins("JOIN users ON transactions.user_id = users.id").
 		Where("users.user_mode != ? OR users.user_mode IS NULL", "promotor")
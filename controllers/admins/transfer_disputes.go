@@ -0,0 +1,251 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransferDisputeResponse struct {
+	models.TransferDispute
+	SenderName     string `json:"sender_name"`
+	SenderNumber   string `json:"sender_number"`
+	ReceiverName   string `json:"receiver_name"`
+	ReceiverNumber string `json:"receiver_number"`
+}
+
+type TransferDisputeReviewRequest struct {
+	Note string `json:"note"`
+}
+
+var (
+	errDisputeNotPending = errors.New("dispute_not_pending")
+	errAlreadyReversed   = errors.New("transfer_already_reversed")
+)
+
+// GET /api/admin/transfer-disputes?status=Pending&page=1&limit=20
+func GetTransferDisputes(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	query := database.DB.Model(&models.TransferDispute{}).Preload("Transfer")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		query = query.Where("sender_id = ? OR receiver_id = ?", userID, userID)
+	}
+
+	var disputes []models.TransferDispute
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&disputes).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil data sengketa transfer"})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(disputes)*2)
+	for _, d := range disputes {
+		userIDs = append(userIDs, d.SenderID, d.ReceiverID)
+	}
+	var userRows []models.User
+	if len(userIDs) > 0 {
+		database.DB.Select("id", "name", "number").Where("id IN ?", userIDs).Find(&userRows)
+	}
+	usersByID := make(map[uint]models.User, len(userRows))
+	for _, u := range userRows {
+		usersByID[u.ID] = u
+	}
+
+	response := make([]TransferDisputeResponse, 0, len(disputes))
+	for _, d := range disputes {
+		sender, receiver := usersByID[d.SenderID], usersByID[d.ReceiverID]
+		response = append(response, TransferDisputeResponse{
+			TransferDispute: d,
+			SenderName:      sender.Name,
+			SenderNumber:    sender.Number,
+			ReceiverName:    receiver.Name,
+			ReceiverNumber:  receiver.Number,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: response})
+}
+
+// PUT /api/admin/transfer-disputes/{id}/reject
+func RejectTransferDispute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req TransferDisputeReviewRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	adminID, _ := utils.GetAdminID(r)
+
+	var dispute models.TransferDispute
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return err
+		}
+		if dispute.Status != "Pending" {
+			return errDisputeNotPending
+		}
+		now := time.Now()
+		dispute.Status = "Rejected"
+		dispute.ReviewedBy = &adminID
+		dispute.ReviewedAt = &now
+		if note := strings.TrimSpace(req.Note); note != "" {
+			dispute.AdminNote = &note
+		}
+		return tx.Save(&dispute).Error
+	})
+	if err != nil {
+		writeDisputeError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Sengketa transfer ditolak", Data: dispute})
+}
+
+// PUT /api/admin/transfer-disputes/{id}/reverse
+// Debits the receiver and refunds the sender atomically. If the receiver no longer has
+// enough balance, the balance goes negative and the uncovered part is recorded as the
+// shortfall; later incoming funds settle it before anything can be withdrawn.
+func ReverseTransferDispute(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req TransferDisputeReviewRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	adminID, _ := utils.GetAdminID(r)
+
+	var dispute models.TransferDispute
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return err
+		}
+		if dispute.Status != "Pending" {
+			return errDisputeNotPending
+		}
+
+		var transfer models.Transfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, dispute.TransferID).Error; err != nil {
+			return err
+		}
+		if transfer.ReversedAt != nil {
+			return errAlreadyReversed
+		}
+
+		// Lock both users in id order, same as TransferHandler
+		var sender, receiver models.User
+		first, second := &sender, &receiver
+		firstID, secondID := transfer.SenderID, transfer.ReceiverID
+		if secondID < firstID {
+			first, second = second, first
+			firstID, secondID = secondID, firstID
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(first, firstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(second, secondID).Error; err != nil {
+			return err
+		}
+
+		shortfall := 0.0
+		if receiver.Balance < transfer.Amount {
+			shortfall = utils.RoundFloat(transfer.Amount-math.Max(receiver.Balance, 0), 2)
+		}
+		if err := tx.Model(&receiver).Update("balance", utils.RoundFloat(receiver.Balance-transfer.Amount, 2)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&sender).Update("balance", utils.RoundFloat(sender.Balance+transfer.Amount, 2)).Error; err != nil {
+			return err
+		}
+
+		reversalOrderID := utils.GenerateOrderID(receiver.ID)
+		msgReceiver := fmt.Sprintf("Pembatalan transfer %s dari %s", transfer.ReceiverOrderID, sender.Name)
+		if err := tx.Create(&models.Transaction{
+			UserID:          receiver.ID,
+			Amount:          transfer.Amount,
+			OrderID:         reversalOrderID,
+			TransactionFlow: "credit",
+			TransactionType: "transfer_reversal",
+			Message:         &msgReceiver,
+			Status:          "Success",
+		}).Error; err != nil {
+			return err
+		}
+
+		refundOrderID := utils.GenerateOrderID(sender.ID)
+		msgSender := fmt.Sprintf("Pengembalian transfer %s ke %s", transfer.SenderOrderID, receiver.Name)
+		if err := tx.Create(&models.Transaction{
+			UserID:          sender.ID,
+			Amount:          transfer.Amount,
+			OrderID:         refundOrderID,
+			TransactionFlow: "debit",
+			TransactionType: "transfer_refund",
+			Message:         &msgSender,
+			Status:          "Success",
+		}).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&transfer).Updates(map[string]interface{}{
+			"reversed_at": now,
+			"hold_until":  nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		dispute.Status = "Reversed"
+		dispute.ReviewedBy = &adminID
+		dispute.ReviewedAt = &now
+		dispute.ShortfallAmount = shortfall
+		dispute.SenderRefundOrderID = &refundOrderID
+		dispute.ReceiverReversalOrderID = &reversalOrderID
+		if note := strings.TrimSpace(req.Note); note != "" {
+			dispute.AdminNote = &note
+		}
+		return tx.Save(&dispute).Error
+	})
+	if err != nil {
+		writeDisputeError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Transfer berhasil dibatalkan", Data: dispute})
+}
+
+func writeDisputeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Sengketa transfer tidak ditemukan"})
+	case errors.Is(err, errDisputeNotPending):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hanya sengketa dengan status Pending yang dapat diproses"})
+	case errors.Is(err, errAlreadyReversed):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Transfer ini sudah dibatalkan"})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+	}
+}
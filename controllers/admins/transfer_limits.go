@@ -0,0 +1,225 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type TransferLimitRequest struct {
+	MinAmount              float64 `json:"min_amount"`
+	MaxAmount              float64 `json:"max_amount"`
+	DailyOutLimit          float64 `json:"daily_out_limit"`
+	MonthlyOutLimit        float64 `json:"monthly_out_limit"`
+	DailyInLimit           float64 `json:"daily_in_limit"`
+	MonthlyInLimit         float64 `json:"monthly_in_limit"`
+	PerRecipientDailyLimit float64 `json:"per_recipient_daily_limit"`
+	HoldHours              int     `json:"hold_hours"`
+}
+
+// GET /api/admin/transfer-limits
+// Returns the effective limits for VIP 0-5, including defaults for levels without a row.
+func GetTransferLimits(w http.ResponseWriter, r *http.Request) {
+	var rows []models.TransferLimit
+	if err := database.DB.Order("vip_level ASC").Find(&rows).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil batas transfer"})
+		return
+	}
+	byLevel := make(map[uint]models.TransferLimit, len(rows))
+	for _, l := range rows {
+		byLevel[l.VIPLevel] = l
+	}
+	limits := make([]models.TransferLimit, 0, 6)
+	for level := uint(0); level <= 5; level++ {
+		if l, ok := byLevel[level]; ok {
+			limits = append(limits, l)
+			continue
+		}
+		limits = append(limits, utils.DefaultTransferLimit(level))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: limits})
+}
+
+// PUT /api/admin/transfer-limits/{level}
+func UpdateTransferLimit(w http.ResponseWriter, r *http.Request) {
+	level, err := strconv.ParseUint(mux.Vars(r)["level"], 10, 32)
+	if err != nil || level > 5 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Level VIP tidak valid"})
+		return
+	}
+
+	var req TransferLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	if req.MinAmount < 0 || req.MaxAmount < 0 || req.DailyOutLimit < 0 || req.MonthlyOutLimit < 0 ||
+		req.DailyInLimit < 0 || req.MonthlyInLimit < 0 || req.PerRecipientDailyLimit < 0 || req.HoldHours < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nilai batas tidak boleh negatif"})
+		return
+	}
+	if req.MaxAmount > 0 && req.MinAmount > req.MaxAmount {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Minimal transfer tidak boleh lebih besar dari maksimal"})
+		return
+	}
+
+	db := database.DB
+	var limit models.TransferLimit
+	if err := db.Where("vip_level = ?", level).First(&limit).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+			return
+		}
+		limit = models.TransferLimit{VIPLevel: uint(level)}
+	}
+
+	limit.MinAmount = req.MinAmount
+	limit.MaxAmount = req.MaxAmount
+	limit.DailyOutLimit = req.DailyOutLimit
+	limit.MonthlyOutLimit = req.MonthlyOutLimit
+	limit.DailyInLimit = req.DailyInLimit
+	limit.MonthlyInLimit = req.MonthlyInLimit
+	limit.PerRecipientDailyLimit = req.PerRecipientDailyLimit
+	limit.HoldHours = req.HoldHours
+
+	if err := db.Save(&limit).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan batas transfer"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Batas transfer berhasil diperbarui", Data: limit})
+}
+
+type TransferVelocityRuleRequest struct {
+	Name               string  `json:"name"`
+	WindowMinutes      int     `json:"window_minutes"`
+	MaxDistinctSenders int     `json:"max_distinct_senders"`
+	SmallAmountMax     float64 `json:"small_amount_max"`
+	Action             string  `json:"action"`
+	HoldHours          int     `json:"hold_hours"`
+	Status             string  `json:"status"`
+}
+
+func (req TransferVelocityRuleRequest) apply(rule *models.TransferVelocityRule) error {
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.WindowMinutes = req.WindowMinutes
+	rule.MaxDistinctSenders = req.MaxDistinctSenders
+	rule.SmallAmountMax = req.SmallAmountMax
+	rule.Action = req.Action
+	rule.HoldHours = req.HoldHours
+	rule.Status = req.Status
+	if rule.Status != "Active" && rule.Status != "Inactive" {
+		rule.Status = "Active"
+	}
+	if rule.Name == "" {
+		return errors.New("Nama aturan wajib diisi")
+	}
+	if rule.WindowMinutes <= 0 || rule.MaxDistinctSenders <= 0 {
+		return errors.New("window_minutes dan max_distinct_senders harus lebih dari 0")
+	}
+	if rule.SmallAmountMax < 0 {
+		return errors.New("small_amount_max tidak boleh negatif")
+	}
+	switch rule.Action {
+	case "block":
+	case "hold":
+		if rule.HoldHours <= 0 {
+			return errors.New("hold_hours harus lebih dari 0 untuk action hold")
+		}
+	default:
+		return errors.New("action harus block atau hold")
+	}
+	return nil
+}
+
+// GET /api/admin/transfer-velocity-rules
+func GetTransferVelocityRules(w http.ResponseWriter, r *http.Request) {
+	var rules []models.TransferVelocityRule
+	if err := database.DB.Order("id ASC").Find(&rules).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil aturan velocity"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: rules})
+}
+
+// POST /api/admin/transfer-velocity-rules
+func CreateTransferVelocityRule(w http.ResponseWriter, r *http.Request) {
+	var req TransferVelocityRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+	var rule models.TransferVelocityRule
+	if err := req.apply(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menambahkan aturan velocity"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Aturan velocity berhasil ditambahkan", Data: rule})
+}
+
+// PUT /api/admin/transfer-velocity-rules/{id}
+func UpdateTransferVelocityRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	var req TransferVelocityRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	db := database.DB
+	var rule models.TransferVelocityRule
+	if err := db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Aturan velocity tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if err := req.apply(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+	if err := db.Save(&rule).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui aturan velocity"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Aturan velocity berhasil diperbarui", Data: rule})
+}
+
+// DELETE /api/admin/transfer-velocity-rules/{id}
+func DeleteTransferVelocityRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+	res := database.DB.Delete(&models.TransferVelocityRule{}, id)
+	if res.Error != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus aturan velocity"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Aturan velocity tidak ditemukan"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Aturan velocity berhasil dihapus"})
+}
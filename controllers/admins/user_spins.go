@@ -1,6 +1,8 @@
 package admins
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,6 +10,9 @@ import (
 	"project/database"
 	"project/models"
 	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // GET /api/admin/user-spins
@@ -75,6 +80,8 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 		Amount  float64
 		Code    string
 		WonAt   time.Time
+		FallbackFromPrizeID *uint
+		FallbackReason      *string
 	}
 
 	var rows []rowScan
@@ -87,7 +94,9 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 			us.prize_id,
 			us.amount,
 			us.code,
-			us.won_at
+			us.won_at,
+			us.fallback_from_prize_id,
+			us.fallback_reason
 		`).
 		Order("us.won_at DESC").
 		Offset(offset).
@@ -109,10 +118,17 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 		Amount   float64 `json:"amount"`
 		Code     string  `json:"code"`
 		WonAt    string  `json:"won_at"`
+
+		FallbackFromPrizeID *uint  `json:"fallback_from_prize_id,omitempty"`
+		FallbackReason      string `json:"fallback_reason,omitempty"`
 	}
 
 	items := make([]UserSpinResponse, 0, len(rows))
 	for _, r := range rows {
+		fallbackReason := ""
+		if r.FallbackReason != nil {
+			fallbackReason = *r.FallbackReason
+		}
 		items = append(items, UserSpinResponse{
 			ID:       r.ID,
 			UserID:   r.UserID,
@@ -122,7 +138,19 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 			Amount:   r.Amount,
 			Code:     r.Code,
 			WonAt:    r.WonAt.Format(time.RFC3339),
+
+			FallbackFromPrizeID: r.FallbackFromPrizeID,
+			FallbackReason:      fallbackReason,
+		})
+	}
+
+	budget, err := spinBudgetReport()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+			Success: false,
+			Message: "Terjadi kesalahan sistem, silakan coba lagi",
 		})
+		return
 	}
 
 	// Wrap data
@@ -130,11 +158,13 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 		TotalWins int64               `json:"total_wins"`
 		TotalPaid float64             `json:"total_paid"`
 		Items     []UserSpinResponse  `json:"items"`
+		Budget    *SpinBudgetReport   `json:"budget"`
 	}
 	data := Data{
 		TotalWins: totalWins,
 		TotalPaid: agg.TotalPaid,
 		Items:     items,
+		Budget:    budget,
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
@@ -143,3 +173,106 @@ func UserSpinsHandler(w http.ResponseWriter, r *http.Request) {
 		Data:    data,
 	})
 }
+
+type SpinBudgetLine struct {
+	Limit     float64  `json:"limit"` // 0 = unlimited
+	Used      float64  `json:"used"`
+	Remaining *float64 `json:"remaining"`
+	Percent   *float64 `json:"percent"`
+}
+
+type SpinPrizeQuantityReport struct {
+	PrizeID            uint    `json:"prize_id"`
+	Code               string  `json:"code"`
+	Amount             float64 `json:"amount"`
+	Status             string  `json:"status"`
+	DailyQuantityLimit int     `json:"daily_quantity_limit"`
+	TotalQuantityLimit int     `json:"total_quantity_limit"`
+	WinsToday          int64   `json:"wins_today"`
+	WinsTotal          int64   `json:"wins_total"`
+	PaidToday          float64 `json:"paid_today"`
+	PaidTotal          float64 `json:"paid_total"`
+}
+
+type SpinBudgetReport struct {
+	Date           string                    `json:"date"`
+	Daily          SpinBudgetLine            `json:"daily"`
+	Total          SpinBudgetLine            `json:"total"`
+	FallbacksToday int64                     `json:"fallbacks_today"`
+	Prizes         []SpinPrizeQuantityReport `json:"prizes"`
+}
+
+func newSpinBudgetLine(limit, used float64) SpinBudgetLine {
+	line := SpinBudgetLine{Limit: limit, Used: used}
+	if limit > 0 {
+		remaining := utils.RoundFloat(math.Max(limit-used, 0), 2)
+		percent := utils.RoundFloat(used/limit*100, 2)
+		line.Remaining, line.Percent = &remaining, &percent
+	}
+	return line
+}
+
+// spinBudgetReport summarizes spin payouts against the budgets in settings and the
+// per-prize quantity limits.
+func spinBudgetReport() (*SpinBudgetReport, error) {
+	db := database.DB
+	now := time.Now()
+
+	var setting models.Setting
+	if err := db.First(&setting).Error; err != nil {
+		return nil, err
+	}
+	usage, err := utils.LoadSpinBudgetUsage(db, now)
+	if err != nil {
+		return nil, err
+	}
+	var prizes []models.SpinPrize
+	if err := db.Order("amount ASC, id ASC").Find(&prizes).Error; err != nil {
+		return nil, err
+	}
+
+	report := &SpinBudgetReport{
+		Date:           utils.DateKey(now.In(utils.JakartaLocation())),
+		Daily:          newSpinBudgetLine(setting.SpinDailyBudget, usage.PaidToday),
+		Total:          newSpinBudgetLine(setting.SpinTotalBudget, usage.PaidTotal),
+		FallbacksToday: usage.FallbacksToday,
+		Prizes:         make([]SpinPrizeQuantityReport, 0, len(prizes)),
+	}
+	for _, p := range prizes {
+		pu := usage.Prizes[p.ID]
+		report.Prizes = append(report.Prizes, SpinPrizeQuantityReport{
+			PrizeID:            p.ID,
+			Code:               p.Code,
+			Amount:             p.Amount,
+			Status:             p.Status,
+			DailyQuantityLimit: p.DailyQuantityLimit,
+			TotalQuantityLimit: p.TotalQuantityLimit,
+			WinsToday:          pu.WinsToday,
+			WinsTotal:          pu.WinsTotal,
+			PaidToday:          pu.PaidToday,
+			PaidTotal:          pu.PaidTotal,
+		})
+	}
+	return report, nil
+}
+
+// GET /api/admin/user-spins/{id}/verify
+func VerifyUserSpinHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	spin, seed, verification, err := utils.LoadSpinVerification(database.DB, uint(id))
+	switch {
+	case err == nil:
+		utils.WriteJSON(w, http.StatusOK, utils.SpinVerificationResponse(spin, seed, verification))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Riwayat spin tidak ditemukan"})
+	case errors.Is(err, utils.ErrSpinNotFair):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Spin ini dibuat sebelum sistem provably fair dan tidak dapat diverifikasi"})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+	}
+}
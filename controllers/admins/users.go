@@ -53,7 +53,7 @@ func GetUsers(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	// Start the query
-	db := database.DB
+	db := database.ReadDB()
 	query := db.Model(&models.User{})
 
 	// Apply filters
@@ -428,33 +428,10 @@ func UpdateUserBalance(w http.ResponseWriter, r *http.Request) {
 
 	switch req.Type {
 	case "add":
-		user.Balance += req.Amount
-
-		// Jalankan dalam transaksi: update saldo + buat log transaksi
+		// Update saldo + log transaksi; ditahan (Pending) selama bonus pengguna dibekukan
 		err = db.Transaction(func(tx *gorm.DB) error {
-			// Simpan perubahan saldo
-			if err := tx.Save(&user).Error; err != nil {
-				return err
-			}
-
-			// Buat record transaksi
-			msg := "Bonus saldo dari admin"
-			trx := models.Transaction{
-				UserID:          user.ID,
-				Amount:          req.Amount,
-				Charge:          0,
-				OrderID:         utils.GenerateOrderID(user.ID),
-				TransactionFlow: "debit",
-				TransactionType: "bonus",
-				Message:         &msg,
-				Status:          "Success",
-			}
-
-			if err := tx.Create(&trx).Error; err != nil {
-				return err
-			}
-
-			return nil
+			_, err := utils.CreditBonus(tx, user.ID, req.Amount, "bonus", "Bonus saldo dari admin")
+			return err
 		})
 
 		if err != nil {
@@ -0,0 +1,171 @@
+package admins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type WithdrawalRuleRequest struct {
+	RuleType      string  `json:"rule_type"`
+	VIPLevel      *uint   `json:"vip_level"`
+	Weekday       *int    `json:"weekday"`
+	Date          string  `json:"date"` // YYYY-MM-DD
+	StartTime     string  `json:"start_time"`
+	EndTime       string  `json:"end_time"`
+	LimitCount    int     `json:"limit_count"`
+	LimitAmount   float64 `json:"limit_amount"`
+	MinAmount     float64 `json:"min_amount"`
+	MaxAmount     float64 `json:"max_amount"`
+	CooldownHours int     `json:"cooldown_hours"`
+	Message       string  `json:"message"`
+	Status        string  `json:"status"`
+}
+
+func (req WithdrawalRuleRequest) apply(rule *models.WithdrawalRule) error {
+	rule.RuleType = strings.TrimSpace(req.RuleType)
+	rule.VIPLevel = req.VIPLevel
+	rule.Weekday = req.Weekday
+	rule.Date = nil
+	if d := strings.TrimSpace(req.Date); d != "" {
+		parsed, err := utils.ParseDate(d)
+		if err != nil {
+			return errors.New("date harus berformat YYYY-MM-DD")
+		}
+		rule.Date = &parsed
+	}
+	rule.StartTime = strings.TrimSpace(req.StartTime)
+	rule.EndTime = strings.TrimSpace(req.EndTime)
+	rule.LimitCount = req.LimitCount
+	rule.LimitAmount = req.LimitAmount
+	rule.MinAmount = req.MinAmount
+	rule.MaxAmount = req.MaxAmount
+	rule.CooldownHours = req.CooldownHours
+	rule.Message = strings.TrimSpace(req.Message)
+	rule.Status = req.Status
+	if rule.Status != "Active" && rule.Status != "Inactive" {
+		rule.Status = "Active"
+	}
+	return utils.ValidateWithdrawalRule(rule)
+}
+
+// GET /api/admin/withdrawal-rules
+func GetWithdrawalRules(w http.ResponseWriter, r *http.Request) {
+	query := database.DB.Model(&models.WithdrawalRule{})
+	if ruleType := strings.TrimSpace(r.URL.Query().Get("rule_type")); ruleType != "" {
+		query = query.Where("rule_type = ?", ruleType)
+	}
+	if status := strings.TrimSpace(r.URL.Query().Get("status")); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var rules []models.WithdrawalRule
+	if err := query.Order("rule_type ASC, vip_level ASC, id ASC").Find(&rules).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil aturan penarikan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    rules,
+	})
+}
+
+// POST /api/admin/withdrawal-rules
+func CreateWithdrawalRule(w http.ResponseWriter, r *http.Request) {
+	var req WithdrawalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	var rule models.WithdrawalRule
+	if err := req.apply(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menambahkan aturan penarikan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Aturan penarikan berhasil ditambahkan",
+		Data:    rule,
+	})
+}
+
+// PUT /api/admin/withdrawal-rules/{id}
+func UpdateWithdrawalRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	var req WithdrawalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+		return
+	}
+
+	db := database.DB
+	var rule models.WithdrawalRule
+	if err := db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Aturan penarikan tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	if err := req.apply(&rule); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	if err := db.Save(&rule).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui aturan penarikan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Aturan penarikan berhasil diperbarui",
+		Data:    rule,
+	})
+}
+
+// DELETE /api/admin/withdrawal-rules/{id}
+func DeleteWithdrawalRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	res := database.DB.Delete(&models.WithdrawalRule{}, id)
+	if res.Error != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus aturan penarikan"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Aturan penarikan tidak ditemukan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Aturan penarikan berhasil dihapus"})
+}
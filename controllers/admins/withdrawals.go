@@ -2,6 +2,8 @@ package admins
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -9,11 +11,13 @@ import (
 	"time"
 
 	"project/database"
+	"project/metrics"
 	"project/models"
 	"project/utils"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WithdrawalResponse struct {
@@ -117,6 +121,27 @@ func GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// errWithdrawalNotPending aborts an admin decision on a withdrawal that is no longer Pending
+var errWithdrawalNotPending = errors.New("withdrawal not pending")
+
+// writeAdminWithdrawalError maps the errors of the locked approve/reject step.
+func writeAdminWithdrawalError(w http.ResponseWriter, err error, withdrawal *models.Withdrawal, notPendingMessage string) {
+	switch {
+	case errors.Is(err, utils.ErrPartnerWithdrawalNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Penarikan tidak ditemukan"})
+	case errors.Is(err, errWithdrawalNotPending):
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: notPendingMessage})
+	case errors.Is(err, utils.ErrPartnerWithdrawalClaimed):
+		// A partner holding the claim may be paying it right now
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{
+			Success: false,
+			Message: "Penarikan sedang diproses oleh partner hingga " + withdrawal.ClaimExpiresAt.Format(time.RFC3339),
+		})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui status penarikan"})
+	}
+}
+
 func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 	client := &http.Client{Timeout: 30 * time.Second}
 	vars := mux.Vars(r)
@@ -129,26 +154,19 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var withdrawal models.Withdrawal
-	if err := database.DB.First(&withdrawal, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
-				Success: false,
-				Message: "Penarikan tidak ditemukan",
-			})
-			return
-		}
+	// Banks do not settle transfers on national holidays
+	holidays, err := utils.LoadHolidayCalendarAround(database.DB, utils.SystemClock.Now())
+	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Gagal mengambil data penarikan",
+			Message: "Gagal mengambil kalender hari libur",
 		})
 		return
 	}
-
-	if withdrawal.Status != "Pending" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+	if name, ok := holidays.HolidayOn(utils.SystemClock.Now()); ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Hanya penarikan dengan status Pending yang dapat disetujui",
+			Message: fmt.Sprintf("Penarikan tidak dapat disetujui pada hari libur nasional (%s)", name),
 		})
 		return
 	}
@@ -162,36 +180,50 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check auto_withdraw setting
-	if !setting.AutoWithdraw {
-		tx := database.DB.Begin()
-
-		withdrawal.Status = "Success"
-		if err := tx.Save(&withdrawal).Error; err != nil {
-			tx.Rollback()
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-				Success: false,
-				Message: "Gagal memperbarui status penarikan",
-			})
-			return
+	// The partner claim check and the decision happen under the row lock, so a partner
+	// cannot claim the withdrawal in between. Manual approval settles it right away;
+	// auto withdrawal marks it as sent to the gateway, which takes it out of the claim pool.
+	var withdrawal *models.Withdrawal
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		withdrawal, err = utils.LockWithdrawalForAdmin(tx, uint(id), utils.SystemClock.Now())
+		if err != nil {
+			return err
 		}
-
-		if err := tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Success").Error; err != nil {
-			tx.Rollback()
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memperbarui status transaksi"})
-			return
+		if withdrawal.Status != "Pending" {
+			return errWithdrawalNotPending
 		}
-
-		if err := tx.Commit().Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan perubahan"})
-			return
+		if setting.AutoWithdraw {
+			return tx.Model(withdrawal).Update("gateway_dispatched_at", utils.SystemClock.Now()).Error
+		}
+		withdrawal.Status = "Success"
+		if err := tx.Model(withdrawal).Update("status", "Success").Error; err != nil {
+			return err
 		}
+		return tx.Model(&models.Transaction{}).Where("order_id = ?", withdrawal.OrderID).Update("status", "Success").Error
+	})
+	if err != nil {
+		writeAdminWithdrawalError(w, err, withdrawal, "Hanya penarikan dengan status Pending yang dapat disetujui")
+		return
+	}
 
+	if !setting.AutoWithdraw {
 		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Penarikan berhasil disetujui (transfer manual)"})
 		return
 	}
 
-	// Auto withdrawal using Pakailink
+	// Auto withdrawal using Pakailink. Unless the gateway accepts the request, no
+	// callback will clear the dispatch mark, so put it back in the partner claim pool.
+	sent := false
+	defer func() {
+		if sent {
+			return
+		}
+		if err := utils.ClearGatewayDispatch(database.DB, withdrawal.ID); err != nil {
+			log.Printf("[Pakailink] Clear gateway dispatch for %s: %v", withdrawal.OrderID, err)
+		}
+	}()
+
 	var ba models.BankAccount
 	if err := database.DB.Preload("Bank").First(&ba, withdrawal.BankAccountID).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengambil rekening"})
@@ -233,12 +265,14 @@ func ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		log.Printf("[Pakailink] Payout error: %v", err)
+		metrics.PayoutFailures.WithLabelValues("pakailink", "request").Inc()
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Terjadi kesalahan saat memproses transfer",
 		})
 		return
 	}
+	sent = true
 
 	// Pakailink returns 2004300/2003800 = request accepted, status Pending. Callback akan update ke Success/Failed.
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
@@ -262,83 +296,51 @@ func RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var withdrawal models.Withdrawal
-	if err := database.DB.First(&withdrawal, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
-				Success: false,
-				Message: "Penarikan tidak ditemukan",
-			})
-			return
+	var withdrawal *models.Withdrawal
+	var failMessage string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		withdrawal, err = utils.LockWithdrawalForAdmin(tx, uint(id), utils.SystemClock.Now())
+		if err != nil {
+			return err
+		}
+		// Only allow rejecting pending withdrawals
+		if withdrawal.Status != "Pending" {
+			return errWithdrawalNotPending
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal mengambil data penarikan",
-		})
-		return
-	}
-
-	// Only allow rejecting pending withdrawals
-	if withdrawal.Status != "Pending" {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Hanya penarikan dengan status Pending yang dapat ditolak",
-		})
-		return
-	}
-
-	// Start transaction
-	tx := database.DB.Begin()
-
-	// Update withdrawal status
-	withdrawal.Status = "Failed"
-	if err := tx.Save(&withdrawal).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status penarikan",
-		})
-		return
-	}
-
-	// Update related transaction status
-	if err := tx.Model(&models.Transaction{}).
-		Where("order_id = ?", withdrawal.OrderID).
-		Update("status", "Failed").Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status transaksi",
-		})
-		return
-	}
 
-	// Refund the amount to user's balance
-	var user models.User
-	if err := tx.First(&user, withdrawal.UserID).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal mengambil data pengguna",
-		})
-		return
-	}
+		// Update withdrawal status
+		withdrawal.Status = "Failed"
+		if err := tx.Model(withdrawal).Update("status", "Failed").Error; err != nil {
+			return err
+		}
 
-	user.Balance += withdrawal.Amount
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui saldo pengguna",
-		})
-		return
-	}
+		// Update related transaction status
+		if err := tx.Model(&models.Transaction{}).
+			Where("order_id = ?", withdrawal.OrderID).
+			Update("status", "Failed").Error; err != nil {
+			failMessage = "Gagal memperbarui status transaksi"
+			return err
+		}
 
-	if err := tx.Commit().Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal menyimpan perubahan",
-		})
+		// Refund the amount to user's balance
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, withdrawal.UserID).Error; err != nil {
+			failMessage = "Gagal mengambil data pengguna"
+			return err
+		}
+		if err := tx.Model(&user).Update("balance", user.Balance+withdrawal.Amount).Error; err != nil {
+			failMessage = "Gagal memperbarui saldo pengguna"
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if failMessage != "" {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: failMessage})
+			return
+		}
+		writeAdminWithdrawalError(w, err, withdrawal, "Hanya penarikan dengan status Pending yang dapat ditolak")
 		return
 	}
 
@@ -477,6 +479,7 @@ func PakailinkPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 
 	// If status is Failed, update withdrawal status to Pending (for admin to retry)
 	withdrawal.Status = "Pending"
+	withdrawal.GatewayDispatchedAt = nil
 	if err := tx.Save(&withdrawal).Error; err != nil {
 		tx.Rollback()
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
@@ -504,6 +507,7 @@ func PakailinkPayoutCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	metrics.PayoutFailures.WithLabelValues("pakailink", "callback").Inc()
 
 	writePakailinkPayoutSuccess()
 }
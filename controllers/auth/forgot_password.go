@@ -35,12 +35,19 @@ type ForgotPasswordResetPasswordRequest struct {
 	Token           string `json:"token"`
 }
 
+// OTP request purposes. Each flow only replaces and accepts requests of its own purpose.
+const (
+	OTPPurposePasswordReset = "password_reset"
+	OTPPurposePINReset      = "pin_reset"
+)
+
 // OTPRequest stores OTP request information
 type OTPRequest struct {
 	ID        uint      `gorm:"primaryKey"`
 	UserID    uint      `gorm:"not null;index"`
 	Phone     string    `gorm:"size:20;not null;index"`
 	OTPID     string    `gorm:"type:varchar(255);not null"`
+	Purpose   string    `gorm:"size:20;not null;default:password_reset"`
 	Verified  bool      `gorm:"default:false"`
 	ExpiresAt time.Time `gorm:"not null"`
 	CreatedAt time.Time
@@ -149,8 +156,9 @@ func ForgotPasswordRequestOTPHandler(w http.ResponseWriter, r *http.Request) {
 		UserID:    user.ID,
 		Phone:     req.Number,
 		OTPID:     fazpassResp.Data.ID,
+		Purpose:   OTPPurposePasswordReset,
 		Verified:  false,
-		ExpiresAt: time.Now().Add(10 * time.Minute),
+		ExpiresAt: utils.SystemClock.Now().Add(10 * time.Minute),
 	}
 
 	if err := db.Create(&otpReq).Error; err != nil {
@@ -273,12 +281,13 @@ func ForgotPasswordResendOTPHandler(w http.ResponseWriter, r *http.Request) {
 		UserID:    user.ID,
 		Phone:     req.Number,
 		OTPID:     fazpassResp.Data.ID,
+		Purpose:   OTPPurposePasswordReset,
 		Verified:  false,
-		ExpiresAt: time.Now().Add(10 * time.Minute),
+		ExpiresAt: utils.SystemClock.Now().Add(10 * time.Minute),
 	}
 
 	// Delete old unverified OTP requests for this phone
-	db.Where("phone = ? AND verified = ?", req.Number, false).Delete(&OTPRequest{})
+	db.Where("phone = ? AND purpose = ? AND verified = ?", req.Number, OTPPurposePasswordReset, false).Delete(&OTPRequest{})
 
 	// Create new OTP request
 	if err := db.Create(&otpReq).Error; err != nil {
@@ -334,7 +343,7 @@ func ForgotPasswordVerifyOTPHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Find OTP request
 	var otpReq OTPRequest
-	if err := db.Where("otp_id = ? AND verified = ?", req.RequestID, false).First(&otpReq).Error; err != nil {
+	if err := db.Where("otp_id = ? AND purpose = ? AND verified = ?", req.RequestID, OTPPurposePasswordReset, false).First(&otpReq).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
 				Success: false,
@@ -350,7 +359,7 @@ func ForgotPasswordVerifyOTPHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if OTP request has expired
-	if time.Now().After(otpReq.ExpiresAt) {
+	if utils.SystemClock.Now().After(otpReq.ExpiresAt) {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
 			Message: "Kode Verifikasi sudah kadaluarsa",
@@ -557,13 +566,13 @@ func ForgotPasswordResetPasswordHandler(w http.ResponseWriter, r *http.Request)
 			switch v := expRaw.(type) {
 			case float64:
 				expTime := time.Unix(int64(v), 0)
-				ttl = time.Until(expTime)
+				ttl = expTime.Sub(utils.SystemClock.Now())
 			case int64:
 				expTime := time.Unix(v, 0)
-				ttl = time.Until(expTime)
+				ttl = expTime.Sub(utils.SystemClock.Now())
 			case int:
 				expTime := time.Unix(int64(v), 0)
-				ttl = time.Until(expTime)
+				ttl = expTime.Sub(utils.SystemClock.Now())
 			}
 		}
 		if ttl < 0 {
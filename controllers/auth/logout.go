@@ -41,13 +41,13 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 						switch v := expRaw.(type) {
 						case float64:
 							expTime := time.Unix(int64(v), 0)
-							ttl = time.Until(expTime)
+							ttl = expTime.Sub(utils.SystemClock.Now())
 						case int64:
 							expTime := time.Unix(v, 0)
-							ttl = time.Until(expTime)
+							ttl = expTime.Sub(utils.SystemClock.Now())
 						case int:
 							expTime := time.Unix(int64(v), 0)
-							ttl = time.Until(expTime)
+							ttl = expTime.Sub(utils.SystemClock.Now())
 						}
 					}
 					if ttl < 0 {
@@ -58,10 +58,10 @@ func RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	isApp := req.IsApp != nil && *req.IsApp
 	if isApp {
 		tokenExpiry = 30 * 24 * time.Hour // 30 days
-		exp = time.Now().Add(tokenExpiry)
+		exp = utils.SystemClock.Now().Add(tokenExpiry)
 	} else {
 		tokenExpiry = 15 * time.Minute // Default 15 minutes
-		exp = time.Now().Add(tokenExpiry)
+		exp = utils.SystemClock.Now().Add(tokenExpiry)
 	}
 
 	// issue new access token
@@ -124,39 +124,63 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Registration signals for referral fraud detection
+	now := utils.SystemClock.Now()
+	signal := models.RegistrationSignal{
+		ReffBy:    reffBy,
+		IP:        truncate(middleware.GetClientIP(r), 45),
+		DeviceID:  truncate(strings.TrimSpace(r.Header.Get(utils.DeviceFingerprintHeader)), 128),
+		UserAgent: truncate(r.UserAgent(), 255),
+	}
+	evidence, err := utils.CollectRegistrationEvidence(db, signal, now)
+	if err != nil {
+		log.Printf("[register] collect fraud evidence error: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
+		return
+	}
+	findings := utils.EvaluateRegistration(evidence)
+
 	newUser := models.User{
 		Name:            req.Name,
 		Number:          req.Number,
 		Password:        string(hashed),
 		ReffCode:        code,
 		ReffBy:          reffBy,
-		Balance:         2000,
+		Balance:         0,
 		TotalInvest:     0,
 		Status:          "Active",
 		StatusPublisher: "Inactive",
+		BonusFrozen:     len(findings) > 0,
 	}
 
-	if err := db.Create(&newUser).Error; err != nil {
+	var bonusStatus string
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		if err := utils.AddReferralClosure(tx, newUser.ID, newUser.ReffBy); err != nil {
+			return err
+		}
+		signal.UserID = newUser.ID
+		if err := tx.Create(&signal).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordReferralFlags(tx, newUser.ID, findings); err != nil {
+			return err
+		}
+		// Held as Pending while the account is under review
+		bonusStatus, err = utils.CreditBonus(tx, newUser.ID, 2000, "bonus", "Bonus pendaftaran")
+		return err
+	}); err != nil {
 		log.Printf("[register] DB Create user error: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Registrasi gagal, silakan coba lagi"})
 		return
 	}
-
-	newTransaction := models.Transaction{
-		UserID:          newUser.ID,
-		Amount:          2000,
-		Charge:          0,
-		OrderID:         utils.GenerateOrderID(newUser.ID),
-		TransactionFlow: "debit",
-		TransactionType: "bonus",
-		Message:         ptrString("Bonus pendaftaran"),
-		Status:          "Success",
+	if bonusStatus == "Success" {
+		newUser.Balance = 2000
 	}
-
-	if err := db.Create(&newTransaction).Error; err != nil {
-		log.Printf("[register] DB Create transaction error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Server error"})
-		return
+	if len(findings) > 0 {
+		log.Printf("[register] user %d flagged for referral review: %d finding(s)", newUser.ID, len(findings))
 	}
 
 	// Determine token expiry based on is_app flag
@@ -165,10 +189,10 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	isApp := req.IsApp != nil && *req.IsApp
 	if isApp {
 		tokenExpiry = 30 * 24 * time.Hour // 30 days
-		exp = time.Now().Add(tokenExpiry)
+		exp = utils.SystemClock.Now().Add(tokenExpiry)
 	} else {
 		tokenExpiry = 15 * time.Minute // Default 15 minutes
-		exp = time.Now().Add(tokenExpiry)
+		exp = utils.SystemClock.Now().Add(tokenExpiry)
 	}
 
 	// Generate access and refresh tokens
@@ -271,3 +295,11 @@ func randomString(alphabet string, length int) (string, error) {
 func ptrString(s string) *string {
 	return &s
 }
+
+// truncate cuts s to at most n bytes so it fits its column.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -47,16 +47,6 @@ func InfoPublicHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /v3/all-user-balance
 func GetAllUserBalanceHandler(w http.ResponseWriter, r *http.Request) {
-	// Validate X-VLA-KEY header
-	vlaKey := r.Header.Get("X-VLA-KEY")
-	if vlaKey != "VLADMIN" {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
-		return
-	}
-
 	db := database.DB
 
 	// Query users with balance >= 50000
@@ -98,16 +88,6 @@ func GetAllUserBalanceHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /v3/information/investment
 func GetInvestmentInformationHandler(w http.ResponseWriter, r *http.Request) {
-	// Validate X-VLA-KEY header
-	vlaKey := r.Header.Get("X-VLA-KEY")
-	if vlaKey != "VLA010124" {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
-		return
-	}
-
 	db := database.DB
 
 	// Get total purchased and total amount for Running/Completed investments
@@ -223,16 +203,6 @@ func GetInvestmentInformationHandler(w http.ResponseWriter, r *http.Request) {
 
 // GET /v3/information/withdrawal
 func GetWithdrawalInformationHandler(w http.ResponseWriter, r *http.Request) {
-	// Validate X-VLA-KEY header
-	vlaKey := r.Header.Get("X-VLA-KEY")
-	if vlaKey != "VLA010124" {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
-		return
-	}
-
 	db := database.DB
 
 	// Get total withdrawals with Success status
@@ -275,16 +245,6 @@ func GetWithdrawalInformationHandler(w http.ResponseWriter, r *http.Request) {
 
 // POST /v3/management-transactions
 func ManagementTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	// Validate X-VLA-KEY header
-	vlaKey := r.Header.Get("X-VLA-KEY")
-	if vlaKey != "VLA010124" {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
-		return
-	}
-
 	var req struct {
 		Name   string `json:"name"`
 		Number string `json:"number"`
@@ -390,7 +350,12 @@ func ManagementTransactionsHandler(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:       userDate,
 	}
 
-	if err := db.Create(&newUser).Error; err != nil {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return utils.AddReferralClosure(tx, newUser.ID, newUser.ReffBy)
+	}); err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal membuat user",
@@ -148,30 +148,9 @@ func NewsRewardHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Update user balance and create transaction in a single transaction
 	err := db.Transaction(func(tx *gorm.DB) error {
-		// Update user balance
-		newBalance := user.Balance + req.Amount
-		if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-			return err
-		}
-
-		// Create transaction record
-		msg := "Bonus publish berita terbaru"
-		trx := models.Transaction{
-			UserID:          user.ID,
-			Amount:          req.Amount,
-			Charge:          0,
-			OrderID:         utils.GenerateOrderID(user.ID),
-			TransactionFlow: "debit",
-			TransactionType: "bonus",
-			Message:         &msg,
-			Status:          "Success",
-		}
-
-		if err := tx.Create(&trx).Error; err != nil {
-			return err
-		}
-
-		return nil
+		// Held as Pending while the user's bonuses are frozen for review
+		_, err := utils.CreditBonus(tx, user.ID, req.Amount, "bonus", "Bonus publish berita terbaru")
+		return err
 	})
 
 	if err != nil {
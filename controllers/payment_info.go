@@ -11,8 +11,6 @@ import (
 	"gorm.io/gorm"
 )
 
-const vlaKey = "VLA010124"
-
 func getSingletonPaymentSettings(db *gorm.DB) (*models.PaymentSettings, error) {
 	var ps models.PaymentSettings
 	if err := db.First(&ps).Error; err != nil {
@@ -23,10 +21,6 @@ func getSingletonPaymentSettings(db *gorm.DB) (*models.PaymentSettings, error) {
 
 // GET /api/payment_info
 func GetPaymentInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get("X-VLA-KEY") != vlaKey {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
-		return
-	}
 	ps, err := getSingletonPaymentSettings(database.DB)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "payment_settings not found"})
@@ -37,11 +31,6 @@ func GetPaymentInfo(w http.ResponseWriter, r *http.Request) {
 
 // PUT /api/payment_info
 func PutPaymentInfo(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get("X-VLA-KEY") != vlaKey {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
-		return
-	}
-	
 	var body models.PaymentSettings
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid JSON"})
@@ -2,15 +2,24 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"project/metrics"
+	"project/middleware"
 	"project/models"
 	"project/utils"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+// SFXCRController serves the StoneForm withdrawal processing API. Partners browse
+// pending withdrawals without personal data, claim a batch to get the payout details,
+// then report each result. Every call is written to the partner audit trail.
 type SFXCRController struct {
 	DB *gorm.DB
 }
@@ -19,47 +28,114 @@ func NewSFXCRController(db *gorm.DB) *SFXCRController {
 	return &SFXCRController{DB: db}
 }
 
-// GetPendingWithdrawals - API untuk StoneForm mengambil pending withdrawals
-func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.Request) {
-	// Verifikasi API key
-	if !c.verifyAPIKey(r) {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
-		return
+// pendingWithdrawalView is a pending withdrawal as listed to partners: enough to plan a
+// claim, nothing that identifies the user.
+type pendingWithdrawalView struct {
+	OrderID        string     `json:"order_id"`
+	BankName       string     `json:"bank_name"`
+	AccountNumber  string     `json:"account_number"` // last four digits only
+	FinalAmount    float64    `json:"final_amount"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClaimedByYou   bool       `json:"claimed_by_you"`
+	ClaimExpiresAt *time.Time `json:"claim_expires_at,omitempty"`
+}
+
+type pendingWithdrawalRow struct {
+	OrderID            string
+	BankName           string
+	AccountNumber      string
+	FinalAmount        float64
+	CreatedAt          time.Time
+	ClaimedByPartnerID *uint
+	ClaimExpiresAt     *time.Time
+}
+
+func maskAccountTail(accountNumber string) string {
+	if len(accountNumber) <= 4 {
+		return strings.Repeat("*", len(accountNumber))
+	}
+	return strings.Repeat("*", len(accountNumber)-4) + accountNumber[len(accountNumber)-4:]
+}
+
+func (row pendingWithdrawalRow) view(partnerID uint) pendingWithdrawalView {
+	v := pendingWithdrawalView{
+		OrderID:       row.OrderID,
+		BankName:      row.BankName,
+		AccountNumber: maskAccountTail(row.AccountNumber),
+		FinalAmount:   row.FinalAmount,
+		CreatedAt:     row.CreatedAt,
+	}
+	if row.ClaimedByPartnerID != nil && *row.ClaimedByPartnerID == partnerID {
+		v.ClaimedByYou = true
+		v.ClaimExpiresAt = row.ClaimExpiresAt
 	}
+	return v
+}
 
-	var withdrawals []struct {
-		UserID        uint    `json:"user_id"`
-		UserName      string  `json:"user_name"`
-		Phone         string  `json:"phone"`
-		BankAccountID uint    `json:"bank_account_id"`
-		BankName      string  `json:"bank_name"`
-		AccountName   string  `json:"account_name"`
-		AccountNumber string  `json:"account_number"`
-		Amount        float64 `json:"amount"`
-		Charge        float64 `json:"charge"`
-		FinalAmount   float64 `json:"final_amount"`
-		OrderID       string  `json:"order_id"`
-		Status        string  `json:"status"`
-		CreatedAt     string  `json:"created_at"`
-	}
-
-	// Query pending withdrawals dengan join ke tabel terkait
-	err := c.DB.Table("withdrawals").
-		Select("withdrawals.user_id, users.name as user_name, users.number as phone, withdrawals.bank_account_id, "+
-			"banks.name as bank_name, bank_accounts.account_name, bank_accounts.account_number, "+
-			"withdrawals.amount, withdrawals.charge, withdrawals.final_amount, "+
-			"withdrawals.order_id, withdrawals.status, withdrawals.created_at").
-		Joins("JOIN users ON withdrawals.user_id = users.id").
+// pendingQuery selects pending withdrawals the partner may see at now: claimable ones
+// and the ones it holds.
+func (c *SFXCRController) pendingQuery(partnerID uint, now time.Time) *gorm.DB {
+	return c.DB.Table("withdrawals").
+		Select("withdrawals.order_id, banks.name AS bank_name, bank_accounts.account_number, "+
+			"withdrawals.final_amount, withdrawals.created_at, withdrawals.claimed_by_partner_id, withdrawals.claim_expires_at").
 		Joins("JOIN bank_accounts ON withdrawals.bank_account_id = bank_accounts.id").
 		Joins("JOIN banks ON bank_accounts.bank_id = banks.id").
-		Where("withdrawals.status = ?", "Pending").
-		Order("withdrawals.created_at ASC").
-		Find(&withdrawals).Error
+		Where("withdrawals.status = ? AND withdrawals.gateway_dispatched_at IS NULL", "Pending").
+		Where("((withdrawals.claim_expires_at IS NULL OR withdrawals.claim_expires_at <= ?) AND withdrawals.partner_failures < ?)"+
+			" OR (withdrawals.claimed_by_partner_id = ? AND withdrawals.claim_expires_at > ?)",
+			now, utils.PartnerMaxFailures, partnerID, now)
+}
 
+// audit records a partner action; outcome is "rejected" when err is set.
+func (c *SFXCRController) audit(r *http.Request, action, orderID string, err error, detail string) {
+	partnerID, _ := utils.GetPartnerID(r)
+	keyID, _ := utils.GetPartnerKeyID(r)
+	entry := models.PartnerAuditLog{
+		PartnerID:    partnerID,
+		PartnerKeyID: keyID,
+		Action:       action,
+		Outcome:      "ok",
+		Detail:       detail,
+		IP:           middleware.GetClientIP(r),
+	}
+	if orderID != "" {
+		entry.OrderID = &orderID
+	}
 	if err != nil {
+		entry.Outcome = "rejected"
+		if entry.Detail == "" {
+			entry.Detail = err.Error()
+		}
+	}
+	if err := utils.RecordPartnerAction(c.DB, entry); err != nil {
+		log.Printf("[sfxcr] audit %s %s partner %d: %v", action, orderID, partnerID, err)
+	}
+}
+
+// writeClaimError maps claim/report errors to responses.
+func writeClaimError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, utils.ErrPartnerWithdrawalNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Penarikan tidak ditemukan"})
+	case errors.Is(err, utils.ErrPartnerWithdrawalNotClaimed):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Penarikan tidak sedang di-claim oleh partner ini"})
+	case errors.Is(err, utils.ErrPartnerWithdrawalSettled):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Penarikan sudah diselesaikan"})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal memproses penarikan"})
+	}
+}
+
+// GetPendingWithdrawals - API untuk StoneForm melihat pending withdrawals (tanpa data pribadi)
+func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.Request) {
+	partnerID, _ := utils.GetPartnerID(r)
+	now := utils.SystemClock.Now()
+
+	var rows []pendingWithdrawalRow
+	if err := c.pendingQuery(partnerID, now).
+		Order("withdrawals.created_at ASC, withdrawals.id ASC").
+		Scan(&rows).Error; err != nil {
+		c.audit(r, "list_pending", "", err, "query failed")
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
 			Message: "Gagal mengambil data penarikan",
@@ -67,12 +143,11 @@ func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Format created_at
-	for i := range withdrawals {
-		if createdAt, err := time.Parse(time.RFC3339, withdrawals[i].CreatedAt); err == nil {
-			withdrawals[i].CreatedAt = createdAt.Format(time.RFC3339)
-		}
+	withdrawals := make([]pendingWithdrawalView, 0, len(rows))
+	for _, row := range rows {
+		withdrawals = append(withdrawals, row.view(partnerID))
 	}
+	c.audit(r, "list_pending", "", nil, fmt.Sprintf("%d withdrawals", len(withdrawals)))
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
@@ -81,81 +156,110 @@ func (c *SFXCRController) GetPendingWithdrawals(w http.ResponseWriter, r *http.R
 	})
 }
 
-// GetPendingWithdrawalByOrderID - API untuk mengambil data withdrawal spesifik
+// GetPendingWithdrawalByOrderID - API untuk mengambil data withdrawal spesifik (tanpa data pribadi)
 func (c *SFXCRController) GetPendingWithdrawalByOrderID(w http.ResponseWriter, r *http.Request) {
-	if !c.verifyAPIKey(r) {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
+	partnerID, _ := utils.GetPartnerID(r)
+	orderID := mux.Vars(r)["order_id"]
+
+	var row pendingWithdrawalRow
+	res := c.pendingQuery(partnerID, utils.SystemClock.Now()).
+		Where("withdrawals.order_id = ?", orderID).
+		Limit(1).Scan(&row)
+	if res.Error != nil {
+		c.audit(r, "view_pending", orderID, res.Error, "query failed")
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
 			Success: false,
-			Message: "Unauthorized",
+			Message: "Gagal mengambil data penarikan",
 		})
 		return
 	}
+	if res.RowsAffected == 0 {
+		c.audit(r, "view_pending", orderID, utils.ErrPartnerWithdrawalNotFound, "")
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+			Success: false,
+			Message: "Penarikan tidak ditemukan",
+		})
+		return
+	}
+	c.audit(r, "view_pending", orderID, nil, "")
 
-	vars := mux.Vars(r)
-	orderID := vars["order_id"]
-
-	var withdrawal struct {
-		UserID        uint    `json:"user_id"`
-		UserName      string  `json:"user_name"`
-		Phone         string  `json:"phone"`
-		BankAccountID uint    `json:"bank_account_id"`
-		BankName      string  `json:"bank_name"`
-		AccountName   string  `json:"account_name"`
-		AccountNumber string  `json:"account_number"`
-		Amount        float64 `json:"amount"`
-		Charge        float64 `json:"charge"`
-		FinalAmount   float64 `json:"final_amount"`
-		OrderID       string  `json:"order_id"`
-		Status        string  `json:"status"`
-		CreatedAt     string  `json:"created_at"`
-	}
-
-	err := c.DB.Table("withdrawals").
-		Select("withdrawals.user_id, users.name as user_name, users.number as phone, withdrawals.bank_account_id, "+
-			"banks.name as bank_name, bank_accounts.account_name, bank_accounts.account_number, "+
-			"withdrawals.amount, withdrawals.charge, withdrawals.final_amount, "+
-			"withdrawals.order_id, withdrawals.status, withdrawals.created_at").
-		Joins("JOIN users ON withdrawals.user_id = users.id").
-		Joins("JOIN bank_accounts ON withdrawals.bank_account_id = bank_accounts.id").
-		Joins("JOIN banks ON bank_accounts.bank_id = banks.id").
-		Where("withdrawals.order_id = ? AND withdrawals.status = ?", orderID, "Pending").
-		First(&withdrawal).Error
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data:    []interface{}{row.view(partnerID)},
+	})
+}
 
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+// ClaimWithdrawals - API untuk StoneForm mengambil (claim) withdrawals yang akan dibayar.
+// Hanya response ini yang berisi nomor rekening lengkap.
+func (c *SFXCRController) ClaimWithdrawals(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Limit int `json:"limit"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 				Success: false,
-				Message: "Penarikan tidak ditemukan",
+				Message: "Invalid request body",
 			})
 			return
 		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+	}
+	if req.Limit < 0 || req.Limit > utils.PartnerClaimMaxBatch {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Gagal mengambil data penarikan",
+			Message: fmt.Sprintf("limit harus antara 1 dan %d", utils.PartnerClaimMaxBatch),
 		})
 		return
 	}
+	if req.Limit == 0 {
+		req.Limit = 10
+	}
+
+	partnerID, _ := utils.GetPartnerID(r)
+	payouts, err := utils.ClaimPartnerWithdrawals(c.DB, partnerID, req.Limit, utils.SystemClock.Now())
+	if err != nil {
+		log.Printf("[sfxcr] claim partner %d: %v", partnerID, err)
+		c.audit(r, "claim", "", err, "claim failed")
+		writeClaimError(w, err)
+		return
+	}
+	for _, p := range payouts {
+		c.audit(r, "claim", p.OrderID, nil, "lease until "+p.ClaimExpiresAt.Format(time.RFC3339))
+	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    []interface{}{withdrawal},
+		Data:    payouts,
 	})
 }
 
-// WithdrawalCallback - API untuk menerima callback dari StoneForm
-func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Request) {
-	if !c.verifyAPIKey(r) {
-		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{
-			Success: false,
-			Message: "Unauthorized",
-		})
+// ReleaseWithdrawal - API untuk StoneForm mengembalikan claim yang tidak jadi dibayar
+func (c *SFXCRController) ReleaseWithdrawal(w http.ResponseWriter, r *http.Request) {
+	partnerID, _ := utils.GetPartnerID(r)
+	orderID := mux.Vars(r)["order_id"]
+
+	if err := utils.ReleasePartnerWithdrawal(c.DB, partnerID, orderID, utils.SystemClock.Now()); err != nil {
+		c.audit(r, "release", orderID, err, "")
+		writeClaimError(w, err)
 		return
 	}
+	c.audit(r, "release", orderID, nil, "")
 
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Claim penarikan berhasil dilepas",
+	})
+}
+
+// WithdrawalCallback - API untuk menerima hasil pembayaran dari StoneForm (signed).
+// Hanya partner yang memegang claim yang dapat melapor.
+func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Request) {
 	var callback struct {
-		OrderID string `json:"order_id"`
-		Status  string `json:"status"`
+		OrderID   string `json:"order_id"`
+		Status    string `json:"status"`
+		Reference string `json:"reference"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&callback); err != nil {
@@ -165,65 +269,63 @@ func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Requ
 		})
 		return
 	}
-
-	// Validasi status
-	if callback.Status != "Success" && callback.Status != "Failed" {
+	callback.OrderID = strings.TrimSpace(callback.OrderID)
+	callback.Reference = strings.TrimSpace(callback.Reference)
+	if callback.OrderID == "" {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Status harus Success atau Failed",
+			Message: "order_id wajib diisi",
 		})
 		return
 	}
-
-	// Untuk status Failed, hanya kirim response success tanpa update database
-	if callback.Status == "Failed" {
-		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
-			Success: true,
-			Message: "Rejected berhasil diterima",
-		})
-		return
-	}
-
-	// Untuk status Success, update database
-	tx := c.DB.Begin()
-
-	// Update withdrawal
-	var withdrawal models.Withdrawal
-	if err := tx.Where("order_id = ?", callback.OrderID).First(&withdrawal).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{
+	if len(callback.Reference) > 100 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Withdrawal tidak ditemukan",
+			Message: "reference maksimal 100 karakter",
 		})
 		return
 	}
 
-	withdrawal.Status = callback.Status
-	if err := tx.Save(&withdrawal).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
+	// Validasi status
+	if callback.Status != "Success" && callback.Status != "Failed" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{
 			Success: false,
-			Message: "Gagal memperbarui status penarikan",
+			Message: "Status harus Success atau Failed",
 		})
 		return
 	}
 
-	// Update related transaction
-	if err := tx.Model(&models.Transaction{}).
-		Where("order_id = ?", callback.OrderID).
-		Update("status", callback.Status).Error; err != nil {
-		tx.Rollback()
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal memperbarui status transaksi",
-		})
+	partnerID, _ := utils.GetPartnerID(r)
+	success := callback.Status == "Success"
+	withdrawal, replay, err := utils.ReportPartnerWithdrawal(c.DB, partnerID, callback.OrderID, success, callback.Reference, utils.SystemClock.Now())
+	detail := "status " + callback.Status
+	if callback.Reference != "" {
+		detail += ", reference " + callback.Reference
+	}
+	if err != nil {
+		if !errors.Is(err, utils.ErrPartnerWithdrawalNotFound) && !errors.Is(err, utils.ErrPartnerWithdrawalNotClaimed) && !errors.Is(err, utils.ErrPartnerWithdrawalSettled) {
+			log.Printf("[sfxcr] report %s partner %d: %v", callback.OrderID, partnerID, err)
+		}
+		c.audit(r, "report", callback.OrderID, err, detail+": "+err.Error())
+		writeClaimError(w, err)
 		return
 	}
+	if replay {
+		detail += " (replay)"
+	}
+	c.audit(r, "report", callback.OrderID, nil, detail)
 
-	if err := tx.Commit().Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{
-			Success: false,
-			Message: "Gagal menyimpan perubahan",
+	if !success {
+		if !replay {
+			metrics.PayoutFailures.WithLabelValues("partner", "callback").Inc()
+		}
+		message := "Rejected berhasil diterima, penarikan dikembalikan ke antrian"
+		if withdrawal.PartnerFailures >= utils.PartnerMaxFailures {
+			message = "Rejected berhasil diterima, penarikan diteruskan ke admin"
+		}
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+			Success: true,
+			Message: message,
 		})
 		return
 	}
@@ -233,21 +335,3 @@ func (c *SFXCRController) WithdrawalCallback(w http.ResponseWriter, r *http.Requ
 		Message: "Penarikan berhasil diproses",
 	})
 }
-
-// verifyAPIKey - Verifikasi API key dari StoneForm
-func (c *SFXCRController) verifyAPIKey(r *http.Request) bool {
-	authHeader := r.Header.Get("Authorization")
-	expectedAPIKey := "pxloNUadKfHzjPVbSxdwjMHgUjlgVoPj" // Simpan di environment variable
-
-	if authHeader == "" {
-		return false
-	}
-
-	// Format: "Bearer {api_key}" atau langsung api_key
-	token := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
-	}
-
-	return token == expectedAPIKey
-}
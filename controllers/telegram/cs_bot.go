@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"project/config"
 	"project/database"
 	"project/models"
 	"project/utils"
@@ -100,26 +100,11 @@ var (
 	conversationHistory = make(map[int64]*ConversationHistory)
 	historyMutex        sync.RWMutex
 	rateLimiter         = NewRateLimiter()
-	allowedGroupIDs     []int64
 )
 
-func init() {
-	// Load allowed group IDs from environment
-	groupIDsStr := os.Getenv("TELEGRAM_ALLOWED_GROUP_IDS")
-	if groupIDsStr != "" {
-		groupIDs := strings.Split(groupIDsStr, ",")
-		for _, idStr := range groupIDs {
-			var id int64
-			if _, err := fmt.Sscanf(strings.TrimSpace(idStr), "%d", &id); err == nil {
-				allowedGroupIDs = append(allowedGroupIDs, id)
-			}
-		}
-	}
-}
-
 // SendTelegramMessage sends a message via Telegram Bot API
 func SendTelegramMessage(chatID int64, text string, replyToID int64) error {
-	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	botToken := config.Get().Telegram.BotToken
 	if botToken == "" {
 		return fmt.Errorf("TELEGRAM_BOT_TOKEN not set")
 	}
@@ -183,7 +168,7 @@ func ShouldRespond(update *TelegramUpdate) bool {
 	// For groups, only respond if message is clearly directed to the bot
 	if chatType == "group" || chatType == "supergroup" {
 		// Check if group is allowed
-		if len(allowedGroupIDs) > 0 {
+		if allowedGroupIDs := config.Get().Telegram.AllowedGroupIDs; len(allowedGroupIDs) > 0 {
 			allowed := false
 			for _, id := range allowedGroupIDs {
 				if update.Message.Chat.ID == id {
@@ -209,7 +194,7 @@ func isMessageForBot(update *TelegramUpdate, text string) bool {
 	textLower := strings.ToLower(text)
 
 	// Check if bot is mentioned
-	botUsername := os.Getenv("TELEGRAM_BOT_USERNAME")
+	botUsername := config.Get().Telegram.BotUsername
 	if botUsername != "" {
 		botUsername = strings.ToLower(strings.TrimPrefix(botUsername, "@"))
 		if strings.Contains(textLower, "@"+botUsername) {
@@ -758,8 +743,24 @@ func getTaskInfo() string {
 	for _, task := range tasks {
 		response.WriteString(fmt.Sprintf("- <b>%s</b>\n", task.Name))
 		response.WriteString(fmt.Sprintf("  Hadiah: Rp%.0f\n", task.Reward))
-		response.WriteString(fmt.Sprintf("  Level Diperlukan: %d\n", task.RequiredLevel))
-		response.WriteString(fmt.Sprintf("  Member Aktif Diperlukan: %d\n\n", task.RequiredActiveMembers))
+		switch task.Criterion {
+		case "invested_amount":
+			response.WriteString(fmt.Sprintf("  Target Investasi: Rp%.0f\n", task.TargetValue))
+		case "checkin_streak":
+			response.WriteString(fmt.Sprintf("  Check-in Berturut-turut: %.0f hari\n", task.TargetValue))
+		case "forum_accepted":
+			response.WriteString(fmt.Sprintf("  Postingan Forum Disetujui: %.0f\n", task.TargetValue))
+		default:
+			response.WriteString(fmt.Sprintf("  Level Diperlukan: %d\n", task.RequiredLevel))
+			response.WriteString(fmt.Sprintf("  Member Aktif Diperlukan: %d\n", task.RequiredActiveMembers))
+		}
+		switch task.Type {
+		case "daily":
+			response.WriteString("  Dapat diklaim setiap hari\n")
+		case "weekly":
+			response.WriteString("  Dapat diklaim setiap minggu\n")
+		}
+		response.WriteString("\n")
 	}
 
 	response.WriteString("Akses <a href=\"https://novavant.com/referral\">https://novavant.com/referral</a> untuk melihat detail dan claim tugas.")
@@ -1262,7 +1263,7 @@ INFORMASI PENTING TENTANG NOVA VANT:
   * JIKA USER BERTANYA TENTANG DEPOSIT/MINIMAL DEPOSIT/PRODUK YANG HARUS DIAMBIL: TANYA DULU apakah user baru atau sudah pernah investasi. Jika baru, sarankan Neura saja. Jika sudah investasi, tanyakan level VIP lalu sarankan produk sesuai level VIP. JANGAN langsung sarankan Finora/Corex tanpa tahu level VIP user!
 - PRODUK ROUTER: Produk neura akan diterima oleh member SETELAH KONTRAK BERAKHIR. Profit harian akan tetap berjalan sesuai durasi kontrak, dan neura fisik akan dikirim setelah kontrak selesai. 📦
 - PROFIT ROUTER (PENTING!): Untuk produk ROUTER, profit TIDAK masuk setiap hari karena profit terkunci (locked). Profit akan dikembalikan FULL selama durasi kontrak (70 hari) BESERTA modal setelah kontrak 70 hari selesai. Jadi jika member bertanya "kenapa profit saya tidak masuk?" dan mereka membeli neura, itu NORMAL karena profit neura terkunci dan akan dikembalikan sekaligus setelah kontrak selesai. ⚠️💰
-- KOMISI: Sistem komisi referral 30% dari investasi referral. Unlimited earning, easy start
+- KOMISI: Sistem komisi referral 30%% dari investasi referral. Unlimited earning, easy start
 - VIP LEVEL: Ada 6 level VIP (0-5) dengan syarat investasi Neura. Semakin tinggi level, semakin banyak produk eksklusif
 - EVENT: Event upload TikTok/YouTube dengan hadiah berdasarkan views (20K-500K views = Rp100K-Rp2JT)
 - NEWS: Ada berita terbaru di https://news.novavant.com (akan diberikan di context jika ditanya)
@@ -1374,7 +1375,7 @@ PENANGANAN KELUHAN:
 - Coba bantu dengan informasi yang ada di context
 - Jika tidak tahu atau tidak yakin solusinya, arahkan ke CS dengan tag @novavant_cs
 - Jangan biarkan keluhan tidak terjawab - selalu respons!
-- Contoh: "Wah, maaf ya [nama user] 😅 Untuk masalah ini, lebih baik langsung chat CS aja ya @novavant_cs, mereka lebih bisa bantu detail nih! 💪"`, userGreeting)
+- Contoh: "Wah, maaf ya [nama user] 😅 Untuk masalah ini, lebih baik langsung chat CS aja ya @novavant_cs, mereka lebih bisa bantu detail nih! 💪"`, userGreeting, userGreeting)
 
 	// Add context data to system prompt if available
 	if contextData != "" {
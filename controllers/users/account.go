@@ -0,0 +1,135 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"project/database"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+type CloseAccountRequest struct {
+	PIN     string `json:"pin"`
+	Confirm bool   `json:"confirm"`
+}
+
+// GET /api/users/account/export?format=zip|json
+// Personal data export (UU PDP): profile, bank accounts, investments, transactions,
+// gifts, spins, forum posts and chat history. ZIP by default.
+func ExportAccountDataHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "zip"
+	}
+	if format != "zip" && format != "json" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format harus zip atau json"})
+		return
+	}
+
+	now := time.Now()
+	export, err := utils.BuildUserDataExport(database.DB, uid, now)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+			return
+		}
+		log.Printf("[account/export] user %d: %v", uid, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyiapkan data Anda"})
+		return
+	}
+
+	filename := fmt.Sprintf("data-akun-%d-%s", uid, now.In(utils.JakartaLocation()).Format("20060102"))
+	if format == "json" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: export})
+		return
+	}
+
+	// Build in memory so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	if err := utils.WriteUserDataZip(&buf, export); err != nil {
+		log.Printf("[account/export] zip user %d: %v", uid, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyiapkan data Anda"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// POST /api/users/account/close
+// Requires a zero balance, no pending/running investments, no pending withdrawals and no
+// active gifts. Financial records are kept; personal data is anonymized.
+func CloseAccountHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	var req CloseAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Not valid JSON"})
+		return
+	}
+	if !req.Confirm {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Konfirmasi penutupan akun diperlukan"})
+		return
+	}
+	if !authorizeTransaction(w, uid, req.PIN) {
+		return
+	}
+
+	var objects []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		objects, err = utils.CloseAccount(tx, uid, time.Now())
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		case errors.Is(err, utils.ErrAccountClosed):
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Akun sudah ditutup"})
+		case errors.Is(err, utils.ErrAccountBalanceNotZero):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo harus Rp0 sebelum akun dapat ditutup, silakan tarik saldo Anda terlebih dahulu"})
+		case errors.Is(err, utils.ErrAccountRunningInvestments):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Masih ada investasi yang berjalan"})
+		case errors.Is(err, utils.ErrAccountPendingWithdrawals):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Masih ada penarikan yang sedang diproses"})
+		case errors.Is(err, utils.ErrAccountActiveGifts):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Masih ada hadiah aktif yang belum selesai"})
+		default:
+			log.Printf("[account/close] user %d: %v", uid, err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		}
+		return
+	}
+
+	// Refresh tokens were deleted with the account; the access token used here is revoked
+	// too, and the auth middleware refuses any other one because the account is closed
+	if err := utils.RevokeRequestToken(r); err != nil {
+		log.Printf("[account/close] revoke token of user %d: %v", uid, err)
+	}
+
+	for _, key := range objects {
+		if err := utils.DeleteFromS3(key); err != nil {
+			log.Printf("[account/close] delete object %s: %v", key, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Akun Anda telah ditutup dan data pribadi telah dihapus"})
+}
@@ -17,6 +17,7 @@ type AddBankAccountRequest struct {
 	BankID        uint   `json:"bank_id"`
 	AccountName   string `json:"account_name"`
 	AccountNumber string `json:"account_number"`
+	PIN           string `json:"pin"`
 }
 
 func AddBankAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -100,6 +101,10 @@ func AddBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeTransaction(w, uid, req.PIN) {
+		return
+	}
+
 	acc := models.BankAccount{
 		UserID:        uid,
 		BankID:        req.BankID,
@@ -210,6 +215,7 @@ func EditBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		AccountName   string `json:"account_name"`
 		AccountNumber string `json:"account_number"`
 		BankID        uint   `json:"bank_id"`
+		PIN           string `json:"pin"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Not valid request"})
@@ -239,6 +245,9 @@ func EditBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Minimum one field must be filled"})
 		return
 	}
+	if !authorizeTransaction(w, uid, req.PIN) {
+		return
+	}
 	if err := db.Model(&acc).Updates(update).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupdate rekening"})
 		return
@@ -254,12 +263,16 @@ func DeleteBankAccountHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		ID uint `json:"id"`
+		ID  uint   `json:"id"`
+		PIN string `json:"pin"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Not valid request"})
 		return
 	}
+	if !authorizeTransaction(w, uid, req.PIN) {
+		return
+	}
 	db := database.DB
 	if err := db.Where("user_id = ? AND id = ?", uid, req.ID).Delete(&models.BankAccount{}).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menghapus rekening"})
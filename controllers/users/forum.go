@@ -30,7 +30,7 @@ func ForumListHandler(w http.ResponseWriter, r *http.Request) {
 		masked := strings.Repeat("*", len(num)-6)
 		return prefix + masked + suffix
 	}
-	db := database.DB
+	db := database.ReadDB()
 
 	// Get query parameters
 	pageStr := r.URL.Query().Get("page")
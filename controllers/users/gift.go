@@ -1,32 +1,23 @@
 package users
 
 import (
-	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"strings"
 	"time"
 
+	"project/config"
 	"project/database"
 	"project/models"
+	"project/services"
 	"project/utils"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
-)
-
-const (
-	minGiftAmount   float64 = 1000
-	maxGiftAmount   float64 = 10000000
-	minGiftWinners  int     = 1
-	maxGiftWinners  int     = 100
-	minRandomAmount float64 = 100 // minimum per winner for random mode
 )
 
 // CreateGiftRequest for POST /gift
@@ -37,6 +28,8 @@ type CreateGiftRequest struct {
 	WinnerCount      int     `json:"winner_count"`
 	DistributionType string  `json:"distribution_type"` // "random" | "equal"
 	RecipientType    string  `json:"recipient_type"`    // "all" | "referral_only"
+	ExpiresInHours   int     `json:"expires_in_hours"`  // 0 = default from settings
+	PIN              string  `json:"pin"`
 }
 
 // CreateGiftHandler POST /gift - create gift
@@ -53,132 +46,35 @@ func CreateGiftHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validations
-	req.DistributionType = strings.ToLower(strings.TrimSpace(req.DistributionType))
-	req.RecipientType = strings.ToLower(strings.TrimSpace(req.RecipientType))
-
-	if req.DistributionType != "random" && req.DistributionType != "equal" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tipe distribusi harus random atau equal"})
-		return
-	}
-	if req.RecipientType != "all" && req.RecipientType != "referral_only" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Penerima harus all atau referral_only"})
-		return
-	}
-	if req.WinnerCount < minGiftWinners || req.WinnerCount > maxGiftWinners {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Jumlah pemenang harus antara %d dan %d", minGiftWinners, maxGiftWinners)})
-		return
-	}
-	if req.Amount < minGiftAmount || req.Amount > maxGiftAmount {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Jumlah harus antara Rp %.0f dan Rp %.0f", minGiftAmount, maxGiftAmount)})
-		return
-	}
-
-	// Calculate total to deduct
-	var totalDeducted float64
-	if req.DistributionType == "random" {
-		totalDeducted = req.Amount
-		// Min per winner check
-		if req.Amount < float64(req.WinnerCount)*minRandomAmount {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: fmt.Sprintf("Total minimal Rp %.0f untuk %d pemenang (Rp %.0f per pemenang)", float64(req.WinnerCount)*minRandomAmount, req.WinnerCount, minRandomAmount)})
-			return
-		}
-	} else {
-		totalDeducted = req.Amount * float64(req.WinnerCount)
-	}
-
-	// Check sender balance
-	var sender models.User
-	if err := database.DB.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sender, uid).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan"})
-			return
-		}
-		log.Printf("[gift/create] DB error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
-		return
-	}
-
-	if sender.Balance < totalDeducted {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo tidak mencukupi"})
-		return
-	}
-
-	// Only level 1+ can create gift
-	userLevel := uint(0)
-	if sender.Level != nil {
-		userLevel = *sender.Level
-	}
-	if userLevel < 1 {
-		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Fitur gift hanya untuk VIP level 1 ke atas"})
-		return
-	}
-
-	// Check promotor mode - gift may not be allowed for promotor
-	if strings.ToLower(sender.UserMode) == "promotor" {
-		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Fitur hadiah tidak tersedia untuk mode promotor"})
-		return
-	}
-
-	// Generate unique gift code
-	code, err := generateGiftCode()
-	if err != nil {
-		log.Printf("[gift/create] generate code error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat kode hadiah"})
-		return
-	}
-
-	// Prepare gift and slots (for random)
-	var slots []models.GiftAmountSlot
-	if req.DistributionType == "random" {
-		slots = generateRandomAmounts(req.Amount, req.WinnerCount)
-	}
-
-	gift := models.Gift{
+	gift, err := svc().Gifts.Create(r.Context(), services.CreateGiftRequest{
 		UserID:           uid,
-		Code:             code,
 		Amount:           req.Amount,
 		WinnerCount:      req.WinnerCount,
 		DistributionType: req.DistributionType,
 		RecipientType:    req.RecipientType,
-		Status:           "active",
-		TotalDeducted:    totalDeducted,
-	}
-
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		// Deduct balance
-		if err := tx.Model(&sender).Update("balance", gorm.Expr("balance - ?", totalDeducted)).Error; err != nil {
-			return err
-		}
-		// Create gift
-		if err := tx.Create(&gift).Error; err != nil {
-			return err
-		}
-		// Create slots for random
-		for i := range slots {
-			slots[i].GiftID = gift.ID
-			if err := tx.Create(&slots[i]).Error; err != nil {
-				return err
-			}
-		}
-		// Create transaction record
-		msg := fmt.Sprintf("Hadiah %s - %s", code, req.DistributionType)
-		trx := models.Transaction{
-			UserID:          uid,
-			Amount:          totalDeducted,
-			Charge:          0,
-			OrderID:         utils.GenerateOrderID(uid),
-			TransactionFlow: "credit",
-			TransactionType: "gift",
-			Message:         &msg,
-			Status:          "Success",
-		}
-		return tx.Create(&trx).Error
+		ExpiresInHours:   req.ExpiresInHours,
+		PIN:              req.PIN,
 	})
-
 	if err != nil {
-		log.Printf("[gift/create] transaction error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat hadiah"})
+		if writePINError(w, err) || writePolicyViolation(w, err, false) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan"})
+		case errors.Is(err, services.ErrInsufficientBalance):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo tidak mencukupi"})
+		case errors.Is(err, services.ErrGiftNotEligible):
+			utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Fitur gift hanya untuk VIP level 1 ke atas"})
+		case errors.Is(err, services.ErrGiftPromotorSender):
+			utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Fitur hadiah tidak tersedia untuk mode promotor"})
+		case errors.Is(err, services.ErrGiftCode):
+			log.Printf("[gift/create] generate code error: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat kode hadiah"})
+		default:
+			log.Printf("[gift/create] transaction error: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat hadiah"})
+		}
 		return
 	}
 
@@ -194,92 +90,12 @@ func CreateGiftHandler(w http.ResponseWriter, r *http.Request) {
 			"recipient_type":    gift.RecipientType,
 			"total_deducted":    gift.TotalDeducted,
 			"status":            gift.Status,
+			"expires_at":        gift.ExpiresAt.Format(time.RFC3339),
 			"created_at":        gift.CreatedAt.Format(time.RFC3339),
 		},
 	})
 }
 
-// generateRandomAmounts splits total into winnerCount amounts (fair random distribution)
-// Each winner gets at least minRandomAmount, total exactly equals input
-func generateRandomAmounts(total float64, winnerCount int) []models.GiftAmountSlot {
-	slots := make([]models.GiftAmountSlot, winnerCount)
-	weights := make([]float64, winnerCount)
-	var sum float64
-
-	for i := 0; i < winnerCount; i++ {
-		b := make([]byte, 8)
-		rand.Read(b)
-		w := float64(0)
-		for _, v := range b {
-			w = w*256 + float64(v)
-		}
-		weights[i] = w + 1
-		sum += weights[i]
-	}
-
-	// Assign proportional amounts (floored)
-	remaining := total
-	for i := 0; i < winnerCount-1; i++ {
-		prop := total * weights[i] / sum
-		slotAmount := math.Floor(prop)
-		if slotAmount < minRandomAmount {
-			slotAmount = minRandomAmount
-		}
-		remaining -= slotAmount
-		slots[i] = models.GiftAmountSlot{SlotIndex: i, Amount: slotAmount}
-	}
-	// Last slot gets remainder (ensure >= minRandomAmount)
-	if remaining < minRandomAmount {
-		// Take from largest previous slot
-		maxIdx := 0
-		for i := 1; i < winnerCount-1; i++ {
-			if slots[i].Amount > slots[maxIdx].Amount {
-				maxIdx = i
-			}
-		}
-		need := minRandomAmount - remaining
-		slots[maxIdx].Amount -= need
-		remaining += need
-	}
-	slots[winnerCount-1] = models.GiftAmountSlot{SlotIndex: winnerCount - 1, Amount: remaining}
-
-	// Adjust for floating point: ensure sum exactly equals total
-	actualSum := 0.0
-	for _, s := range slots {
-		actualSum += s.Amount
-	}
-	diff := total - actualSum
-	if diff != 0 {
-		slots[winnerCount-1].Amount += diff
-		if slots[winnerCount-1].Amount < minRandomAmount {
-			slots[winnerCount-1].Amount = minRandomAmount
-		}
-	}
-
-	return slots
-}
-
-func generateGiftCode() (string, error) {
-	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
-	const length = 8
-	for attempt := 0; attempt < 20; attempt++ {
-		b := make([]byte, length)
-		if _, err := rand.Read(b); err != nil {
-			return "", err
-		}
-		var code strings.Builder
-		for _, v := range b {
-			code.WriteByte(chars[int(v)%len(chars)])
-		}
-		c := code.String()
-		var count int64
-		if database.DB.Model(&models.Gift{}).Where("code = ?", c).Count(&count).Error == nil && count == 0 {
-			return c, nil
-		}
-	}
-	return "", fmt.Errorf("could not generate unique gift code")
-}
-
 // RedeemGiftRequest for POST /gift/redeem
 type RedeemGiftRequest struct {
 	Code string `json:"code"`
@@ -300,127 +116,35 @@ func RedeemGiftHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	code := strings.ToUpper(strings.TrimSpace(req.Code))
-	if code == "" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode hadiah tidak boleh kosong"})
-		return
-	}
-
-	var gift models.Gift
-	if err := database.DB.Preload("Claims").First(&gift, "code = ?", code).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Hadiah tidak ditemukan"})
-			return
-		}
-		log.Printf("[gift/redeem] DB error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
-		return
-	}
-
-	if gift.Status != "active" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah sudah tidak aktif"})
-		return
-	}
-
-	// Cannot claim own gift
-	if gift.UserID == uid {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak dapat mengklaim hadiah sendiri"})
-		return
-	}
-
-	// Check if already claimed
-	var existingClaim models.GiftClaim
-	if err := database.DB.Where("gift_id = ? AND user_id = ?", gift.ID, uid).First(&existingClaim).Error; err == nil {
-		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Anda sudah mengklaim hadiah ini"})
-		return
-	}
-
-	// Check eligibility: recipient_type
-	var claimant models.User
-	if err := database.DB.Select("id, reff_by, user_mode").First(&claimant, uid).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	claim, err := svc().Gifts.Redeem(r.Context(), uid, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrGiftCodeRequired):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode hadiah tidak boleh kosong"})
+		case errors.Is(err, services.ErrUserNotFound):
 			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pengguna tidak ditemukan"})
-			return
-		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
-		return
-	}
-
-	if gift.RecipientType == "referral_only" {
-		if claimant.ReffBy == nil || *claimant.ReffBy != gift.UserID {
+		case errors.Is(err, utils.ErrGiftNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Hadiah tidak ditemukan"})
+		case errors.Is(err, utils.ErrGiftNotActive):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah sudah tidak aktif"})
+		case errors.Is(err, utils.ErrGiftExpired):
+			utils.WriteJSON(w, http.StatusGone, utils.APIResponse{Success: false, Message: "Hadiah sudah kedaluwarsa"})
+		case errors.Is(err, utils.ErrGiftOwn):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tidak dapat mengklaim hadiah sendiri"})
+		case errors.Is(err, utils.ErrGiftReferralOnly):
 			utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Hadiah ini hanya untuk referral pengirim"})
-			return
+		case errors.Is(err, utils.ErrGiftPromotor):
+			utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Mode promotor tidak dapat mengklaim hadiah"})
+		case errors.Is(err, utils.ErrGiftBonusFrozen):
+			utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Akun Anda sedang dalam peninjauan, bonus dan hadiah ditahan sementara"})
+		case errors.Is(err, utils.ErrGiftAlreadyClaimed):
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Anda sudah mengklaim hadiah ini"})
+		case errors.Is(err, utils.ErrGiftExhausted):
+			utils.WriteJSON(w, http.StatusGone, utils.APIResponse{Success: false, Message: "Hadiah sudah habis"})
+		default:
+			log.Printf("[gift/redeem] transaction error: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengklaim hadiah"})
 		}
-	}
-
-	// Promotor cannot claim
-	if strings.ToLower(claimant.UserMode) == "promotor" {
-		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Mode promotor tidak dapat mengklaim hadiah"})
-		return
-	}
-
-	// Get amount and slot (for random: next slot; for equal: fixed amount)
-	var amount float64
-	var slotIndex int
-	claimedCount := len(gift.Claims)
-
-	if claimedCount >= gift.WinnerCount {
-		utils.WriteJSON(w, http.StatusGone, utils.APIResponse{Success: false, Message: "Hadiah sudah habis"})
-		return
-	}
-
-	if gift.DistributionType == "random" {
-		var slot models.GiftAmountSlot
-		if err := database.DB.Where("gift_id = ?", gift.ID).Order("slot_index").Offset(claimedCount).First(&slot).Error; err != nil {
-			log.Printf("[gift/redeem] slot error: %v", err)
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
-			return
-		}
-		amount = slot.Amount
-		slotIndex = slot.SlotIndex
-	} else {
-		amount = gift.Amount
-		slotIndex = claimedCount
-	}
-
-	// Create claim and credit balance
-	claim := models.GiftClaim{
-		GiftID:    gift.ID,
-		UserID:    uid,
-		Amount:    amount,
-		SlotIndex: slotIndex,
-	}
-
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&claim).Error; err != nil {
-			return err
-		}
-		if err := tx.Model(&models.User{}).Where("id = ?", uid).Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
-			return err
-		}
-		msg := fmt.Sprintf("Hadiah dari %s", code)
-		trx := models.Transaction{
-			UserID:          uid,
-			Amount:          amount,
-			Charge:          0,
-			OrderID:         utils.GenerateOrderID(uid),
-			TransactionFlow: "debit",
-			TransactionType: "bonus",
-			Message:         &msg,
-			Status:          "Success",
-		}
-		if err := tx.Create(&trx).Error; err != nil {
-			return err
-		}
-		// Mark gift completed if all claimed
-		if claimedCount+1 >= gift.WinnerCount {
-			return tx.Model(&gift).Update("status", "completed").Error
-		}
-		return nil
-	})
-
-	if err != nil {
-		log.Printf("[gift/redeem] transaction error: %v", err)
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengklaim hadiah"})
 		return
 	}
 
@@ -428,9 +152,9 @@ func RedeemGiftHandler(w http.ResponseWriter, r *http.Request) {
 		Success: true,
 		Message: "Selamat! Anda berhasil mengklaim hadiah",
 		Data: map[string]interface{}{
-			"amount":  amount,
+			"amount":  claim.Amount,
 			"code":    code,
-			"gift_id": gift.ID,
+			"gift_id": claim.GiftID,
 		},
 	})
 }
@@ -456,7 +180,7 @@ func GiftInquiryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	claimedCount := len(gift.Claims)
-	canClaim := gift.Status == "active" && claimedCount < gift.WinnerCount
+	canClaim := gift.Status == "active" && claimedCount < gift.WinnerCount && !giftExpired(&gift, time.Now())
 
 	// Check if current user can claim (if authenticated)
 	uid, hasAuth := utils.GetUserID(r)
@@ -494,6 +218,7 @@ func GiftInquiryHandler(w http.ResponseWriter, r *http.Request) {
 			"recipient_type":    gift.RecipientType,
 			"status":            gift.Status,
 			"total_deducted":    gift.TotalDeducted,
+			"expires_at":        gift.ExpiresAt,
 			"sender_name":       senderName,
 			"can_claim":         canClaim,
 			"reason":            reason,
@@ -541,6 +266,8 @@ func GiftHistoryHandler(w http.ResponseWriter, r *http.Request) {
 			"recipient_type":    g.RecipientType,
 			"status":            g.Status,
 			"total_deducted":    g.TotalDeducted,
+			"refunded_amount":   g.RefundedAmount,
+			"expires_at":        g.ExpiresAt,
 			"created_at":        g.CreatedAt.Format(time.RFC3339),
 		})
 	}
@@ -710,3 +437,51 @@ func parseInt(s string, defaultVal int) (int, bool) {
 	}
 	return n, true
 }
+
+// giftExpired reports whether an active gift is past its expiry but not yet swept by the cron.
+func giftExpired(gift *models.Gift, now time.Time) bool {
+	return gift.ExpiresAt != nil && !now.Before(*gift.ExpiresAt)
+}
+
+// CronExpireGiftsHandler POST /cron/gift-expiry (protected via X-CRON-KEY header)
+// Marks active gifts past expires_at as expired and refunds the unclaimed amount to the sender.
+func CronExpireGiftsHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != config.Get().Cron.Key {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	db := database.DB
+	var giftIDs []uint
+	if err := db.Model(&models.Gift{}).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", "active", utils.SystemClock.Now()).
+		Order("expires_at ASC").
+		Limit(500).
+		Pluck("id", &giftIDs).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	processed := 0
+	refunded := 0.0
+	for _, id := range giftIDs {
+		var refund *utils.GiftRefund
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			refund, err = utils.RefundGift(tx, id, "expired")
+			return err
+		})
+		if err != nil {
+			// Completed or cancelled in the meantime
+			if !errors.Is(err, utils.ErrGiftNotActive) {
+				log.Printf("[cron/gift-expiry] gift %d: %v", id, err)
+			}
+			continue
+		}
+		processed++
+		refunded += refund.Amount
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": processed, "refunded": utils.RoundFloat(refunded, 2)}})
+}
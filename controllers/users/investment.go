@@ -8,17 +8,18 @@ import (
 	"log"
 	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"project/config"
 	"project/database"
+	"project/metrics"
 	"project/models"
+	"project/services"
 	"project/utils"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type CreateInvestmentRequest struct {
@@ -107,7 +108,7 @@ func GetActiveInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
 }
 
-// POST /api/users/investments - FIXED VERSION
+// POST /api/users/investments
 func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 	var req CreateInvestmentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -121,372 +122,37 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	method := strings.ToUpper(strings.TrimSpace(req.PaymentMethod))
-	channel := strings.ToUpper(strings.TrimSpace(req.PaymentChannel))
-	if method != "QRIS" && method != "BANK" {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Silahkan pilih metode pembayaran"})
-		return
-	}
-	if method == "BANK" {
-		allowed := map[string]struct{}{
-			"BCA": {}, "BNI": {}, "BRI": {}, "BSI": {}, "CIMB": {}, "DANAMON": {},
-			"MANDIRI": {}, "BMI": {}, "BNC": {}, "OCBC": {}, "PERMATA": {}, "SINARMAS": {},
-		}
-		if _, ok := allowed[channel]; !ok {
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank tidak valid"})
+	res, err := svc().Investments.Create(r.Context(), services.CreateInvestmentRequest{
+		UserID:         uid,
+		ProductID:      req.ProductID,
+		PaymentMethod:  req.PaymentMethod,
+		PaymentChannel: req.PaymentChannel,
+	})
+	if err != nil {
+		if writePolicyViolation(w, err, false) {
 			return
 		}
-	}
-
-	db := database.DB
-	var product models.Product
-	if err := db.Preload("Category").Where("id = ? AND status = 'Active'", req.ProductID).First(&product).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		switch {
+		case errors.Is(err, services.ErrInvalidPaymentMethod):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Silahkan pilih metode pembayaran"})
+		case errors.Is(err, services.ErrInvalidBank):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Bank tidak valid"})
+		case errors.Is(err, services.ErrProductNotFound):
 			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Produk tidak ditemukan"})
-			return
-		}
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
-		return
-	}
-
-	if product.Category == nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori produk tidak valid"})
-		return
-	}
-
-	var user models.User
-	if err := db.Select("level, user_mode, balance, name").Where("id = ?", uid).First(&user).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
-		return
-	}
-
-	userLevel := uint(0)
-	if user.Level != nil {
-		userLevel = *user.Level
-	}
-
-	if userLevel < uint(product.RequiredVIP) {
-		msg := fmt.Sprintf("Produk %s memerlukan VIP level %d. Level VIP Anda saat ini: %d", product.Name, product.RequiredVIP, userLevel)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
-		return
-	}
-
-	if product.PurchaseLimit > 0 {
-		var purchaseCount int64
-		if err := db.Model(&models.Investment{}).
-			Where("user_id = ? AND product_id = ? AND status IN ?", uid, product.ID, []string{"Running", "Completed", "Suspended"}).
-			Count(&purchaseCount).Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan, coba lagi"})
-			return
-		}
-		if purchaseCount >= int64(product.PurchaseLimit) {
-			msg := fmt.Sprintf("Anda telah mencapai batas pembelian untuk produk %s (maksimal %dx)", product.Name, product.PurchaseLimit)
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
-			return
-		}
-	}
-
-	amount := product.Amount
-	daily := product.DailyProfit
-	orderID := utils.GenerateOrderID(uid)
-	referenceID := orderID
-
-	// Check if user is promotor
-	isPromotor := user.UserMode == "promotor"
-
-	// Sentinel error for insufficient balance
-	var errInsufficientBalance = errors.New("insufficient_balance")
-
-	if isPromotor {
-		// For promotor: check balance and process directly without payment gateway
-		if user.Balance < amount {
+		case errors.Is(err, services.ErrInvalidCategory):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kategori produk tidak valid"})
+		case errors.Is(err, services.ErrInsufficientBalance):
 			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo tidak mencukupi"})
-			return
-		}
-
-		inv := models.Investment{
-			UserID:        uid,
-			ProductID:     product.ID,
-			CategoryID:    product.CategoryID,
-			Amount:        amount,
-			DailyProfit:   daily,
-			Duration:      product.Duration,
-			TotalPaid:     0,
-			TotalReturned: 0,
-			OrderID:       orderID,
-			Status:        "Pending",
-		}
-
-		if err := db.Transaction(func(tx *gorm.DB) error {
-			// Lock user row for update
-			var lockedUser models.User
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lockedUser, uid).Error; err != nil {
-				return err
-			}
-			if lockedUser.Balance < amount {
-				return errInsufficientBalance
-			}
-
-			// Deduct balance
-			newBalance := round3(lockedUser.Balance - amount)
-			if err := tx.Model(&lockedUser).Update("balance", newBalance).Error; err != nil {
-				return err
-			}
-
-			// Create investment
-			if err := tx.Create(&inv).Error; err != nil {
-				return err
-			}
-
-			// Create payment record (for promotor, mark as Success immediately)
-			methodToSave := strings.ToUpper(method)
-			payment := models.Payment{
-				InvestmentID: inv.ID,
-				ReferenceID: func() *string {
-					x := referenceID
-					return &x
-				}(),
-				OrderID:       inv.OrderID,
-				PaymentMethod: &methodToSave,
-				PaymentChannel: func() *string {
-					if methodToSave == "BANK" {
-						return &channel
-					}
-					return nil
-				}(),
-				Status: "Success",
-			}
-			if err := tx.Create(&payment).Error; err != nil {
-				return err
-			}
-
-			// Create transaction (Success immediately for promotor)
-			msg := fmt.Sprintf("Investasi %s", product.Name)
-			trx := models.Transaction{
-				UserID:          uid,
-				Amount:          inv.Amount,
-				Charge:          0,
-				OrderID:         inv.OrderID,
-				TransactionFlow: "credit",
-				TransactionType: "investment",
-				Message:         &msg,
-				Status:          "Success",
-			}
-			if err := tx.Create(&trx).Error; err != nil {
-				return err
-			}
-
-			// Update investment to Running
-			now := time.Now()
-			next := now.Add(24 * time.Hour)
-			updates := map[string]interface{}{
-				"status":         "Running",
-				"last_return_at": nil,
-				"next_return_at": next,
-			}
-			if err := tx.Model(&inv).Updates(updates).Error; err != nil {
-				return err
-			}
-
-			// Get category info to determine if this is Monitor (locked profit)
-			var category models.Category
-			isMonitor := false
-			if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err == nil {
-				if category.ProfitType == "locked" {
-					isMonitor = true
-				}
-			}
-
-			// Update user total_invest and total_invest_vip
-			userUpdates := map[string]interface{}{
-				"total_invest":      gorm.Expr("total_invest + ?", inv.Amount),
-				"investment_status": "Active",
-			}
-			if isMonitor {
-				userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", inv.Amount)
-			}
-			if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
-				return err
-			}
-
-			// Calculate VIP level based on total_invest_vip for locked categories
-			if isMonitor {
-				var updatedUser models.User
-				if err := tx.Model(&models.User{}).Select("total_invest_vip").Where("id = ?", inv.UserID).First(&updatedUser).Error; err == nil {
-					newLevel := calculateVIPLevel(updatedUser.TotalInvestVIP)
-					if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Update("level", newLevel).Error; err != nil {
-						return err
-					}
-				}
-			}
-
-			// Untuk mode promotor: TIDAK memberikan bonus rekomendasi dan spin ticket
-			// Bonus hanya diberikan untuk mode real
-
-			return nil
-		}); err != nil {
-			if errors.Is(err, errInsufficientBalance) {
-				utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Saldo tidak mencukupi"})
-				return
-			}
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat investasi"})
-			return
-		}
-
-		// Reload investment to get updated status
-		db.First(&inv, inv.ID)
-
-		resp := map[string]interface{}{
-			"order_id":     inv.OrderID,
-			"amount":       inv.Amount,
-			"product":      product.Name,
-			"category":     product.Category.Name,
-			"category_id":  product.CategoryID,
-			"duration":     product.Duration,
-			"daily_profit": daily,
-			"status":       inv.Status,
-		}
-		utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Investasi berhasil diproses", Data: resp})
-		return
-	}
-
-	// For real users: use Pakailink payment gateway
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-
-	accessToken, err := utils.GetPakailinkAccessToken(r.Context(), httpClient)
-	if err != nil {
-		log.Printf("[Pakailink] GetPakailinkAccessToken error: %v", err)
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Terjadi kesalahan saat memanggil layanan pembayaran"})
-		return
-	}
-
-	if method == "QRIS" && amount > 10000000 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Jumlah pembayaran maksimal menggunakan QRIS adalah Rp 10.000.000, Silahkan gunakan metode pembayaran lain"})
-		return
-	}
-
-	if method == "BANK" && amount < 10000 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Jumlah pembayaran minimal menggunakan BANK adalah Rp 10.000, Silahkan gunakan metode pembayaran lain"})
-		return
-	}
-
-	var paymentCode string
-	var expiredAt time.Time
-
-	if method == "QRIS" {
-		qrResp, err := utils.CreatePakailinkQRIS(r.Context(), httpClient, accessToken, orderID, amount)
-		if err != nil {
-			log.Printf("[Pakailink] CreatePakailinkQRIS error: %v", err)
+		case errors.Is(err, services.ErrPaymentGateway):
 			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Terjadi kesalahan saat memanggil layanan pembayaran"})
-			return
-		}
-		paymentCode = qrResp.QRContent
-		if qrResp.ValidityPeriod != "" {
-			if t, err := time.Parse(time.RFC3339, qrResp.ValidityPeriod); err == nil {
-				expiredAt = t
-			} else {
-				expiredAt = time.Now().Add(24 * time.Hour)
-			}
-		} else {
-			expiredAt = time.Now().Add(24 * time.Hour)
-		}
-	} else {
-		bankCode := utils.GetVABankCode(channel)
-		customerNo := fmt.Sprintf("%d%010d", uid, time.Now().UnixNano()%10000000000)
-		userName := strings.TrimSpace(user.Name)
-		if userName == "" {
-			userName = "Pelanggan"
-		}
-		vaName := fmt.Sprintf("%s - NovaVant", userName)
-		vaResp, err := utils.CreatePakailinkVA(r.Context(), httpClient, accessToken, orderID, customerNo, vaName, amount, bankCode)
-		if err != nil {
-			log.Printf("[Pakailink] CreatePakailinkVA error: %v", err)
-			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Terjadi kesalahan saat memanggil layanan pembayaran"})
-			return
-		}
-		paymentCode = vaResp.VirtualAccountData.VirtualAccountNo
-		if vaResp.VirtualAccountData.ExpiredDate != "" {
-			if t, err := time.Parse("2006-01-02T15:04:05-07:00", vaResp.VirtualAccountData.ExpiredDate); err == nil {
-				expiredAt = t
-			} else {
-				expiredAt = time.Now().Add(24 * time.Hour)
-			}
-		} else {
-			expiredAt = time.Now().Add(24 * time.Hour)
-		}
-	}
-
-	inv := models.Investment{
-		UserID:        uid,
-		ProductID:     product.ID,
-		CategoryID:    product.CategoryID,
-		Amount:        amount,
-		DailyProfit:   daily,
-		Duration:      product.Duration,
-		TotalPaid:     0,
-		TotalReturned: 0,
-		OrderID:       orderID,
-		Status:        "Pending",
-	}
-
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&inv).Error; err != nil {
-			return err
-		}
-
-		methodToSave := strings.ToUpper(method)
-		pc := strings.TrimSpace(paymentCode)
-		expAt := expiredAt
-
-		payment := models.Payment{
-			InvestmentID: inv.ID,
-			ReferenceID: func() *string {
-				x := referenceID
-				return &x
-			}(),
-			OrderID:       inv.OrderID,
-			PaymentMethod: &methodToSave,
-			PaymentChannel: func() *string {
-				if methodToSave == "BANK" {
-					return &channel
-				}
-				return nil
-			}(),
-			PaymentCode: func() *string {
-				if pc != "" {
-					return &pc
-				}
-				return nil
-			}(),
-			PaymentLink: nil, // Pakailink tidak menyediakan checkout URL
-			Status:      "Pending",
-			ExpiredAt:   &expAt,
-		}
-
-		if err := tx.Create(&payment).Error; err != nil {
-			return err
-		}
-
-		msg := fmt.Sprintf("Investasi %s", product.Name)
-		trx := models.Transaction{
-			UserID:          uid,
-			Amount:          inv.Amount,
-			Charge:          0,
-			OrderID:         inv.OrderID,
-			TransactionFlow: "credit",
-			TransactionType: "investment",
-			Message:         &msg,
-			Status:          "Pending",
-		}
-		if err := tx.Create(&trx).Error; err != nil {
-			return err
+		default:
+			log.Printf("[investment] user %d: %v", uid, err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat investasi"})
 		}
-		return nil
-	}); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal membuat investasi"})
 		return
 	}
 
+	inv, product := res.Investment, res.Product
 	resp := map[string]interface{}{
 		"order_id":     inv.OrderID,
 		"amount":       inv.Amount,
@@ -494,10 +160,14 @@ func CreateInvestmentHandler(w http.ResponseWriter, r *http.Request) {
 		"category":     product.Category.Name,
 		"category_id":  product.CategoryID,
 		"duration":     product.Duration,
-		"daily_profit": daily,
+		"daily_profit": product.DailyProfit,
 		"status":       inv.Status,
 	}
-	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Pembelian berhasil, silakan lakukan pembayaran", Data: resp})
+	message := "Pembelian berhasil, silakan lakukan pembayaran"
+	if res.Promotor {
+		message = "Investasi berhasil diproses"
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: message, Data: resp})
 }
 
 // GET /api/users/investments
@@ -582,7 +252,7 @@ func ListInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check and update expired investments
-	now := time.Now()
+	now := utils.SystemClock.Now()
 	for i := range rows {
 		inv := &rows[i]
 		if inv.Status == "Pending" {
@@ -594,6 +264,7 @@ func ListInvestmentsHandler(w http.ResponseWriter, r *http.Request) {
 						if err := tx.Model(&models.Investment{}).Where("id = ?", inv.ID).Update("status", "Cancelled").Error; err == nil {
 							if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error; err == nil {
 								tx.Commit()
+								metrics.PaymentsExpired.WithLabelValues(metrics.PaymentMethod(payment.PaymentMethod)).Inc()
 								// Update local data
 								inv.Status = "Cancelled"
 							} else {
@@ -734,36 +405,15 @@ func GetPaymentDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Inquiry Pakailink status when payment is Pending and not expired (validasi cek)
-	now := time.Now()
-	if payment.Status == "Pending" && inv.Status == "Pending" && payment.ExpiredAt != nil && payment.ExpiredAt.After(now) {
-		httpClient := &http.Client{Timeout: 10 * time.Second}
-		accessToken, err := utils.GetPakailinkAccessToken(r.Context(), httpClient)
-		if err == nil {
-			var paid bool
-			method := ""
-			if payment.PaymentMethod != nil {
-				method = *payment.PaymentMethod
-			}
-			if method == "BANK" {
-				res, err := utils.InquiryPakailinkVAStatus(r.Context(), httpClient, accessToken, orderID)
-				if err == nil && utils.IsPakailinkSuccessStatus(res.LatestTransactionStatus) {
-					paid = true
-				}
-			} else if method == "QRIS" {
-				res, err := utils.InquiryPakailinkQRStatus(r.Context(), httpClient, accessToken, orderID)
-				if err == nil && utils.IsPakailinkSuccessStatus(res.LatestTransactionStatus) {
-					paid = true
-				}
-			}
-			if paid {
-				_ = db.Model(&payment).Update("status", "Success").Error
-				_ = db.Transaction(func(tx *gorm.DB) error {
-					return processInvestmentPaymentSuccess(tx, &inv)
-				})
-				db.Where("id = ?", payment.ID).First(&payment)
-				db.Where("id = ?", inv.ID).First(&inv)
-			}
+	// Ask the gateway while the payment is Pending and not expired, in case the callback was missed
+	if inv.Status == "Pending" {
+		settled, err := svc().Investments.RefreshPayment(r.Context(), &payment)
+		if err != nil {
+			log.Printf("[payment] refresh %s: %v", orderID, err)
+		}
+		if settled {
+			db.Where("id = ?", payment.ID).First(&payment)
+			db.Where("id = ?", inv.ID).First(&inv)
 		}
 	}
 
@@ -806,69 +456,6 @@ func GetPaymentDetailsHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
 }
 
-// processInvestmentPaymentSuccess updates transaction, investment, user, and referral bonus when payment succeeds
-func processInvestmentPaymentSuccess(tx *gorm.DB, inv *models.Investment) error {
-	now := time.Now()
-	next := now.Add(24 * time.Hour)
-	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Updates(map[string]interface{}{"status": "Success"}).Error; err != nil {
-		return err
-	}
-	if err := tx.Model(inv).Updates(map[string]interface{}{"status": "Running", "last_return_at": nil, "next_return_at": next}).Error; err != nil {
-		return err
-	}
-	var category models.Category
-	isMonitor := false
-	if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err == nil && category.ProfitType == "locked" {
-		isMonitor = true
-	}
-	userUpdates := map[string]interface{}{
-		"total_invest":      gorm.Expr("total_invest + ?", inv.Amount),
-		"investment_status": "Active",
-	}
-	if isMonitor {
-		userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", inv.Amount)
-	}
-	if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
-		return err
-	}
-	if isMonitor {
-		var user models.User
-		if err := tx.Model(&models.User{}).Select("total_invest_vip").Where("id = ?", inv.UserID).First(&user).Error; err == nil {
-			newLevel := calculateVIPLevel(user.TotalInvestVIP)
-			_ = tx.Model(&models.User{}).Where("id = ?", inv.UserID).Update("level", newLevel).Error
-		}
-	}
-	var user models.User
-	if err := tx.Select("id, reff_by").Where("id = ?", inv.UserID).First(&user).Error; err == nil && user.ReffBy != nil {
-		var level1 models.User
-		if err := tx.Select("id, spin_ticket").Where("id = ?", *user.ReffBy).First(&level1).Error; err == nil && isMonitor {
-			if inv.Amount >= 100000 {
-				if level1.SpinTicket == nil {
-					one := uint(1)
-					tx.Model(&models.User{}).Where("id = ?", level1.ID).Update("spin_ticket", one)
-				} else {
-					tx.Model(&models.User{}).Where("id = ?", level1.ID).UpdateColumn("spin_ticket", gorm.Expr("spin_ticket + 1"))
-				}
-			}
-			bonus := round3(inv.Amount * 0.30)
-			tx.Model(&models.User{}).Where("id = ?", level1.ID).UpdateColumn("balance", gorm.Expr("balance + ?", bonus))
-			msg := "Bonus rekomendasi investor"
-			trx := models.Transaction{
-				UserID:          level1.ID,
-				Amount:          bonus,
-				Charge:          0,
-				OrderID:         utils.GenerateOrderID(level1.ID),
-				TransactionFlow: "debit",
-				TransactionType: "team",
-				Message:         &msg,
-				Status:          "Success",
-			}
-			tx.Create(&trx)
-		}
-	}
-	return nil
-}
-
 // PakailinkCallbackPayload handles both VA and QR callbacks
 type PakailinkCallbackPayload struct {
 	// VA callback format
@@ -933,199 +520,56 @@ func PakailinkWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	success := status == "00"
-
-	db := database.DB
-
-	var payment models.Payment
-	if err := db.Where("order_id = ?", partnerRefNo).First(&payment).Error; err != nil {
+	err = svc().Investments.ConfirmPayment(r.Context(), partnerRefNo, status == "00")
+	switch {
+	case errors.Is(err, services.ErrPaymentNotFound):
 		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Pembayaran tidak ditemukan"})
 		return
-	}
-
-	paymentUpdates := map[string]interface{}{}
-	if success {
-		paymentUpdates["status"] = "Success"
-	} else {
-		paymentUpdates["status"] = "Failed"
-	}
-	if len(paymentUpdates) > 0 {
-		_ = db.Model(&payment).Updates(paymentUpdates).Error
-	}
-
-	var inv models.Investment
-	if err := db.Where("id = ?", payment.InvestmentID).First(&inv).Error; err != nil {
+	case errors.Is(err, services.ErrInvestmentNotFound):
 		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Investasi tidak ditemukan"})
 		return
-	}
-
-	if inv.Status != "Pending" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"responseCode":"2002800","responseMessage":"Successful"}`))
-		return
-	}
-
-	writePakailinkCallbackSuccess := func(w http.ResponseWriter) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(`{"responseCode":"2002800","responseMessage":"Successful"}`))
-	}
-
-	if success {
-		_ = db.Transaction(func(tx *gorm.DB) error {
-			return processInvestmentPaymentSuccess(tx, &inv)
-		})
-		writePakailinkCallbackSuccess(w)
+	case err != nil:
+		// Settling is idempotent, so let the gateway retry
+		log.Printf("[Pakailink] callback %s: %v", partnerRefNo, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
 
-	_ = db.Transaction(func(tx *gorm.DB) error {
-		_ = tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error
-		_ = tx.Model(&inv).Update("status", "Cancelled").Error
-		return nil
-	})
-	writePakailinkCallbackSuccess(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"responseCode":"2002800","responseMessage":"Successful"}`))
 }
 
 // POST /api/cron/daily-returns
 func CronDailyReturnsHandler(w http.ResponseWriter, r *http.Request) {
 	key := r.Header.Get("X-CRON-KEY")
-	if key == "" || key != os.Getenv("CRON_KEY") {
+	if key == "" || key != config.Get().Cron.Key {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
 		return
 	}
 
-	db := database.DB
-	now := time.Now()
-	var due []models.Investment
-	if err := db.Where("status = 'Running' AND next_return_at IS NOT NULL AND next_return_at <= ? AND total_paid < duration", now).Find(&due).Error; err != nil {
+	res, err := svc().Investments.ProcessDailyReturns(r.Context())
+	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
 		return
 	}
-	processed := 0
-	for i := range due {
-		inv := due[i]
-		_ = db.Transaction(func(tx *gorm.DB) error {
-			var user models.User
-			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, inv.UserID).Error; err != nil {
-				return err
-			}
-
-			// Get category to check profit type
-			var category models.Category
-			if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err != nil {
-				return err
-			}
-
-			amount := inv.DailyProfit
-			paid := inv.TotalPaid + 1
-			returned := round3(inv.TotalReturned + amount)
-
-			var product models.Product
-			if err := tx.Where("id = ?", inv.ProductID).First(&product).Error; err != nil {
-				return err
-			}
-
-			// For locked (Monitor) category: Don't pay to balance until completion, just accumulate
-			// For unlocked (Insight/AutoPilot): Pay to balance immediately
-			if category.ProfitType == "unlocked" {
-				newBalance := round3(user.Balance + amount)
-				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-					return err
-				}
-
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Profit investasi produk %s", product.Name)
-				trx := models.Transaction{
-					UserID:          inv.UserID,
-					Amount:          amount,
-					Charge:          0,
-					OrderID:         orderID,
-					TransactionFlow: "debit",
-					TransactionType: "return",
-					Message:         &msg,
-					Status:          "Success",
-				}
-				if err := tx.Create(&trx).Error; err != nil {
-					return err
-				}
-			}
-
-			// For locked (Monitor): If completing, pay total accumulated profit
-			if category.ProfitType == "locked" && paid >= inv.Duration {
-				totalProfit := round3(inv.DailyProfit * float64(inv.Duration))
-				newBalance := round3(user.Balance + totalProfit)
-				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-					return err
-				}
-
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Total profit investasi produk %s selesai", product.Name)
-				trx := models.Transaction{
-					UserID:          inv.UserID,
-					Amount:          totalProfit,
-					Charge:          0,
-					OrderID:         orderID,
-					TransactionFlow: "debit",
-					TransactionType: "return",
-					Message:         &msg,
-					Status:          "Success",
-				}
-				if err := tx.Create(&trx).Error; err != nil {
-					return err
-				}
-			}
-
-			// NO TEAM BONUSES - removed completely
-
-			nowTime := time.Now()
-			nextTime := nowTime.Add(24 * time.Hour)
-			updates := map[string]interface{}{"total_paid": paid, "total_returned": returned, "last_return_at": nowTime, "next_return_at": nextTime}
-			if paid >= inv.Duration {
-				updates["status"] = "Completed"
-
-				newBalance := round3(user.Balance + inv.Amount)
-				if err := tx.Model(&user).Update("balance", newBalance).Error; err != nil {
-					return err
-				}
-
-				orderID := utils.GenerateOrderID(inv.UserID)
-				msg := fmt.Sprintf("Pengembalian modal investasi produk %s", product.Name)
-				trx := models.Transaction{
-					UserID:          inv.UserID,
-					Amount:          inv.Amount,
-					Charge:          0,
-					OrderID:         orderID,
-					TransactionFlow: "debit",
-					TransactionType: "return",
-					Message:         &msg,
-					Status:          "Success",
-				}
-				if err := tx.Create(&trx).Error; err != nil {
-					return err
-				}
-			}
-			if err := tx.Model(&inv).Updates(updates).Error; err != nil {
-				return err
-			}
-			processed++
-			return nil
-		})
+	if res.Holiday != "" {
+		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron skipped: " + res.Holiday, Data: map[string]interface{}{"processed": 0, "holiday": res.Holiday}})
+		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": processed}})
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": res.Processed}})
 }
 
 // POST /v3/cron/expired-handlers
 func ExpiredPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 	key := r.Header.Get("X-CRON-KEY")
-	if key == "" || key != os.Getenv("CRON_KEY") {
+	if key == "" || key != config.Get().Cron.Key {
 		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
 		return
 	}
 
 	db := database.DB
-	now := time.Now()
+	now := utils.SystemClock.Now()
 
 	// Find all payments that are expired (expired_at <= now) and still Pending
 	var expiredPayments []models.Payment
@@ -1173,6 +617,7 @@ func ExpiredPaymentsHandler(w http.ResponseWriter, r *http.Request) {
 			// Log error but continue processing other payments
 			continue
 		}
+		metrics.PaymentsExpired.WithLabelValues(metrics.PaymentMethod(payment.PaymentMethod)).Inc()
 	}
 
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"processed": processed}})
@@ -1193,24 +638,3 @@ func parseTimeFlexible(s string) (time.Time, error) {
 	}
 	return time.Time{}, fmt.Errorf("cannot parse time: %s", s)
 }
-
-func round3(f float64) float64 {
-	return float64(int(f*100+0.5)) / 100
-}
-
-// calculateVIPLevel determines VIP level based on total locked category investments
-// VIP1: 50k, VIP2: 1.2M, VIP3: 7M, VIP4: 30M, VIP5: 150M
-func calculateVIPLevel(totalInvestVIP float64) uint {
-	if totalInvestVIP >= 150000000 {
-		return 5
-	} else if totalInvestVIP >= 30000000 {
-		return 4
-	} else if totalInvestVIP >= 10000000 {
-		return 3
-	} else if totalInvestVIP >= 1200000 {
-		return 2
-	} else if totalInvestVIP >= 50000 {
-		return 1
-	}
-	return 0
-}
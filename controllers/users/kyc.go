@@ -0,0 +1,227 @@
+package users
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	kycMaxImageSize = 5 << 20
+	// kycMaxImagePixels bounds width×height before decoding: a small, highly compressed
+	// file can declare dimensions that take gigabytes to decode. 25 MP covers phone cameras.
+	kycMaxImagePixels = 25_000_000
+)
+
+// GET /api/users/kyc
+func KYCStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.Select("id", "kyc_status").First(&user, uid).Error; err != nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		return
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	setting, err := models.GetSetting(sqlDB)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	data := map[string]interface{}{
+		"status":                     user.KYCStatus,
+		"withdrawal_threshold":       setting.KYCWithdrawalThreshold,
+		"daily_withdrawal_threshold": setting.KYCDailyWithdrawalThreshold,
+		"submission":                 nil,
+	}
+	var latest models.KYCSubmission
+	if err := db.Where("user_id = ?", uid).Order("id DESC").First(&latest).Error; err == nil {
+		data["submission"] = map[string]interface{}{
+			"id":            latest.ID,
+			"full_name":     latest.FullName,
+			"id_number":     maskIDNumber(latest.IDNumber),
+			"status":        latest.Status,
+			"reject_reason": latest.RejectReason,
+			"submitted_at":  latest.CreatedAt,
+			"reviewed_at":   latest.ReviewedAt,
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: data})
+}
+
+// POST /api/users/kyc
+// Multipart form: full_name, id_number, id_card (image), selfie (image holding the ID card)
+func SubmitKYCHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 2*kycMaxImageSize+(1<<20))
+	if err := r.ParseMultipartForm(2 * kycMaxImageSize); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid form data"})
+		return
+	}
+
+	fullName := strings.TrimSpace(r.FormValue("full_name"))
+	if fullName == "" || len(fullName) > 100 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Nama lengkap sesuai KTP wajib diisi"})
+		return
+	}
+	idNumber := strings.TrimSpace(r.FormValue("id_number"))
+	if err := utils.ValidateIDNumber(idNumber); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	// Check the state before uploading anything; SubmitKYC re-checks under lock
+	var user models.User
+	if err := database.DB.Select("id", "kyc_status").First(&user, uid).Error; err != nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		return
+	}
+	switch user.KYCStatus {
+	case utils.KYCStatusVerified:
+		writeKYCSubmitError(w, utils.ErrKYCAlreadyVerified)
+		return
+	case utils.KYCStatusPending:
+		writeKYCSubmitError(w, utils.ErrKYCPending)
+		return
+	}
+
+	now := time.Now()
+	keys := make(map[string]string, 2)
+	for _, doc := range []string{utils.KYCDocumentIDCard, utils.KYCDocumentSelfie} {
+		data, ext, msg := readKYCImage(r, doc)
+		if msg != "" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: msg})
+			return
+		}
+		key := utils.KYCObjectKey(uid, doc, ext, now)
+		if err := utils.UploadToS3(key, bytes.NewReader(data), int64(len(data))); err != nil {
+			log.Printf("[kyc] upload %s for user %d: %v", doc, uid, err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengupload dokumen"})
+			return
+		}
+		keys[doc] = key
+	}
+
+	var sub *models.KYCSubmission
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		sub, err = utils.SubmitKYC(tx, uid, fullName, idNumber, keys[utils.KYCDocumentIDCard], keys[utils.KYCDocumentSelfie])
+		return err
+	})
+	if err != nil {
+		for _, key := range keys {
+			_ = utils.DeleteFromS3(key)
+		}
+		writeKYCSubmitError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Dokumen verifikasi berhasil dikirim dan sedang ditinjau",
+		Data: map[string]interface{}{
+			"id":           sub.ID,
+			"status":       sub.Status,
+			"submitted_at": sub.CreatedAt,
+		},
+	})
+}
+
+func writeKYCSubmitError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, utils.ErrKYCAlreadyVerified):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Akun Anda sudah terverifikasi"})
+	case errors.Is(err, utils.ErrKYCPending):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Dokumen Anda masih dalam peninjauan"})
+	case errors.Is(err, utils.ErrKYCIDNumberUsed):
+		utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "NIK sudah terverifikasi di akun lain"})
+	default:
+		log.Printf("[kyc] submit error: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+	}
+}
+
+// readKYCImage reads a JPG/PNG upload and re-encodes it, which also drops EXIF data such
+// as GPS location. Returns a user-facing message on failure.
+func readKYCImage(r *http.Request, field string) ([]byte, string, string) {
+	file, handler, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", "Foto KTP dan selfie wajib diunggah"
+	}
+	defer file.Close()
+	if handler.Size > kycMaxImageSize {
+		return nil, "", "Gambar maksimal 5MB"
+	}
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", "Gagal membaca gambar"
+	}
+	detected := http.DetectContentType(raw)
+	if detected != "image/jpeg" && detected != "image/png" {
+		return nil, "", "Gambar harus JPG/PNG"
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", "Invalid image format"
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > kycMaxImagePixels {
+		return nil, "", "Resolusi gambar terlalu besar"
+	}
+	img, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, "", "Invalid image format"
+	}
+
+	var out bytes.Buffer
+	ext := ".jpg"
+	if format == "png" {
+		ext = ".png"
+		err = png.Encode(&out, img)
+	} else {
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, "", "Gagal memproses gambar"
+	}
+	return out.Bytes(), ext, ""
+}
+
+// maskIDNumber keeps the first 6 (region) and last 4 digits of an NIK.
+func maskIDNumber(nik string) string {
+	if len(nik) <= 10 {
+		return nik
+	}
+	return nik[:6] + strings.Repeat("*", len(nik)-10) + nik[len(nik)-4:]
+}
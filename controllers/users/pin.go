@@ -0,0 +1,339 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"project/controllers/auth"
+	"project/database"
+	"project/middleware"
+	"project/models"
+	"project/utils"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+type SetPINRequest struct {
+	Password   string `json:"password"`
+	PIN        string `json:"pin"`
+	ConfirmPIN string `json:"confirm_pin"`
+}
+
+type ChangePINRequest struct {
+	CurrentPIN string `json:"current_pin"`
+	PIN        string `json:"pin"`
+	ConfirmPIN string `json:"confirm_pin"`
+}
+
+type ResetPINRequest struct {
+	RequestID  string `json:"request_id"`
+	OTP        string `json:"otp"`
+	PIN        string `json:"pin"`
+	ConfirmPIN string `json:"confirm_pin"`
+}
+
+// authorizeTransaction verifies the transaction PIN sent with a sensitive request and
+// writes the error response itself. It returns false when the handler must stop.
+func authorizeTransaction(w http.ResponseWriter, uid uint, pin string) bool {
+	if pin == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "PIN transaksi wajib diisi"})
+		return false
+	}
+
+	attempt, err := utils.VerifyTransactionPIN(database.DB, uid, pin, utils.SystemClock.Now())
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return false
+	}
+
+	if attempt.Err == nil {
+		return true
+	}
+	writePINAttempt(w, attempt)
+	return false
+}
+
+// writePINAttempt writes the response for a failed PIN attempt.
+func writePINAttempt(w http.ResponseWriter, attempt utils.PINAttempt) {
+	switch attempt.Err {
+	case utils.ErrPINNotSet:
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{
+			Success: false,
+			Message: "Silakan buat PIN transaksi terlebih dahulu",
+			Data:    map[string]interface{}{"pin_required": true},
+		})
+	case utils.ErrPINLocked:
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{
+			Success: false,
+			Message: "PIN transaksi terkunci karena terlalu banyak percobaan salah. Silakan coba lagi nanti atau reset PIN",
+			Data:    map[string]interface{}{"locked_until": attempt.LockedUntil},
+		})
+	default:
+		utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{
+			Success: false,
+			Message: "PIN transaksi salah",
+			Data:    map[string]interface{}{"remaining_attempts": attempt.RemainingAttempts},
+		})
+	}
+}
+
+func validateNewPIN(w http.ResponseWriter, pin, confirm string) bool {
+	if err := utils.ValidatePINFormat(pin); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+		return false
+	}
+	if pin != confirm {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Konfirmasi PIN tidak cocok"})
+		return false
+	}
+	return true
+}
+
+// savePIN stores a new PIN hash for the user and clears any lockout.
+func savePIN(db *gorm.DB, uid uint, pin string) error {
+	hash, err := utils.HashPIN(pin)
+	if err != nil {
+		return err
+	}
+	var p models.UserPIN
+	err = db.Where("user_id = ?", uid).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Create(&models.UserPIN{UserID: uid, PINHash: hash}).Error
+	}
+	if err != nil {
+		return err
+	}
+	return db.Model(&p).Updates(map[string]interface{}{
+		"pin_hash":        hash,
+		"failed_attempts": 0,
+		"locked_until":    nil,
+	}).Error
+}
+
+// GET /api/users/pin
+func PINStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var pins []models.UserPIN
+	if err := database.DB.Where("user_id = ?", uid).Limit(1).Find(&pins).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	data := map[string]interface{}{"has_pin": len(pins) > 0, "locked_until": nil}
+	if len(pins) > 0 && pins[0].LockedUntil != nil && utils.SystemClock.Now().Before(*pins[0].LockedUntil) {
+		data["locked_until"] = pins[0].LockedUntil
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: data})
+}
+
+// POST /api/users/pin
+// Creates the first PIN; the account password is required so a leaked token alone cannot set it.
+func SetPINHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	var req SetPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request"})
+		return
+	}
+	if !validateNewPIN(w, req.PIN, req.ConfirmPIN) {
+		return
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.First(&user, uid).Error; err != nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kata sandi tidak cocok"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.UserPIN{}).Where("user_id = ?", uid).Count(&count).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	if count > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "PIN transaksi sudah dibuat, gunakan ubah PIN atau reset PIN"})
+		return
+	}
+
+	if err := savePIN(db, uid, req.PIN); err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan PIN transaksi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "PIN transaksi berhasil dibuat"})
+}
+
+// PUT /api/users/pin
+func ChangePINHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	var req ChangePINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request"})
+		return
+	}
+	if !validateNewPIN(w, req.PIN, req.ConfirmPIN) {
+		return
+	}
+	if !authorizeTransaction(w, uid, req.CurrentPIN) {
+		return
+	}
+
+	if err := savePIN(database.DB, uid, req.PIN); err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan PIN transaksi"})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "PIN transaksi berhasil diubah"})
+}
+
+// POST /api/users/pin/reset/request-otp
+// Sends a Fazpass OTP to the registered number, same flow as forgot password.
+func ResetPINRequestOTPHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	db := database.DB
+	var user models.User
+	if err := db.First(&user, uid).Error; err != nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "User not found"})
+		return
+	}
+
+	otpLimiter := middleware.GetOTPRateLimiter()
+	allowed, waitTime, msg := otpLimiter.CheckIPRateLimit(middleware.GetClientIP(r))
+	if allowed {
+		allowed, waitTime, msg = otpLimiter.CheckPhoneRateLimit(user.Number)
+	}
+	if !allowed {
+		utils.WriteJSON(w, http.StatusTooManyRequests, utils.APIResponse{
+			Success: false,
+			Message: msg,
+			Data:    map[string]interface{}{"retry_after_seconds": int(waitTime.Seconds())},
+		})
+		return
+	}
+
+	fazpassResp, err := utils.RequestOTP(user.Number)
+	if err != nil {
+		if fazpassErr, ok := err.(*utils.FazpassError); ok {
+			httpStatus := http.StatusBadRequest
+			if fazpassErr.HTTPCode >= 400 && fazpassErr.HTTPCode < 600 {
+				httpStatus = fazpassErr.HTTPCode
+			}
+			utils.WriteJSON(w, httpStatus, utils.APIResponse{Success: false, Message: utils.GetUserFriendlyMessage(fazpassErr.Code)})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal mengirim Kode Verifikasi. Silakan coba lagi nanti."})
+		return
+	}
+
+	// Drop older unverified PIN reset requests so only the latest OTP can reset the PIN;
+	// a forgot-password OTP in flight is left alone
+	db.Where("user_id = ? AND purpose = ? AND verified = ?", uid, auth.OTPPurposePINReset, false).Delete(&auth.OTPRequest{})
+	otpReq := auth.OTPRequest{
+		UserID:    uid,
+		Phone:     user.Number,
+		OTPID:     fazpassResp.Data.ID,
+		Purpose:   auth.OTPPurposePINReset,
+		ExpiresAt: utils.SystemClock.Now().Add(10 * time.Minute),
+	}
+	if err := db.Create(&otpReq).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan. Silakan coba lagi nanti."})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Kode Verifikasi berhasil dikirim",
+		Data: map[string]interface{}{
+			"request_id":          fazpassResp.Data.ID,
+			"retry_after_seconds": otpLimiter.GetRetryAfterSeconds(user.Number),
+		},
+	})
+}
+
+// POST /api/users/pin/reset
+// Verifies the OTP and replaces the PIN, clearing any lockout.
+func ResetPINHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	var req ResetPINRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request"})
+		return
+	}
+	if req.OTP == "" || req.RequestID == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode Verifikasi harus diisi"})
+		return
+	}
+	if !validateNewPIN(w, req.PIN, req.ConfirmPIN) {
+		return
+	}
+
+	db := database.DB
+	var otpReq auth.OTPRequest
+	if err := db.Where("otp_id = ? AND user_id = ? AND purpose = ? AND verified = ?", req.RequestID, uid, auth.OTPPurposePINReset, false).First(&otpReq).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Request Kode Verifikasi tidak ditemukan atau sudah digunakan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan. Silakan coba lagi nanti."})
+		return
+	}
+	if utils.SystemClock.Now().After(otpReq.ExpiresAt) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode Verifikasi sudah kadaluarsa"})
+		return
+	}
+
+	if _, err := utils.VerifyOTP(req.RequestID, req.OTP); err != nil {
+		if fazpassErr, ok := err.(*utils.FazpassError); ok {
+			httpStatus := http.StatusBadRequest
+			if fazpassErr.HTTPCode >= 400 && fazpassErr.HTTPCode < 600 {
+				httpStatus = fazpassErr.HTTPCode
+			}
+			utils.WriteJSON(w, httpStatus, utils.APIResponse{Success: false, Message: utils.GetUserFriendlyMessage(fazpassErr.Code)})
+			return
+		}
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Kode Verifikasi salah"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&otpReq).Update("verified", true).Error; err != nil {
+			return err
+		}
+		return savePIN(tx, uid, req.PIN)
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Gagal menyimpan PIN transaksi"})
+		return
+	}
+
+	middleware.GetOTPRateLimiter().ResetPhoneLimit(otpReq.Phone)
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "PIN transaksi berhasil direset"})
+}
@@ -0,0 +1,60 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"project/database"
+	"project/services"
+	"project/utils"
+)
+
+// Services backs the money handlers (investments, withdrawals, transfers, gifts).
+// InitRouter installs it; when unset the handlers build one over database.DB.
+var Services *services.Services
+
+var (
+	defaultServicesOnce sync.Once
+	defaultServices     *services.Services
+)
+
+func svc() *services.Services {
+	if Services != nil {
+		return Services
+	}
+	defaultServicesOnce.Do(func() {
+		defaultServices = services.New(services.Deps{DB: database.DB})
+	})
+	return defaultServices
+}
+
+// writePINError writes the response for a rejected transaction PIN and reports whether
+// err was a PIN error.
+func writePINError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, services.ErrPINRequired) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "PIN transaksi wajib diisi"})
+		return true
+	}
+	var pinErr *services.PINError
+	if !errors.As(err, &pinErr) {
+		return false
+	}
+	writePINAttempt(w, pinErr.Attempt)
+	return true
+}
+
+// writePolicyViolation writes a rule violation as 400 with its message, and reports
+// whether err was one. withData also returns the violation itself for the client.
+func writePolicyViolation(w http.ResponseWriter, err error, withData bool) bool {
+	var v *utils.PolicyViolation
+	if !errors.As(err, &v) {
+		return false
+	}
+	resp := utils.APIResponse{Success: false, Message: v.Message}
+	if withData {
+		resp.Data = v
+	}
+	utils.WriteJSON(w, http.StatusBadRequest, resp)
+	return true
+}
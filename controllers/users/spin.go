@@ -1,17 +1,22 @@
 package users
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
+	"project/config"
 	"project/database"
 	"project/models"
 	"project/utils"
 
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func userIDFromAuthHeader(r *http.Request) (uint, error) {
@@ -180,7 +185,9 @@ func SpinPrizeListHandler(w http.ResponseWriter, r *http.Request) {
 // 	})
 // }
 
-// GET /api/users/spin-v2
+// POST /api/users/spin
+// The prize is drawn from the user's committed server seed (see GET /users/spin/seed) and
+// the seed's next nonce, against the weight table of the prizes active at this moment.
 func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := userIDFromAuthHeader(r)
 	if err != nil || userID == 0 {
@@ -190,127 +197,347 @@ func UserSpinHandler(w http.ResponseWriter, r *http.Request) {
 
 	db := database.DB
 
-	// Get user and check spin_ticket
-	var user models.User
-	if err := db.Select("id, balance, spin_ticket").Where("id = ?", userID).First(&user).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan data, silakan coba lagi"})
-		log.Println(err)
-		return
-	}
-	if user.SpinTicket == nil || *user.SpinTicket == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tiket spin Anda habis, silakan dapatkan tiket terlebih dahulu"})
-		return
-	}
-
-	// Load active prizes (ensure chance field populated from chance_weight)
-
-	var prizes []models.SpinPrize
-	if err := db.Select("id, amount, code, chance_weight, status").Where("status = 'Active'").Order("amount ASC").Find(&prizes).Error; err != nil || len(prizes) == 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia"})
-		return
-	}
-
-	// Sum weights
-	totalWeight := 0
-	for _, p := range prizes {
-		if p.ChanceWeight > 0 {
-			totalWeight += p.ChanceWeight
+	var previousBalance, currentBalance float64
+	var userSpin models.UserSpin
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := utils.SystemClock.Now()
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id, balance").Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
 		}
-	}
-	if totalWeight <= 0 {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia"})
-		return
-	}
-
-	// Pick prize by weighted chance
-	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
-	pick := rnd.Intn(totalWeight) + 1
-	acc := 0
-	var finalPrize models.SpinPrize
-	for _, p := range prizes {
-		acc += p.ChanceWeight
-		if pick <= acc {
-			finalPrize = p
-			break
+		ticket, err := utils.ConsumeSpinTicket(tx, userID, now)
+		if err != nil {
+			return err
 		}
-	}
+		previousBalance = user.Balance
 
-	previousBalance := user.Balance
-	var currentBalance float64
+		// Locking the active prizes serializes spins so budgets and quantity limits cannot be overrun
+		var prizes []models.SpinPrize
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status = 'Active'").Order("amount ASC, id ASC").Find(&prizes).Error; err != nil {
+			return err
+		}
+		table, totalWeight := utils.SpinWeightTable(prizes)
+		if totalWeight <= 0 {
+			return utils.ErrSpinNoPrizes
+		}
+		snapshot, err := json.Marshal(table)
+		if err != nil {
+			return err
+		}
 
-	err = db.Transaction(func(tx *gorm.DB) error {
-		// Decrement spin_ticket
-		if err := tx.Model(&models.User{}).Where("id = ? AND spin_ticket > 0", userID).UpdateColumn("spin_ticket", gorm.Expr("spin_ticket - 1")).Error; err != nil {
+		seed, err := utils.ActiveSpinSeed(tx, userID)
+		if err != nil {
 			return err
 		}
+		roll := utils.SpinRoll(seed.ServerSeed, seed.ClientSeed, seed.Nonce, totalWeight)
+		drawn, ok := utils.PickSpinPrize(table, roll)
+		if !ok {
+			return utils.ErrSpinNoPrizes
+		}
 
-		// Create transaction
-		msg := "Hadiah Spin Wheel"
-		orderID := utils.GenerateOrderID(userID)
-
-		trx := models.Transaction{
-			UserID:          userID,
-			Amount:          finalPrize.Amount,
-			Charge:          0,
-			OrderID:         orderID,
-			TransactionFlow: "debit",
-			TransactionType: "bonus",
-			Message:         &msg,
-			Status:          "Success",
+		var setting models.Setting
+		if err := tx.First(&setting).Error; err != nil {
+			return err
 		}
-		if err := tx.Create(&trx).Error; err != nil {
-			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
-			log.Println(err)
+		usage, err := utils.LoadSpinBudgetUsage(tx, now)
+		if err != nil {
 			return err
 		}
-
-		// Record user spin history
-		userSpin := models.UserSpin{
-			UserID:  userID,
-			PrizeID: finalPrize.ID,
-			Amount:  finalPrize.Amount,
-			Code:    finalPrize.Code,
-			WonAt:   time.Now(),
+		var drawnPrize models.SpinPrize
+		for _, p := range prizes {
+			if p.ID == drawn.PrizeID {
+				drawnPrize = p
+				break
+			}
 		}
-		if err := tx.Create(&userSpin).Error; err != nil {
+		prize, fallbackReason := utils.ResolveSpinPrize(drawnPrize, prizes[0], utils.SpinBudgetFromSetting(&setting), usage)
+		if err := tx.Model(seed).UpdateColumn("nonce", gorm.Expr("nonce + 1")).Error; err != nil {
 			return err
 		}
 
-		// Increase user's balance
-		if err := tx.Model(&models.User{}).
-			Where("id = ?", userID).
-			UpdateColumn("balance", gorm.Expr("balance + ?", finalPrize.Amount)).Error; err != nil {
+		// Held as Pending while the user's bonuses are frozen for review
+		prizeStatus, err := utils.CreditBonus(tx, userID, prize.Amount, "bonus", "Hadiah Spin Wheel")
+		if err != nil {
 			return err
 		}
 
-		// Read updated balance
-		if err := tx.Select("balance").Where("id = ?", userID).First(&user).Error; err != nil {
+		userSpin = models.UserSpin{
+			UserID:         userID,
+			PrizeID:        prize.ID,
+			Amount:         prize.Amount,
+			Code:           prize.Code,
+			WonAt:          now,
+			SeedID:         &seed.ID,
+			ServerSeedHash: seed.ServerSeedHash,
+			ClientSeed:     seed.ClientSeed,
+			Nonce:          seed.Nonce,
+			Roll:           roll,
+			TotalWeight:    totalWeight,
+			WeightSnapshot: string(snapshot),
+		}
+		if fallbackReason != "" {
+			userSpin.FallbackFromPrizeID = &drawn.PrizeID
+			userSpin.FallbackReason = fallbackReason
+		}
+		if err := tx.Create(&userSpin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(ticket).Update("user_spin_id", userSpin.ID).Error; err != nil {
+			return err
+		}
+		if err := utils.RecordSpinWin(tx, prize.ID, prize.Amount, fallbackReason != "", now); err != nil {
 			return err
 		}
-		currentBalance = user.Balance
+
+		currentBalance = previousBalance
+		if prizeStatus == "Success" {
+			currentBalance = utils.RoundFloat(previousBalance+prize.Amount, 2)
+		}
 		return nil
 	})
 
 	if err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan server, silakan coba lagi"})
-		log.Println(err)
+		switch {
+		case errors.Is(err, utils.ErrNoSpinTicket):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tiket spin Anda habis, silakan dapatkan tiket terlebih dahulu"})
+		case errors.Is(err, utils.ErrSpinNoPrizes):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Hadiah tidak valid atau sudah tidak tersedia"})
+		case errors.Is(err, utils.ErrNoSpinSeed):
+			utils.WriteJSON(w, http.StatusConflict, utils.APIResponse{Success: false, Message: "Seed spin belum tersedia, silakan muat ulang halaman spin"})
+		default:
+			log.Println(err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan server, silakan coba lagi"})
+		}
 		return
 	}
 
-	// Success response (same shape as claim handler)
 	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
 		Success: true,
-		Message: fmt.Sprintf("Selamat! Anda memenangkan Rp%.0f", finalPrize.Amount),
+		Message: fmt.Sprintf("Selamat! Anda memenangkan Rp%.0f", userSpin.Amount),
 		Data: map[string]interface{}{
 			"spin_result": map[string]interface{}{
-				"amount": finalPrize.Amount,
-				"code":   finalPrize.Code,
+				"id":     userSpin.ID,
+				"amount": userSpin.Amount,
+				"code":   userSpin.Code,
 			},
 			"balance_info": map[string]interface{}{
 				"previous_balance": int64(previousBalance),
-				"prize_amount":     int64(finalPrize.Amount),
+				"prize_amount":     int64(userSpin.Amount),
 				"current_balance":  int64(currentBalance),
 			},
+			"fairness": map[string]interface{}{
+				"server_seed_hash": userSpin.ServerSeedHash,
+				"client_seed":      userSpin.ClientSeed,
+				"nonce":            userSpin.Nonce,
+				"roll":             userSpin.Roll,
+				"total_weight":     userSpin.TotalWeight,
+			},
 		},
 	})
 }
+
+// GET /api/users/spin/seed
+// Returns the hash of the active server seed, committed before any spin that uses it.
+// The first call creates the seed; spinning is refused until then.
+func SpinSeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromAuthHeader(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var seed *models.SpinSeed
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		seed, err = utils.PublishSpinSeed(tx, userID)
+		return err
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: seed})
+}
+
+type RotateSpinSeedRequest struct {
+	ClientSeed string `json:"client_seed"`
+}
+
+// POST /api/users/spin/seed/rotate
+// Reveals the current server seed so past spins can be verified, and commits to a new one.
+func RotateSpinSeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromAuthHeader(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var req RotateSpinSeedRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	req.ClientSeed = strings.TrimSpace(req.ClientSeed)
+	if req.ClientSeed != "" {
+		if err := utils.ValidateClientSeed(req.ClientSeed); err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: err.Error()})
+			return
+		}
+	}
+
+	var revealed, next *models.SpinSeed
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		revealed, next, err = utils.RotateSpinSeed(tx, userID, req.ClientSeed, utils.SystemClock.Now())
+		return err
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	data := map[string]interface{}{"active": next}
+	if revealed != nil {
+		data["revealed"] = map[string]interface{}{
+			"id":               revealed.ID,
+			"server_seed":      revealed.ServerSeed,
+			"server_seed_hash": revealed.ServerSeedHash,
+			"client_seed":      revealed.ClientSeed,
+			"last_nonce":       revealed.Nonce,
+			"revealed_at":      revealed.RevealedAt,
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Server seed berhasil diganti", Data: data})
+}
+
+// GET /api/users/spin/{id}/verify
+func VerifySpinHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromAuthHeader(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "ID tidak valid"})
+		return
+	}
+
+	spin, seed, verification, err := utils.LoadSpinVerification(database.DB, uint(id))
+	if err == nil || errors.Is(err, utils.ErrSpinNotFair) {
+		if spin.UserID != userID {
+			err = gorm.ErrRecordNotFound
+		}
+	}
+	switch {
+	case err == nil:
+		utils.WriteJSON(w, http.StatusOK, utils.SpinVerificationResponse(spin, seed, verification))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Riwayat spin tidak ditemukan"})
+	case errors.Is(err, utils.ErrSpinNotFair):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Spin ini dibuat sebelum sistem provably fair dan tidak dapat diverifikasi"})
+	default:
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+	}
+}
+
+// GET /api/users/spin/tickets?status=available&page=1&limit=20
+func SpinTicketHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := userIDFromAuthHeader(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	db := database.DB
+	now := utils.SystemClock.Now()
+	query := db.Model(&models.SpinTicketGrant{}).Where("user_id = ?", userID)
+	switch r.URL.Query().Get("status") {
+	case utils.SpinTicketAvailable:
+		query = query.Where("consumed_at IS NULL AND expired_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+	case utils.SpinTicketConsumed:
+		query = query.Where("consumed_at IS NOT NULL")
+	case utils.SpinTicketExpired:
+		query = query.Where("consumed_at IS NULL AND (expired_at IS NOT NULL OR expires_at <= ?)", now)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+	var grants []models.SpinTicketGrant
+	if err := query.Order("granted_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&grants).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	var available int64
+	if err := db.Model(&models.SpinTicketGrant{}).
+		Where("user_id = ? AND consumed_at IS NULL AND expired_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&available).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		return
+	}
+
+	type ticketItem struct {
+		models.SpinTicketGrant
+		Status string `json:"status"`
+	}
+	items := make([]ticketItem, 0, len(grants))
+	for _, g := range grants {
+		items = append(items, ticketItem{SpinTicketGrant: g, Status: utils.SpinTicketStatus(g, now)})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"available": available,
+			"total":     total,
+			"page":      page,
+			"limit":     limit,
+			"items":     items,
+		},
+	})
+}
+
+// POST /api/cron/spin-ticket-expiry
+func CronExpireSpinTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != config.Get().Cron.Key {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	db := database.DB
+	now := utils.SystemClock.Now()
+	var userIDs []uint
+	if err := db.Model(&models.SpinTicketGrant{}).
+		Where("consumed_at IS NULL AND expired_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", now).
+		Distinct("user_id").
+		Limit(1000).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	var expired int64
+	for _, id := range userIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			n, err := utils.ExpireSpinTickets(tx, id, now)
+			expired += n
+			return err
+		})
+		if err != nil {
+			log.Printf("[cron/spin-ticket-expiry] user %d: %v", id, err)
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"users": len(userIDs), "expired": expired}})
+}
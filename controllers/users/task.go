@@ -2,13 +2,17 @@ package users
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"project/database"
 	"project/models"
 	"project/utils"
-	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GET /api/users/task
@@ -19,96 +23,69 @@ func TaskListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	db := database.DB
+	now := time.Now()
 	var tasks []models.Task
 	if err := db.Where("status = ?", "Active").Order("id ASC").Find(&tasks).Error; err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
-	// Get claimed tasks
+
+	progress, err := utils.LoadTaskProgress(db, uid, tasks, now)
+	if err != nil {
+		log.Printf("[task] progress for user %d: %v", uid, err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
+		return
+	}
+
+	// Claims in each task's current period
 	var claimed []models.UserTask
 	db.Where("user_id = ?", uid).Find(&claimed)
-	claimedMap := map[uint]bool{}
+	claimedMap := map[string]bool{}
 	for _, ut := range claimed {
-		claimedMap[ut.TaskID] = true
+		claimedMap[taskClaimKey(ut.TaskID, ut.PeriodKey)] = true
 	}
-	// Calculate active subordinates for each level
-	getActiveCount := func(level int) int64 {
-		db := database.DB
-		var level1 []models.User
-		if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
-			return 0
-		}
-		if level == 1 {
-			n := 0
-			for _, u := range level1 {
-				if strings.ToLower(u.InvestmentStatus) == "active" {
-					n++
-				}
-			}
-			return int64(n)
-		}
-		// Level 2
-		level1IDs := make([]uint, 0, len(level1))
-		for _, u := range level1 {
-			level1IDs = append(level1IDs, u.ID)
-		}
-		var level2 []models.User
-		if len(level1IDs) > 0 {
-			db.Where("reff_by IN ?", level1IDs).Find(&level2)
-		}
-		if level == 2 {
-			n := 0
-			for _, u := range level2 {
-				if strings.ToLower(u.InvestmentStatus) == "active" {
-					n++
-				}
-			}
-			return int64(n)
-		}
-		// Level 3
-		level2IDs := make([]uint, 0, len(level2))
-		for _, u := range level2 {
-			level2IDs = append(level2IDs, u.ID)
-		}
-		var level3 []models.User
-		if len(level2IDs) > 0 {
-			db.Where("reff_by IN ?", level2IDs).Find(&level3)
-		}
-		n := 0
-		for _, u := range level3 {
-			if strings.ToLower(u.InvestmentStatus) == "active" {
-				n++
-			}
-		}
-		return int64(n)
-	}
-	var resp []map[string]interface{}
+
+	resp := make([]map[string]interface{}, 0, len(tasks))
 	for _, t := range tasks {
-		activeCount := getActiveCount(t.RequiredLevel)
+		p := progress[t.ID]
 		percent := 0
-		if t.RequiredActiveMembers > 0 {
-			percent = int((float64(activeCount) / float64(t.RequiredActiveMembers)) * 100)
+		if p.Target > 0 {
+			percent = int(p.Progress / p.Target * 100)
 			if percent > 100 {
 				percent = 100
 			}
 		}
-		taken := claimedMap[t.ID]
-		lock := activeCount < t.RequiredActiveMembers
-		resp = append(resp, map[string]interface{}{
-			"id":                       t.ID,
-			"name":                     t.Name,
-			"reward":                   t.Reward,
-			"required_level":           t.RequiredLevel,
-			"required_active_members":  t.RequiredActiveMembers,
-			"active_subordinate_count": activeCount,
-			"taken":                    taken,
-			"lock":                     lock,
-			"percent":                  percent,
-		})
+		periodKey, _, periodEnd := utils.TaskPeriod(t.Type, now)
+		item := map[string]interface{}{
+			"id":                      t.ID,
+			"name":                    t.Name,
+			"reward":                  t.Reward,
+			"type":                    t.Type,
+			"criterion":               t.Criterion,
+			"required_level":          t.RequiredLevel,
+			"required_active_members": t.RequiredActiveMembers,
+			"target":                  p.Target,
+			"progress":                p.Progress,
+			"period_key":              periodKey,
+			"taken":                   claimedMap[taskClaimKey(t.ID, periodKey)],
+			"lock":                    p.Progress < p.Target,
+			"percent":                 percent,
+		}
+		if !periodEnd.IsZero() {
+			item["period_ends_at"] = periodEnd.Format(time.RFC3339)
+		}
+		if t.Criterion == utils.TaskCriterionActiveMembers {
+			item["active_subordinate_count"] = int64(p.Progress)
+		}
+		resp = append(resp, item)
 	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: resp})
 }
 
+func taskClaimKey(taskID uint, periodKey string) string {
+	return fmt.Sprintf("%d/%s", taskID, periodKey)
+}
+
 // POST /api/users/task/submit
 func TaskSubmitHandler(w http.ResponseWriter, r *http.Request) {
 	uid, ok := utils.GetUserID(r)
@@ -123,98 +100,110 @@ func TaskSubmitHandler(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request"})
 		return
 	}
-	db := database.DB
-	var task models.Task
-	if err := db.First(&task, req.TaskID).Error; err != nil {
-		utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Task not found"})
+
+	claim, task, err := utils.ClaimTask(database.DB, uid, req.TaskID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrTaskNotFound):
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Task not found"})
+		case errors.Is(err, utils.ErrTaskAlreadyClaimed):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tugas sudah pernah diambil"})
+		case errors.Is(err, utils.ErrTaskIncomplete):
+			utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Belum memenuhi syarat tugas"})
+		default:
+			log.Printf("[task] claim task %d for user %d: %v", req.TaskID, uid, err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+		}
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Hadiah berhasil diselesaikan",
+		Data: map[string]interface{}{
+			"task_id":    task.ID,
+			"reward":     task.Reward,
+			"period_key": claim.PeriodKey,
+			"claimed_at": claim.ClaimedAt,
+		},
+	})
+}
+
+// GET /api/users/checkin
+func CheckinStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
 		return
 	}
-	// Check if already claimed
-	var userTask models.UserTask
-	if err := db.Where("user_id = ? AND task_id = ?", uid, task.ID).First(&userTask).Error; err == nil {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Tugas sudah pernah diambil"})
+	now := time.Now()
+	var last *models.UserCheckin
+	var row models.UserCheckin
+	if err := database.DB.Where("user_id = ?", uid).Order("checkin_date DESC").First(&row).Error; err == nil {
+		last = &row
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
-	// Check active subordinates
-	getActiveCount := func(level int) int64 {
-		db := database.DB
-		var level1 []models.User
-		if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
-			return 0
-		}
-		if level == 1 {
-			n := 0
-			for _, u := range level1 {
-				if strings.ToLower(u.InvestmentStatus) == "active" {
-					n++
-				}
-			}
-			return int64(n)
-		}
-		// Level 2
-		level1IDs := make([]uint, 0, len(level1))
-		for _, u := range level1 {
-			level1IDs = append(level1IDs, u.ID)
-		}
-		var level2 []models.User
-		if len(level1IDs) > 0 {
-			db.Where("reff_by IN ?", level1IDs).Find(&level2)
-		}
-		if level == 2 {
-			n := 0
-			for _, u := range level2 {
-				if strings.ToLower(u.InvestmentStatus) == "active" {
-					n++
-				}
-			}
-			return int64(n)
-		}
-		// Level 3
-		level2IDs := make([]uint, 0, len(level2))
-		for _, u := range level2 {
-			level2IDs = append(level2IDs, u.ID)
-		}
-		var level3 []models.User
-		if len(level2IDs) > 0 {
-			db.Where("reff_by IN ?", level2IDs).Find(&level3)
+
+	today := utils.DateKey(utils.StartOfDay(now, utils.JakartaLocation()))
+	var lastDate *string
+	if last != nil {
+		lastDate = &last.CheckinDate
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
+		Success: true,
+		Message: "Successfully",
+		Data: map[string]interface{}{
+			"today":             today,
+			"checked_in_today":  lastDate != nil && *lastDate == today,
+			"streak":            utils.CurrentCheckinStreak(last, now),
+			"last_checkin_date": lastDate,
+		},
+	})
+}
+
+// POST /api/users/checkin
+func CheckinHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+	now := time.Now()
+	today := utils.DateKey(utils.StartOfDay(now, utils.JakartaLocation()))
+
+	var checkin models.UserCheckin
+	alreadyCheckedIn := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, uid).Error; err != nil {
+			return err
+		}
+		var last *models.UserCheckin
+		var row models.UserCheckin
+		if err := tx.Where("user_id = ?", uid).Order("checkin_date DESC").First(&row).Error; err == nil {
+			last = &row
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if last != nil && last.CheckinDate == today {
+			checkin, alreadyCheckedIn = *last, true
+			return nil
 		}
-		n := 0
-		for _, u := range level3 {
-			if strings.ToLower(u.InvestmentStatus) == "active" {
-				n++
-			}
+
+		checkin = models.UserCheckin{UserID: uid, CheckinDate: today, Streak: utils.NextCheckinStreak(last, now)}
+		if err := tx.Create(&checkin).Error; err != nil {
+			return err
 		}
-		return int64(n)
-	}
-	activeCount := getActiveCount(task.RequiredLevel)
-	if activeCount < task.RequiredActiveMembers {
-		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Belum memenuhi syarat tugas"})
+		return utils.InvalidateTaskProgress(tx, []uint{uid}, utils.TaskCriterionCheckinStreak)
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
 		return
 	}
-	// Add reward to user balance
-	if err := db.Model(&models.User{}).Where("id = ?", uid).Update("balance", gorm.Expr("balance + ?", task.Reward)).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Failed to update balance"})
+	if alreadyCheckedIn {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Anda sudah check-in hari ini", Data: checkin})
 		return
 	}
-	// Mark as claimed (let claimed_at use DB default)
-	db.Model(&models.UserTask{}).Create(map[string]interface{}{
-		"user_id": uid,
-		"task_id": task.ID,
-	})
-
-	db.Model(&models.Transaction{}).Create(map[string]interface{}{
-		"user_id":          uid,
-		"amount":           task.Reward,
-		"charge":           0,
-		"order_id":         utils.GenerateOrderID(uid),
-		"transaction_flow": "debit",
-		"transaction_type": "bonus",
-		"message":          ptrString("Reward tugas: " + task.Name),
-		"status":           "Success",
-	})
-	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Hadiah berhasil diselesaikan"})
-}
 
-func ptrString(s string) *string {
-	return &s
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{Success: true, Message: "Check-in berhasil", Data: checkin})
 }
@@ -1,8 +1,10 @@
 package users
 
 import (
+	"log"
 	"math"
 	"net/http"
+	"project/config"
 	"project/database"
 	"project/models"
 	"project/utils"
@@ -19,7 +21,6 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.DB
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
 	var levelStr string
@@ -27,87 +28,18 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 		levelStr = parts[4]
 	}
 	level, levelErr := strconv.Atoi(levelStr)
-	hasLevel := (levelErr == nil && level >= 1 && level <= 3)
+	hasLevel := (levelErr == nil && level >= 1 && level <= utils.MaxTeamDepth)
 
-	// Helper to get users by reff_by
-	getUsers := func(parentIDs []uint) ([]models.User, error) {
-		var users []models.User
-		if len(parentIDs) == 0 {
-			return users, nil
-		}
-		if err := db.Where("reff_by IN ?", parentIDs).Find(&users).Error; err != nil {
-			return nil, err
-		}
-		return users, nil
-	}
-
-	// Level 1
-	var level1 []models.User
-	if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
+	stats, err := utils.TeamStats(database.ReadDB(), uid, utils.MaxTeamDepth)
+	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
 		return
 	}
-	// Level 2
-	level1IDs := make([]uint, 0, len(level1))
-	for _, u := range level1 {
-		level1IDs = append(level1IDs, u.ID)
-	}
-	level2, _ := getUsers(level1IDs)
-	// Level 3
-	level2IDs := make([]uint, 0, len(level2))
-	for _, u := range level2 {
-		level2IDs = append(level2IDs, u.ID)
-	}
-	level3, _ := getUsers(level2IDs)
-
-	// Helper to count active
-	countActive := func(users []models.User) int {
-		n := 0
-		for _, u := range users {
-			if strings.ToLower(u.InvestmentStatus) == "active" {
-				n++
-			}
-		}
-		return n
-	}
-
-	countInactive := func(users []models.User) int {
-		n := 0
-		for _, u := range users {
-			if strings.ToLower(u.InvestmentStatus) == "inactive" {
-				n++
-			}
-		}
-		return n
-	}
-
-	// Helper to sum total_invest
-	sumTotalInvest := func(users []models.User) float64 {
-		total := 0.0
-		for _, u := range users {
-			total += u.TotalInvest
-		}
-		return total
-	}
 
 	// If /api/users/team-invited/{level}
 	if hasLevel {
-		var users []models.User
-		switch level {
-		case 1:
-			users = level1
-		case 2:
-			users = level2
-		case 3:
-			users = level3
-		}
 		resp := map[string]interface{}{
-			strconv.Itoa(level): map[string]interface{}{
-				"count":        len(users),
-				"active":       countActive(users),
-				"inactive":     countInactive(users),
-				"total_invest": sumTotalInvest(users),
-			},
+			strconv.Itoa(level): stats[level],
 		}
 		utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 			Success: true,
@@ -118,17 +50,14 @@ func TeamInvitedHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If /api/users/team-invited (all levels)
-	resp := map[string]interface{}{
-		"level": map[string]interface{}{
-			"1": map[string]interface{}{"count": len(level1), "active": countActive(level1), "inactive": countInactive(level1), "total_invest": sumTotalInvest(level1)},
-			"2": map[string]interface{}{"count": len(level2), "active": countActive(level2), "inactive": countInactive(level2), "total_invest": sumTotalInvest(level2)},
-			"3": map[string]interface{}{"count": len(level3), "active": countActive(level3), "inactive": countInactive(level3), "total_invest": sumTotalInvest(level3)},
-		},
+	resp := make(map[string]interface{}, utils.MaxTeamDepth)
+	for d := 1; d <= utils.MaxTeamDepth; d++ {
+		resp[strconv.Itoa(d)] = stats[d]
 	}
 	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{
 		Success: true,
 		Message: "Successfully",
-		Data:    resp["level"],
+		Data:    resp,
 	})
 }
 
@@ -140,7 +69,6 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.DB
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
 	var levelStr string
@@ -148,52 +76,11 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 		levelStr = parts[4]
 	}
 	level, err := strconv.Atoi(levelStr)
-	if err != nil || level < 1 || level > 3 {
+	if err != nil || level < 1 || level > utils.MaxTeamDepth {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Level must be 1, 2, or 3"})
 		return
 	}
 
-	// Helper to get users by reff_by
-	getUsers := func(parentIDs []uint) ([]models.User, error) {
-		var users []models.User
-		if len(parentIDs) == 0 {
-			return users, nil
-		}
-		if err := db.Where("reff_by IN ?", parentIDs).Find(&users).Error; err != nil {
-			return nil, err
-		}
-		return users, nil
-	}
-
-	// Level 1
-	var level1 []models.User
-	if err := db.Where("reff_by = ?", uid).Find(&level1).Error; err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
-		return
-	}
-	// Level 2
-	level1IDs := make([]uint, 0, len(level1))
-	for _, u := range level1 {
-		level1IDs = append(level1IDs, u.ID)
-	}
-	level2, _ := getUsers(level1IDs)
-	// Level 3
-	level2IDs := make([]uint, 0, len(level2))
-	for _, u := range level2 {
-		level2IDs = append(level2IDs, u.ID)
-	}
-	level3, _ := getUsers(level2IDs)
-
-	var users []models.User
-	switch level {
-	case 1:
-		users = level1
-	case 2:
-		users = level2
-	case 3:
-		users = level3
-	}
-
 	// Helper to censor phone number (mask last 4 digits)
 	censorNumber := func(num string) string {
 		n := len(num)
@@ -209,7 +96,7 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get query parameters
 	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
-	statusQuery := strings.TrimSpace(r.URL.Query().Get("status"))
+	statusQuery := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
 	pageStr := r.URL.Query().Get("page")
 	limitStr := r.URL.Query().Get("limit")
 
@@ -223,71 +110,52 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
-	// Apply search filter if provided
-	filteredUsers := users
+	query := utils.TeamMembersQuery(database.ReadDB(), uid, level)
 	if searchQuery != "" {
-		searchLower := strings.ToLower(searchQuery)
-		tempUsers := []models.User{}
-		for _, u := range users {
-			nameMatch := strings.Contains(strings.ToLower(u.Name), searchLower)
-			numberMatch := strings.Contains(strings.ToLower(u.Number), searchLower)
-			if nameMatch || numberMatch {
-				tempUsers = append(tempUsers, u)
-			}
-		}
-		filteredUsers = tempUsers
+		like := "%" + searchQuery + "%"
+		query = query.Where("u.name LIKE ? OR u.number LIKE ?", like, like)
 	}
-
-	// Apply status filter if provided
-	if statusQuery != "" {
-		statusLower := strings.ToLower(statusQuery)
-		tempUsers := []models.User{}
-		for _, u := range filteredUsers {
-			userStatus := strings.ToLower(u.InvestmentStatus)
-			if statusLower == "active" && userStatus == "active" {
-				tempUsers = append(tempUsers, u)
-			} else if statusLower == "inactive" && userStatus == "inactive" {
-				tempUsers = append(tempUsers, u)
-			}
-		}
-		filteredUsers = tempUsers
+	if statusQuery == "active" || statusQuery == "inactive" {
+		query = query.Where("u.investment_status = ?", statusQuery)
 	}
 
-	// Calculate pagination
-	totalRows := len(filteredUsers)
+	var totalRows int64
+	if err := query.Count(&totalRows).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
+		return
+	}
 	totalPages := int(math.Ceil(float64(totalRows) / float64(limit)))
-	start := (page - 1) * limit
-	end := start + limit
 
-	// Ensure start and end are within bounds
-	if start > totalRows {
-		start = totalRows
-	}
-	if end > totalRows {
-		end = totalRows
+	var users []models.User
+	if err := query.
+		Select("u.id, u.name, u.number, u.profile, u.investment_status, u.total_invest").
+		Order("u.id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&users).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "DB error"})
+		return
 	}
 
 	// Get paginated data
 	var data []map[string]interface{}
-	if start < end {
-		for _, u := range filteredUsers[start:end] {
-			data = append(data, map[string]interface{}{
-				"name":         u.Name,
-				"number":       censorNumber(u.Number),
-				"profile":      u.Profile,
-				"active":       strings.ToLower(u.InvestmentStatus) == "active",
-				"total_invest": u.TotalInvest,
-			})
-		}
+	for _, u := range users {
+		data = append(data, map[string]interface{}{
+			"name":         u.Name,
+			"number":       censorNumber(u.Number),
+			"profile":      u.Profile,
+			"active":       strings.ToLower(u.InvestmentStatus) == "active",
+			"total_invest": u.TotalInvest,
+		})
 	}
 
 	resp := map[string]interface{}{
 		"level":   level,
 		"members": data,
 		"pagination": map[string]interface{}{
-			"page":       page,
-			"limit":      limit,
-			"total_rows": totalRows,
+			"page":        page,
+			"limit":       limit,
+			"total_rows":  totalRows,
 			"total_pages": totalPages,
 		},
 	}
@@ -297,3 +165,22 @@ func TeamDataHandler(w http.ResponseWriter, r *http.Request) {
 		Data:    resp,
 	})
 }
+
+// POST /api/cron/referral-fraud-scan
+// CronReferralFraudScanHandler flags gift-farming accounts for admin review.
+func CronReferralFraudScanHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("X-CRON-KEY")
+	if key == "" || key != config.Get().Cron.Key {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	flagged, err := utils.ScanGiftFarms(database.DB, utils.SystemClock.Now())
+	if err != nil {
+		log.Printf("[cron/referral-fraud-scan] %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Cron executed", Data: map[string]interface{}{"flagged": flagged}})
+}
@@ -0,0 +1,118 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/database"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// Disputes can only be opened this long after the transfer
+const transferDisputeWindow = 7 * 24 * time.Hour
+
+type TransferDisputeRequest struct {
+	OrderID string `json:"order_id"` // order ID of the sender's transfer transaction
+	Reason  string `json:"reason"`
+}
+
+// CreateTransferDisputeHandler POST /transfer/dispute
+func CreateTransferDisputeHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var req TransferDisputeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Format data tidak valid"})
+		return
+	}
+	req.OrderID = strings.TrimSpace(req.OrderID)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.OrderID == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Order ID transfer wajib diisi"})
+		return
+	}
+	if len(req.Reason) < 10 || len(req.Reason) > 1000 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Alasan harus 10-1000 karakter"})
+		return
+	}
+
+	db := database.DB
+	var transfer models.Transfer
+	if err := db.Where("sender_order_id = ? AND sender_id = ?", req.OrderID, uid).First(&transfer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, utils.APIResponse{Success: false, Message: "Transfer tidak ditemukan"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
+		return
+	}
+	if transfer.ReversedAt != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Transfer ini sudah dibatalkan"})
+		return
+	}
+	if utils.SystemClock.Now().Sub(transfer.CreatedAt) > transferDisputeWindow {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Batas waktu pengajuan sengketa transfer adalah 7 hari"})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.TransferDispute{}).Where("transfer_id = ?", transfer.ID).Count(&count).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
+		return
+	}
+	if count > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Sengketa untuk transfer ini sudah pernah diajukan"})
+		return
+	}
+
+	dispute := models.TransferDispute{
+		TransferID: transfer.ID,
+		SenderID:   transfer.SenderID,
+		ReceiverID: transfer.ReceiverID,
+		Amount:     transfer.Amount,
+		Reason:     req.Reason,
+		Status:     "Pending",
+	}
+	if err := db.Create(&dispute).Error; err != nil {
+		// uk_transfer_disputes_transfer catches a concurrent duplicate
+		utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Sengketa untuk transfer ini sudah pernah diajukan"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.APIResponse{
+		Success: true,
+		Message: "Sengketa transfer berhasil diajukan dan akan ditinjau oleh Admin",
+		Data:    dispute,
+	})
+}
+
+// ListTransferDisputesHandler GET /transfer/dispute
+func ListTransferDisputesHandler(w http.ResponseWriter, r *http.Request) {
+	uid, ok := utils.GetUserID(r)
+	if !ok || uid == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+		return
+	}
+
+	var disputes []models.TransferDispute
+	if err := database.DB.Preload("Transfer").
+		Where("sender_id = ?", uid).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&disputes).Error; err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.APIResponse{Success: true, Message: "Successfully", Data: disputes})
+}
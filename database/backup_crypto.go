@@ -0,0 +1,212 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted backups are a sequence of AES-256-GCM sealed chunks so dumps of any size
+// can be streamed. Layout: magic, 8-byte random nonce prefix, then per chunk a 4-byte
+// big-endian ciphertext length and the ciphertext. The nonce is prefix || chunk counter
+// and the last chunk is sealed with a different additional data byte, so truncating or
+// reordering the file fails authentication.
+const (
+	backupCipherMagic = "CRBK\x01"
+	backupChunkSize   = 64 * 1024
+)
+
+var (
+	ErrBackupKeyInvalid = errors.New("DB_BACKUP_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	ErrBackupTruncated  = errors.New("encrypted backup is truncated")
+	ErrBackupDecrypt    = errors.New("encrypted backup cannot be decrypted (wrong key or corrupted file)")
+)
+
+// ParseBackupKey decodes a base64 AES-256 key.
+func ParseBackupKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != 32 {
+		return nil, ErrBackupKeyInvalid
+	}
+	return key, nil
+}
+
+// BackupKeyID identifies a key in manifests without revealing it.
+func BackupKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func chunkNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[8:], counter)
+	return nonce
+}
+
+func chunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// newEncryptWriter writes the header and returns a writer that must be closed to
+// emit the final chunk.
+func newEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrBackupKeyInvalid
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, 8)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, backupCipherMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, backupChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		// Keep a full chunk buffered until more data arrives so the final chunk is
+		// only decided on Close
+		if len(e.buf) == backupChunkSize {
+			if err := e.flush(false); err != nil {
+				return 0, err
+			}
+		}
+		take := backupChunkSize - len(e.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+		e.buf = append(e.buf, p[:take]...)
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (e *encryptWriter) flush(final bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter), e.buf, chunkAD(final))
+	e.counter++
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := e.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.flush(true)
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	final   bool
+}
+
+// newDecryptReader reads the header and returns a reader of the plaintext. It reports
+// ErrBackupTruncated if the stream ends before the final chunk.
+func newDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrBackupKeyInvalid
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(backupCipherMagic)+8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrBackupTruncated
+	}
+	if string(header[:len(backupCipherMagic)]) != backupCipherMagic {
+		return nil, fmt.Errorf("not an encrypted backup")
+	}
+	return &decryptReader{r: r, aead: aead, prefix: header[len(backupCipherMagic):]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrBackupTruncated
+		}
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > backupChunkSize+uint32(d.aead.Overhead()) {
+		return ErrBackupDecrypt
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return ErrBackupTruncated
+	}
+	nonce := chunkNonce(d.prefix, d.counter)
+	d.counter++
+	plain, err := d.aead.Open(nil, nonce, sealed, chunkAD(false))
+	if err != nil {
+		plain, err = d.aead.Open(nil, nonce, sealed, chunkAD(true))
+		if err != nil {
+			return ErrBackupDecrypt
+		}
+		d.final = true
+	}
+	d.plain = plain
+	return nil
+}
@@ -0,0 +1,299 @@
+package database
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MoneyTable lists the columns whose values are checksummed for a table holding money.
+// The first column must be unique per row (the primary key).
+type MoneyTable struct {
+	Table   string
+	Columns []string
+}
+
+// MoneyTables are verified after every restore.
+var MoneyTables = []MoneyTable{
+	{Table: "users", Columns: []string{"id", "balance"}},
+	{Table: "transactions", Columns: []string{"id", "user_id", "amount", "charge", "status"}},
+	{Table: "withdrawals", Columns: []string{"id", "user_id", "amount", "charge", "final_amount", "status"}},
+	{Table: "investments", Columns: []string{"id", "user_id", "amount", "total_returned", "status"}},
+	{Table: "deposits", Columns: []string{"id", "user_id", "amount", "status"}},
+	{Table: "transfers", Columns: []string{"id", "sender_id", "receiver_id", "amount"}},
+	{Table: "gift_claims", Columns: []string{"id", "user_id", "amount"}},
+}
+
+// TableChecksum is a row count plus the SHA-256 of CONCAT_WS('|', columns...) + "\n" for
+// every row in primary key order. mysqldump writes rows in that order (--order-by-primary),
+// so it can be computed both from the dump text and by reading the restored table.
+type TableChecksum struct {
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// tableHasher accumulates a TableChecksum.
+type tableHasher struct {
+	rows int64
+	h    hash.Hash
+}
+
+func newTableHasher() *tableHasher { return &tableHasher{h: sha256.New()} }
+
+func (t *tableHasher) add(row string) {
+	t.rows++
+	t.h.Write([]byte(row))
+	t.h.Write([]byte{'\n'})
+}
+
+func (t *tableHasher) sum() *TableChecksum {
+	return &TableChecksum{Rows: t.rows, SHA256: hex.EncodeToString(t.h.Sum(nil))}
+}
+
+var (
+	createTableRe = regexp.MustCompile("^CREATE TABLE `([^`]+)`")
+	insertRe      = regexp.MustCompile("^INSERT INTO `([^`]+)` \\(([^)]*)\\) VALUES ")
+	binlogRe      = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)',\s*(?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+)
+
+// dumpScanner watches mysqldump output line by line. It records the binlog coordinates
+// written by --master-data=2, checksums money tables (requires --complete-insert) and
+// whether the dump ran to completion.
+type dumpScanner struct {
+	tables     map[string]MoneyTable
+	hashers    map[string]*tableHasher
+	Sums       map[string]*TableChecksum // filled in by Close
+	BinlogFile string
+	BinlogPos  int64
+	Completed  bool
+	Err        error
+
+	partial []byte
+}
+
+func newDumpScanner(tables []MoneyTable) *dumpScanner {
+	s := &dumpScanner{tables: map[string]MoneyTable{}, hashers: map[string]*tableHasher{}, Sums: map[string]*TableChecksum{}}
+	for _, t := range tables {
+		s.tables[t.Table] = t
+	}
+	return s
+}
+
+func (s *dumpScanner) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.partial = append(s.partial, p...)
+			break
+		}
+		if len(s.partial) > 0 {
+			s.partial = append(s.partial, p[:i]...)
+			s.line(string(s.partial))
+			s.partial = s.partial[:0]
+		} else {
+			s.line(string(p[:i]))
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close processes a last line without a trailing newline and computes Sums.
+func (s *dumpScanner) Close() error {
+	if len(s.partial) > 0 {
+		s.line(string(s.partial))
+		s.partial = nil
+	}
+	for table, h := range s.hashers {
+		s.Sums[table] = h.sum()
+	}
+	return s.Err
+}
+
+func (s *dumpScanner) line(line string) {
+	switch {
+	case strings.HasPrefix(line, "-- Dump completed"):
+		s.Completed = true
+	case strings.HasPrefix(line, "-- CHANGE ") || strings.HasPrefix(line, "CHANGE "):
+		if m := binlogRe.FindStringSubmatch(line); m != nil {
+			s.BinlogFile = m[1]
+			s.BinlogPos, _ = strconv.ParseInt(m[2], 10, 64)
+		}
+	case strings.HasPrefix(line, "CREATE TABLE "):
+		if m := createTableRe.FindStringSubmatch(line); m != nil {
+			if _, ok := s.tables[m[1]]; ok && s.hashers[m[1]] == nil {
+				s.hashers[m[1]] = newTableHasher()
+			}
+		}
+	case strings.HasPrefix(line, "INSERT INTO "):
+		m := insertRe.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		spec, ok := s.tables[m[1]]
+		if !ok {
+			return
+		}
+		if err := s.insert(spec, m[2], line[len(m[0]):]); err != nil && s.Err == nil {
+			s.Err = fmt.Errorf("%s: %w", spec.Table, err)
+		}
+	}
+}
+
+func (s *dumpScanner) insert(spec MoneyTable, columnList, values string) error {
+	var cols []string
+	for _, c := range strings.Split(columnList, ",") {
+		cols = append(cols, strings.Trim(strings.TrimSpace(c), "`"))
+	}
+	idx := make([]int, len(spec.Columns))
+	for i, want := range spec.Columns {
+		idx[i] = -1
+		for j, c := range cols {
+			if c == want {
+				idx[i] = j
+			}
+		}
+		if idx[i] < 0 {
+			return fmt.Errorf("column %s not in dump", want)
+		}
+	}
+
+	sum := s.hashers[spec.Table]
+	if sum == nil {
+		sum = newTableHasher()
+		s.hashers[spec.Table] = sum
+	}
+	parts := make([]string, 0, len(idx))
+	return parseInsertValues(values, func(row []*string) error {
+		parts = parts[:0]
+		for _, i := range idx {
+			if i >= len(row) {
+				return fmt.Errorf("row has %d values, want at least %d", len(row), i+1)
+			}
+			// CONCAT_WS skips NULLs
+			if row[i] != nil {
+				parts = append(parts, *row[i])
+			}
+		}
+		sum.add(strings.Join(parts, "|"))
+		return nil
+	})
+}
+
+// parseInsertValues parses the tuples of a mysqldump extended INSERT, e.g.
+// (1,'a\'b',NULL),(2,'c',3.50); and calls fn with each row. NULL is a nil value.
+func parseInsertValues(s string, fn func(row []*string) error) error {
+	i := 0
+	skipSpace := func() {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r') {
+			i++
+		}
+	}
+	var row []*string
+	for {
+		skipSpace()
+		if i >= len(s) || s[i] == ';' {
+			return nil
+		}
+		if s[i] != '(' {
+			return fmt.Errorf("expected ( at offset %d", i)
+		}
+		i++
+		row = row[:0]
+		for {
+			skipSpace()
+			if i >= len(s) {
+				return fmt.Errorf("unterminated row")
+			}
+			// Charset introducers such as _binary 'x' precede a quoted string
+			if s[i] == '_' {
+				for i < len(s) && s[i] != ' ' && s[i] != '\'' {
+					i++
+				}
+				skipSpace()
+			}
+			if i < len(s) && s[i] == '\'' {
+				v, next, err := unquoteDumpString(s, i)
+				if err != nil {
+					return err
+				}
+				row = append(row, &v)
+				i = next
+			} else {
+				start := i
+				for i < len(s) && s[i] != ',' && s[i] != ')' {
+					i++
+				}
+				tok := strings.TrimSpace(s[start:i])
+				if tok == "NULL" {
+					row = append(row, nil)
+				} else {
+					row = append(row, &tok)
+				}
+			}
+			skipSpace()
+			if i >= len(s) {
+				return fmt.Errorf("unterminated row")
+			}
+			if s[i] == ',' {
+				i++
+				continue
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+			return fmt.Errorf("unexpected %q at offset %d", s[i], i)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+		skipSpace()
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+}
+
+// unquoteDumpString reads the quoted string starting at s[start] and returns its value
+// and the offset after the closing quote.
+func unquoteDumpString(s string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'Z':
+				b.WriteByte(26)
+			default:
+				b.WriteByte(s[i])
+			}
+		case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case c == '\'':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", len(s), fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBackupKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestBackupEncryptionRoundTrip(t *testing.T) {
+	key := testBackupKey(t)
+	plain := make([]byte, 3*backupChunkSize+123)
+	rand.Read(plain)
+
+	for _, size := range []int{0, backupChunkSize, len(plain)} {
+		var buf bytes.Buffer
+		w, err := newEncryptWriter(&buf, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(plain[:size])
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sealed := buf.Bytes()
+
+		r, err := newDecryptReader(bytes.NewReader(sealed), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, plain[:size]) {
+			t.Fatalf("size %d: round trip failed: %v", size, err)
+		}
+
+		// Dropping the final chunk must not look like a shorter valid file
+		if size > backupChunkSize {
+			cut := len(backupCipherMagic) + 8 + 4 + backupChunkSize + 16
+			r, _ := newDecryptReader(bytes.NewReader(sealed[:cut]), key)
+			if _, err := io.ReadAll(r); !errors.Is(err, ErrBackupTruncated) {
+				t.Errorf("size %d: truncated file gave %v, want ErrBackupTruncated", size, err)
+			}
+		}
+
+		tampered := append([]byte(nil), sealed...)
+		tampered[len(tampered)-1] ^= 1
+		r, _ = newDecryptReader(bytes.NewReader(tampered), key)
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrBackupDecrypt) {
+			t.Errorf("size %d: tampered file gave %v, want ErrBackupDecrypt", size, err)
+		}
+
+		r, _ = newDecryptReader(bytes.NewReader(sealed), testBackupKey(t))
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrBackupDecrypt) {
+			t.Errorf("size %d: wrong key gave %v, want ErrBackupDecrypt", size, err)
+		}
+	}
+}
+
+const sampleDump = "-- MySQL dump 10.13\n" +
+	"-- CHANGE MASTER TO MASTER_LOG_FILE='binlog.000042', MASTER_LOG_POS=1337;\n" +
+	"DROP TABLE IF EXISTS `users`;\n" +
+	"CREATE TABLE `users` (\n  `id` bigint unsigned NOT NULL AUTO_INCREMENT,\n  `balance` decimal(15,2) DEFAULT '0.00'\n) ENGINE=InnoDB;\n" +
+	"INSERT INTO `users` (`id`, `name`, `balance`) VALUES (1,'O\\'Brien, \\\"Jr\\\"','1500.00'),(2,'b',NULL);\n" +
+	"INSERT INTO `users` (`id`, `name`, `balance`) VALUES (3,'c (x)',0.50);\n" +
+	"CREATE TABLE `withdrawals` (\n  `id` int\n);\n" +
+	"CREATE TABLE `products` (\n  `id` int\n);\n" +
+	"INSERT INTO `products` (`id`, `name`) VALUES (1,'ignored');\n" +
+	"-- Dump completed on 2026-10-19  3:00:00\n"
+
+func TestDumpScanner(t *testing.T) {
+	s := newDumpScanner(MoneyTables)
+	// Split writes mid-line to exercise buffering
+	for _, chunk := range []string{sampleDump[:50], sampleDump[50:300], sampleDump[300:]} {
+		s.Write([]byte(chunk))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Completed || s.BinlogFile != "binlog.000042" || s.BinlogPos != 1337 {
+		t.Fatalf("completed=%v binlog=%s:%d", s.Completed, s.BinlogFile, s.BinlogPos)
+	}
+
+	digest := func(rows string) string {
+		sum := sha256.Sum256([]byte(rows))
+		return hex.EncodeToString(sum[:])
+	}
+	if u, want := s.Sums["users"], digest("1|1500.00\n2\n3|0.50\n"); u == nil || u.Rows != 3 || u.SHA256 != want {
+		t.Errorf("users = %+v, want 3 rows sha256 %s", u, want)
+	}
+	if w := s.Sums["withdrawals"]; w == nil || w.Rows != 0 || w.SHA256 != digest("") {
+		t.Errorf("empty withdrawals = %+v", w)
+	}
+	if _, ok := s.Sums["products"]; ok {
+		t.Error("products is not a money table")
+	}
+}
+
+func TestParseInsertValuesEscapes(t *testing.T) {
+	var rows [][]*string
+	err := parseInsertValues(`(1,'a\'b''c\\',NULL, _binary 'x'),(2,'',-3.25);`, func(row []*string) error {
+		rows = append(rows, append([]*string(nil), row...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || *rows[0][1] != `a'b'c\` || rows[0][2] != nil || *rows[0][3] != "x" || *rows[1][2] != "-3.25" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func packToStore(t *testing.T, store DirBackupStore, dump string, key []byte) *BackupManifest {
+	t.Helper()
+	var buf bytes.Buffer
+	m, err := packDump(strings.NewReader(dump), &buf, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.DumpKey = "backups/db/app-20261019T030000Z.sql.gz"
+	if err := store.Put(m.DumpKey, bytes.NewReader(buf.Bytes()), m.Size); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestVerifyBackup(t *testing.T) {
+	store := DirBackupStore{Dir: t.TempDir()}
+	key := testBackupKey(t)
+	cfg := BackupConfig{WorkDir: t.TempDir(), EncryptionKey: key}
+
+	m := packToStore(t, store, sampleDump, key)
+	if m.Encryption == "" || m.KeyID != BackupKeyID(key) || m.Tables["users"].Rows != 3 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if err := VerifyBackup(cfg, store, m); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	bad := *m
+	bad.Tables = map[string]TableChecksum{"users": {Rows: 3, SHA256: strings.Repeat("0", 64)}}
+	var check *BackupCheckError
+	if err := VerifyBackup(cfg, store, &bad); !errors.As(err, &check) || check.Stage != "dump" {
+		t.Errorf("mismatched manifest gave %v", err)
+	}
+
+	bad = *m
+	bad.SHA256 = strings.Repeat("0", 64)
+	if err := VerifyBackup(cfg, store, &bad); !errors.Is(err, ErrBackupChecksum) {
+		t.Errorf("wrong hash gave %v", err)
+	}
+
+	if err := VerifyBackup(BackupConfig{WorkDir: cfg.WorkDir}, store, m); !errors.Is(err, ErrBackupKeyMissing) {
+		t.Errorf("missing key gave %v", err)
+	}
+
+	if _, err := packDump(strings.NewReader(strings.Split(sampleDump, "-- Dump completed")[0]), io.Discard, nil); !errors.Is(err, ErrBackupIncomplete) {
+		t.Errorf("dump without completion marker gave %v", err)
+	}
+
+	// Manifests round-trip through JSON for LoadBackupManifest
+	body, _ := json.Marshal(m)
+	store.Put("backups/db/app-20261019T030000Z"+manifestExt, bytes.NewReader(body), int64(len(body)))
+	b, err := FindBackup(BackupConfig{Prefix: "backups/db/"}, store, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadBackupManifest(store, b)
+	if err != nil || loaded.SHA256 != m.SHA256 || loaded.Tables["users"] != m.Tables["users"] {
+		t.Fatalf("loaded manifest %+v, err %v", loaded, err)
+	}
+	if entries, _ := os.ReadDir(cfg.WorkDir); len(entries) != 0 {
+		t.Errorf("temporary files left behind: %d", len(entries))
+	}
+}
+
+func TestSelectExpiredBackups(t *testing.T) {
+	now := time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	complete := func(name string, at time.Time) BackupInfo {
+		return BackupInfo{Base: name, CreatedAt: at, DumpKey: name + ".sql.gz", ManifestKey: name + manifestExt}
+	}
+	backups := []BackupInfo{
+		complete("d1", day(1)),
+		{Base: "partial-new", CreatedAt: day(2), DumpKey: "partial-new.sql.gz"},
+		complete("d20", day(20)),
+		complete("d30", day(30)),
+		{Base: "partial-old", CreatedAt: day(40), DumpKey: "partial-old.sql.gz"},
+		complete("d50", day(50)),
+	}
+
+	names := func(list []BackupInfo) string {
+		var out []string
+		for _, b := range list {
+			out = append(out, b.Base)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := names(SelectExpiredBackups(backups, now, 14, 2)); got != "d30,partial-old,d50" {
+		t.Errorf("keepMin 2: expired %s", got)
+	}
+	if got := names(SelectExpiredBackups(backups, now, 14, 10)); got != "partial-old" {
+		t.Errorf("keepMin 10: expired %s", got)
+	}
+}
@@ -0,0 +1,594 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Drift severities. Errors fail the schema-drift command; warnings only do so with -strict.
+const (
+	DriftError   = "error"
+	DriftWarning = "warning"
+)
+
+// Drift kinds reported by DiffSchema.
+const (
+	DriftMissingTable      = "missing_table"
+	DriftMissingColumn     = "missing_column"
+	DriftExtraColumn       = "extra_column"
+	DriftTypeMismatch      = "type_mismatch"
+	DriftTypeWidth         = "type_width"
+	DriftEnumMismatch      = "enum_mismatch"
+	DriftEnumOrder         = "enum_order"
+	DriftNullable          = "nullable"
+	DriftMissingIndex      = "missing_index"
+	DriftIndexName         = "index_name"
+	DriftIndexColumns      = "index_columns"
+	DriftIndexUnique       = "index_unique"
+	DriftMissingForeignKey = "missing_foreign_key"
+)
+
+// DriftIssue is one difference between the GORM models and the live database.
+type DriftIssue struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+func (i DriftIssue) String() string {
+	target := i.Table
+	if i.Column != "" {
+		target += "." + i.Column
+	}
+	return fmt.Sprintf("%-7s %s %s: %s", i.Severity, target, i.Kind, i.Detail)
+}
+
+// TableSpec is what the models expect a table to look like.
+type TableSpec struct {
+	Table       string
+	Model       string
+	Columns     []ExpectedColumn
+	Indexes     []ExpectedIndex
+	ForeignKeys []ExpectedForeignKey
+}
+
+type ExpectedColumn struct {
+	Name    string
+	Type    string // SQL type as GORM would create it, e.g. varchar(16) or enum('a','b')
+	NotNull bool
+}
+
+type ExpectedIndex struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+type ExpectedForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// LiveTable is a table as read from information_schema. Column keys are lower-case.
+type LiveTable struct {
+	Columns     map[string]LiveColumn
+	Order       []string
+	Indexes     map[string]LiveIndex
+	ForeignKeys []LiveForeignKey
+}
+
+type LiveColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+type LiveIndex struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+type LiveForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// DetectSchemaDrift compares the given models against the connected database.
+func DetectSchemaDrift(db *gorm.DB, models []interface{}) ([]DriftIssue, error) {
+	specs, err := ModelSpecs(db, models)
+	if err != nil {
+		return nil, err
+	}
+	live, err := LoadLiveSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSchema(specs, live), nil
+}
+
+// HasDriftErrors reports whether issues should fail a deploy check.
+func HasDriftErrors(issues []DriftIssue, strict bool) bool {
+	for _, i := range issues {
+		if i.Severity == DriftError || strict {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelSpecs parses the models with GORM and returns the tables they describe. Column
+// types come from the connection's dialector so they match what AutoMigrate would create.
+func ModelSpecs(db *gorm.DB, models []interface{}) ([]TableSpec, error) {
+	var specs []TableSpec
+	byTable := map[string]int{}
+	var constraints []*schema.Constraint
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("parse %T: %w", model, err)
+		}
+		sch := stmt.Schema
+		spec := TableSpec{Table: sch.Table, Model: sch.Name}
+
+		for _, field := range sch.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			spec.Columns = append(spec.Columns, ExpectedColumn{
+				Name:    field.DBName,
+				Type:    db.Dialector.DataTypeOf(field),
+				NotNull: field.NotNull || field.PrimaryKey,
+			})
+			// A plain `unique` tag becomes a unique key named after the column
+			if field.Unique {
+				spec.Indexes = append(spec.Indexes, ExpectedIndex{Name: field.DBName, Columns: []string{field.DBName}, Unique: true})
+			}
+		}
+
+		for _, idx := range sch.ParseIndexes() {
+			ei := ExpectedIndex{Name: idx.Name, Unique: idx.Class == "UNIQUE"}
+			for _, opt := range idx.Fields {
+				if opt.Field != nil {
+					ei.Columns = append(ei.Columns, opt.Field.DBName)
+				}
+			}
+			if len(ei.Columns) > 0 {
+				spec.Indexes = append(spec.Indexes, ei)
+			}
+		}
+		sort.Slice(spec.Indexes, func(a, b int) bool { return spec.Indexes[a].Name < spec.Indexes[b].Name })
+
+		for _, rel := range sch.Relationships.Relations {
+			if c := rel.ParseConstraint(); c != nil {
+				constraints = append(constraints, c)
+			}
+		}
+
+		byTable[spec.Table] = len(specs)
+		specs = append(specs, spec)
+	}
+
+	// Constraints may be declared on either side of a relation; attach each to the
+	// table that holds the foreign key columns
+	seen := map[string]bool{}
+	for _, c := range constraints {
+		i, ok := byTable[c.Schema.Table]
+		if !ok || seen[c.Schema.Table+"."+c.Name] {
+			continue
+		}
+		seen[c.Schema.Table+"."+c.Name] = true
+		fk := ExpectedForeignKey{Name: c.Name, RefTable: c.ReferenceSchema.Table}
+		for _, f := range c.ForeignKeys {
+			fk.Columns = append(fk.Columns, f.DBName)
+		}
+		for _, f := range c.References {
+			fk.RefColumns = append(fk.RefColumns, f.DBName)
+		}
+		specs[i].ForeignKeys = append(specs[i].ForeignKeys, fk)
+	}
+	for i := range specs {
+		fks := specs[i].ForeignKeys
+		sort.Slice(fks, func(a, b int) bool { return fks[a].Name < fks[b].Name })
+	}
+	return specs, nil
+}
+
+// LoadLiveSchema reads columns, indexes and foreign keys of the current database from
+// information_schema, keyed by table name.
+func LoadLiveSchema(db *gorm.DB) (map[string]*LiveTable, error) {
+	tables := map[string]*LiveTable{}
+	table := func(name string) *LiveTable {
+		t, ok := tables[name]
+		if !ok {
+			t = &LiveTable{Columns: map[string]LiveColumn{}, Indexes: map[string]LiveIndex{}}
+			tables[name] = t
+		}
+		return t
+	}
+
+	rows, err := db.Raw(`SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+	for rows.Next() {
+		var tbl, col, typ, nullable string
+		if err := rows.Scan(&tbl, &col, &typ, &nullable); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("read columns: %w", err)
+		}
+		t := table(tbl)
+		key := strings.ToLower(col)
+		t.Columns[key] = LiveColumn{Name: col, Type: typ, Nullable: nullable == "YES"}
+		t.Order = append(t.Order, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+
+	rows, err = db.Raw(`SELECT TABLE_NAME, INDEX_NAME, COLUMN_NAME, NON_UNIQUE
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND INDEX_NAME <> 'PRIMARY'
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("read indexes: %w", err)
+	}
+	for rows.Next() {
+		var tbl, name string
+		var col *string // NULL for functional indexes
+		var nonUnique int
+		if err := rows.Scan(&tbl, &name, &col, &nonUnique); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("read indexes: %w", err)
+		}
+		t := table(tbl)
+		idx := t.Indexes[name]
+		idx.Name = name
+		idx.Unique = nonUnique == 0
+		if col != nil {
+			idx.Columns = append(idx.Columns, *col)
+		}
+		t.Indexes[name] = idx
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read indexes: %w", err)
+	}
+
+	rows, err = db.Raw(`SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("read foreign keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tbl, name, col, refTable, refCol string
+		if err := rows.Scan(&tbl, &name, &col, &refTable, &refCol); err != nil {
+			return nil, fmt.Errorf("read foreign keys: %w", err)
+		}
+		t := table(tbl)
+		if n := len(t.ForeignKeys); n > 0 && t.ForeignKeys[n-1].Name == name {
+			fk := &t.ForeignKeys[n-1]
+			fk.Columns = append(fk.Columns, col)
+			fk.RefColumns = append(fk.RefColumns, refCol)
+			continue
+		}
+		t.ForeignKeys = append(t.ForeignKeys, LiveForeignKey{Name: name, Columns: []string{col}, RefTable: refTable, RefColumns: []string{refCol}})
+	}
+	return tables, rows.Err()
+}
+
+// DiffSchema compares model specs with the live schema. Tables that no model describes
+// are ignored; columns the models do not know about are reported as warnings.
+func DiffSchema(specs []TableSpec, live map[string]*LiveTable) []DriftIssue {
+	var issues []DriftIssue
+	add := func(severity, kind, table, column, detail string) {
+		issues = append(issues, DriftIssue{Severity: severity, Kind: kind, Table: table, Column: column, Detail: detail})
+	}
+
+	for _, spec := range specs {
+		lt, ok := live[spec.Table]
+		if !ok {
+			add(DriftError, DriftMissingTable, spec.Table, "", "table for model "+spec.Model+" does not exist")
+			continue
+		}
+
+		known := map[string]bool{}
+		for _, col := range spec.Columns {
+			key := strings.ToLower(col.Name)
+			known[key] = true
+			lc, ok := lt.Columns[key]
+			if !ok {
+				add(DriftError, DriftMissingColumn, spec.Table, col.Name, "model "+spec.Model+" expects "+col.Type)
+				continue
+			}
+			if m := compareColumnType(col.Type, lc.Type); m != nil {
+				add(m.severity, m.kind, spec.Table, col.Name, m.detail)
+			}
+			if col.NotNull && lc.Nullable {
+				add(DriftWarning, DriftNullable, spec.Table, col.Name, "model expects NOT NULL, database allows NULL")
+			}
+		}
+		for _, key := range lt.Order {
+			if !known[key] {
+				lc := lt.Columns[key]
+				add(DriftWarning, DriftExtraColumn, spec.Table, lc.Name, "column "+lc.Type+" is not mapped by model "+spec.Model)
+			}
+		}
+
+		for _, idx := range spec.Indexes {
+			diffIndex(spec.Table, idx, lt, add)
+		}
+
+		for _, fk := range spec.ForeignKeys {
+			if !hasForeignKey(lt, fk) {
+				add(DriftError, DriftMissingForeignKey, spec.Table, strings.Join(fk.Columns, ","),
+					fmt.Sprintf("%s (%s) -> %s (%s)", fk.Name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", ")))
+			}
+		}
+	}
+	return issues
+}
+
+func diffIndex(table string, idx ExpectedIndex, lt *LiveTable, add func(severity, kind, table, column, detail string)) {
+	cols := strings.Join(idx.Columns, ", ")
+	li, ok := lt.Indexes[idx.Name]
+	if !ok {
+		// The same key may exist under another name, e.g. created by hand-written SQL
+		for _, name := range sortedIndexNames(lt) {
+			other := lt.Indexes[name]
+			if sameColumns(other.Columns, idx.Columns) && (other.Unique || !idx.Unique) {
+				add(DriftWarning, DriftIndexName, table, "", fmt.Sprintf("index %s (%s) exists as %s", idx.Name, cols, other.Name))
+				return
+			}
+		}
+		add(DriftError, DriftMissingIndex, table, "", fmt.Sprintf("%s %s (%s)", indexKind(idx.Unique), idx.Name, cols))
+		return
+	}
+	if !sameColumns(li.Columns, idx.Columns) {
+		add(DriftWarning, DriftIndexColumns, table, "", fmt.Sprintf("index %s is on (%s), model expects (%s)", idx.Name, strings.Join(li.Columns, ", "), cols))
+	}
+	if li.Unique != idx.Unique {
+		add(DriftError, DriftIndexUnique, table, "", fmt.Sprintf("index %s is %s, model expects %s", idx.Name, indexKind(li.Unique), indexKind(idx.Unique)))
+	}
+}
+
+func indexKind(unique bool) string {
+	if unique {
+		return "unique index"
+	}
+	return "index"
+}
+
+func sortedIndexNames(lt *LiveTable) []string {
+	names := make([]string, 0, len(lt.Indexes))
+	for name := range lt.Indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasForeignKey(lt *LiveTable, fk ExpectedForeignKey) bool {
+	for _, live := range lt.ForeignKeys {
+		if strings.EqualFold(live.RefTable, fk.RefTable) && sameColumns(live.Columns, fk.Columns) && sameColumns(live.RefColumns, fk.RefColumns) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnType is a parsed SQL column type such as "decimal(15,2)" or "int unsigned".
+type columnType struct {
+	Base     string   // lower-case name without arguments
+	Args     []string // size/precision, or the values of an enum or set
+	Unsigned bool
+}
+
+func parseColumnType(s string) columnType {
+	s = strings.TrimSpace(s)
+	var ct columnType
+	rest := s
+	if open := strings.IndexByte(s, '('); open >= 0 {
+		if close := strings.LastIndexByte(s, ')'); close > open {
+			ct.Base = strings.ToLower(strings.TrimSpace(s[:open]))
+			inner := s[open+1 : close]
+			if ct.Base == "enum" || ct.Base == "set" {
+				ct.Args = parseQuotedList(inner)
+			} else {
+				for _, a := range strings.Split(inner, ",") {
+					ct.Args = append(ct.Args, strings.TrimSpace(a))
+				}
+			}
+			rest = s[close+1:]
+		}
+	}
+	words := strings.Fields(strings.ToLower(rest))
+	if ct.Base == "" && len(words) > 0 {
+		ct.Base, words = words[0], words[1:]
+	}
+	for _, w := range words {
+		if w == "unsigned" {
+			ct.Unsigned = true
+		}
+	}
+	return ct
+}
+
+// parseQuotedList splits 'a','b”c' into its unquoted values.
+func parseQuotedList(s string) []string {
+	var values []string
+	var cur strings.Builder
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote && c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			cur.WriteByte('\'')
+			i++
+		case inQuote && c == '\\' && i+1 < len(s):
+			cur.WriteByte(s[i+1])
+			i++
+		case c == '\'':
+			if inQuote {
+				values = append(values, cur.String())
+				cur.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteByte(c)
+		}
+	}
+	return values
+}
+
+// typeClass groups types that hold the same kind of value.
+func typeClass(ct columnType) string {
+	switch ct.Base {
+	case "bool", "boolean":
+		return "bool"
+	case "tinyint":
+		if len(ct.Args) == 1 && ct.Args[0] == "1" {
+			return "bool"
+		}
+		return "int"
+	case "smallint", "mediumint", "int", "integer", "bigint":
+		return "int"
+	case "decimal", "numeric", "dec":
+		return "decimal"
+	case "float", "double", "real":
+		return "float"
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+		return "string"
+	case "datetime", "timestamp":
+		return "datetime"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "bytes"
+	}
+	return ct.Base
+}
+
+// canonicalType drops what MySQL ignores or reports inconsistently: integer display
+// widths and fractional-second precision.
+func canonicalType(ct columnType) string {
+	base := ct.Base
+	switch typeClass(ct) {
+	case "bool":
+		return "boolean"
+	case "int", "datetime", "float":
+		if base == "integer" {
+			base = "int"
+		}
+	case "decimal":
+		base = "decimal(" + strings.Join(ct.Args, ",") + ")"
+	default:
+		if len(ct.Args) > 0 {
+			base += "(" + strings.Join(ct.Args, ",") + ")"
+		}
+	}
+	if ct.Unsigned {
+		base += " unsigned"
+	}
+	return base
+}
+
+type typeMismatch struct {
+	severity string
+	kind     string
+	detail   string
+}
+
+// compareColumnType returns nil when the database column type satisfies the model.
+// Different kinds of value or differing enum values are errors; size and signedness
+// differences within the same kind are warnings.
+func compareColumnType(expected, actual string) *typeMismatch {
+	e, a := parseColumnType(expected), parseColumnType(actual)
+	ce, ca := typeClass(e), typeClass(a)
+	detail := fmt.Sprintf("model %s, database %s", strings.TrimSpace(expected), actual)
+
+	if ce == "enum" || ca == "enum" || ce == "set" || ca == "set" {
+		if ce != ca {
+			return &typeMismatch{DriftError, DriftTypeMismatch, detail}
+		}
+		missing, unknown := diffValues(e.Args, a.Args)
+		if len(missing) > 0 || len(unknown) > 0 {
+			var parts []string
+			if len(missing) > 0 {
+				parts = append(parts, "missing in database: "+strings.Join(missing, ", "))
+			}
+			if len(unknown) > 0 {
+				parts = append(parts, "unknown to model: "+strings.Join(unknown, ", "))
+			}
+			return &typeMismatch{DriftError, DriftEnumMismatch, strings.Join(parts, "; ")}
+		}
+		if strings.Join(e.Args, "\x00") != strings.Join(a.Args, "\x00") {
+			return &typeMismatch{DriftWarning, DriftEnumOrder, detail}
+		}
+		return nil
+	}
+
+	if ce != ca {
+		// Booleans are stored as tinyint, so bool/int is a width issue rather than a wrong type
+		if (ce == "bool" && ca == "int") || (ce == "int" && ca == "bool") {
+			return &typeMismatch{DriftWarning, DriftTypeWidth, detail}
+		}
+		return &typeMismatch{DriftError, DriftTypeMismatch, detail}
+	}
+	if canonicalType(e) != canonicalType(a) {
+		return &typeMismatch{DriftWarning, DriftTypeWidth, detail}
+	}
+	return nil
+}
+
+// diffValues returns the expected values absent from actual and the actual values absent
+// from expected, each quoted.
+func diffValues(expected, actual []string) (missing, unknown []string) {
+	in := func(list []string, v string) bool {
+		for _, x := range list {
+			if x == v {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range expected {
+		if !in(actual, v) {
+			missing = append(missing, "'"+v+"'")
+		}
+	}
+	for _, v := range actual {
+		if !in(expected, v) {
+			unknown = append(unknown, "'"+v+"'")
+		}
+	}
+	return missing, unknown
+}
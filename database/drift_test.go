@@ -0,0 +1,196 @@
+package database
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestCompareColumnType(t *testing.T) {
+	cases := []struct {
+		expected, actual string
+		kind             string // empty when the types match
+		severity         string
+	}{
+		{"varchar(16)", "varchar(16)", "", ""},
+		{"bigint unsigned AUTO_INCREMENT", "bigint unsigned", "", ""},
+		{"int", "int(11)", "", ""},
+		{"boolean", "tinyint(1)", "", ""},
+		{"decimal(15, 2)", "decimal(15,2)", "", ""},
+		{"datetime(3)", "datetime", "", ""},
+		{"enum('Active','Inactive')", "enum('Active','Inactive')", "", ""},
+		{"varchar(16)", "enum('Pending','Success','Failed')", DriftTypeMismatch, DriftError},
+		{"decimal(15,2)", "varchar(32)", DriftTypeMismatch, DriftError},
+		{"enum('Pending','Success','Expired')", "enum('Pending','Success')", DriftEnumMismatch, DriftError},
+		{"enum('a','b')", "enum('b','a')", DriftEnumOrder, DriftWarning},
+		{"bigint unsigned", "int unsigned", DriftTypeWidth, DriftWarning},
+		{"bigint unsigned", "bigint", DriftTypeWidth, DriftWarning},
+		{"varchar(191)", "varchar(255)", DriftTypeWidth, DriftWarning},
+		{"bigint", "tinyint(1)", DriftTypeWidth, DriftWarning},
+	}
+	for _, c := range cases {
+		m := compareColumnType(c.expected, c.actual)
+		if c.kind == "" {
+			if m != nil {
+				t.Errorf("%s vs %s: unexpected %s (%s)", c.expected, c.actual, m.kind, m.detail)
+			}
+			continue
+		}
+		if m == nil || m.kind != c.kind || m.severity != c.severity {
+			t.Errorf("%s vs %s: got %+v, want %s/%s", c.expected, c.actual, m, c.severity, c.kind)
+		}
+	}
+}
+
+func TestParseQuotedList(t *testing.T) {
+	got := parseQuotedList(`'a','it''s','c,d'`)
+	want := []string{"a", "it's", "c,d"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("value %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	specs := []TableSpec{
+		{
+			Table: "payments", Model: "Payment",
+			Columns: []ExpectedColumn{
+				{Name: "id", Type: "bigint unsigned AUTO_INCREMENT", NotNull: true},
+				{Name: "status", Type: "varchar(16)"},
+				{Name: "order_id", Type: "varchar(191)", NotNull: true},
+				{Name: "expired_at", Type: "datetime(3)"},
+			},
+			Indexes: []ExpectedIndex{
+				{Name: "idx_payments_order_id", Columns: []string{"order_id"}, Unique: true},
+				{Name: "idx_payments_status", Columns: []string{"status"}},
+			},
+			ForeignKeys: []ExpectedForeignKey{
+				{Name: "fk_payments_investment", Columns: []string{"investment_id"}, RefTable: "investments", RefColumns: []string{"id"}},
+			},
+		},
+		{Table: "gifts", Model: "Gift"},
+	}
+	live := map[string]*LiveTable{
+		"payments": {
+			Columns: map[string]LiveColumn{
+				"id":       {Name: "id", Type: "bigint unsigned"},
+				"status":   {Name: "status", Type: "enum('Pending','Success')"},
+				"order_id": {Name: "order_id", Type: "varchar(191)", Nullable: true},
+				"legacy":   {Name: "legacy", Type: "int"},
+			},
+			Order: []string{"id", "status", "order_id", "legacy"},
+			Indexes: map[string]LiveIndex{
+				"uk_order_id": {Name: "uk_order_id", Columns: []string{"order_id"}, Unique: true},
+			},
+		},
+	}
+
+	got := map[string]DriftIssue{}
+	for _, issue := range DiffSchema(specs, live) {
+		got[issue.Kind+" "+issue.Table+"."+issue.Column] = issue
+	}
+	want := map[string]string{
+		"type_mismatch payments.status":              DriftError,
+		"missing_column payments.expired_at":         DriftError,
+		"nullable payments.order_id":                 DriftWarning,
+		"extra_column payments.legacy":               DriftWarning,
+		"index_name payments.":                       DriftWarning,
+		"missing_index payments.":                    DriftError,
+		"missing_foreign_key payments.investment_id": DriftError,
+		"missing_table gifts.":                       DriftError,
+	}
+	for key, severity := range want {
+		issue, ok := got[key]
+		if !ok {
+			t.Errorf("missing issue %q", key)
+			continue
+		}
+		if issue.Severity != severity {
+			t.Errorf("%s: severity %s, want %s", key, issue.Severity, severity)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d issues, want %d: %v", len(got), len(want), got)
+	}
+	if !HasDriftErrors(DiffSchema(specs, live), false) {
+		t.Error("HasDriftErrors = false with errors present")
+	}
+}
+
+// offlineDB returns a GORM handle that can parse models but never connects.
+func offlineDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/none",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type driftParent struct {
+	ID     uint   `gorm:"primaryKey"`
+	Email  string `gorm:"unique"`
+	Status string `gorm:"type:enum('Active','Inactive');not null"`
+}
+
+type driftChild struct {
+	ID       uint        `gorm:"primaryKey"`
+	ParentID uint        `gorm:"not null;index"`
+	OrderID  string      `gorm:"type:varchar(191);not null;uniqueIndex"`
+	Code     string      `gorm:"type:varchar(20);uniqueIndex:uk_child_code,priority:2"`
+	Kind     string      `gorm:"type:varchar(10);uniqueIndex:uk_child_code,priority:1"`
+	Parent   driftParent `gorm:"foreignKey:ParentID"`
+}
+
+func TestModelSpecs(t *testing.T) {
+	specs, err := ModelSpecs(offlineDB(t), []interface{}{&driftParent{}, &driftChild{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || specs[0].Table != "drift_parents" || specs[1].Table != "drift_children" {
+		t.Fatalf("unexpected tables: %+v", specs)
+	}
+	parent, child := specs[0], specs[1]
+
+	types := map[string]string{}
+	for _, c := range parent.Columns {
+		types[c.Name] = c.Type
+	}
+	if types["status"] != "enum('Active','Inactive')" || !parent.Columns[0].NotNull {
+		t.Errorf("parent columns = %+v", parent.Columns)
+	}
+	if len(parent.Indexes) != 1 || parent.Indexes[0].Name != "email" || !parent.Indexes[0].Unique {
+		t.Errorf("parent indexes = %+v, want unique email", parent.Indexes)
+	}
+
+	indexes := map[string]ExpectedIndex{}
+	for _, idx := range child.Indexes {
+		indexes[idx.Name] = idx
+	}
+	if idx := indexes["uk_child_code"]; !idx.Unique || !sameColumns(idx.Columns, []string{"kind", "code"}) {
+		t.Errorf("uk_child_code = %+v, want unique (kind, code)", idx)
+	}
+	if idx := indexes["idx_drift_children_order_id"]; !idx.Unique {
+		t.Errorf("order_id index = %+v, want unique", idx)
+	}
+	if idx, ok := indexes["idx_drift_children_parent_id"]; !ok || idx.Unique {
+		t.Errorf("parent_id index = %+v, want plain index", idx)
+	}
+
+	if len(child.ForeignKeys) != 1 {
+		t.Fatalf("child foreign keys = %+v", child.ForeignKeys)
+	}
+	fk := child.ForeignKeys[0]
+	if fk.RefTable != "drift_parents" || !sameColumns(fk.Columns, []string{"parent_id"}) || !sameColumns(fk.RefColumns, []string{"id"}) {
+		t.Errorf("foreign key = %+v", fk)
+	}
+}
@@ -0,0 +1,431 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change read from the migrations directory.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // empty when the migration is irreversible
+	Checksum string // sha256 of the up file
+}
+
+// AppliedMigration is a row of schema_migrations. Dirty marks a migration that failed
+// halfway; MySQL commits DDL implicitly, so the schema must be fixed by hand.
+type AppliedMigration struct {
+	Version     int       `gorm:"primaryKey;autoIncrement:false"`
+	Name        string    `gorm:"type:varchar(191);not null"`
+	Checksum    string    `gorm:"type:char(64);not null"`
+	Dirty       bool      `gorm:"not null;default:false"`
+	ExecutionMs int64     `gorm:"not null;default:0"`
+	AppliedAt   time.Time `gorm:"not null"`
+}
+
+func (AppliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationState pairs a migration with its row in schema_migrations, if any.
+type MigrationState struct {
+	Migration
+	Applied          *AppliedMigration
+	ChecksumMismatch bool
+}
+
+var (
+	ErrMigrationDirty = errors.New("a migration is dirty")
+	ErrSchemaBehind   = errors.New("database schema is behind the code")
+
+	migrationFileRe = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+// LoadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql files from fsys, sorted by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %s: name must look like 0001_description.up.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			sum := sha256.Sum256(body)
+			mig.Up = string(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// SplitStatements splits a SQL script into statements the way the mysql client does:
+// on the current delimiter outside quotes and comments, honouring DELIMITER lines so
+// triggers and procedures can be written as usual. Line comments are dropped.
+func SplitStatements(script string) []string {
+	var (
+		out       []string
+		cur       strings.Builder
+		delimiter = ";"
+		quote     byte
+		block     bool
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			out = append(out, s)
+		}
+		cur.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if quote == 0 && !block && len(trimmed) > 10 && strings.EqualFold(trimmed[:10], "DELIMITER ") {
+			flush()
+			delimiter = strings.TrimSpace(trimmed[10:])
+			continue
+		}
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case block:
+				cur.WriteByte(c)
+				if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+					cur.WriteByte('/')
+					i++
+					block = false
+				}
+			case quote != 0:
+				cur.WriteByte(c)
+				if c == '\\' && quote != '`' && i+1 < len(line) {
+					cur.WriteByte(line[i+1])
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '\'' || c == '"' || c == '`':
+				quote = c
+				cur.WriteByte(c)
+			case c == '#' || (c == '-' && strings.HasPrefix(line[i:], "--") && (i+2 >= len(line) || line[i+2] == ' ' || line[i+2] == '\t' || line[i+2] == '\n' || line[i+2] == '\r')):
+				cur.WriteByte('\n')
+				i = len(line)
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				block = true
+				cur.WriteString("/*")
+				i++
+			case strings.HasPrefix(line[i:], delimiter):
+				flush()
+				i += len(delimiter) - 1
+			default:
+				cur.WriteByte(c)
+			}
+		}
+	}
+	flush()
+	return out
+}
+
+// Migrator applies migrations to a database and tracks them in schema_migrations.
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	Logf       func(format string, args ...interface{})
+}
+
+// NewMigrator loads the migrations in fsys for db.
+func NewMigrator(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{DB: db, Migrations: migrations, Logf: func(string, ...interface{}) {}}, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT NOT NULL PRIMARY KEY,
+    name VARCHAR(191) NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    dirty TINYINT(1) NOT NULL DEFAULT 0,
+    execution_ms BIGINT NOT NULL DEFAULT 0,
+    applied_at DATETIME NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`).Error
+}
+
+// Status returns every known migration with its applied row, plus applied versions
+// that have no file (the database is ahead of this binary).
+func (m *Migrator) Status() ([]MigrationState, []AppliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, nil, err
+	}
+	var rows []AppliedMigration
+	if err := m.DB.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	applied := make(map[int]AppliedMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+
+	states := make([]MigrationState, 0, len(m.Migrations))
+	known := make(map[int]bool, len(m.Migrations))
+	for _, mig := range m.Migrations {
+		known[mig.Version] = true
+		st := MigrationState{Migration: mig}
+		if r, ok := applied[mig.Version]; ok {
+			st.Applied = &r
+			st.ChecksumMismatch = r.Checksum != mig.Checksum
+		}
+		states = append(states, st)
+	}
+	var unknown []AppliedMigration
+	for _, r := range rows {
+		if !known[r.Version] {
+			unknown = append(unknown, r)
+		}
+	}
+	return states, unknown, nil
+}
+
+// Verify returns every inconsistency between the migration files and schema_migrations:
+// dirty or edited migrations, applied versions without a file and pending migrations.
+func (m *Migrator) Verify() ([]string, error) {
+	states, unknown, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, st := range states {
+		switch {
+		case st.Applied == nil:
+			problems = append(problems, fmt.Sprintf("%04d_%s is pending", st.Version, st.Name))
+		case st.Applied.Dirty:
+			problems = append(problems, fmt.Sprintf("%04d_%s is dirty (failed halfway)", st.Version, st.Name))
+		case st.ChecksumMismatch:
+			problems = append(problems, fmt.Sprintf("%04d_%s was edited after it was applied (checksum %s, file %s)", st.Version, st.Name, short(st.Applied.Checksum), short(st.Checksum)))
+		}
+	}
+	for _, r := range unknown {
+		problems = append(problems, fmt.Sprintf("%04d_%s is applied but has no migration file", r.Version, r.Name))
+	}
+	return problems, nil
+}
+
+// Up applies pending migrations in order, up to and including target (0 = all).
+func (m *Migrator) Up(target int) (int, error) {
+	states, _, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	if err := refuseDirty(states); err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, st := range states {
+		if st.Applied != nil {
+			continue
+		}
+		if target > 0 && st.Version > target {
+			break
+		}
+		if err := m.run(st.Migration, true); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(steps int) (int, error) {
+	states, _, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	if err := refuseDirty(states); err != nil {
+		return 0, err
+	}
+	reverted := 0
+	for i := len(states) - 1; i >= 0 && reverted < steps; i-- {
+		st := states[i]
+		if st.Applied == nil {
+			continue
+		}
+		if strings.TrimSpace(st.Down) == "" {
+			return reverted, fmt.Errorf("%04d_%s is irreversible (no down file)", st.Version, st.Name)
+		}
+		if err := m.run(st.Migration, false); err != nil {
+			return reverted, err
+		}
+		reverted++
+	}
+	return reverted, nil
+}
+
+// Baseline records every migration up to version as applied without running it, for
+// databases whose schema was brought up to date by hand.
+func (m *Migrator) Baseline(version int) (int, error) {
+	states, _, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	recorded := 0
+	for _, st := range states {
+		if st.Version > version || st.Applied != nil {
+			continue
+		}
+		row := AppliedMigration{Version: st.Version, Name: st.Name, Checksum: st.Checksum, AppliedAt: now}
+		if err := m.DB.Create(&row).Error; err != nil {
+			return recorded, err
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// Force clears the dirty flag of a migration after its schema was fixed by hand, and
+// records the current checksum of its file.
+func (m *Migrator) Force(version int) error {
+	for _, mig := range m.Migrations {
+		if mig.Version == version {
+			res := m.DB.Model(&AppliedMigration{}).Where("version = ?", version).
+				Updates(map[string]interface{}{"dirty": false, "checksum": mig.Checksum})
+			if res.Error == nil && res.RowsAffected == 0 {
+				return fmt.Errorf("%04d_%s is not recorded as applied", mig.Version, mig.Name)
+			}
+			return res.Error
+		}
+	}
+	return fmt.Errorf("no migration with version %d", version)
+}
+
+// run executes one direction of a migration on a single connection, so session
+// settings such as SET FOREIGN_KEY_CHECKS last for the whole file. The row is written
+// dirty first and cleaned up only after the last statement succeeds.
+func (m *Migrator) run(mig Migration, up bool) error {
+	script, direction := mig.Up, "up"
+	if !up {
+		script, direction = mig.Down, "down"
+	}
+	m.Logf("migrate %s: %04d_%s", direction, mig.Version, mig.Name)
+	start := time.Now()
+
+	if up {
+		row := AppliedMigration{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum, Dirty: true, AppliedAt: start}
+		if err := m.DB.Create(&row).Error; err != nil {
+			return err
+		}
+	} else if err := m.DB.Model(&AppliedMigration{}).Where("version = ?", mig.Version).Update("dirty", true).Error; err != nil {
+		return err
+	}
+
+	err := m.DB.Connection(func(conn *gorm.DB) error {
+		for i, stmt := range SplitStatements(script) {
+			if err := conn.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("%04d_%s %s, statement %d: %w", mig.Version, mig.Name, direction, i+1, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !up {
+		return m.DB.Where("version = ?", mig.Version).Delete(&AppliedMigration{}).Error
+	}
+	return m.DB.Model(&AppliedMigration{}).Where("version = ?", mig.Version).Updates(map[string]interface{}{
+		"dirty":        false,
+		"execution_ms": time.Since(start).Milliseconds(),
+		"applied_at":   time.Now(),
+	}).Error
+}
+
+// CheckSchemaCurrent fails when any migration is pending, dirty or was edited after it
+// was applied. Applied versions this binary does not know about are tolerated so an
+// older build can still start during a rollback.
+func CheckSchemaCurrent(db *gorm.DB, fsys fs.FS) error {
+	m, err := NewMigrator(db, fsys)
+	if err != nil {
+		return err
+	}
+	states, _, err := m.Status()
+	if err != nil {
+		return err
+	}
+	if err := refuseDirty(states); err != nil {
+		return err
+	}
+	var pending, edited []string
+	for _, st := range states {
+		if st.Applied == nil {
+			pending = append(pending, fmt.Sprintf("%04d_%s", st.Version, st.Name))
+		} else if st.ChecksumMismatch {
+			edited = append(edited, fmt.Sprintf("%04d_%s", st.Version, st.Name))
+		}
+	}
+	if len(edited) > 0 {
+		return fmt.Errorf("migrations edited after they were applied: %s", strings.Join(edited, ", "))
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) (%s); run: migrate up", ErrSchemaBehind, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+func refuseDirty(states []MigrationState) error {
+	for _, st := range states {
+		if st.Applied != nil && st.Applied.Dirty {
+			return fmt.Errorf("%w: %04d_%s failed halfway; fix the schema by hand, then run: migrate force %d", ErrMigrationDirty, st.Version, st.Name, st.Version)
+		}
+	}
+	return nil
+}
+
+func short(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
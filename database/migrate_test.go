@@ -0,0 +1,87 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"project/migrations"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- header comment; not a statement
+CREATE TABLE a (id INT); # trailing comment
+INSERT INTO a VALUES (1), (2);
+UPDATE a SET note = 'semi;colon -- not a comment', other = "it\"s";
+/* block; comment */ SELECT 1;
+
+DELIMITER $$
+CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW
+BEGIN
+    SET NEW.id = NEW.id + 1;
+END$$
+DELIMITER ;
+SELECT 2`
+
+	got := SplitStatements(script)
+	want := []string{
+		"CREATE TABLE a (id INT)",
+		"INSERT INTO a VALUES (1), (2)",
+		`UPDATE a SET note = 'semi;colon -- not a comment', other = "it\"s"`,
+		"/* block; comment */ SELECT 1",
+		"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n    SET NEW.id = NEW.id + 1;\nEND",
+		"SELECT 2",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d statements %q, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statement %d:\n got  %q\n want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_b.up.sql":      {Data: []byte("ALTER TABLE a ADD COLUMN b INT;")},
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"embed.go":               {Data: []byte("package migrations")},
+	}
+	got, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 2 {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+	if got[0].Down == "" || got[1].Down != "" {
+		t.Fatalf("down files not matched: %+v", got)
+	}
+	if len(got[0].Checksum) != 64 || got[0].Checksum == got[1].Checksum {
+		t.Fatalf("bad checksums: %q %q", got[0].Checksum, got[1].Checksum)
+	}
+
+	if _, err := LoadMigrations(fstest.MapFS{"0001_x.down.sql": {Data: []byte("DROP TABLE x;")}}); err == nil {
+		t.Fatal("a down file without an up file must be rejected")
+	}
+	if _, err := LoadMigrations(fstest.MapFS{"add_x.sql": {Data: []byte("SELECT 1;")}}); err == nil {
+		t.Fatal("unnumbered files must be rejected")
+	}
+}
+
+// The embedded migrations must parse and be numbered without gaps.
+func TestEmbeddedMigrations(t *testing.T) {
+	all, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("migration %04d_%s: expected version %d", m.Version, m.Name, i+1)
+		}
+		if len(SplitStatements(m.Up)) == 0 {
+			t.Errorf("%04d_%s: empty up file", m.Version, m.Name)
+		}
+	}
+}
@@ -4,50 +4,46 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"time"
 
+	"project/config"
+
 	"gorm.io/gorm"
 )
 
-// BackupDatabase attempts to create a SQL dump using mysqldump if it's available on PATH.
-// It writes to the provided path and returns an error if the command fails.
+// BackupDatabase writes a consistent, gzip-compressed (and, with DB_BACKUP_ENCRYPTION_KEY,
+// encrypted) dump to outPath. Connection settings come from the environment; a non-empty
+// dsn overrides them.
 func BackupDatabase(dsn string, outPath string) error {
-	// If mysqldump is not installed, return an informative error
-	if _, err := exec.LookPath("mysqldump"); err != nil {
-		return fmt.Errorf("mysqldump not found in PATH: %w", err)
+	cfg, err := LoadBackupConfig()
+	if err != nil {
+		return err
 	}
+	if dsn != "" {
+		if err := cfg.applyDSN(dsn); err != nil {
+			return err
+		}
+	}
+	// Stage next to outPath so the final rename stays on one filesystem
+	dir := DirBackupStore{Dir: filepath.Dir(outPath)}
+	cfg.Prefix = fmt.Sprintf(".backup-%d/", time.Now().UnixNano())
+	defer os.RemoveAll(dir.path(cfg.Prefix))
 
-	// NOTE: dsn should be parsed by the caller to extract user/host/db or provide a secure wrapper
-	// For simplicity we expect caller to supply the appropriate flags in DB_BACKUP_FLAGS env or similar
-	args := []string{os.Getenv("DB_BACKUP_FLAGS")}
-	// Attempt simple invocation; this can be customized via env
-	cmd := exec.CommandContext(context.Background(), "mysqldump", args...)
-	outFile, err := os.Create(outPath)
+	m, err := CreateBackup(context.Background(), cfg, dir, time.Now())
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
-	cmd.Stdout = outFile
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("mysqldump failed: %w", err)
-	}
-	return nil
+	return os.Rename(dir.path(m.DumpKey), outPath)
 }
 
-// RunMigrationsWithBackup runs AutoMigrate after attempting a backup (best-effort).
-// It accepts a list of models to migrate. The function attempts a mysqldump backup if
-// DB_BACKUP_PATH env is set. It runs migrations inside a transaction where possible.
+// RunMigrationsWithBackup runs AutoMigrate after taking a backup when DB_BACKUP_PATH is
+// set. A failed backup aborts the migration.
 func RunMigrationsWithBackup(db *gorm.DB, models ...interface{}) error {
-	backupPath := os.Getenv("DB_BACKUP_PATH")
-	if backupPath != "" {
-		// Perform backup asynchronously but wait a short time
-		go func() {
-			_ = BackupDatabase(os.Getenv("DB_DSN"), backupPath)
-		}()
-		// allow a small window for the backup to start
-		time.Sleep(500 * time.Millisecond)
+	if backupPath := config.Get().Backup.PreMigratePath; backupPath != "" {
+		if err := BackupDatabase(config.Get().DB.DSN, backupPath); err != nil {
+			return fmt.Errorf("backup before migration failed: %w", err)
+		}
 	}
 
 	// Use AutoMigrate as usual; callers should ensure models are correct and migrations reviewed
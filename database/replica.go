@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"project/config"
+	"project/metrics"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Replicas routes heavy reads to read replicas. It is nil when DB_REPLICA_HOSTS and
+// DB_REPLICA_DSNS are unset, in which case ReadDB returns the primary.
+var Replicas *ReplicaSet
+
+// ReadDB returns a replica whose replication lag is within DB_REPLICA_MAX_LAG, or the
+// primary when none qualifies. Use it only for read-only listings and reports that
+// tolerate a few seconds of staleness: anything inside a transaction, or read before
+// moving money, must use DB.
+func ReadDB() *gorm.DB {
+	if Replicas == nil {
+		return DB
+	}
+	return Replicas.Pick(time.Now())
+}
+
+// replica is one read replica and the result of its last health check.
+type replica struct {
+	name string
+	db   *gorm.DB
+
+	mu        sync.RWMutex
+	healthy   bool
+	lag       time.Duration
+	checkedAt time.Time
+	lastErr   error
+}
+
+func (r *replica) state() (healthy bool, lag time.Duration, checkedAt time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy, r.lag, r.checkedAt
+}
+
+func (r *replica) record(lag time.Duration, err error, at time.Time, maxLag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wasHealthy := r.healthy
+	r.lag, r.lastErr, r.checkedAt = lag, err, at
+	r.healthy = err == nil && lag <= maxLag
+	if wasHealthy != r.healthy {
+		if r.healthy {
+			log.Printf("[database] replica %s back in rotation (lag %s)", r.name, lag)
+		} else if err != nil {
+			log.Printf("[database] replica %s out of rotation: %v", r.name, err)
+		} else {
+			log.Printf("[database] replica %s out of rotation: lag %s exceeds %s", r.name, lag, maxLag)
+		}
+	}
+}
+
+// ReplicaSet picks a fresh replica round-robin and falls back to the primary.
+type ReplicaSet struct {
+	primary  *gorm.DB
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	next     uint32
+}
+
+// Pick returns the next replica that passed its last health check recently enough, or
+// the primary. A check older than three intervals means the monitor stalled, so the
+// replica is not trusted.
+func (s *ReplicaSet) Pick(now time.Time) *gorm.DB {
+	n := len(s.replicas)
+	start := int(atomic.AddUint32(&s.next, 1))
+	for i := 0; i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		healthy, _, checkedAt := r.state()
+		if healthy && now.Sub(checkedAt) <= 3*s.interval {
+			return r.db
+		}
+	}
+	return s.primary
+}
+
+// ReplicaStatus describes a replica for the admin health view.
+type ReplicaStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	LagMs     int64     `json:"lag_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func (s *ReplicaSet) Status() []ReplicaStatus {
+	out := make([]ReplicaStatus, len(s.replicas))
+	for i, r := range s.replicas {
+		r.mu.RLock()
+		out[i] = ReplicaStatus{Name: r.name, Healthy: r.healthy, LagMs: r.lag.Milliseconds(), CheckedAt: r.checkedAt}
+		if r.lastErr != nil {
+			out[i].Error = r.lastErr.Error()
+		}
+		r.mu.RUnlock()
+	}
+	return out
+}
+
+// Check measures the lag of every replica once.
+func (s *ReplicaSet) Check(ctx context.Context) {
+	for _, r := range s.replicas {
+		lag, err := replicationLag(ctx, r.db)
+		r.record(lag, err, time.Now(), s.maxLag)
+	}
+}
+
+// Monitor re-checks the replicas every interval until ctx is cancelled.
+func (s *ReplicaSet) Monitor(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
+
+// replicationLag reads Seconds_Behind_Source (or _Master on older servers). A NULL value
+// means replication is stopped. A server that reports no replica status is not
+// replicating at all and is treated as current.
+func replicationLag(ctx context.Context, db *gorm.DB) (time.Duration, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	rows, err := sqlDB.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		rows, err = sqlDB.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return 0, err
+	}
+	for i, c := range cols {
+		if c != "Seconds_Behind_Source" && c != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, fmt.Errorf("replication is not running")
+		}
+		var secs int64
+		if _, err := fmt.Sscan(string(values[i]), &secs); err != nil {
+			return 0, err
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+	return 0, fmt.Errorf("replica status has no lag column")
+}
+
+// ConnectReplicas opens the read replicas configured by DB_REPLICA_HOSTS (host:port list,
+// using DB_READ_USER/DB_READ_PASS when set) or DB_REPLICA_DSNS (semicolon separated), runs
+// a first health check and starts the monitor. Without configuration it does nothing.
+func ConnectReplicas(ctx context.Context, primary *gorm.DB) error {
+	dsns := replicaDSNs()
+	if len(dsns) == 0 {
+		return nil
+	}
+	set := &ReplicaSet{
+		primary:  primary,
+		maxLag:   time.Duration(config.Get().Replica.MaxLag) * time.Second,
+		interval: time.Duration(config.Get().Replica.CheckInterval) * time.Second,
+	}
+	if set.interval <= 0 {
+		set.interval = 5 * time.Second
+	}
+	for name, dsn := range dsns {
+		dsn, err := withReplicaTLS(name, dsn)
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", name, err)
+		}
+		db, err := openPool(dsn)
+		if err != nil {
+			return fmt.Errorf("replica %s: %w", name, err)
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			if err := metrics.RegisterDB(name, sqlDB); err != nil {
+				log.Printf("[database] replica %s pool metrics: %v", name, err)
+			}
+		}
+		set.replicas = append(set.replicas, &replica{name: name, db: db})
+	}
+	set.Check(ctx)
+	go set.Monitor(ctx)
+	Replicas = set
+	log.Printf("[database] %d read replica(s) configured, max lag %s", len(set.replicas), set.maxLag)
+	return nil
+}
+
+// withReplicaTLS gives a replica DSN that asks for tls=custom its own registered TLS
+// config, verifying the replica's host name. The primary's "custom" config is only
+// registered when the primary itself uses it, and is not tied to any replica host.
+func withReplicaTLS(name, dsn string) (string, error) {
+	if !strings.Contains(dsn, "tls=custom") {
+		return dsn, nil
+	}
+	// user:pass@tcp(host:port)/name?params; ParseDSN cannot be used before the
+	// config is registered
+	var addr string
+	if at := strings.LastIndex(dsn, "@"); at >= 0 {
+		rest := dsn[at+1:]
+		if open, end := strings.Index(rest, "("), strings.Index(rest, ")"); open >= 0 && end > open {
+			addr = rest[open+1 : end]
+		}
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	tlsCfg, err := customTLSConfig(host)
+	if err != nil {
+		return "", err
+	}
+	key := "replica-" + name
+	if err := mysqldriver.RegisterTLSConfig(key, tlsCfg); err != nil {
+		return "", err
+	}
+	return strings.Replace(dsn, "tls=custom", "tls="+key, 1), nil
+}
+
+// replicaDSNs maps a display name (host, without credentials) to each replica DSN.
+func replicaDSNs() map[string]string {
+	cfg := config.Get()
+	out := map[string]string{}
+	if len(cfg.Replica.DSNs) > 0 {
+		for i, dsn := range cfg.Replica.DSNs {
+			name := fmt.Sprintf("replica-%d", i+1)
+			if at := strings.LastIndex(dsn, "@"); at >= 0 {
+				name = strings.SplitN(dsn[at+1:], "/", 2)[0]
+			}
+			out[name] = dsn
+		}
+		return out
+	}
+
+	if len(cfg.Replica.Hosts) == 0 {
+		return out
+	}
+	user, pass := cfg.DB.User, cfg.DB.Pass
+	if cfg.DB.ReadUser != "" {
+		user, pass = cfg.DB.ReadUser, cfg.DB.ReadPass
+	}
+	params := withConnDefaults(cfg.DB.Params)
+	for _, host := range cfg.Replica.Hosts {
+		if !strings.Contains(host, ":") {
+			host += ":" + cfg.DB.Port
+		}
+		out[host] = fmt.Sprintf("%s:%s@tcp(%s)/%s?%s", user, pass, host, cfg.DB.Name, params)
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestReplicaSetPick(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	primary, a, b := &gorm.DB{}, &gorm.DB{}, &gorm.DB{}
+	ra, rb := &replica{name: "a", db: a}, &replica{name: "b", db: b}
+	set := &ReplicaSet{primary: primary, replicas: []*replica{ra, rb}, maxLag: 5 * time.Second, interval: 5 * time.Second}
+
+	if got := set.Pick(now); got != primary {
+		t.Fatal("unchecked replicas must not be used")
+	}
+
+	ra.record(time.Second, nil, now, set.maxLag)
+	rb.record(2*time.Second, nil, now, set.maxLag)
+	seen := map[*gorm.DB]int{}
+	for i := 0; i < 4; i++ {
+		seen[set.Pick(now)]++
+	}
+	if seen[a] != 2 || seen[b] != 2 {
+		t.Errorf("round robin over healthy replicas: %v", seen)
+	}
+
+	// Lagging or broken replicas drop out
+	rb.record(30*time.Second, nil, now, set.maxLag)
+	for i := 0; i < 3; i++ {
+		if got := set.Pick(now); got != a {
+			t.Fatal("lagging replica was picked")
+		}
+	}
+	ra.record(0, errors.New("replication is not running"), now, set.maxLag)
+	if got := set.Pick(now); got != primary {
+		t.Error("expected primary when no replica is fresh")
+	}
+
+	// A stalled monitor makes old results untrustworthy
+	ra.record(0, nil, now, set.maxLag)
+	if got := set.Pick(now.Add(16 * time.Second)); got != primary {
+		t.Error("stale health check should fall back to the primary")
+	}
+}
+
+func TestReplicaTLSPerHost(t *testing.T) {
+	t.Setenv("DB_TLS", "true")
+	t.Setenv("DB_TLS_VERIFY", "true")
+	t.Setenv("DB_PARAMS", "charset=utf8mb4&parseTime=True")
+	t.Setenv("DB_REPLICA_DSNS", "")
+	t.Setenv("DB_REPLICA_HOSTS", "replica-a.internal:3306,replica-b.internal")
+
+	dsns := replicaDSNs()
+	if len(dsns) != 2 {
+		t.Fatalf("replica DSNs = %v", dsns)
+	}
+	for name, dsn := range dsns {
+		out, err := withReplicaTLS(name, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := mysqldriver.ParseDSN(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		host, _, _ := strings.Cut(name, ":")
+		if parsed.TLSConfig != "replica-"+name || parsed.TLS == nil || parsed.TLS.ServerName != host {
+			t.Errorf("%s: tls %q server name %q", name, parsed.TLSConfig, parsed.TLS.ServerName)
+		}
+	}
+
+	// DSNs without tls=custom are left alone
+	plain := "ro:pw@tcp(replica-c.internal:3306)/vla?tls=true"
+	if out, err := withReplicaTLS("replica-c.internal:3306", plain); err != nil || out != plain {
+		t.Fatalf("plain DSN became %q, %v", out, err)
+	}
+}
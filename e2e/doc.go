@@ -0,0 +1,4 @@
+// Package e2e holds the end-to-end tests: the full router over a throwaway MySQL, an
+// in-process Redis, a fake payment gateway and a controllable clock. See the scenario
+// tests for the flows covered.
+package e2e
@@ -0,0 +1,273 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"project/controllers/users"
+	"project/database"
+	"project/internal/testdb"
+	"project/models"
+	"project/routes"
+	"project/services"
+	"project/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	testCronKey = "e2e-cron-key"
+	testPIN     = "135790"
+)
+
+// fakeGateway stands in for Pakailink on both sides: it hands out payment codes and
+// records payouts. Payment and payout results reach the app through the real callback
+// endpoints, as they would in production.
+type fakeGateway struct {
+	mu      sync.Mutex
+	vas     map[string]float64 // order id -> amount
+	qris    map[string]float64
+	paid    map[string]bool
+	payouts []services.PayoutRequest
+}
+
+func newFakeGateway() *fakeGateway {
+	return &fakeGateway{vas: map[string]float64{}, qris: map[string]float64{}, paid: map[string]bool{}}
+}
+
+func (g *fakeGateway) CreateQRIS(ctx context.Context, orderID string, amount float64) (*services.PaymentInstruction, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.qris[orderID] = amount
+	return &services.PaymentInstruction{Code: "00020101021226" + orderID}, nil
+}
+
+func (g *fakeGateway) CreateVA(ctx context.Context, orderID, bankCode, customerNo, name string, amount float64) (*services.PaymentInstruction, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.vas[orderID] = amount
+	return &services.PaymentInstruction{Code: "8808" + customerNo}, nil
+}
+
+func (g *fakeGateway) IsPaid(ctx context.Context, method, orderID string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paid[orderID], nil
+}
+
+func (g *fakeGateway) Payout(ctx context.Context, req services.PayoutRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.payouts = append(g.payouts, req)
+	return nil
+}
+
+// VA returns the amount of the virtual account opened for orderID.
+func (g *fakeGateway) VA(orderID string) (float64, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	amount, ok := g.vas[orderID]
+	return amount, ok
+}
+
+func (g *fakeGateway) Payouts() []services.PayoutRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]services.PayoutRequest(nil), g.payouts...)
+}
+
+// Harness is the whole API served by routes.InitRouter over a throwaway MySQL, an
+// in-process Redis and the fake gateway, with time under the test's control.
+type Harness struct {
+	t       *testing.T
+	DB      *gorm.DB
+	Clock   *testdb.Clock
+	Redis   *miniredis.Miniredis
+	Gateway *fakeGateway
+	Server  *httptest.Server
+}
+
+// newHarness boots the API. MySQL comes from TEST_MYSQL_DSN or a mysql:8.0 container
+// started with the docker CLI; the test is skipped when neither is available. The
+// harness swaps package globals (database.DB, utils.SystemClock, utils.RedisClient,
+// users.Services), so tests using it must not run in parallel.
+func newHarness(t *testing.T, start time.Time) *Harness {
+	t.Helper()
+	t.Setenv("CRON_KEY", testCronKey)
+	if os.Getenv("JWT_SECRET") == "" {
+		t.Setenv("JWT_SECRET", "e2e-jwt-secret")
+	}
+	t.Setenv("JWT_AUD", "")
+	t.Setenv("JWT_ISS", "")
+
+	clock := testdb.NewClock(start)
+	db := testdb.OpenMySQL(t, clock.Now)
+	rds := startRedis(t, clock)
+	gw := newFakeGateway()
+
+	prevDB, prevClock, prevRedis, prevServices := database.DB, utils.SystemClock, utils.RedisClient, users.Services
+	database.DB = db
+	utils.SystemClock = clock
+	utils.RedisClient = redis.NewClient(&redis.Options{Addr: rds.Addr()})
+	users.Services = services.New(services.Deps{DB: db, Clock: clock, Payments: gw, Payouts: gw})
+	t.Cleanup(func() {
+		_ = utils.RedisClient.Close()
+		database.DB, utils.SystemClock, utils.RedisClient, users.Services = prevDB, prevClock, prevRedis, prevServices
+	})
+
+	srv := httptest.NewServer(routes.InitRouter())
+	t.Cleanup(srv.Close)
+	return &Harness{t: t, DB: db, Clock: clock, Redis: rds, Gateway: gw, Server: srv}
+}
+
+// Response is a decoded utils.APIResponse, or a raw gateway acknowledgement.
+type Response struct {
+	Status  int
+	Success bool                   `json:"success"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data"`
+	Raw     map[string]interface{} `json:"-"`
+}
+
+// Do sends a JSON request to the API. token is the bearer token, if any; headers are
+// extra key/value pairs.
+func (h *Harness) Do(method, path, token string, body interface{}, headers ...string) Response {
+	h.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, h.Server.URL+path, &buf)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := h.Server.Client().Do(req)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	out := Response{Status: res.StatusCode}
+	var raw map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&raw); err == nil {
+		out.Raw = raw
+		out.Success, _ = raw["success"].(bool)
+		out.Message, _ = raw["message"].(string)
+		out.Data, _ = raw["data"].(map[string]interface{})
+	}
+	return out
+}
+
+// Expect fails the test unless the response has the given status.
+func (r Response) Expect(t *testing.T, status int, what string) Response {
+	t.Helper()
+	if r.Status != status {
+		t.Fatalf("%s: status %d, want %d (message %q, body %v)", what, r.Status, status, r.Message, r.Raw)
+	}
+	return r
+}
+
+// Cron triggers a cron endpoint the way the scheduler does.
+func (h *Harness) Cron(name string) Response {
+	h.t.Helper()
+	return h.Do(http.MethodPost, "/v3/cron/"+name, "", nil, "X-CRON-KEY", testCronKey)
+}
+
+// Balance reads the user's balance straight from the database.
+func (h *Harness) Balance(userID uint) float64 {
+	h.t.Helper()
+	var u models.User
+	if err := h.DB.Select("balance").First(&u, userID).Error; err != nil {
+		h.t.Fatal(err)
+	}
+	return u.Balance
+}
+
+// ExpectBalance fails the test unless the user's balance is want.
+func (h *Harness) ExpectBalance(userID uint, want float64, step string) {
+	h.t.Helper()
+	if got := h.Balance(userID); got != want {
+		h.t.Fatalf("%s: balance %.2f, want %.2f", step, got, want)
+	}
+}
+
+// Transactions returns the user's ledger, oldest first.
+func (h *Harness) Transactions(userID uint) []models.Transaction {
+	h.t.Helper()
+	var trx []models.Transaction
+	if err := h.DB.Where("user_id = ?", userID).Order("id ASC").Find(&trx).Error; err != nil {
+		h.t.Fatal(err)
+	}
+	return trx
+}
+
+// ledgerEntry is the part of a transaction the scenarios assert on.
+type ledgerEntry struct {
+	Type   string
+	Flow   string
+	Amount float64
+	Status string
+}
+
+// ExpectLedger fails the test unless the user's transactions match want in order.
+func (h *Harness) ExpectLedger(userID uint, step string, want ...ledgerEntry) {
+	h.t.Helper()
+	trx := h.Transactions(userID)
+	got := make([]ledgerEntry, len(trx))
+	for i, t := range trx {
+		got[i] = ledgerEntry{Type: t.TransactionType, Flow: t.TransactionFlow, Amount: t.Amount, Status: t.Status}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		h.t.Fatalf("%s: ledger\n got  %v\n want %v", step, got, want)
+	}
+}
+
+// seed holds the reference data every scenario starts from.
+type seed struct {
+	Referrer models.User
+	Bank     models.Bank
+	Product  models.Product
+}
+
+// Seed creates the settings row, a bank, a locked (Monitor) product paying 10% a day for
+// two days, and the referrer whose code new users register with.
+func (h *Harness) Seed() seed {
+	h.t.Helper()
+	var s seed
+	must := func(err error) {
+		h.t.Helper()
+		if err != nil {
+			h.t.Fatalf("seed: %v", err)
+		}
+	}
+	must(h.DB.Create(&models.Setting{Name: "NovaVant", MinWithdraw: 50000, MaxWithdraw: 10000000, WithdrawCharge: 10, AutoWithdraw: true}).Error)
+	s.Bank = models.Bank{Name: "BCA", ShortName: "BCA", Code: "014", Type: "bank", Status: "Active"}
+	must(h.DB.Create(&s.Bank).Error)
+	category := models.Category{Name: "Monitor", ProfitType: "locked", Status: "Active"}
+	must(h.DB.Create(&category).Error)
+	s.Product = models.Product{CategoryID: category.ID, Name: "Monitor 1", Amount: 100000, DailyProfit: 10000, Duration: 2, Status: "Active"}
+	must(h.DB.Create(&s.Product).Error)
+	s.Referrer = models.User{Name: "Referrer", Number: "81100000000", Password: "x", ReffCode: "HOUSE001", Status: "Active", StatusPublisher: "Inactive"}
+	must(h.DB.Create(&s.Referrer).Error)
+	must(utils.AddReferralClosure(h.DB, s.Referrer.ID, nil))
+	return s
+}
@@ -0,0 +1,70 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+)
+
+// startRedis runs an in-process miniredis whose clock follows the harness clock:
+// advancing the clock fast-forwards Redis, so login lockouts and token blacklists
+// expire the same way they would in real time.
+func startRedis(t *testing.T, clock *testdb.Clock) *miniredis.Miniredis {
+	t.Helper()
+	m := miniredis.RunT(t)
+	m.SetTime(clock.Now())
+	clock.OnAdvance(func(d time.Duration) {
+		m.SetTime(clock.Now())
+		m.FastForward(d)
+	})
+	return m
+}
+
+// redisKeys returns the live keys with the given prefix.
+func redisKeys(m *miniredis.Miniredis, prefix string) []string {
+	var keys []string
+	for _, k := range m.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The Redis wiring needs no database, so it is checked on every run.
+func TestRedisFollowsClock(t *testing.T) {
+	clock := testdb.NewClock(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC))
+	m := startRedis(t, clock)
+	rc := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	defer rc.Close()
+	ctx := context.Background()
+
+	if err := rc.Set(ctx, "jwt:blacklist:abc", "1", 15*time.Minute).Err(); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if n, _ := rc.Incr(ctx, "login:fail:u:1").Result(); n != 1 {
+		t.Fatalf("incr = %d", n)
+	}
+	rc.Expire(ctx, "login:fail:u:1", time.Minute)
+
+	clock.Advance(time.Minute)
+	if _, err := rc.Get(ctx, "login:fail:u:1").Result(); !errors.Is(err, redis.Nil) {
+		t.Fatalf("expired key gave %v", err)
+	}
+	if keys := redisKeys(m, "jwt:blacklist:"); len(keys) != 1 {
+		t.Fatalf("blacklist keys after a minute = %v", keys)
+	}
+	clock.Advance(14 * time.Minute)
+	if keys := redisKeys(m, "jwt:blacklist:"); len(keys) != 0 {
+		t.Fatalf("blacklist keys after expiry = %v", keys)
+	}
+}
@@ -0,0 +1,279 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/services"
+	"project/utils"
+)
+
+// register signs up through the API and returns the new user with its tokens.
+func register(h *Harness, t *testing.T, number, referral string) (models.User, string, string) {
+	t.Helper()
+	res := h.Do(http.MethodPost, "/v3/register", "", map[string]interface{}{
+		"name":                  "Budi Santoso",
+		"number":                number,
+		"password":              "rahasia123",
+		"password_confirmation": "rahasia123",
+		"referral_code":         referral,
+	}).Expect(t, http.StatusCreated, "register")
+	var u models.User
+	if err := h.DB.Where("number = ?", number).First(&u).Error; err != nil {
+		t.Fatalf("registered user: %v", err)
+	}
+	access, _ := res.Data["access_token"].(string)
+	refresh, _ := res.Data["refresh_token"].(string)
+	if access == "" || refresh == "" {
+		t.Fatalf("register returned no tokens: %v", res.Raw)
+	}
+	return u, access, refresh
+}
+
+// Register → invest → payment callback → daily returns → completion → withdrawal →
+// payout callback, checking balances and the ledger after every step.
+func TestInvestmentLifecycleScenario(t *testing.T) {
+	// Monday 08:00 WIB
+	h := newHarness(t, time.Date(2026, 10, 19, 8, 0, 0, 0, utils.JakartaLocation()))
+	s := h.Seed()
+
+	user, token, refresh := register(h, t, "81234567890", s.Referrer.ReffCode)
+	h.ExpectBalance(user.ID, 2000, "register")
+	h.ExpectLedger(user.ID, "register", ledgerEntry{"bonus", "debit", 2000, "Success"})
+
+	// Invest through a BCA virtual account
+	res := h.Do(http.MethodPost, "/v3/users/investments", token, map[string]interface{}{
+		"product_id": s.Product.ID, "payment_method": "BANK", "payment_channel": "BCA",
+	}).Expect(t, http.StatusCreated, "create investment")
+	orderID, _ := res.Data["order_id"].(string)
+	if amount, ok := h.Gateway.VA(orderID); !ok || amount != 100000 {
+		t.Fatalf("no 100000 virtual account for %s", orderID)
+	}
+	h.ExpectBalance(user.ID, 2000, "investment pending")
+	h.ExpectLedger(user.ID, "investment pending",
+		ledgerEntry{"bonus", "debit", 2000, "Success"},
+		ledgerEntry{"investment", "credit", 100000, "Pending"})
+
+	// The gateway reports the payment; a retried callback must not settle twice
+	callback := map[string]interface{}{
+		"transactionData": map[string]interface{}{
+			"partnerReferenceNo": orderID, "callbackType": "payment", "paymentFlagStatus": "00",
+		},
+	}
+	for i := 0; i < 2; i++ {
+		ack := h.Do(http.MethodPost, "/v3/callback/payments", "", callback).Expect(t, http.StatusOK, "payment callback")
+		if ack.Raw["responseCode"] != "2002800" {
+			t.Fatalf("payment callback ack %v", ack.Raw)
+		}
+	}
+	var inv models.Investment
+	h.DB.Where("order_id = ?", orderID).First(&inv)
+	if inv.Status != "Running" {
+		t.Fatalf("investment status %s after payment", inv.Status)
+	}
+	var payment models.Payment
+	h.DB.Where("order_id = ?", orderID).First(&payment)
+	if payment.Status != "Success" {
+		t.Fatalf("payment status %s after callback", payment.Status)
+	}
+	h.DB.First(&user, user.ID)
+	if user.TotalInvest != 100000 || user.TotalInvestVIP != 100000 || *user.Level != services.CalculateVIPLevel(100000) {
+		t.Fatalf("user after payment: invest %.0f vip %.0f level %v", user.TotalInvest, user.TotalInvestVIP, *user.Level)
+	}
+	h.ExpectBalance(user.ID, 2000, "investment paid")
+	h.ExpectLedger(user.ID, "investment paid",
+		ledgerEntry{"bonus", "debit", 2000, "Success"},
+		ledgerEntry{"investment", "credit", 100000, "Success"})
+	// The referrer earns 30% of a Monitor purchase, once
+	h.ExpectBalance(s.Referrer.ID, 30000, "referral bonus")
+	h.ExpectLedger(s.Referrer.ID, "referral bonus", ledgerEntry{"team", "debit", 30000, "Success"})
+
+	// Nothing is due until a day after activation
+	if res := h.Cron("daily-returns").Expect(t, http.StatusOK, "early cron"); res.Data["processed"] != float64(0) {
+		t.Fatalf("early cron processed %v", res.Data["processed"])
+	}
+
+	// Day 1: a locked product only accumulates
+	h.Clock.Advance(24*time.Hour + time.Minute)
+	if res := h.Cron("daily-returns").Expect(t, http.StatusOK, "day 1 cron"); res.Data["processed"] != float64(1) {
+		t.Fatalf("day 1 processed %v", res.Data["processed"])
+	}
+	h.DB.First(&inv, inv.ID)
+	if inv.TotalPaid != 1 || inv.TotalReturned != 10000 || inv.Status != "Running" {
+		t.Fatalf("day 1 investment %+v", inv)
+	}
+	h.ExpectBalance(user.ID, 2000, "day 1")
+
+	// Day 2: completion pays the accumulated profit and the principal
+	h.Clock.Advance(24 * time.Hour)
+	h.Cron("daily-returns").Expect(t, http.StatusOK, "day 2 cron")
+	h.DB.First(&inv, inv.ID)
+	if inv.Status != "Completed" || inv.TotalPaid != 2 {
+		t.Fatalf("day 2 investment %+v", inv)
+	}
+	h.ExpectBalance(user.ID, 122000, "completion")
+	h.ExpectLedger(user.ID, "completion",
+		ledgerEntry{"bonus", "debit", 2000, "Success"},
+		ledgerEntry{"investment", "credit", 100000, "Success"},
+		ledgerEntry{"return", "debit", 20000, "Success"},
+		ledgerEntry{"return", "debit", 100000, "Success"})
+
+	// Day 3: nothing left to pay
+	h.Clock.Advance(24 * time.Hour)
+	if res := h.Cron("daily-returns").Expect(t, http.StatusOK, "day 3 cron"); res.Data["processed"] != float64(0) {
+		t.Fatalf("completed investment processed again: %v", res.Data["processed"])
+	}
+
+	// The 15 minute access token from registration has long expired
+	h.Do(http.MethodGet, "/v3/users/info", token, nil).Expect(t, http.StatusUnauthorized, "expired token")
+	res = h.Do(http.MethodPost, "/v3/refresh", "", map[string]interface{}{"refresh_token": refresh}).Expect(t, http.StatusOK, "refresh")
+	token, _ = res.Data["access_token"].(string)
+
+	// PIN and bank account
+	h.Do(http.MethodPost, "/v3/users/pin", token, map[string]interface{}{
+		"password": "rahasia123", "pin": testPIN, "confirm_pin": testPIN,
+	}).Expect(t, http.StatusCreated, "set pin")
+	res = h.Do(http.MethodPost, "/v3/users/bank", token, map[string]interface{}{
+		"bank_id": s.Bank.ID, "account_name": "Budi Santoso", "account_number": "1234567890", "pin": testPIN,
+	}).Expect(t, http.StatusCreated, "add bank account")
+	var acc models.BankAccount
+	h.DB.Where("user_id = ?", user.ID).First(&acc)
+
+	withdraw := map[string]interface{}{"bank_account_id": acc.ID, "amount": 100000, "pin": testPIN}
+
+	// Thursday 08:01 WIB is before the 09:00 opening
+	res = h.Do(http.MethodPost, "/v3/users/withdrawal", token, withdraw).Expect(t, http.StatusBadRequest, "withdrawal before opening")
+	if res.Data["rule"] != "operating_window" {
+		t.Fatalf("early withdrawal rejected by %v: %q", res.Data["rule"], res.Message)
+	}
+	h.ExpectBalance(user.ID, 122000, "rejected withdrawal")
+
+	h.Clock.Advance(2 * time.Hour)
+	res = h.Do(http.MethodPost, "/v3/users/withdrawal", token, withdraw).Expect(t, http.StatusCreated, "withdrawal")
+	wdData, _ := res.Data["withdrawal"].(map[string]interface{})
+	wdOrderID, _ := wdData["order_id"].(string)
+	if wdData["status"] != "Pending" {
+		t.Fatalf("withdrawal before payout callback: %v", wdData)
+	}
+	payouts := h.Gateway.Payouts()
+	if len(payouts) != 1 || payouts[0].OrderID != wdOrderID || payouts[0].Amount != 90000 || payouts[0].Code != "014" {
+		t.Fatalf("payouts %+v", payouts)
+	}
+	h.ExpectBalance(user.ID, 22000, "withdrawal requested")
+
+	// The payout settles
+	ack := h.Do(http.MethodPost, "/v3/callback/payouts", "", map[string]interface{}{
+		"transactionData": map[string]interface{}{"partnerReferenceNo": wdOrderID, "paymentFlagStatus": "00"},
+	}).Expect(t, http.StatusOK, "payout callback")
+	if ack.Raw["responseCode"] != "2004400" {
+		t.Fatalf("payout callback ack %v", ack.Raw)
+	}
+	var wd models.Withdrawal
+	h.DB.Where("order_id = ?", wdOrderID).First(&wd)
+	if wd.Status != "Success" || wd.Charge != 10000 || wd.FinalAmount != 90000 {
+		t.Fatalf("withdrawal after payout %+v", wd)
+	}
+	h.ExpectBalance(user.ID, 22000, "payout")
+	h.ExpectLedger(user.ID, "payout",
+		ledgerEntry{"bonus", "debit", 2000, "Success"},
+		ledgerEntry{"investment", "credit", 100000, "Success"},
+		ledgerEntry{"return", "debit", 20000, "Success"},
+		ledgerEntry{"return", "debit", 100000, "Success"},
+		ledgerEntry{"withdrawal", "credit", 100000, "Success"})
+
+	// One withdrawal per business day
+	res = h.Do(http.MethodPost, "/v3/users/withdrawal", token, map[string]interface{}{"bank_account_id": acc.ID, "amount": 50000, "pin": testPIN}).
+		Expect(t, http.StatusBadRequest, "second withdrawal")
+	if res.Data["rule"] != "daily_count" {
+		t.Fatalf("second withdrawal rejected by %v: %q", res.Data["rule"], res.Message)
+	}
+}
+
+// An unpaid investment is cancelled by the expiry cron once the virtual account lapses.
+func TestUnpaidInvestmentExpiresScenario(t *testing.T) {
+	h := newHarness(t, time.Date(2026, 10, 19, 8, 0, 0, 0, utils.JakartaLocation()))
+	s := h.Seed()
+	user, token, _ := register(h, t, "81234567891", s.Referrer.ReffCode)
+
+	res := h.Do(http.MethodPost, "/v3/users/investments", token, map[string]interface{}{
+		"product_id": s.Product.ID, "payment_method": "QRIS",
+	}).Expect(t, http.StatusCreated, "create investment")
+	orderID, _ := res.Data["order_id"].(string)
+
+	if res := h.Cron("expired-handlers").Expect(t, http.StatusOK, "cron before expiry"); res.Data["processed"] != float64(0) {
+		t.Fatalf("expired before 24h: %v", res.Data["processed"])
+	}
+	h.Clock.Advance(24*time.Hour + time.Minute)
+	if res := h.Cron("expired-handlers").Expect(t, http.StatusOK, "cron after expiry"); res.Data["processed"] != float64(1) {
+		t.Fatalf("expiry processed %v", res.Data["processed"])
+	}
+
+	var inv models.Investment
+	h.DB.Where("order_id = ?", orderID).First(&inv)
+	if inv.Status != "Cancelled" {
+		t.Fatalf("investment status %s after expiry", inv.Status)
+	}
+	h.ExpectBalance(user.ID, 2000, "expired investment")
+	h.ExpectLedger(user.ID, "expired investment",
+		ledgerEntry{"bonus", "debit", 2000, "Success"},
+		ledgerEntry{"investment", "credit", 100000, "Failed"})
+	h.ExpectBalance(s.Referrer.ID, 0, "no referral bonus")
+}
+
+// Login lockouts and token revocation live in Redis and expire with the clock.
+func TestLoginLockoutAndLogoutScenario(t *testing.T) {
+	h := newHarness(t, time.Date(2026, 10, 19, 8, 0, 0, 0, utils.JakartaLocation()))
+	s := h.Seed()
+	user, _, _ := register(h, t, "81234567892", s.Referrer.ReffCode)
+
+	login := func(password string) Response {
+		return h.Do(http.MethodPost, "/v3/login", "", map[string]interface{}{"number": user.Number, "password": password})
+	}
+	login("salah123").Expect(t, http.StatusUnauthorized, "wrong password")
+	login("rahasia123").Expect(t, http.StatusTooManyRequests, "locked account")
+	if keys := redisKeys(h.Redis, "login:lock:"); len(keys) != 1 {
+		t.Fatalf("lock keys %v", keys)
+	}
+
+	h.Clock.Advance(61 * time.Second)
+	res := login("rahasia123").Expect(t, http.StatusOK, "login after lockout")
+	token, _ := res.Data["access_token"].(string)
+	refresh, _ := res.Data["refresh_token"].(string)
+	h.Do(http.MethodGet, "/v3/users/info", token, nil).Expect(t, http.StatusOK, "info")
+
+	h.Do(http.MethodPost, "/v3/logout", token, map[string]interface{}{"refresh_token": refresh}).Expect(t, http.StatusOK, "logout")
+	if keys := redisKeys(h.Redis, "jwt:blacklist:"); len(keys) != 1 {
+		t.Fatalf("blacklist keys %v", keys)
+	}
+	h.Do(http.MethodGet, "/v3/users/info", token, nil).Expect(t, http.StatusUnauthorized, "revoked token")
+	h.Do(http.MethodPost, "/v3/refresh", "", map[string]interface{}{"refresh_token": refresh}).Expect(t, http.StatusUnauthorized, "revoked refresh token")
+
+	// The blacklist entry only lives as long as the token would have
+	h.Clock.Advance(16 * time.Minute)
+	if keys := redisKeys(h.Redis, "jwt:blacklist:"); len(keys) != 0 {
+		t.Fatalf("blacklist not expired: %v", keys)
+	}
+}
+
+// A closed account's access tokens stop working at once, not when they expire.
+func TestClosedAccountTokensScenario(t *testing.T) {
+	h := newHarness(t, time.Date(2026, 10, 19, 8, 0, 0, 0, utils.JakartaLocation()))
+	s := h.Seed()
+	user, first, _ := register(h, t, "81234567893", s.Referrer.ReffCode)
+	second, _ := h.Do(http.MethodPost, "/v3/login", "", map[string]interface{}{"number": user.Number, "password": "rahasia123"}).
+		Expect(t, http.StatusOK, "second device login").Data["access_token"].(string)
+	hash, _ := utils.HashPIN("135790")
+	if err := h.DB.Create(&models.UserPIN{UserID: user.ID, PINHash: hash}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h.Do(http.MethodPost, "/v3/users/account/close", first, map[string]interface{}{"pin": "135790", "confirm": true}).
+		Expect(t, http.StatusOK, "close account")
+	if keys := redisKeys(h.Redis, "jwt:blacklist:"); len(keys) != 1 {
+		t.Fatalf("closing token not revoked: %v", keys)
+	}
+	h.Do(http.MethodGet, "/v3/users/info", first, nil).Expect(t, http.StatusUnauthorized, "closing token")
+	h.Do(http.MethodGet, "/v3/users/info", second, nil).Expect(t, http.StatusUnauthorized, "other device token")
+}
@@ -0,0 +1,207 @@
+// Package testdb holds the helpers shared by the MySQL-backed tests: a throwaway database
+// built the way production builds one, and a clock the test moves forward.
+package testdb
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project/database"
+	"project/migrations"
+	"project/models"
+
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Clock is a clock the test moves forward. It satisfies utils.Clock, and GORM timestamps
+// follow it when it is passed to OpenMySQL.
+type Clock struct {
+	mu        sync.Mutex
+	t         time.Time
+	onAdvance []func(time.Duration)
+}
+
+func NewClock(t time.Time) *Clock { return &Clock{t: t} }
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	hooks := c.onAdvance
+	c.mu.Unlock()
+	for _, f := range hooks {
+		f(d)
+	}
+}
+
+// OnAdvance registers f to run after each Advance, for fakes that keep their own time.
+func (c *Clock) OnAdvance(f func(time.Duration)) {
+	c.mu.Lock()
+	c.onAdvance = append(c.onAdvance, f)
+	c.mu.Unlock()
+}
+
+// OpenMySQL returns a throwaway database with every table dropped and the schema rebuilt
+// as for a new production database: database/db.sql without its seed rows, then the
+// versioned migrations, then AutoMigrate of models.All() as in development. It uses
+// TEST_MYSQL_DSN when set, otherwise starts a mysql:8.0 container with the docker CLI;
+// the test is skipped when neither is available. now sets GORM's timestamps; nil means
+// the wall clock.
+func OpenMySQL(t testing.TB, now func() time.Time) *gorm.DB {
+	t.Helper()
+	db := connect(t, now)
+	migrateTo(t, db, 0)
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("auto-migrate: %v", err)
+	}
+	return db
+}
+
+// OpenMySQLAt is OpenMySQL for migration tests: the schema stops at the given migration
+// version and AutoMigrate is not run.
+func OpenMySQLAt(t testing.TB, version int) *gorm.DB {
+	t.Helper()
+	db := connect(t, nil)
+	migrateTo(t, db, version)
+	return db
+}
+
+func connect(t testing.TB, now func() time.Time) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		if _, err := exec.LookPath("docker"); err != nil {
+			t.Skip("set TEST_MYSQL_DSN or install docker to run MySQL tests")
+		}
+		out, err := exec.Command("docker", "run", "-d", "--rm",
+			"-e", "MYSQL_ROOT_PASSWORD=test", "-e", "MYSQL_DATABASE=novavant_test",
+			"-p", "127.0.0.1::3306", "mysql:8.0").Output()
+		if err != nil {
+			t.Skipf("could not start mysql container: %v", err)
+		}
+		id := strings.TrimSpace(string(out))
+		t.Cleanup(func() { _ = exec.Command("docker", "rm", "-f", id).Run() })
+
+		portOut, err := exec.Command("docker", "port", id, "3306/tcp").Output()
+		if err != nil {
+			t.Fatalf("docker port: %v", err)
+		}
+		hostPort := strings.TrimSpace(strings.Split(string(portOut), "\n")[0])
+		dsn = fmt.Sprintf("root:test@tcp(%s)/novavant_test?charset=utf8mb4&parseTime=True&loc=Local", hostPort)
+	}
+
+	var db *gorm.DB
+	var err error
+	deadline := time.Now().Add(90 * time.Second)
+	for {
+		db, err = gorm.Open(gormmysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent), NowFunc: now})
+		if err == nil {
+			if sqlDB, e := db.DB(); e == nil {
+				if err = sqlDB.Ping(); err == nil {
+					sqlDB.SetMaxOpenConns(50)
+					break
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mysql not ready: %v", err)
+		}
+		time.Sleep(time.Second)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// migrateTo rebuilds the schema up to version (0 = all migrations).
+func migrateTo(t testing.TB, db *gorm.DB, version int) {
+	t.Helper()
+	if err := resetSchema(db); err != nil {
+		t.Fatalf("reset schema: %v", err)
+	}
+	if err := loadBaseline(db); err != nil {
+		t.Fatalf("load db.sql: %v", err)
+	}
+	m, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	m.Logf = func(string, ...interface{}) {}
+	if _, err := m.Up(version); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+}
+
+// resetSchema drops every table and view in the current database.
+func resetSchema(db *gorm.DB) error {
+	var objects []struct {
+		TableName string
+		TableType string
+	}
+	if err := db.Raw("SELECT table_name AS table_name, table_type AS table_type FROM information_schema.tables WHERE table_schema = DATABASE()").
+		Scan(&objects).Error; err != nil {
+		return err
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+		for _, o := range objects {
+			kind := "TABLE"
+			if o.TableType == "VIEW" {
+				kind = "VIEW"
+			}
+			if err := conn.Exec("DROP " + kind + " IF EXISTS `" + o.TableName + "`").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var definerClause = regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*` ")
+
+// loadBaseline creates the pre-migration schema from database/db.sql. Seed rows and the
+// dump's session settings are skipped so every test starts from empty tables; DEFINER
+// clauses are dropped so the test user does not need SET_USER_ID.
+func loadBaseline(db *gorm.DB) error {
+	_, file, _, _ := runtime.Caller(0)
+	script, err := os.ReadFile(filepath.Join(filepath.Dir(file), "..", "..", "database", "db.sql"))
+	if err != nil {
+		return err
+	}
+	// One connection, so the dump's START TRANSACTION and COMMIT pair up
+	return db.Connection(func(conn *gorm.DB) error {
+		for _, stmt := range database.SplitStatements(string(script)) {
+			upper := strings.ToUpper(stmt)
+			if strings.HasPrefix(upper, "INSERT ") || strings.HasPrefix(upper, "SET ") || strings.HasPrefix(stmt, "/*!") {
+				continue
+			}
+			if err := conn.Exec(definerClause.ReplaceAllString(stmt, "")).Error; err != nil {
+				return fmt.Errorf("%.60s: %w", stmt, err)
+			}
+		}
+		return nil
+	})
+}
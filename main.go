@@ -6,36 +6,28 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
+	"project/config"
 	"project/database"
+	"project/metrics"
 	"project/middleware"
+	"project/migrations"
 	"project/models"
 	"project/routes"
-
-	"github.com/joho/godotenv"
+	"project/utils"
 )
 
 func main() {
-	// Load .env if present (do not overwrite already-set environment variables).
-	// Always attempt to load so DB_HOST, DB_NAME, etc are available when running locally.
-	if envMap, err := godotenv.Read(); err == nil {
-		for k, v := range envMap {
-			if os.Getenv(k) == "" {
-				os.Setenv(k, v)
-			}
-		}
-	}
-
-	// Validate required environment variables
-	requiredEnvVars := []string{"DB_HOST", "DB_USER", "DB_PASS", "DB_NAME", "JWT_SECRET"}
-	for _, envVar := range requiredEnvVars {
-		if os.Getenv(envVar) == "" {
-			log.Fatalf("Required environment variable %s is not set", envVar)
-		}
+	// Load configuration from .env / CONFIG_FILE, the environment and *_FILE secrets.
+	// Everything is validated up front so a bad deploy stops here with all problems listed.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("invalid configuration:\n%v", err)
 	}
+	config.Set(cfg)
+	utils.ConnectRedis(cfg.Redis)
 
 	// Connect to the database
 	db, err := database.Connect()
@@ -43,40 +35,49 @@ func main() {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
+	// One-off maintenance commands: ./app <command> [flags]. They run before
+	// auto-migration so migrate and schema-drift see the schema as it really is.
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(db, os.Args[1:]))
+	}
+
 	// Auto-migrate only in development to avoid accidental production schema changes
-	if strings.ToLower(os.Getenv("ENV")) == "development" {
+	if cfg.IsDevelopment() {
 		log.Println("Running in development mode - performing auto-migration")
-		if err := db.AutoMigrate(
-			&models.Admin{},
-			&models.RefreshToken{},
-			&models.User{},
-			&models.Category{},
-			&models.Product{},
-			&models.Investment{},
-			&models.UserSpin{},
-			&models.Setting{ClosedRegister: false, Maintenance: false},
-			&models.Payment{},
-			&models.PaymentSettings{},
-			&models.ChatSession{},
-			&models.ChatMessage{},
-			&models.TransferContact{},
-			&models.Gift{},
-			&models.GiftAmountSlot{},
-			&models.GiftClaim{},
-		); err != nil {
+		if err := db.AutoMigrate(models.All()...); err != nil {
 			log.Fatalf("failed to migrate database: %v", err)
 		}
 		log.Println("Auto-migration completed successfully")
 	} else {
 		log.Println("Running in production mode - skipping auto-migration")
-		// Ensure critical columns exist (add profile to users if missing)
-		if err := db.Exec("ALTER TABLE users ADD COLUMN profile VARCHAR(255) NULL").Error; err != nil {
-			if !strings.Contains(err.Error(), "Duplicate column") {
-				log.Printf("[warn] schema migration (profile): %v", err)
-			}
+	}
+
+	// Refuse to serve against a schema older than the code; development databases are
+	// shaped by AutoMigrate, so there it is only a warning
+	if err := database.CheckSchemaCurrent(db, migrations.FS); err != nil {
+		if cfg.IsDevelopment() {
+			log.Printf("[warn] schema migrations: %v", err)
+		} else {
+			log.Fatalf("refusing to start: %v", err)
 		}
 	}
 
+	// Heavy read-only listings go to replicas when configured; a replica that cannot be
+	// reached at boot is not fatal, reads then stay on the primary
+	if err := database.ConnectReplicas(context.Background(), db); err != nil {
+		log.Printf("[warn] read replicas disabled: %v", err)
+	}
+
+	// Pool stats and withdrawal counts for /metrics
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB("primary", sqlDB); err != nil {
+			log.Printf("[warn] primary pool metrics: %v", err)
+		}
+	}
+	if err := metrics.RegisterWithdrawals(db); err != nil {
+		log.Printf("[warn] withdrawal metrics: %v", err)
+	}
+
 	// Initialize router
 	router := routes.InitRouter()
 
@@ -99,10 +100,7 @@ func main() {
 	)
 
 	// Create HTTP server with production-ready configuration
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := cfg.Port
 	addr := ":" + port
 
 	server := &http.Server{
@@ -121,6 +119,21 @@ func main() {
 		}
 	}()
 
+	// SIGHUP re-reads the configuration; only the settings marked reloadable change
+	// without a restart
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			res, err := config.Reload()
+			if err != nil {
+				log.Printf("[config] reload rejected, keeping the current configuration:\n%v", err)
+				continue
+			}
+			log.Printf("[config] reloaded: changed %v, restart required for %v", res.Changed, res.RestartRequired)
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
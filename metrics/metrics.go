@@ -0,0 +1,163 @@
+// Package metrics holds the Prometheus collectors exported on /metrics: HTTP latency and
+// status counts by route template, database pool stats, and business counters for
+// payments, withdrawals, payouts, cron jobs and Groq calls.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "novavant"
+
+// Registry is the registry served by Handler. It is separate from the client library's
+// default registry so only collectors registered here are exported.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route"})
+
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP responses by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	PaymentsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_created_total",
+		Help:      "Gateway payments created for investments, by payment method.",
+	}, []string{"method"})
+
+	PaymentsSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_succeeded_total",
+		Help:      "Gateway payments confirmed paid (callback or status refresh), by payment method.",
+	}, []string{"method"})
+
+	PaymentsExpired = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_expired_total",
+		Help:      "Pending payments expired unpaid, by payment method.",
+	}, []string{"method"})
+
+	PayoutFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payout_failures_total",
+		Help:      "Failed withdrawal payouts by provider (pakailink, partner) and stage (request, callback).",
+	}, []string{"provider", "stage"})
+
+	CronRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "cron",
+		Name:      "run_duration_seconds",
+		Help:      "Cron endpoint run time by job and outcome (success, unauthorized, error).",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"job", "outcome"})
+
+	GroqRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "groq",
+		Name:      "request_duration_seconds",
+		Help:      "Groq chat completion latency, including failed calls.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30},
+	})
+
+	GroqErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "groq",
+		Name:      "errors_total",
+		Help:      "Failed Groq calls by reason (transport, status, decode, empty).",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration, HTTPRequests,
+		PaymentsCreated, PaymentsSucceeded, PaymentsExpired,
+		PayoutFailures, CronRunDuration,
+		GroqRequestDuration, GroqErrors,
+	)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records one HTTP response.
+func ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	HTTPRequestDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+	HTTPRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+}
+
+// PaymentMethod is the method label for a payment's nullable payment_method column.
+func PaymentMethod(method *string) string {
+	if method == nil || *method == "" {
+		return "unknown"
+	}
+	return *method
+}
+
+// RegisterDB exports the connection pool stats (sql.DB.Stats) of db labelled with name.
+func RegisterDB(name string, db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// withdrawalsCollector reports the number of withdrawals per status, read from the
+// database at scrape time.
+type withdrawalsCollector struct {
+	db   *gorm.DB
+	desc *prometheus.Desc
+}
+
+// RegisterWithdrawals exports novavant_withdrawals{status}, counted on each scrape.
+func RegisterWithdrawals(db *gorm.DB) error {
+	return Registry.Register(&withdrawalsCollector{
+		db:   db,
+		desc: prometheus.NewDesc(namespace+"_withdrawals", "Withdrawals by status.", []string{"status"}, nil),
+	})
+}
+
+func (c *withdrawalsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *withdrawalsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var rows []struct {
+		Status string
+		Count  float64
+	}
+	if err := c.db.WithContext(ctx).Table("withdrawals").
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	counts := map[string]float64{"Pending": 0, "Success": 0, "Failed": 0}
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, n, status)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"project/database"
@@ -82,6 +83,7 @@ func AdminAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Admin is authenticated, proceed
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), utils.AdminIDKey, uint(admin.ID))
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -3,11 +3,16 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
+	"project/database"
 	"project/utils"
+
+	"gorm.io/gorm"
 )
 
 func writeJSON(w http.ResponseWriter, status int, resp map[string]interface{}) {
@@ -76,6 +81,29 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Tokens outlive account changes: closed or deactivated accounts are refused
+		if err := utils.CheckUserAccess(database.DB, userID); err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"message": "Invalid token",
+				})
+			case errors.Is(err, utils.ErrAccountClosed), errors.Is(err, utils.ErrAccountInactive):
+				writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+					"success": false,
+					"message": "Akun Anda sudah tidak aktif",
+				})
+			default:
+				log.Printf("[auth] user %d status: %v", userID, err)
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+					"success": false,
+					"message": "Terjadi kesalahan sistem, silakan coba lagi",
+				})
+			}
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), utils.UserIDKey, userID)
 		ctx = context.WithValue(ctx, utils.UserRoleKey, role)
 
@@ -2,19 +2,14 @@ package middleware
 
 import (
 	"net/http"
-	"os"
-	"strconv"
+
+	"project/config"
 )
 
-// MaxBodyMiddleware enforces a maximum request body size read from env var MAX_BODY_BYTES (in bytes)
+// MaxBodyMiddleware enforces a maximum request body size from MAX_BODY_BYTES (in bytes)
 // default is 1<<20 (1 MiB)
 func MaxBodyMiddleware(next http.Handler) http.Handler {
-	max := int64(1 << 20) // 1 MiB default
-	if s := os.Getenv("MAX_BODY_BYTES"); s != "" {
-		if v, err := strconv.ParseInt(s, 10, 64); err == nil && v > 0 {
-			max = v
-		}
-	}
+	max := config.Get().HTTP.MaxBodyBytes
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// apply MaxBytesReader to limit request body size
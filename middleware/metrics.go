@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/config"
+	"project/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+type routeLabelKey struct{}
+
+// routeLabel is placed in the request context by MetricsMiddleware and filled in by
+// RouteLabelMiddleware once mux has matched a route, so latency is labelled with the
+// route template ("/v3/users/{id}") instead of the raw path.
+type routeLabel struct {
+	route string
+}
+
+// RouteLabelMiddleware records the matched route template for MetricsMiddleware.
+// Register it on the root router with r.Use; it only runs for matched routes.
+func RouteLabelMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if label, ok := r.Context().Value(routeLabelKey{}).(*routeLabel); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				if tpl, err := route.GetPathTemplate(); err == nil {
+					label.route = tpl
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// metricsMethod bounds the method label to the standard methods
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+func withRouteLabel(r *http.Request) (*http.Request, *routeLabel) {
+	label := &routeLabel{route: "unmatched"}
+	return r.WithContext(context.WithValue(r.Context(), routeLabelKey{}, label)), label
+}
+
+// CronMetrics records the run time of a cron endpoint by job and outcome. The
+// outcome comes from the response status: 2xx success, 401/403 unauthorized,
+// anything else error.
+func CronMetrics(job string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, status: 200}
+		next.ServeHTTP(rec, r)
+		outcome := "error"
+		switch {
+		case rec.status >= 200 && rec.status < 300:
+			outcome = "success"
+		case rec.status == http.StatusUnauthorized || rec.status == http.StatusForbidden:
+			outcome = "unauthorized"
+		}
+		metrics.CronRunDuration.WithLabelValues(job, outcome).Observe(time.Since(start).Seconds())
+	})
+}
+
+// MetricsAuth guards the scrape endpoint with the METRICS_TOKEN bearer token. While no
+// token is configured the endpoint does not exist (404).
+func MetricsAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := config.Get().HTTP.MetricsToken
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		got := ""
+		if authz := r.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+			got = strings.TrimPrefix(authz, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
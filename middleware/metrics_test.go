@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"project/metrics"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(RouteLabelMiddleware)
+	api := r.PathPrefix("/v3").Subrouter()
+	api.HandleFunc("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}).Methods(http.MethodGet)
+	h := MetricsMiddleware(r)
+
+	count := func(method, route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(method, route, status))
+	}
+	beforeRoute := count("GET", "/v3/products/{id}", "418")
+	beforeMissing := count("GET", "unmatched", "404")
+	beforeOther := count("OTHER", "unmatched", "404")
+
+	for _, path := range []string{"/v3/products/1", "/v3/products/2"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v3/nope", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/v3/nope", nil))
+
+	if got := count("GET", "/v3/products/{id}", "418") - beforeRoute; got != 2 {
+		t.Fatalf("route template count: got %v, want 2", got)
+	}
+	if got := count("GET", "unmatched", "404") - beforeMissing; got != 1 {
+		t.Fatalf("unmatched count: got %v, want 1", got)
+	}
+	if got := count("OTHER", "unmatched", "404") - beforeOther; got != 1 {
+		t.Fatalf("unknown method count: got %v, want 1", got)
+	}
+}
+
+func TestCronMetrics_Outcome(t *testing.T) {
+	for _, tc := range []struct {
+		status  int
+		outcome string
+	}{
+		{http.StatusOK, "success"},
+		{http.StatusUnauthorized, "unauthorized"},
+		{http.StatusInternalServerError, "error"},
+	} {
+		job := "test-" + tc.outcome
+		h := CronMetrics(job, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v3/cron/x", nil))
+		var m dto.Metric
+		if err := metrics.CronRunDuration.WithLabelValues(job, tc.outcome).(prometheus.Histogram).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m.GetHistogram().GetSampleCount() != 1 {
+			t.Fatalf("status %d: no %s observation", tc.status, tc.outcome)
+		}
+	}
+}
+
+func TestMetricsAuth(t *testing.T) {
+	h := MetricsAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	do := func(authz string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Setenv("METRICS_TOKEN", "")
+	if code := do("Bearer anything"); code != http.StatusNotFound {
+		t.Fatalf("without token configured: got %d, want 404", code)
+	}
+
+	t.Setenv("METRICS_TOKEN", "scrape-secret")
+	for authz, want := range map[string]int{
+		"":                     http.StatusUnauthorized,
+		"scrape-secret":        http.StatusUnauthorized,
+		"Bearer wrong":         http.StatusUnauthorized,
+		"Bearer scrape-secret": http.StatusOK,
+	} {
+		if code := do(authz); code != want {
+			t.Fatalf("Authorization %q: got %d, want %d", authz, code, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"project/database"
+	"project/utils"
+)
+
+// partnerMaxBodyBytes caps the body read into memory to verify a signature; partner
+// requests are small JSON documents.
+const partnerMaxBodyBytes = 64 << 10
+
+// PartnerAuth authenticates partner requests by API key and requires scope. The key
+// is sent as "Authorization: Bearer pk_..." or in X-API-Key. Keys that require
+// signing must also send X-Timestamp and X-Signature (see utils.SignPartnerRequest).
+func PartnerAuth(scope string) func(http.Handler) http.Handler {
+	return partnerAuth(scope, false)
+}
+
+// SignedPartnerAuth is PartnerAuth for routes that change state on the partner's word
+// (withdrawal results): keys that do not require signing are refused.
+func SignedPartnerAuth(scope string) func(http.Handler) http.Handler {
+	return partnerAuth(scope, true)
+}
+
+func partnerAuth(scope string, signed bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := strings.TrimSpace(r.Header.Get("X-API-Key"))
+			if raw == "" {
+				if authz := r.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+					raw = strings.TrimSpace(strings.TrimPrefix(authz, "Bearer "))
+				}
+			}
+			if raw == "" {
+				utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+				return
+			}
+
+			now := utils.SystemClock.Now()
+			key, err := utils.AuthenticatePartnerKey(database.DB, raw, now)
+			if err != nil {
+				if !isPartnerAuthError(err) {
+					log.Printf("[partner-auth] %s %s: %v", r.Method, r.URL.Path, err)
+					utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+					return
+				}
+				utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Unauthorized"})
+				return
+			}
+
+			if signed && !key.RequireSignature {
+				utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Forbidden: this endpoint requires a signing key"})
+				return
+			}
+			if key.RequireSignature {
+				body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, partnerMaxBodyBytes))
+				if err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.APIResponse{Success: false, Message: "Request body too large"})
+						return
+					}
+					utils.WriteJSON(w, http.StatusBadRequest, utils.APIResponse{Success: false, Message: "Invalid request body"})
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				err = utils.VerifyPartnerSignature(key, r.Method, r.URL.RequestURI(),
+					r.Header.Get("X-Timestamp"), r.Header.Get("X-Signature"), body, now)
+				if err != nil {
+					if !isPartnerAuthError(err) {
+						log.Printf("[partner-auth] key %d signature: %v", key.ID, err)
+						utils.WriteJSON(w, http.StatusInternalServerError, utils.APIResponse{Success: false, Message: "Terjadi kesalahan sistem, silakan coba lagi"})
+						return
+					}
+					utils.WriteJSON(w, http.StatusUnauthorized, utils.APIResponse{Success: false, Message: "Invalid signature"})
+					return
+				}
+			}
+
+			if !key.HasScope(scope) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.APIResponse{Success: false, Message: "Forbidden: missing scope " + scope})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), utils.PartnerIDKey, key.PartnerID)
+			ctx = context.WithValue(ctx, utils.PartnerKeyIDKey, key.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func isPartnerAuthError(err error) bool {
+	for _, e := range []error{
+		utils.ErrPartnerKeyInvalid, utils.ErrPartnerKeyExpired, utils.ErrPartnerKeyRevoked,
+		utils.ErrPartnerInactive, utils.ErrPartnerSignature, utils.ErrPartnerReplay,
+	} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
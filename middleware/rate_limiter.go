@@ -6,12 +6,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"project/config"
 	"project/utils"
 )
 
@@ -23,23 +22,9 @@ type timestamps []int64 // unix nanos
 
 func nowUnix() int64 { return time.Now().UnixNano() }
 
-// Configuration defaults (override via env)
-func getEnvInt(key string, def int) int {
-	if s := os.Getenv(key); s != "" {
-		if v, err := strconv.Atoi(s); err == nil {
-			return v
-		}
-	}
-	return def
-}
-
-func getEnvDuration(key string, def time.Duration) time.Duration {
-	if s := os.Getenv(key); s != "" {
-		if v, err := strconv.Atoi(s); err == nil {
-			return time.Duration(v) * time.Second
-		}
-	}
-	return def
+// cleanupInterval is how often expired windows are dropped (RATE_CLEANUP_SECONDS).
+func cleanupInterval() time.Duration {
+	return time.Duration(config.Get().RateLimit.CleanupSeconds) * time.Second
 }
 
 // IPRateLimiter implements per-IP fixed-window counters with optional trusted-proxy parsing
@@ -59,12 +44,10 @@ func NewIPRateLimiter(maxReq int, window time.Duration) *IPRateLimiter {
 	l := &IPRateLimiter{
 		window:      window,
 		state:       make(map[string]timestamps),
-		cleanupTick: getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second),
+		cleanupTick: cleanupInterval(),
+		trustedCIDR: config.Get().HTTP.TrustedProxies,
 		instanceMax: maxReq,
 	}
-	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
-		l.trustedCIDR = strings.Split(v, ",")
-	}
 	go l.cleanupLoop()
 	return l
 }
@@ -140,18 +123,18 @@ func (l *IPRateLimiter) Middleware(next http.Handler) http.Handler {
 		l.mu.Unlock()
 
 		// Determine limit based on endpoint category. Prefer constructor-provided instanceMax
-		// and fall back to env var defaults.
+		// and fall back to the configured defaults.
+		limits := config.Get().RateLimit
 		limit := l.instanceMax
 		if limit <= 0 {
-			limit = getEnvInt("RATE_IP_DEFAULT", 200)
+			limit = limits.IPDefault
 		}
 		if strings.HasPrefix(r.URL.Path, "/auth") {
-			// For auth endpoints prefer env override if set, otherwise use instanceMax or default
-			envLimit := getEnvInt("RATE_IP_AUTH", -1)
-			if envLimit > 0 {
-				limit = envLimit
+			// For auth endpoints prefer RATE_IP_AUTH if set, otherwise use instanceMax or default
+			if limits.IPAuth > 0 {
+				limit = limits.IPAuth
 			} else if l.instanceMax <= 0 {
-				limit = getEnvInt("RATE_IP_AUTH", 50)
+				limit = 50
 			}
 		}
 
@@ -246,7 +229,7 @@ func NewUserRateLimiter(maxReqRead, maxReqWrite int, windowSec int) *UserRateLim
 		state:         make(map[string]timestamps),
 		penalty:       make(map[string]penaltyInfo),
 		windowDefault: window,
-		cleanupTick:   getEnvDuration("RATE_CLEANUP_SECONDS", 60*time.Second),
+		cleanupTick:   cleanupInterval(),
 		// set instance overrides
 		instanceRead:  maxReqRead,
 		instanceWrite: maxReqWrite,
@@ -269,19 +252,23 @@ func routeCategory(path string) string {
 }
 
 func (l *UserRateLimiter) getLimitsForCategory(cat string, role string) (int, time.Duration) {
-	// defaults
+	// Read on every request so a config reload applies immediately
+	limits := config.Get().RateLimit
 	switch cat {
 	case "auth":
-		return getEnvInt("RATE_USER_AUTH", 50), time.Minute
+		return limits.UserAuth, time.Minute
 	case "upload":
-		return getEnvInt("RATE_USER_UPLOAD", 10), time.Minute
+		return limits.UserUpload, time.Minute
 	case "admin":
+		if limits.UserAdmin > 0 {
+			return limits.UserAdmin, time.Minute
+		}
 		if role == "admin" {
-			return getEnvInt("RATE_USER_ADMIN", 500), time.Minute
+			return 500, time.Minute
 		}
-		return getEnvInt("RATE_USER_ADMIN", 50), time.Minute
+		return 50, time.Minute
 	default:
-		return getEnvInt("RATE_USER_API", 100), time.Minute
+		return limits.UserAPI, time.Minute
 	}
 }
 
@@ -7,13 +7,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"os"
 	"runtime/debug"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"project/config"
+	"project/metrics"
 	"project/utils"
 )
 
@@ -26,33 +26,24 @@ func generateRequestID() string {
 	return hex.EncodeToString(b)
 }
 
-func getenv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return def
-}
-
-// SecurityHeadersMiddleware sets CORS and security headers. Behavior is env-driven.
+// SecurityHeadersMiddleware sets CORS and security headers. Behavior is config-driven.
 func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 	// Configurable values
-	env := strings.ToLower(getenv("ENV", "development"))
-	allowedOrigins := getenv("CORS_ALLOWED_ORIGINS", "*")
-	hsts := getenv("SEC_HSTS", "false")
-	csp := getenv("SEC_CSP", "default-src 'none'; frame-ancestors 'none'; base-uri 'self';")
-
-	// Build a list for CORS matches
-	origins := strings.Split(allowedOrigins, ",")
+	cfg := config.Get()
+	development := cfg.IsDevelopment()
+	origins := cfg.HTTP.CORSAllowedOrigins
+	hsts := cfg.HTTP.HSTS
+	csp := cfg.HTTP.CSP
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// CORS
 		origin := r.Header.Get("Origin")
 		allowed := false
-		if allowedOrigins == "*" {
+		if len(origins) == 0 || (len(origins) == 1 && origins[0] == "*") {
 			allowed = true
 		} else if origin != "" {
 			for _, o := range origins {
-				if strings.TrimSpace(o) == origin {
+				if o == origin {
 					allowed = true
 					break
 				}
@@ -69,10 +60,10 @@ func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		if env != "development" {
+		if !development {
 			w.Header().Set("Content-Security-Policy", csp)
 		}
-		if hsts == "true" {
+		if hsts {
 			// 1 year HSTS
 			w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
 		}
@@ -123,7 +114,7 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 
 // TimeoutMiddleware cancels the request context after a configured timeout
 func TimeoutMiddleware(next http.Handler) http.Handler {
-	timeoutSec := atoi(getenv("REQ_TIMEOUT_SEC", "10"))
+	timeoutSec := config.Get().HTTP.RequestTimeout
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(timeoutSec)*time.Second)
 		defer cancel()
@@ -153,33 +144,30 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// Simple in-memory metrics and suspicious activity tracker
+// Suspicious activity tracker: count of slow responses per ip
 var (
-	metricsMu sync.Mutex
-	// track last N response times per route
-	routeTimes = make(map[string][]time.Duration)
-	// track recent suspicious activity per ip
 	suspiciousMu sync.Mutex
 	suspicious   = make(map[string]int)
 )
 
-// MetricsMiddleware measures response time and tracks slow responses
+// MetricsMiddleware records request latency and status by route template (see
+// RouteLabelMiddleware) and tracks slow responses
 func MetricsMiddleware(next http.Handler) http.Handler {
-	slowThresholdMs := atoi(getenv("METRIC_SLOW_MS", "800"))
+	slowThresholdMs := config.Get().HTTP.MetricSlowMs
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
+		rec := &responseRecorder{ResponseWriter: w, status: 200}
+		r, label := withRouteLabel(r)
+		defer func() {
+			// A panic is answered with 500 by RecoveryMiddleware further out
+			if p := recover(); p != nil {
+				metrics.ObserveHTTP(metricsMethod(r.Method), label.route, http.StatusInternalServerError, time.Since(start))
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rec, r)
 		elapsed := time.Since(start)
-		// record route times
-		metricsMu.Lock()
-		key := r.Method + " " + r.URL.Path
-		arr := routeTimes[key]
-		if len(arr) >= 100 {
-			arr = arr[1:]
-		}
-		arr = append(arr, elapsed)
-		routeTimes[key] = arr
-		metricsMu.Unlock()
+		metrics.ObserveHTTP(metricsMethod(r.Method), label.route, rec.status, elapsed)
 
 		if elapsed > time.Duration(slowThresholdMs)*time.Millisecond {
 			// increment suspicious counter for the IP
@@ -193,7 +181,7 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 
 // SuspiciousActivityMiddleware flags IPs with repeated slow responses or other signals
 func SuspiciousActivityMiddleware(next http.Handler) http.Handler {
-	threshold := atoi(getenv("SUSPICIOUS_THRESHOLD", "10"))
+	threshold := config.Get().HTTP.SuspiciousThreshold
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ip := r.RemoteAddr
 		suspiciousMu.Lock()
@@ -209,12 +197,3 @@ func SuspiciousActivityMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-// Helper: atoi with default
-func atoi(s string) int {
-	v, _ := strconv.Atoi(s)
-	if v <= 0 {
-		return 0
-	}
-	return v
-}
@@ -0,0 +1,5 @@
+-- Baseline: the schema in database/db.sql plus the hand-applied scripts in legacy/.
+-- Databases that predate the migration runner already have it; record it with
+--   ./app migrate baseline 1
+-- A new database is loaded from database/db.sql and legacy/ first, then baselined.
+SELECT 1;
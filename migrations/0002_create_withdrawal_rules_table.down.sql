@@ -0,0 +1,2 @@
+ALTER TABLE bank_accounts DROP COLUMN updated_at, DROP COLUMN created_at;
+DROP TABLE IF EXISTS withdrawal_rules;
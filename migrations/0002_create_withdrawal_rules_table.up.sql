@@ -0,0 +1,32 @@
+-- Admin-managed withdrawal policy rules, evaluated by utils.WithdrawalPolicy
+CREATE TABLE IF NOT EXISTS withdrawal_rules (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    rule_type ENUM('operating_window','closed_weekday','closed_date','daily_count','daily_amount','amount_range','cooling_off') NOT NULL,
+    vip_level INT UNSIGNED NULL COMMENT 'NULL = applies to every VIP level',
+    weekday TINYINT NULL COMMENT '0 = Sunday ... 6 = Saturday',
+    date DATE NULL,
+    start_time VARCHAR(5) NULL COMMENT 'HH:MM WIB',
+    end_time VARCHAR(5) NULL COMMENT 'HH:MM WIB, exclusive',
+    limit_count INT NOT NULL DEFAULT 0,
+    limit_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    min_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    max_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    cooldown_hours INT NOT NULL DEFAULT 0,
+    message VARCHAR(255) NULL COMMENT 'custom rejection reason',
+    status ENUM('Active','Inactive') DEFAULT 'Active',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_withdrawal_rules_type (rule_type),
+    INDEX idx_withdrawal_rules_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Seed the rules that used to be hardcoded in WithdrawalHandler
+INSERT INTO withdrawal_rules (rule_type, start_time, end_time) VALUES ('operating_window', '09:00', '17:00');
+INSERT INTO withdrawal_rules (rule_type, weekday) VALUES ('closed_weekday', 0);
+INSERT INTO withdrawal_rules (rule_type, limit_count) VALUES ('daily_count', 1);
+
+-- Bank account timestamps are needed for the cooling_off rule.
+-- Existing rows stay NULL so they are not treated as freshly changed.
+ALTER TABLE bank_accounts
+  ADD COLUMN created_at DATETIME NULL,
+  ADD COLUMN updated_at DATETIME NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS transfer_velocity_rules;
+DROP TABLE IF EXISTS transfer_limits;
+DROP TABLE IF EXISTS transfers;
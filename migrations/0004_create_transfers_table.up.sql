@@ -0,0 +1,48 @@
+-- P2P transfer ledger, per-VIP transfer limits and fan-in velocity rules
+CREATE TABLE IF NOT EXISTS transfers (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    sender_id INT NOT NULL,
+    receiver_id INT NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    sender_order_id VARCHAR(191) NOT NULL,
+    receiver_order_id VARCHAR(191) NOT NULL,
+    hold_until DATETIME NULL COMMENT 'received amount cannot be withdrawn before this time',
+    hold_reason VARCHAR(100) NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_transfers_sender_order (sender_order_id),
+    UNIQUE KEY uk_transfers_receiver_order (receiver_order_id),
+    INDEX idx_transfers_sender_created (sender_id, created_at),
+    INDEX idx_transfers_receiver_created (receiver_id, created_at),
+    INDEX idx_transfers_hold_until (hold_until),
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (receiver_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS transfer_limits (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    vip_level INT UNSIGNED NOT NULL,
+    min_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    max_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    daily_out_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '0 = unlimited',
+    monthly_out_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    daily_in_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    monthly_in_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    per_recipient_daily_limit DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    hold_hours INT NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_transfer_limits_vip (vip_level)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS transfer_velocity_rules (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    window_minutes INT NOT NULL,
+    max_distinct_senders INT NOT NULL,
+    small_amount_max DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT '0 = any amount counts',
+    action ENUM('block','hold') NOT NULL DEFAULT 'block',
+    hold_hours INT NOT NULL DEFAULT 0,
+    status ENUM('Active','Inactive') DEFAULT 'Active',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
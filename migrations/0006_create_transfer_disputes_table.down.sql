@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS transfer_disputes;
+ALTER TABLE transfers DROP COLUMN reversed_at;
@@ -0,0 +1,27 @@
+-- Transfer disputes and reversals
+ALTER TABLE transfers ADD COLUMN reversed_at DATETIME NULL AFTER hold_reason;
+
+CREATE TABLE IF NOT EXISTS transfer_disputes (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    transfer_id INT UNSIGNED NOT NULL,
+    sender_id INT NOT NULL,
+    receiver_id INT NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    reason TEXT NOT NULL,
+    status ENUM('Pending','Rejected','Reversed') NOT NULL DEFAULT 'Pending',
+    admin_note TEXT NULL,
+    reviewed_by INT UNSIGNED NULL,
+    reviewed_at DATETIME NULL,
+    shortfall_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 COMMENT 'part of the reversal not covered by the receiver balance',
+    sender_refund_order_id VARCHAR(191) NULL,
+    receiver_reversal_order_id VARCHAR(191) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_transfer_disputes_transfer (transfer_id),
+    INDEX idx_transfer_disputes_sender (sender_id),
+    INDEX idx_transfer_disputes_receiver (receiver_id),
+    INDEX idx_transfer_disputes_status (status),
+    FOREIGN KEY (transfer_id) REFERENCES transfers(id),
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (receiver_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
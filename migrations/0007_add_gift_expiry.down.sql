@@ -0,0 +1,9 @@
+ALTER TABLE settings
+    DROP COLUMN gift_max_expiry_hours,
+    DROP COLUMN gift_default_expiry_hours;
+
+ALTER TABLE gifts
+    DROP INDEX idx_gifts_expires,
+    DROP COLUMN refund_order_id,
+    DROP COLUMN refunded_amount,
+    DROP COLUMN expires_at;
@@ -0,0 +1,10 @@
+-- Gift expiry and refund of unclaimed amounts
+ALTER TABLE gifts
+    ADD COLUMN expires_at DATETIME NULL AFTER total_deducted,
+    ADD COLUMN refunded_amount DECIMAL(15,2) NOT NULL DEFAULT 0.00 AFTER expires_at,
+    ADD COLUMN refund_order_id VARCHAR(191) NULL AFTER refunded_amount,
+    ADD INDEX idx_gifts_expires (expires_at);
+
+ALTER TABLE settings
+    ADD COLUMN gift_default_expiry_hours INT NOT NULL DEFAULT 24,
+    ADD COLUMN gift_max_expiry_hours INT NOT NULL DEFAULT 168;
@@ -0,0 +1,6 @@
+ALTER TABLE gift_amount_slots
+    DROP INDEX uk_gift_slots_gift_slot;
+
+ALTER TABLE gift_claims
+    DROP INDEX uk_gift_claims_gift_slot,
+    DROP INDEX uk_gift_claims_gift_user;
@@ -0,0 +1,10 @@
+-- Enforce one claim per user and per slot on every gift.
+-- Check for existing duplicates first; the ALTERs fail while any remain:
+--   SELECT gift_id, user_id, COUNT(*) FROM gift_claims GROUP BY gift_id, user_id HAVING COUNT(*) > 1;
+--   SELECT gift_id, slot_index, COUNT(*) FROM gift_claims GROUP BY gift_id, slot_index HAVING COUNT(*) > 1;
+ALTER TABLE gift_claims
+    ADD UNIQUE KEY uk_gift_claims_gift_user (gift_id, user_id),
+    ADD UNIQUE KEY uk_gift_claims_gift_slot (gift_id, slot_index);
+
+ALTER TABLE gift_amount_slots
+    ADD UNIQUE KEY uk_gift_slots_gift_slot (gift_id, slot_index);
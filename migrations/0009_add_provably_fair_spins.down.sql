@@ -0,0 +1,11 @@
+ALTER TABLE user_spins
+    DROP INDEX idx_user_spins_seed,
+    DROP COLUMN weight_snapshot,
+    DROP COLUMN total_weight,
+    DROP COLUMN roll,
+    DROP COLUMN nonce,
+    DROP COLUMN client_seed,
+    DROP COLUMN server_seed_hash,
+    DROP COLUMN seed_id;
+
+DROP TABLE IF EXISTS spin_seeds;
@@ -0,0 +1,25 @@
+-- Provably fair spins: per-user server seeds (commit-reveal) and audit data on each spin
+CREATE TABLE IF NOT EXISTS spin_seeds (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    server_seed CHAR(64) NOT NULL,
+    server_seed_hash CHAR(64) NOT NULL,
+    client_seed VARCHAR(64) NOT NULL,
+    nonce BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    status ENUM('Active','Revealed') NOT NULL DEFAULT 'Active',
+    revealed_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_spin_seeds_user (user_id),
+    INDEX idx_spin_seeds_status (status),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE user_spins
+    ADD COLUMN seed_id INT UNSIGNED NULL,
+    ADD COLUMN server_seed_hash CHAR(64) NULL,
+    ADD COLUMN client_seed VARCHAR(64) NULL,
+    ADD COLUMN nonce BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN roll INT NOT NULL DEFAULT 0,
+    ADD COLUMN total_weight INT NOT NULL DEFAULT 0,
+    ADD COLUMN weight_snapshot TEXT NULL,
+    ADD INDEX idx_user_spins_seed (seed_id);
@@ -0,0 +1,12 @@
+ALTER TABLE user_spins
+    DROP INDEX idx_user_spins_won_at,
+    DROP COLUMN fallback_reason,
+    DROP COLUMN fallback_from_prize_id;
+
+ALTER TABLE settings
+    DROP COLUMN spin_total_budget,
+    DROP COLUMN spin_daily_budget;
+
+ALTER TABLE spin_prizes
+    DROP COLUMN total_quantity_limit,
+    DROP COLUMN daily_quantity_limit;
@@ -0,0 +1,13 @@
+-- Spin payout budgets and per-prize quantity limits (0 = unlimited)
+ALTER TABLE spin_prizes
+    ADD COLUMN daily_quantity_limit INT NOT NULL DEFAULT 0,
+    ADD COLUMN total_quantity_limit INT NOT NULL DEFAULT 0;
+
+ALTER TABLE settings
+    ADD COLUMN spin_daily_budget DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    ADD COLUMN spin_total_budget DECIMAL(15,2) NOT NULL DEFAULT 0.00;
+
+ALTER TABLE user_spins
+    ADD COLUMN fallback_from_prize_id INT UNSIGNED NULL,
+    ADD COLUMN fallback_reason VARCHAR(20) NULL,
+    ADD INDEX idx_user_spins_won_at (won_at);
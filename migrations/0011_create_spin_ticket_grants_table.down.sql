@@ -0,0 +1,3 @@
+-- users.spin_ticket still holds the available count, so nothing is lost
+ALTER TABLE settings DROP COLUMN spin_ticket_expiry_days;
+DROP TABLE IF EXISTS spin_ticket_grants;
@@ -0,0 +1,37 @@
+-- Spin ticket ledger; users.spin_ticket remains as a cache of the available count
+CREATE TABLE IF NOT EXISTS spin_ticket_grants (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    source VARCHAR(30) NOT NULL,
+    reference_order_id VARCHAR(191) NULL,
+    granted_at DATETIME NOT NULL,
+    expires_at DATETIME NULL,
+    consumed_at DATETIME NULL,
+    expired_at DATETIME NULL,
+    user_spin_id INT UNSIGNED NULL,
+    INDEX idx_spin_ticket_grants_user_open (user_id, consumed_at),
+    INDEX idx_spin_ticket_grants_reference (reference_order_id),
+    INDEX idx_spin_ticket_grants_expires (expires_at),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+ALTER TABLE settings
+    ADD COLUMN spin_ticket_expiry_days INT NOT NULL DEFAULT 0;
+
+-- Backfill: one legacy grant per ticket already on the counter. The sequence recurses
+-- once per ticket of the richest user, past the default cte_max_recursion_depth of 1000.
+SET @max_spin_tickets = (SELECT COALESCE(MAX(spin_ticket), 0) FROM users);
+SET SESSION cte_max_recursion_depth = GREATEST(@@SESSION.cte_max_recursion_depth, @max_spin_tickets + 1);
+
+INSERT INTO spin_ticket_grants (user_id, source, granted_at)
+WITH RECURSIVE seq (n) AS (
+    SELECT 1
+    UNION ALL
+    SELECT n + 1 FROM seq WHERE n < (SELECT COALESCE(MAX(spin_ticket), 0) FROM users)
+)
+SELECT u.id, 'legacy', NOW()
+FROM users u
+JOIN seq ON seq.n <= u.spin_ticket
+WHERE u.spin_ticket > 0;
+
+SET SESSION cte_max_recursion_depth = DEFAULT;
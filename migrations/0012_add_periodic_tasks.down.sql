@@ -0,0 +1,13 @@
+-- Fails while a user has claimed the same task in more than one period
+DROP TABLE IF EXISTS user_checkins;
+DROP TABLE IF EXISTS user_task_progress;
+
+ALTER TABLE user_tasks ADD UNIQUE KEY unique_user_task (user_id, task_id);
+ALTER TABLE user_tasks
+    DROP INDEX uk_user_tasks_period,
+    DROP COLUMN period_key;
+
+ALTER TABLE tasks
+    DROP COLUMN target_value,
+    DROP COLUMN criterion,
+    DROP COLUMN type;
@@ -0,0 +1,36 @@
+-- Periodic tasks (one-time/daily/weekly), more criteria, persisted progress and daily check-ins
+ALTER TABLE tasks
+    ADD COLUMN type ENUM('one_time','daily','weekly') NOT NULL DEFAULT 'one_time',
+    ADD COLUMN criterion VARCHAR(30) NOT NULL DEFAULT 'active_members',
+    ADD COLUMN target_value DECIMAL(15,2) NOT NULL DEFAULT 0.00;
+
+-- Claims are unique per period; the new key also covers the user_id foreign key,
+-- so it must exist before the old one is dropped
+ALTER TABLE user_tasks
+    ADD COLUMN period_key VARCHAR(10) NOT NULL DEFAULT 'once',
+    ADD UNIQUE KEY uk_user_tasks_period (user_id, task_id, period_key);
+ALTER TABLE user_tasks DROP INDEX unique_user_task;
+
+CREATE TABLE IF NOT EXISTS user_task_progress (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    task_id INT NOT NULL,
+    period_key VARCHAR(10) NOT NULL,
+    progress DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    target DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    completed_at DATETIME NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_user_task_progress (user_id, task_id, period_key),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS user_checkins (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    checkin_date CHAR(10) NOT NULL,
+    streak INT NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_user_checkins_date (user_id, checkin_date),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS referral_closure;
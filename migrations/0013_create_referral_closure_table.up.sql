@@ -0,0 +1,10 @@
+-- Materialized referral tree (closure table over users.reff_by).
+-- Populate existing users afterwards with: ./app backfill-referral-closure
+CREATE TABLE IF NOT EXISTS referral_closure (
+    ancestor_id INT UNSIGNED NOT NULL,
+    descendant_id INT UNSIGNED NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id),
+    INDEX idx_referral_closure_ancestor_depth (ancestor_id, depth),
+    INDEX idx_referral_closure_descendant (descendant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS referral_flags;
+DROP TABLE IF EXISTS registration_signals;
+ALTER TABLE users DROP COLUMN bonus_frozen;
@@ -0,0 +1,36 @@
+-- Referral fraud detection: registration signals, review queue and bonus freeze
+ALTER TABLE users
+    ADD COLUMN bonus_frozen TINYINT(1) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS registration_signals (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    reff_by INT NULL,
+    ip VARCHAR(45) NOT NULL,
+    device_id VARCHAR(128) NULL,
+    user_agent VARCHAR(255) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_registration_signals_user (user_id),
+    INDEX idx_registration_signals_reff_by (reff_by),
+    INDEX idx_registration_signals_ip (ip),
+    INDEX idx_registration_signals_device (device_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS referral_flags (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    related_user_id INT NULL,
+    kind ENUM('device_cluster','ip_cluster','self_referral','gift_farm') NOT NULL,
+    details VARCHAR(255) NULL,
+    status ENUM('Pending','Cleared','Confirmed') NOT NULL DEFAULT 'Pending',
+    admin_note TEXT NULL,
+    reviewed_by INT UNSIGNED NULL,
+    reviewed_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_referral_flags_user_kind (user_id, kind),
+    INDEX idx_referral_flags_related (related_user_id),
+    INDEX idx_referral_flags_status (status),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
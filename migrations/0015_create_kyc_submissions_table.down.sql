@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS kyc_submissions;
+
+ALTER TABLE settings
+    DROP COLUMN kyc_daily_withdrawal_threshold,
+    DROP COLUMN kyc_withdrawal_threshold;
+
+ALTER TABLE users DROP COLUMN kyc_status;
@@ -0,0 +1,26 @@
+-- KYC: identity document submissions, user verification status and withdrawal thresholds
+ALTER TABLE users
+    ADD COLUMN kyc_status ENUM('Unverified','Pending','Verified','Rejected') NOT NULL DEFAULT 'Unverified';
+
+ALTER TABLE settings
+    ADD COLUMN kyc_withdrawal_threshold DECIMAL(15,2) NOT NULL DEFAULT 0,
+    ADD COLUMN kyc_daily_withdrawal_threshold DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS kyc_submissions (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    full_name VARCHAR(100) NOT NULL,
+    id_number VARCHAR(32) NOT NULL,
+    id_card_key VARCHAR(255) NOT NULL,
+    selfie_key VARCHAR(255) NOT NULL,
+    status ENUM('Pending','Approved','Rejected') NOT NULL DEFAULT 'Pending',
+    reject_reason VARCHAR(255) NULL,
+    reviewed_by INT UNSIGNED NULL,
+    reviewed_at DATETIME NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_kyc_submissions_user (user_id),
+    INDEX idx_kyc_submissions_id_number (id_number),
+    INDEX idx_kyc_submissions_status (status),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+-- Account closure: closed accounts keep their financial records with anonymized personal data
+ALTER TABLE users
+    ADD COLUMN closed_at DATETIME NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS partner_keys;
+DROP TABLE IF EXISTS partners;
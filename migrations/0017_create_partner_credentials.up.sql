@@ -0,0 +1,30 @@
+-- Partner credentials: per-partner API keys (hashed) with scopes, expiry, rotation and
+-- optional HMAC request signing. Replaces the shared X-VLA-KEY and StoneForm keys.
+CREATE TABLE IF NOT EXISTS partners (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    status ENUM('Active','Inactive') NOT NULL DEFAULT 'Active',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_partners_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS partner_keys (
+    id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    partner_id INT UNSIGNED NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    secret_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    require_signature TINYINT(1) NOT NULL DEFAULT 0,
+    signing_secret VARCHAR(255) NULL,
+    expires_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    rotated_from_id INT UNSIGNED NULL,
+    last_used_at DATETIME NULL,
+    created_by INT UNSIGNED NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY idx_partner_keys_prefix (prefix),
+    INDEX idx_partner_keys_partner_id (partner_id),
+    FOREIGN KEY (partner_id) REFERENCES partners(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
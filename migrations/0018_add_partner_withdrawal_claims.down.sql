@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS partner_audit_logs;
+ALTER TABLE withdrawals
+    DROP INDEX idx_withdrawals_claim,
+    DROP COLUMN partner_reference,
+    DROP COLUMN partner_failures,
+    DROP COLUMN claim_expires_at,
+    DROP COLUMN claimed_by_partner_id;
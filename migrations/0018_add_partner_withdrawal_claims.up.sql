@@ -0,0 +1,22 @@
+-- Partner withdrawal processing: a processor claims (leases) pending withdrawals before
+-- paying them, so two processors never pay the same one, and every partner action is audited.
+ALTER TABLE withdrawals
+    ADD COLUMN claimed_by_partner_id INT UNSIGNED NULL,
+    ADD COLUMN claim_expires_at DATETIME NULL,
+    ADD COLUMN partner_failures TINYINT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN partner_reference VARCHAR(100) NULL,
+    ADD INDEX idx_withdrawals_claim (status, claim_expires_at);
+
+CREATE TABLE IF NOT EXISTS partner_audit_logs (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    partner_id INT UNSIGNED NOT NULL,
+    partner_key_id INT UNSIGNED NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    order_id VARCHAR(191) NULL,
+    outcome ENUM('ok','rejected') NOT NULL,
+    detail VARCHAR(255) NULL,
+    ip VARCHAR(45) NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_partner_audit_logs_partner_id (partner_id, created_at),
+    INDEX idx_partner_audit_logs_order_id (order_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,2 @@
+ALTER TABLE withdrawals
+    DROP COLUMN gateway_dispatched_at;
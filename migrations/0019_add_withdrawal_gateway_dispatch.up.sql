@@ -0,0 +1,4 @@
+-- Withdrawals handed to the payout gateway (Pakailink) stay Pending until its callback;
+-- the dispatch time keeps them out of the partner claim pool so they are not paid twice.
+ALTER TABLE withdrawals
+    ADD COLUMN gateway_dispatched_at DATETIME NULL;
@@ -0,0 +1,24 @@
+-- Per-period spin payout counters; the spin handler reads these under the prize lock
+-- instead of aggregating user_spins on every draw
+CREATE TABLE IF NOT EXISTS spin_budget_counters (
+    period VARCHAR(10) NOT NULL,
+    prize_id INT UNSIGNED NOT NULL,
+    wins BIGINT NOT NULL DEFAULT 0,
+    paid DECIMAL(15,2) NOT NULL DEFAULT 0.00,
+    fallbacks BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (period, prize_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Backfill from existing spins. won_at is stored in the application's local zone
+-- (loc=Local), which production runs as Asia/Jakarta, so DATE(won_at) is the WIB day.
+INSERT INTO spin_budget_counters (period, prize_id, wins, paid, fallbacks)
+SELECT 'total', prize_id, COUNT(*), COALESCE(SUM(amount), 0),
+       SUM(CASE WHEN fallback_from_prize_id IS NOT NULL THEN 1 ELSE 0 END)
+FROM user_spins
+GROUP BY prize_id;
+
+INSERT INTO spin_budget_counters (period, prize_id, wins, paid, fallbacks)
+SELECT DATE_FORMAT(won_at, '%Y-%m-%d'), prize_id, COUNT(*), COALESCE(SUM(amount), 0),
+       SUM(CASE WHEN fallback_from_prize_id IS NOT NULL THEN 1 ELSE 0 END)
+FROM user_spins
+GROUP BY DATE_FORMAT(won_at, '%Y-%m-%d'), prize_id;
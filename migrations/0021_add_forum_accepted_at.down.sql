@@ -0,0 +1,3 @@
+ALTER TABLE forums
+    DROP INDEX idx_forums_user_accepted,
+    DROP COLUMN accepted_at;
@@ -0,0 +1,7 @@
+-- When a post was accepted; forum_accepted task progress is counted by this instead of
+-- updated_at, which moves on any later edit. Existing accepted posts take updated_at.
+ALTER TABLE forums
+    ADD COLUMN accepted_at DATETIME NULL,
+    ADD INDEX idx_forums_user_accepted (user_id, accepted_at);
+
+UPDATE forums SET accepted_at = updated_at WHERE status = 'Accepted';
@@ -0,0 +1,2 @@
+ALTER TABLE otp_requests
+    DROP COLUMN purpose;
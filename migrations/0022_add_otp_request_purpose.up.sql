@@ -0,0 +1,4 @@
+-- Forgot-password and PIN reset share otp_requests; each flow only replaces and accepts
+-- its own requests. Existing rows all come from forgot-password.
+ALTER TABLE otp_requests
+    ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'password_reset' AFTER otp_id;
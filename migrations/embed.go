@@ -0,0 +1,13 @@
+// Package migrations holds the versioned schema migrations, embedded into the binary.
+//
+// Files are named NNNN_description.up.sql with an optional NNNN_description.down.sql;
+// a migration without a down file is irreversible. Applied migrations are recorded in
+// schema_migrations with the checksum of their up file, so an applied file must never
+// be edited; add a new migration instead. The scripts in legacy/ were applied by hand
+// before versioning and are kept for reference only.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,34 @@
+package migrations_test
+
+import (
+	"testing"
+
+	"project/database"
+	"project/internal/testdb"
+	"project/migrations"
+)
+
+func TestSpinTicketGrantBackfillMySQL(t *testing.T) {
+	db := testdb.OpenMySQLAt(t, 10)
+
+	// More tickets than MySQL's default cte_max_recursion_depth of 1000
+	if err := db.Exec("INSERT INTO users (name, number, password, reff_code, spin_ticket) VALUES ('Rich', '81200000001', 'x', 'RICH01', 1500), ('Some', '81200000002', 'x', 'SOME01', 2)").Error; err != nil {
+		t.Fatal(err)
+	}
+	m, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Logf = t.Logf
+	if _, err := m.Up(11); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	var grants int64
+	if err := db.Table("spin_ticket_grants").Where("source = ?", "legacy").Count(&grants).Error; err != nil {
+		t.Fatal(err)
+	}
+	if grants != 1502 {
+		t.Fatalf("backfilled %d grants, want 1502", grants)
+	}
+}
@@ -4,11 +4,14 @@ import "time"
 
 type Forum struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	UserID      uint      `gorm:"not null" json:"user_id"`
+	UserID      uint      `gorm:"not null;index:idx_forums_user_accepted,priority:1" json:"user_id"`
 	Reward      float64   `gorm:"type:decimal(15,2);default:0" json:"reward"`
 	Description string    `gorm:"type:varchar(60);not null" json:"description"`
 	Image       string    `gorm:"type:varchar(255);not null" json:"image"`
 	Status      string    `gorm:"type:enum('Accepted','Pending','Rejected');default:'Pending'" json:"status"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Set when an admin accepts the post; dates forum_accepted task progress
+	AcceptedAt *time.Time `gorm:"index:idx_forums_user_accepted,priority:2" json:"accepted_at,omitempty"`
 }
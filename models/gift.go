@@ -4,16 +4,19 @@ import "time"
 
 // Gift represents a gift/dana kaget that user creates to share with others
 type Gift struct {
-	ID               uint      `gorm:"primaryKey" json:"id"`
-	UserID           uint      `gorm:"not null;index" json:"user_id"`
-	Code             string    `gorm:"size:12;uniqueIndex;not null" json:"code"`
-	Amount           float64   `gorm:"type:decimal(15,2);not null" json:"amount"` // total (random) or per-winner (equal)
-	WinnerCount      int       `gorm:"not null" json:"winner_count"`
-	DistributionType string    `gorm:"type:enum('random','equal');not null" json:"distribution_type"`
-	RecipientType    string    `gorm:"type:enum('all','referral_only');not null" json:"recipient_type"`
-	Status           string    `gorm:"type:enum('active','completed','expired','cancelled');default:'active'" json:"status"`
-	TotalDeducted    float64   `gorm:"type:decimal(15,2);not null" json:"total_deducted"` // amount actually deducted from sender
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	Code             string     `gorm:"size:12;uniqueIndex;not null" json:"code"`
+	Amount           float64    `gorm:"type:decimal(15,2);not null" json:"amount"` // total (random) or per-winner (equal)
+	WinnerCount      int        `gorm:"not null" json:"winner_count"`
+	DistributionType string     `gorm:"type:enum('random','equal');not null" json:"distribution_type"`
+	RecipientType    string     `gorm:"type:enum('all','referral_only');not null" json:"recipient_type"`
+	Status           string     `gorm:"type:enum('active','completed','expired','cancelled');default:'active'" json:"status"`
+	TotalDeducted    float64    `gorm:"type:decimal(15,2);not null" json:"total_deducted"` // amount actually deducted from sender
+	ExpiresAt        *time.Time `gorm:"index" json:"expires_at"`                           // nil for gifts created before expiry existed
+	RefundedAmount   float64    `gorm:"type:decimal(15,2);not null;default:0" json:"refunded_amount"`
+	RefundOrderID    *string    `gorm:"type:varchar(191)" json:"refund_order_id,omitempty"` // transaction that returned the unclaimed amount
+	CreatedAt        time.Time  `json:"created_at"`
 
 	// Associations
 	User   *User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -28,8 +31,8 @@ func (Gift) TableName() string {
 // GiftAmountSlot stores pre-allocated amounts for random distribution
 type GiftAmountSlot struct {
 	ID        uint    `gorm:"primaryKey" json:"id"`
-	GiftID    uint    `gorm:"not null;index" json:"gift_id"`
-	SlotIndex int     `gorm:"not null" json:"slot_index"`
+	GiftID    uint    `gorm:"not null;index;uniqueIndex:uk_gift_slots_gift_slot,priority:1" json:"gift_id"`
+	SlotIndex int     `gorm:"not null;uniqueIndex:uk_gift_slots_gift_slot,priority:2" json:"slot_index"`
 	Amount    float64 `gorm:"type:decimal(15,2);not null" json:"amount"`
 }
 
@@ -37,13 +40,14 @@ func (GiftAmountSlot) TableName() string {
 	return "gift_amount_slots"
 }
 
-// GiftClaim represents a user claiming/redeeming a gift
+// GiftClaim represents a user claiming/redeeming a gift. A user claims a gift at most once
+// and each slot is claimed at most once (enforced by unique keys).
 type GiftClaim struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	GiftID    uint      `gorm:"not null;index" json:"gift_id"`
-	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	GiftID    uint      `gorm:"not null;index;uniqueIndex:uk_gift_claims_gift_user,priority:1;uniqueIndex:uk_gift_claims_gift_slot,priority:1" json:"gift_id"`
+	UserID    uint      `gorm:"not null;index;uniqueIndex:uk_gift_claims_gift_user,priority:2" json:"user_id"`
 	Amount    float64   `gorm:"type:decimal(15,2);not null" json:"amount"`
-	SlotIndex int       `gorm:"not null;default:0" json:"slot_index"`
+	SlotIndex int       `gorm:"not null;default:0;uniqueIndex:uk_gift_claims_gift_slot,priority:2" json:"slot_index"`
 	CreatedAt time.Time `json:"created_at"`
 
 	// Associations
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Holiday is a non-business day (national holiday, cuti bersama) on which banks do not settle transfers.
+type Holiday struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Date      time.Time `gorm:"type:date;uniqueIndex;not null" json:"date"`
+	Name      string    `gorm:"type:varchar(150);not null" json:"name"`
+	Source    string    `gorm:"type:enum('manual','ical','csv');not null;default:'manual'" json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Holiday) TableName() string {
+	return "holidays"
+}
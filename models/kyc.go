@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// KYCSubmission is one identity verification attempt. The document fields hold private
+// object keys in the R2 bucket and are only ever exposed as short-lived signed URLs.
+type KYCSubmission struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	FullName     string     `gorm:"type:varchar(100);not null" json:"full_name"`
+	IDNumber     string     `gorm:"column:id_number;type:varchar(32);not null;index" json:"id_number"`
+	IDCardKey    string     `gorm:"column:id_card_key;type:varchar(255);not null" json:"-"`
+	SelfieKey    string     `gorm:"type:varchar(255);not null" json:"-"`
+	Status       string     `gorm:"type:enum('Pending','Approved','Rejected');not null;default:'Pending';index" json:"status"`
+	RejectReason *string    `gorm:"type:varchar(255)" json:"reject_reason"`
+	ReviewedBy   *uint      `json:"reviewed_by"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (KYCSubmission) TableName() string {
+	return "kyc_submissions"
+}
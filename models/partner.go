@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Partner is an external system that calls the partner endpoints (information,
+// management, payment settings, StoneForm withdrawals) with its own API keys.
+type Partner struct {
+	ID        uint         `gorm:"primaryKey" json:"id"`
+	Name      string       `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	Status    string       `gorm:"type:enum('Active','Inactive');not null;default:'Active'" json:"status"`
+	Keys      []PartnerKey `gorm:"foreignKey:PartnerID" json:"keys,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+func (Partner) TableName() string {
+	return "partners"
+}
+
+// PartnerKey is one API key of a partner. Only the SHA-256 of the secret part is
+// stored; the optional HMAC signing secret is kept encrypted because the server needs
+// it to check signatures.
+type PartnerKey struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	PartnerID        uint       `gorm:"not null;index" json:"partner_id"`
+	Prefix           string     `gorm:"type:varchar(16);not null;uniqueIndex" json:"prefix"`
+	SecretHash       string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes           string     `gorm:"type:varchar(255);not null" json:"-"` // comma separated
+	RequireSignature bool       `gorm:"not null;default:false" json:"require_signature"`
+	SigningSecret    string     `gorm:"type:varchar(255)" json:"-"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	RotatedFromID    *uint      `json:"rotated_from_id"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	CreatedBy        *uint      `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (PartnerKey) TableName() string {
+	return "partner_keys"
+}
+
+// ScopeList returns the granted scopes.
+func (k *PartnerKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key grants scope.
+func (k *PartnerKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// PartnerAuditLog records one partner action on the withdrawal API, including refused
+// ones. Details never contain account numbers or phone numbers.
+type PartnerAuditLog struct {
+	ID           uint64    `gorm:"primaryKey" json:"id"`
+	PartnerID    uint      `gorm:"not null;index:idx_partner_audit_logs_partner_id,priority:1" json:"partner_id"`
+	PartnerKeyID uint      `gorm:"not null" json:"partner_key_id"`
+	Action       string    `gorm:"type:varchar(32);not null" json:"action"`
+	OrderID      *string   `gorm:"type:varchar(191);index" json:"order_id"`
+	Outcome      string    `gorm:"type:enum('ok','rejected');not null" json:"outcome"`
+	Detail       string    `gorm:"type:varchar(255)" json:"detail"`
+	IP           string    `gorm:"column:ip;type:varchar(45)" json:"ip"`
+	CreatedAt    time.Time `gorm:"index:idx_partner_audit_logs_partner_id,priority:2" json:"created_at"`
+}
+
+func (PartnerAuditLog) TableName() string {
+	return "partner_audit_logs"
+}
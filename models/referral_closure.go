@@ -0,0 +1,14 @@
+package models
+
+// ReferralClosure is the transitive closure of users.reff_by: one row per (ancestor,
+// descendant) pair with the number of referral hops between them. Every user also has a
+// depth-0 row to itself so a new user's rows can be derived from the referrer's.
+type ReferralClosure struct {
+	AncestorID   uint `gorm:"primaryKey;autoIncrement:false;index:idx_referral_closure_ancestor_depth,priority:1" json:"ancestor_id"`
+	DescendantID uint `gorm:"primaryKey;autoIncrement:false;index" json:"descendant_id"`
+	Depth        int  `gorm:"not null;index:idx_referral_closure_ancestor_depth,priority:2" json:"depth"`
+}
+
+func (ReferralClosure) TableName() string {
+	return "referral_closure"
+}
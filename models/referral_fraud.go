@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RegistrationSignal is what the client looked like when the account was created.
+type RegistrationSignal struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	ReffBy    *uint     `gorm:"index" json:"reff_by"`
+	IP        string    `gorm:"column:ip;type:varchar(45);not null;index" json:"ip"`
+	DeviceID  string    `gorm:"type:varchar(128);index" json:"device_id"`
+	UserAgent string    `gorm:"type:varchar(255)" json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (RegistrationSignal) TableName() string {
+	return "registration_signals"
+}
+
+// ReferralFlag is a fraud finding waiting for admin review. UserID is the suspicious
+// account; RelatedUserID is the referrer (or the matched account) that would profit.
+type ReferralFlag struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;uniqueIndex:uk_referral_flags_user_kind,priority:1" json:"user_id"`
+	RelatedUserID *uint      `gorm:"index" json:"related_user_id"`
+	Kind          string     `gorm:"type:enum('device_cluster','ip_cluster','self_referral','gift_farm');not null;uniqueIndex:uk_referral_flags_user_kind,priority:2" json:"kind"`
+	Details       string     `gorm:"type:varchar(255)" json:"details"`
+	Status        string     `gorm:"type:enum('Pending','Cleared','Confirmed');not null;default:'Pending';index" json:"status"`
+	AdminNote     *string    `gorm:"type:text" json:"admin_note"`
+	ReviewedBy    *uint      `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (ReferralFlag) TableName() string {
+	return "referral_flags"
+}
@@ -0,0 +1,52 @@
+package models
+
+// All returns one value of every model that is backed by a table, for tooling that
+// needs the full schema such as the drift detector. Keep it in sync when adding models.
+func All() []interface{} {
+	return []interface{}{
+		&Admin{},
+		&RefreshToken{},
+		&User{},
+		&UserPIN{},
+		&Setting{},
+		&Category{},
+		&Product{},
+		&Investment{},
+		&Payment{},
+		&PaymentSettings{},
+		&Deposit{},
+		&Transaction{},
+		&Withdrawal{},
+		&WithdrawalRule{},
+		&Holiday{},
+		&Bank{},
+		&BankAccount{},
+		&Forum{},
+		&Task{},
+		&UserTask{},
+		&UserTaskProgress{},
+		&UserCheckin{},
+		&SpinPrize{},
+		&UserSpin{},
+		&SpinSeed{},
+		&SpinTicketGrant{},
+		&SpinBudgetCounter{},
+		&ChatSession{},
+		&ChatMessage{},
+		&TransferContact{},
+		&Transfer{},
+		&TransferLimit{},
+		&TransferVelocityRule{},
+		&TransferDispute{},
+		&Gift{},
+		&GiftAmountSlot{},
+		&GiftClaim{},
+		&ReferralClosure{},
+		&RegistrationSignal{},
+		&ReferralFlag{},
+		&KYCSubmission{},
+		&Partner{},
+		&PartnerKey{},
+		&PartnerAuditLog{},
+	}
+}
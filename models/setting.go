@@ -16,11 +16,27 @@ type Setting struct {
 	LinkCS         string  `json:"link_cs"`
 	LinkGroup      string  `json:"link_group"`
 	LinkApp        string  `json:"link_app"`
+
+	// Gift (dana kaget) lifetime in hours: used when the sender does not choose one, and the upper bound
+	GiftDefaultExpiryHours int `gorm:"default:24" json:"gift_default_expiry_hours"`
+	GiftMaxExpiryHours     int `gorm:"default:168" json:"gift_max_expiry_hours"`
+
+	// Spin wheel payout budgets in rupiah; 0 means unlimited. The day is the WIB calendar day.
+	SpinDailyBudget float64 `gorm:"type:decimal(15,2);default:0" json:"spin_daily_budget"`
+	SpinTotalBudget float64 `gorm:"type:decimal(15,2);default:0" json:"spin_total_budget"`
+
+	// Days a newly granted spin ticket stays valid; 0 means tickets never expire
+	SpinTicketExpiryDays int `gorm:"default:0" json:"spin_ticket_expiry_days"`
+
+	// Withdrawals above these amounts require a verified KYC; 0 disables the check.
+	// The daily threshold counts today's withdrawals plus the requested amount.
+	KYCWithdrawalThreshold      float64 `gorm:"column:kyc_withdrawal_threshold;type:decimal(15,2);default:0" json:"kyc_withdrawal_threshold"`
+	KYCDailyWithdrawalThreshold float64 `gorm:"column:kyc_daily_withdrawal_threshold;type:decimal(15,2);default:0" json:"kyc_daily_withdrawal_threshold"`
 }
 
 func GetSetting(db *sql.DB) (*Setting, error) {
 	setting := &Setting{}
-	row := db.QueryRow("SELECT id, name, company, logo, min_withdraw, max_withdraw, withdraw_charge, auto_withdraw, maintenance, closed_register, link_cs, link_group, link_app FROM settings LIMIT 1")
+	row := db.QueryRow("SELECT id, name, company, logo, min_withdraw, max_withdraw, withdraw_charge, auto_withdraw, maintenance, closed_register, link_cs, link_group, link_app, gift_default_expiry_hours, gift_max_expiry_hours, kyc_withdrawal_threshold, kyc_daily_withdrawal_threshold FROM settings LIMIT 1")
 	err := row.Scan(
 		&setting.ID,
 		&setting.Name,
@@ -35,6 +51,10 @@ func GetSetting(db *sql.DB) (*Setting, error) {
 		&setting.LinkCS,
 		&setting.LinkGroup,
 		&setting.LinkApp,
+		&setting.GiftDefaultExpiryHours,
+		&setting.GiftMaxExpiryHours,
+		&setting.KYCWithdrawalThreshold,
+		&setting.KYCDailyWithdrawalThreshold,
 	)
 	if err != nil {
 		return nil, err
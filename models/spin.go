@@ -11,6 +11,10 @@ type SpinPrize struct {
 	Status       string    `gorm:"type:enum('Active','Inactive');not null;default:'Active'" json:"status"`
 	CreatedAt    time.Time `json:"-"`
 	UpdatedAt    time.Time `json:"-"`
+
+	// Win caps for this prize; 0 means unlimited. Once reached, draws fall back to the lowest prize.
+	DailyQuantityLimit int `gorm:"not null;default:0" json:"daily_quantity_limit"`
+	TotalQuantityLimit int `gorm:"not null;default:0" json:"total_quantity_limit"`
 }
 
 func (SpinPrize) TableName() string {
@@ -0,0 +1,16 @@
+package models
+
+// SpinBudgetCounter is the running spin payout for one prize in one period, kept in step
+// with user_spins inside the spin transaction so budget checks read a few rows instead of
+// aggregating the whole spin history. Period is "total" or a WIB date (YYYY-MM-DD).
+type SpinBudgetCounter struct {
+	Period    string  `gorm:"type:varchar(10);primaryKey" json:"period"`
+	PrizeID   uint    `gorm:"primaryKey;autoIncrement:false" json:"prize_id"`
+	Wins      int64   `gorm:"not null;default:0" json:"wins"`
+	Paid      float64 `gorm:"type:decimal(15,2);not null;default:0" json:"paid"`
+	Fallbacks int64   `gorm:"not null;default:0" json:"fallbacks"` // wins awarded in place of an over-cap draw
+}
+
+func (SpinBudgetCounter) TableName() string {
+	return "spin_budget_counters"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SpinSeed is a user's server seed for provably fair spins. Only its SHA-256 hash is shown
+// while it is active; the seed itself is revealed when the user rotates to a new one.
+type SpinSeed struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	ServerSeed     string     `gorm:"type:char(64);not null" json:"-"`
+	ServerSeedHash string     `gorm:"type:char(64);not null" json:"server_seed_hash"`
+	ClientSeed     string     `gorm:"type:varchar(64);not null" json:"client_seed"`
+	Nonce          uint64     `gorm:"not null;default:0" json:"nonce"` // nonce of the next spin
+	Status         string     `gorm:"type:enum('Active','Revealed');not null;default:'Active';index" json:"status"`
+	RevealedAt     *time.Time `json:"revealed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (SpinSeed) TableName() string {
+	return "spin_seeds"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// SpinTicketGrant is one spin ticket in the ledger. A ticket is available until it is
+// consumed by a spin or expires; users.spin_ticket caches the available count.
+type SpinTicketGrant struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	UserID           uint       `gorm:"not null;index:idx_spin_ticket_grants_user_open,priority:1" json:"user_id"`
+	Source           string     `gorm:"type:varchar(30);not null" json:"source"`
+	ReferenceOrderID *string    `gorm:"type:varchar(191);index" json:"reference_order_id"`
+	GrantedAt        time.Time  `gorm:"not null" json:"granted_at"`
+	ExpiresAt        *time.Time `gorm:"index" json:"expires_at"`
+	ConsumedAt       *time.Time `gorm:"index:idx_spin_ticket_grants_user_open,priority:2" json:"consumed_at"`
+	ExpiredAt        *time.Time `json:"expired_at"`
+	UserSpinID       *uint      `json:"user_spin_id"`
+}
+
+func (SpinTicketGrant) TableName() string {
+	return "spin_ticket_grants"
+}
@@ -11,11 +11,51 @@ type Task struct {
 	Status                string    `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
+
+	// Type sets how often the task can be claimed: once, or once per WIB day/ISO week.
+	Type string `gorm:"type:enum('one_time','daily','weekly');not null;default:'one_time'" json:"type"`
+	// Criterion is what progress measures. active_members uses RequiredLevel and
+	// RequiredActiveMembers; the others use TargetValue.
+	Criterion   string  `gorm:"type:varchar(30);not null;default:'active_members'" json:"criterion"`
+	TargetValue float64 `gorm:"type:decimal(15,2);not null;default:0" json:"target_value"`
 }
 
 type UserTask struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"not null" json:"user_id"`
-	TaskID    uint      `gorm:"not null" json:"task_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:uk_user_tasks_period,priority:1" json:"user_id"`
+	TaskID    uint      `gorm:"not null;uniqueIndex:uk_user_tasks_period,priority:2" json:"task_id"`
 	ClaimedAt time.Time `json:"claimed_at"`
+	// PeriodKey is "once" for one-time tasks, the WIB date for daily and the ISO week for weekly
+	PeriodKey string `gorm:"type:varchar(10);not null;default:'once';uniqueIndex:uk_user_tasks_period,priority:3" json:"period_key"`
+}
+
+// UserTaskProgress caches a user's progress on a task for one period so task lists do
+// not recompute every criterion on each request.
+type UserTaskProgress struct {
+	ID          uint       `gorm:"primaryKey" json:"-"`
+	UserID      uint       `gorm:"not null;uniqueIndex:uk_user_task_progress,priority:1" json:"user_id"`
+	TaskID      uint       `gorm:"not null;uniqueIndex:uk_user_task_progress,priority:2" json:"task_id"`
+	PeriodKey   string     `gorm:"type:varchar(10);not null;uniqueIndex:uk_user_task_progress,priority:3" json:"period_key"`
+	Progress    float64    `gorm:"type:decimal(15,2);not null;default:0" json:"progress"`
+	Target      float64    `gorm:"type:decimal(15,2);not null;default:0" json:"target"`
+	CompletedAt *time.Time `json:"completed_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (UserTaskProgress) TableName() string {
+	return "user_task_progress"
+}
+
+// UserCheckin is one daily check-in. Streak is the number of consecutive WIB days up to
+// and including this one.
+type UserCheckin struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;uniqueIndex:uk_user_checkins_date,priority:1" json:"user_id"`
+	CheckinDate string    `gorm:"type:char(10);not null;uniqueIndex:uk_user_checkins_date,priority:2" json:"checkin_date"`
+	Streak      int       `gorm:"not null;default:1" json:"streak"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (UserCheckin) TableName() string {
+	return "user_checkins"
 }
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Transfer is a P2P balance transfer between two users. The matching sender (credit)
+// and receiver (debit) rows in transactions are referenced by their order IDs.
+type Transfer struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	SenderID        uint       `gorm:"not null;index:idx_transfers_sender_created" json:"sender_id"`
+	ReceiverID      uint       `gorm:"not null;index:idx_transfers_receiver_created" json:"receiver_id"`
+	Amount          float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	SenderOrderID   string     `gorm:"type:varchar(191);not null;uniqueIndex" json:"sender_order_id"`
+	ReceiverOrderID string     `gorm:"type:varchar(191);not null;uniqueIndex" json:"receiver_order_id"`
+	HoldUntil       *time.Time `gorm:"index" json:"hold_until"`
+	HoldReason      *string    `gorm:"type:varchar(100)" json:"hold_reason,omitempty"`
+	ReversedAt      *time.Time `json:"reversed_at,omitempty"`
+	CreatedAt       time.Time  `gorm:"index:idx_transfers_sender_created;index:idx_transfers_receiver_created" json:"created_at"`
+}
+
+func (Transfer) TableName() string {
+	return "transfers"
+}
+
+// TransferDispute is a sender's request to undo a transfer. Admins either reject it or
+// reverse the transfer; a reversal debits the receiver (the balance may go negative, the
+// uncovered part is kept in ShortfallAmount) and refunds the sender in the same DB transaction.
+type TransferDispute struct {
+	ID                      uint       `gorm:"primaryKey" json:"id"`
+	TransferID              uint       `gorm:"not null;uniqueIndex" json:"transfer_id"`
+	SenderID                uint       `gorm:"not null;index" json:"sender_id"`
+	ReceiverID              uint       `gorm:"not null;index" json:"receiver_id"`
+	Amount                  float64    `gorm:"type:decimal(15,2);not null" json:"amount"`
+	Reason                  string     `gorm:"type:text;not null" json:"reason"`
+	Status                  string     `gorm:"type:enum('Pending','Rejected','Reversed');not null;default:'Pending';index" json:"status"`
+	AdminNote               *string    `gorm:"type:text" json:"admin_note,omitempty"`
+	ReviewedBy              *uint      `json:"reviewed_by,omitempty"`
+	ReviewedAt              *time.Time `json:"reviewed_at,omitempty"`
+	ShortfallAmount         float64    `gorm:"type:decimal(15,2);not null;default:0" json:"shortfall_amount"`
+	SenderRefundOrderID     *string    `gorm:"type:varchar(191)" json:"sender_refund_order_id,omitempty"`
+	ReceiverReversalOrderID *string    `gorm:"type:varchar(191)" json:"receiver_reversal_order_id,omitempty"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+
+	Transfer *Transfer `gorm:"foreignKey:TransferID" json:"transfer,omitempty"`
+}
+
+func (TransferDispute) TableName() string {
+	return "transfer_disputes"
+}
+
+// TransferLimit holds the P2P transfer limits for one VIP level. Zero means unlimited.
+type TransferLimit struct {
+	ID                     uint      `gorm:"primaryKey" json:"id"`
+	VIPLevel               uint      `gorm:"not null;uniqueIndex" json:"vip_level"`
+	MinAmount              float64   `gorm:"type:decimal(15,2);not null;default:0" json:"min_amount"`
+	MaxAmount              float64   `gorm:"type:decimal(15,2);not null;default:0" json:"max_amount"`
+	DailyOutLimit          float64   `gorm:"type:decimal(15,2);not null;default:0" json:"daily_out_limit"`
+	MonthlyOutLimit        float64   `gorm:"type:decimal(15,2);not null;default:0" json:"monthly_out_limit"`
+	DailyInLimit           float64   `gorm:"type:decimal(15,2);not null;default:0" json:"daily_in_limit"`
+	MonthlyInLimit         float64   `gorm:"type:decimal(15,2);not null;default:0" json:"monthly_in_limit"`
+	PerRecipientDailyLimit float64   `gorm:"type:decimal(15,2);not null;default:0" json:"per_recipient_daily_limit"`
+	HoldHours              int       `gorm:"not null;default:0" json:"hold_hours"` // received funds cannot be withdrawn for this long
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+func (TransferLimit) TableName() string {
+	return "transfer_limits"
+}
+
+// TransferVelocityRule detects fan-in: many distinct senders moving small amounts
+// into one account within a short window.
+type TransferVelocityRule struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	Name               string    `gorm:"type:varchar(100);not null" json:"name"`
+	WindowMinutes      int       `gorm:"not null" json:"window_minutes"`
+	MaxDistinctSenders int       `gorm:"not null" json:"max_distinct_senders"`
+	SmallAmountMax     float64   `gorm:"type:decimal(15,2);not null;default:0" json:"small_amount_max"` // 0 = any amount counts
+	Action             string    `gorm:"type:enum('block','hold');not null;default:'block'" json:"action"`
+	HoldHours          int       `gorm:"not null;default:0" json:"hold_hours"` // used when Action is hold
+	Status             string    `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+func (TransferVelocityRule) TableName() string {
+	return "transfer_velocity_rules"
+}
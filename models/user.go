@@ -21,6 +21,16 @@ type User struct {
 	Profile          *string   `gorm:"type:varchar(255);null" json:"profile,omitempty"`
 	CreatedAt        time.Time `json:"-"`
 	UpdatedAt        time.Time `json:"-"`
+
+	// BonusFrozen holds registration, referral and gift bonuses as pending while the
+	// account is under referral fraud review
+	BonusFrozen bool `gorm:"column:bonus_frozen;not null;default:false" json:"bonus_frozen"`
+
+	// KYCStatus follows the latest identity verification submission
+	KYCStatus string `gorm:"column:kyc_status;type:enum('Unverified','Pending','Verified','Rejected');not null;default:'Unverified'" json:"kyc_status"`
+
+	// ClosedAt is set when the user closed the account; its personal data is anonymized
+	ClosedAt *time.Time `gorm:"column:closed_at" json:"closed_at,omitempty"`
 }
 
 func (User) TableName() string {
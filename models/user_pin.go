@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UserPIN is the bcrypt-hashed 6-digit transaction PIN that authorizes money movements
+// and bank account changes on top of the access token.
+type UserPIN struct {
+	ID             uint       `gorm:"primaryKey" json:"-"`
+	UserID         uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	PINHash        string     `gorm:"column:pin_hash;size:255;not null" json:"-"`
+	FailedAttempts int        `gorm:"not null;default:0" json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (UserPIN) TableName() string {
+	return "user_pins"
+}
@@ -8,5 +8,18 @@ type UserSpin struct {
 	PrizeID uint      `gorm:"not null;index" json:"prize_id"`
 	Amount  float64   `gorm:"type:decimal(15,2);not null" json:"amount"`
 	Code    string    `gorm:"type:varchar(20);not null" json:"code"`
-	WonAt   time.Time `json:"won_at"`
+	WonAt   time.Time `gorm:"index" json:"won_at"`
+
+	// Set when the drawn prize was over budget and the lowest prize was awarded instead
+	FallbackFromPrizeID *uint  `json:"fallback_from_prize_id,omitempty"`
+	FallbackReason      string `gorm:"type:varchar(20)" json:"fallback_reason,omitempty"`
+
+	// Provably fair audit data; empty for spins made before seeds existed
+	SeedID         *uint  `gorm:"index" json:"seed_id,omitempty"`
+	ServerSeedHash string `gorm:"type:char(64)" json:"server_seed_hash,omitempty"`
+	ClientSeed     string `gorm:"type:varchar(64)" json:"client_seed,omitempty"`
+	Nonce          uint64 `gorm:"not null;default:0" json:"nonce"`
+	Roll           int    `gorm:"not null;default:0" json:"roll"`
+	TotalWeight    int    `gorm:"not null;default:0" json:"total_weight"`
+	WeightSnapshot string `gorm:"type:text" json:"weight_snapshot,omitempty"` // JSON []utils.SpinWeight in effect at spin time
 }
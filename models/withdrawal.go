@@ -10,10 +10,22 @@ type Withdrawal struct {
 	Charge        float64      `gorm:"type:decimal(15,2);not null;default:0.00" json:"charge"`
 	FinalAmount   float64      `gorm:"type:decimal(15,2);not null" json:"final_amount"`
 	OrderID       string       `gorm:"type:varchar(191);not null;uniqueIndex" json:"order_id"`
-	Status        string       `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending'" json:"status"`
+	Status        string       `gorm:"type:enum('Success','Pending','Failed');not null;default:'Pending';index:idx_withdrawals_claim,priority:1" json:"status"`
 	CreatedAt     time.Time    `json:"created_at"`
 	UpdatedAt     time.Time    `json:"updated_at"`
 	BankAccount   *BankAccount `gorm:"foreignKey:BankAccountID" json:"bank_account,omitempty"`
+
+	// Partner processing (StoneForm): the partner holding or that paid the withdrawal,
+	// until when its claim is exclusive, failed partner payouts and the partner's
+	// payout reference.
+	ClaimedByPartnerID *uint      `json:"claimed_by_partner_id,omitempty"`
+	ClaimExpiresAt     *time.Time `gorm:"index:idx_withdrawals_claim,priority:2" json:"claim_expires_at,omitempty"`
+	PartnerFailures    uint8      `gorm:"not null;default:0" json:"partner_failures,omitempty"`
+	PartnerReference   *string    `gorm:"type:varchar(100)" json:"partner_reference,omitempty"`
+
+	// When the payout was sent to the gateway (Pakailink). Dispatched withdrawals are
+	// never offered to partners; a failed gateway callback clears it.
+	GatewayDispatchedAt *time.Time `json:"gateway_dispatched_at,omitempty"`
 }
 
 func (Withdrawal) TableName() string {
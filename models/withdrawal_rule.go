@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Withdrawal rule types evaluated by utils.WithdrawalPolicy
+const (
+	WithdrawalRuleOperatingWindow = "operating_window" // StartTime-EndTime (WIB), optionally on a single Weekday
+	WithdrawalRuleClosedWeekday   = "closed_weekday"   // no withdrawals on Weekday
+	WithdrawalRuleClosedDate      = "closed_date"      // no withdrawals on Date
+	WithdrawalRuleDailyCount      = "daily_count"      // at most LimitCount withdrawals per day
+	WithdrawalRuleDailyAmount     = "daily_amount"     // at most LimitAmount withdrawn per day
+	WithdrawalRuleAmountRange     = "amount_range"     // each withdrawal between MinAmount and MaxAmount
+	WithdrawalRuleCoolingOff      = "cooling_off"      // CooldownHours after the bank account was added or changed
+)
+
+// WithdrawalRule is an admin-defined withdrawal policy rule.
+// Rules with VIPLevel set apply only to users at that level and take precedence
+// over the general (VIPLevel = NULL) rules of the same type.
+type WithdrawalRule struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	RuleType      string     `gorm:"type:enum('operating_window','closed_weekday','closed_date','daily_count','daily_amount','amount_range','cooling_off');not null;index" json:"rule_type"`
+	VIPLevel      *uint      `gorm:"column:vip_level" json:"vip_level"`
+	Weekday       *int       `gorm:"column:weekday" json:"weekday"`
+	Date          *time.Time `gorm:"type:date" json:"date"`
+	StartTime     string     `gorm:"type:varchar(5)" json:"start_time"`
+	EndTime       string     `gorm:"type:varchar(5)" json:"end_time"`
+	LimitCount    int        `gorm:"not null;default:0" json:"limit_count"`
+	LimitAmount   float64    `gorm:"type:decimal(15,2);not null;default:0" json:"limit_amount"`
+	MinAmount     float64    `gorm:"type:decimal(15,2);not null;default:0" json:"min_amount"`
+	MaxAmount     float64    `gorm:"type:decimal(15,2);not null;default:0" json:"max_amount"`
+	CooldownHours int        `gorm:"not null;default:0" json:"cooldown_hours"`
+	Message       string     `gorm:"type:varchar(255)" json:"message"`
+	Status        string     `gorm:"type:enum('Active','Inactive');default:'Active'" json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (WithdrawalRule) TableName() string {
+	return "withdrawal_rules"
+}
@@ -63,6 +63,19 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Handle("/withdrawals/{id:[0-9]+}/approve", http.HandlerFunc(admins.ApproveWithdrawal)).Methods(http.MethodPut)
 	adminRouter.Handle("/withdrawals/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectWithdrawal)).Methods(http.MethodPut)
 
+	// Withdrawal policy rules
+	adminRouter.Handle("/withdrawal-rules", http.HandlerFunc(admins.GetWithdrawalRules)).Methods(http.MethodGet)
+	adminRouter.Handle("/withdrawal-rules", http.HandlerFunc(admins.CreateWithdrawalRule)).Methods(http.MethodPost)
+	adminRouter.Handle("/withdrawal-rules/{id:[0-9]+}", http.HandlerFunc(admins.UpdateWithdrawalRule)).Methods(http.MethodPut)
+	adminRouter.Handle("/withdrawal-rules/{id:[0-9]+}", http.HandlerFunc(admins.DeleteWithdrawalRule)).Methods(http.MethodDelete)
+
+	// Holiday calendar
+	adminRouter.Handle("/holidays", http.HandlerFunc(admins.GetHolidays)).Methods(http.MethodGet)
+	adminRouter.Handle("/holidays", http.HandlerFunc(admins.CreateHoliday)).Methods(http.MethodPost)
+	adminRouter.Handle("/holidays/import", http.HandlerFunc(admins.ImportHolidays)).Methods(http.MethodPost)
+	adminRouter.Handle("/holidays/{id:[0-9]+}", http.HandlerFunc(admins.UpdateHoliday)).Methods(http.MethodPut)
+	adminRouter.Handle("/holidays/{id:[0-9]+}", http.HandlerFunc(admins.DeleteHoliday)).Methods(http.MethodDelete)
+
 	// Bank management
 	adminRouter.Handle("/banks", http.HandlerFunc(admins.GetBanks)).Methods(http.MethodGet)
 	adminRouter.Handle("/banks", http.HandlerFunc(admins.CreateBank)).Methods(http.MethodPost)
@@ -71,6 +84,40 @@ func SetAdminRoutes(api *mux.Router) {
 	// Bank accounts management
 	adminRouter.Handle("/bank-accounts", http.HandlerFunc(admins.GetBankAccounts)).Methods(http.MethodGet)
 
+	// Transfer disputes
+	adminRouter.Handle("/transfer-disputes", http.HandlerFunc(admins.GetTransferDisputes)).Methods(http.MethodGet)
+	adminRouter.Handle("/transfer-disputes/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectTransferDispute)).Methods(http.MethodPut)
+	adminRouter.Handle("/transfer-disputes/{id:[0-9]+}/reverse", http.HandlerFunc(admins.ReverseTransferDispute)).Methods(http.MethodPut)
+
+	// Referral fraud review queue
+	adminRouter.Handle("/referral-flags", http.HandlerFunc(admins.GetReferralFlags)).Methods(http.MethodGet)
+	adminRouter.Handle("/referral-flags/{id:[0-9]+}/clear", http.HandlerFunc(admins.ClearReferralFlag)).Methods(http.MethodPut)
+	adminRouter.Handle("/referral-flags/{id:[0-9]+}/confirm", http.HandlerFunc(admins.ConfirmReferralFlag)).Methods(http.MethodPut)
+	adminRouter.Handle("/users/{id:[0-9]+}/bonus-freeze", http.HandlerFunc(admins.SetUserBonusFreeze)).Methods(http.MethodPut)
+
+	// KYC review
+	adminRouter.Handle("/kyc", http.HandlerFunc(admins.GetKYCSubmissions)).Methods(http.MethodGet)
+	adminRouter.Handle("/kyc/{id:[0-9]+}", http.HandlerFunc(admins.GetKYCSubmission)).Methods(http.MethodGet)
+	adminRouter.Handle("/kyc/{id:[0-9]+}/approve", http.HandlerFunc(admins.ApproveKYCSubmission)).Methods(http.MethodPut)
+	adminRouter.Handle("/kyc/{id:[0-9]+}/reject", http.HandlerFunc(admins.RejectKYCSubmission)).Methods(http.MethodPut)
+
+	// Transfer limits and velocity controls
+	adminRouter.Handle("/transfer-limits", http.HandlerFunc(admins.GetTransferLimits)).Methods(http.MethodGet)
+	adminRouter.Handle("/transfer-limits/{level:[0-9]+}", http.HandlerFunc(admins.UpdateTransferLimit)).Methods(http.MethodPut)
+	adminRouter.Handle("/transfer-velocity-rules", http.HandlerFunc(admins.GetTransferVelocityRules)).Methods(http.MethodGet)
+	adminRouter.Handle("/transfer-velocity-rules", http.HandlerFunc(admins.CreateTransferVelocityRule)).Methods(http.MethodPost)
+	adminRouter.Handle("/transfer-velocity-rules/{id:[0-9]+}", http.HandlerFunc(admins.UpdateTransferVelocityRule)).Methods(http.MethodPut)
+	adminRouter.Handle("/transfer-velocity-rules/{id:[0-9]+}", http.HandlerFunc(admins.DeleteTransferVelocityRule)).Methods(http.MethodDelete)
+
+	// Partner credentials (API keys for information, payment settings and StoneForm endpoints)
+	adminRouter.Handle("/partners", http.HandlerFunc(admins.GetPartners)).Methods(http.MethodGet)
+	adminRouter.Handle("/partners", http.HandlerFunc(admins.CreatePartner)).Methods(http.MethodPost)
+	adminRouter.Handle("/partners/{id:[0-9]+}/status", http.HandlerFunc(admins.UpdatePartnerStatus)).Methods(http.MethodPut)
+	adminRouter.Handle("/partners/{id:[0-9]+}/keys", http.HandlerFunc(admins.CreatePartnerKey)).Methods(http.MethodPost)
+	adminRouter.Handle("/partner-keys/{id:[0-9]+}/rotate", http.HandlerFunc(admins.RotatePartnerKey)).Methods(http.MethodPost)
+	adminRouter.Handle("/partner-keys/{id:[0-9]+}/revoke", http.HandlerFunc(admins.RevokePartnerKey)).Methods(http.MethodPut)
+	adminRouter.Handle("/partner-audit-logs", http.HandlerFunc(admins.GetPartnerAuditLogs)).Methods(http.MethodGet)
+
 	// Transaction management
 	adminRouter.Handle("/transactions", http.HandlerFunc(admins.GetTransactions)).Methods(http.MethodGet)
 
@@ -85,9 +132,11 @@ func SetAdminRoutes(api *mux.Router) {
 	adminRouter.Handle("/tasks", http.HandlerFunc(admins.TaskListHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/tasks", http.HandlerFunc(admins.CreateTaskHandler)).Methods(http.MethodPost)
 	adminRouter.Handle("/tasks/{id:[0-9]+}", http.HandlerFunc(admins.UpdateTaskHandler)).Methods(http.MethodPut)
+	adminRouter.Handle("/tasks/{id:[0-9]+}", http.HandlerFunc(admins.DeleteTaskHandler)).Methods(http.MethodDelete)
 
 	adminRouter.Handle("/user-tasks", http.HandlerFunc(admins.UserTasksHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/user-spins", http.HandlerFunc(admins.UserSpinsHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/user-spins/{id:[0-9]+}/verify", http.HandlerFunc(admins.VerifyUserSpinHandler)).Methods(http.MethodGet)
 
 	// Gift management
 	adminRouter.Handle("/gifts", http.HandlerFunc(admins.GetGifts)).Methods(http.MethodGet)
@@ -103,4 +152,8 @@ func SetAdminRoutes(api *mux.Router) {
 	// Settings management
 	adminRouter.Handle("/settings", http.HandlerFunc(admins.GetSettingsHandler)).Methods(http.MethodGet)
 	adminRouter.Handle("/settings", http.HandlerFunc(admins.UpdateSettingsHandler)).Methods(http.MethodPut)
+
+	// Process configuration (redacted) and hot reload
+	adminRouter.Handle("/config", http.HandlerFunc(admins.GetConfigHandler)).Methods(http.MethodGet)
+	adminRouter.Handle("/config/reload", http.HandlerFunc(admins.ReloadConfigHandler)).Methods(http.MethodPost)
 }
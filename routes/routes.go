@@ -3,15 +3,17 @@ package routes
 import (
 	"encoding/json"
 	"net/http"
-	"os"
 	"project/database"
-	"strings"
 	"time"
 
+	"project/config"
 	"project/controllers"
 	"project/controllers/admins"
 	"project/controllers/users"
+	"project/metrics"
 	"project/middleware"
+	"project/services"
+	"project/utils"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
@@ -23,6 +25,7 @@ func optionsHandler(w http.ResponseWriter, r *http.Request) {
 
 func InitRouter() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(middleware.RouteLabelMiddleware)
 
 	// Health check endpoint for Docker health checks (root level)
 	r.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -35,25 +38,20 @@ func InitRouter() *mux.Router {
 		})
 	})).Methods(http.MethodGet)
 
-	// Add CORS middleware - origins from CORS_ALLOWED_ORIGINS (comma-separated) or defaults
-	originsEnv := os.Getenv("CORS_ALLOWED_ORIGINS")
+	// Prometheus scrape endpoint (bearer METRICS_TOKEN; 404 while unset)
+	r.Handle("/metrics", middleware.MetricsAuth(metrics.Handler())).Methods(http.MethodGet)
+
+	// Add CORS middleware - defaults plus the origins from CORS_ALLOWED_ORIGINS
 	origins := []string{
 		"https://novavant.com", "https://webhook-v2.kytapay.com", "https://api.stoneform.co.id",
 		"http://localhost:3000", "http://localhost:8080", "http://127.0.0.1:3000", "http://127.0.0.1:8080",
 	}
-	if originsEnv != "" {
-		parts := strings.Split(originsEnv, ",")
-		for _, p := range parts {
-			if o := strings.TrimSpace(p); o != "" {
-				origins = append(origins, o)
-			}
-		}
-	}
+	origins = append(origins, config.Get().HTTP.CORSAllowedOrigins...)
 	r.Use(func(next http.Handler) http.Handler {
 		return handlers.CORS(
 			handlers.AllowedOrigins(origins),
 			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}),
-			handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-VLA-KEY", "X-CRON-KEY", "X-Requested-With", "X-Request-ID"}),
+			handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-API-Key", "X-Timestamp", "X-Signature", "X-CRON-KEY", "X-Requested-With", "X-Request-ID"}),
 			handlers.AllowCredentials(),
 		)(next)
 	})
@@ -69,16 +67,31 @@ func InitRouter() *mux.Router {
 	webhookLimiter := middleware.NewWebhookLimiter(500, time.Hour, []string{"127.0.0.1" /* tambahkan IP whitelist di sini */})
 
 	sfxcrController := controllers.NewSFXCRController(database.DB)
+	// Money handlers run on the service layer; tests may install their own before this
+	if users.Services == nil {
+		users.Services = services.New(services.Deps{DB: database.DB})
+	}
 
-	api.Handle("/sfxcr/withdrawals/pending", http.HandlerFunc(sfxcrController.GetPendingWithdrawals)).Methods(http.MethodGet)
-	api.Handle("/sfxcr/withdrawals/pending/{order_id}", http.HandlerFunc(sfxcrController.GetPendingWithdrawalByOrderID)).Methods(http.MethodGet)
-	api.Handle("/sfxcr/withdrawals/callback", http.HandlerFunc(sfxcrController.WithdrawalCallback)).Methods(http.MethodPost)
+	// StoneForm withdrawal processing: browse (masked), claim to get payout details, report
+	// results with a signed request (partner key with withdrawals scopes)
+	api.Handle("/sfxcr/withdrawals/pending", middleware.PartnerAuth(utils.ScopeWithdrawalsRead)(http.HandlerFunc(sfxcrController.GetPendingWithdrawals))).Methods(http.MethodGet)
+	api.Handle("/sfxcr/withdrawals/pending/{order_id}", middleware.PartnerAuth(utils.ScopeWithdrawalsRead)(http.HandlerFunc(sfxcrController.GetPendingWithdrawalByOrderID))).Methods(http.MethodGet)
+	api.Handle("/sfxcr/withdrawals/claim", middleware.PartnerAuth(utils.ScopeWithdrawalsClaim)(http.HandlerFunc(sfxcrController.ClaimWithdrawals))).Methods(http.MethodPost)
+	api.Handle("/sfxcr/withdrawals/{order_id}/release", middleware.PartnerAuth(utils.ScopeWithdrawalsClaim)(http.HandlerFunc(sfxcrController.ReleaseWithdrawal))).Methods(http.MethodPost)
+	api.Handle("/sfxcr/withdrawals/callback", middleware.SignedPartnerAuth(utils.ScopeWithdrawalsReport)(http.HandlerFunc(sfxcrController.WithdrawalCallback))).Methods(http.MethodPost)
 
 	// Cron endpoint for daily returns (protected via X-CRON-KEY header)
-	api.Handle("/cron/daily-returns", cronLimiter.Middleware(http.HandlerFunc(users.CronDailyReturnsHandler))).Methods(http.MethodPost)
+	api.Handle("/cron/daily-returns", cronLimiter.Middleware(middleware.CronMetrics("daily-returns", http.HandlerFunc(users.CronDailyReturnsHandler)))).Methods(http.MethodPost)
 
 	// Cron endpoint for expired payments handler (protected via X-CRON-KEY header)
-	api.Handle("/cron/expired-handlers", cronLimiter.Middleware(http.HandlerFunc(users.ExpiredPaymentsHandler))).Methods(http.MethodPost)
+	api.Handle("/cron/expired-handlers", cronLimiter.Middleware(middleware.CronMetrics("expired-handlers", http.HandlerFunc(users.ExpiredPaymentsHandler)))).Methods(http.MethodPost)
+
+	// Cron endpoint for expiring gifts and refunding unclaimed amounts (protected via X-CRON-KEY header)
+	api.Handle("/cron/gift-expiry", cronLimiter.Middleware(middleware.CronMetrics("gift-expiry", http.HandlerFunc(users.CronExpireGiftsHandler)))).Methods(http.MethodPost)
+	api.Handle("/cron/spin-ticket-expiry", cronLimiter.Middleware(middleware.CronMetrics("spin-ticket-expiry", http.HandlerFunc(users.CronExpireSpinTicketsHandler)))).Methods(http.MethodPost)
+
+	// Cron endpoint for flagging gift-farming accounts (protected via X-CRON-KEY header)
+	api.Handle("/cron/referral-fraud-scan", cronLimiter.Middleware(middleware.CronMetrics("referral-fraud-scan", http.HandlerFunc(users.CronReferralFraudScanHandler)))).Methods(http.MethodPost)
 
 	// Pakailink payment webhook (VA & QRIS callback)
 	api.Handle("/callback/payments", webhookLimiter.Middleware(http.HandlerFunc(users.PakailinkWebhookHandler))).Methods(http.MethodPost)
@@ -98,15 +111,15 @@ func InitRouter() *mux.Router {
 	// Public application info
 	api.Handle("/info", http.HandlerFunc(controllers.InfoPublicHandler)).Methods(http.MethodGet)
 
-	// Get all users with balance >= 50000 (protected via X-VLA-KEY header)
-	api.Handle("/all-user-balance", http.HandlerFunc(controllers.GetAllUserBalanceHandler)).Methods(http.MethodGet)
+	// Get all users with balance >= 50000 (partner key with users:balance:read)
+	api.Handle("/all-user-balance", middleware.PartnerAuth(utils.ScopeUserBalancesRead)(http.HandlerFunc(controllers.GetAllUserBalanceHandler))).Methods(http.MethodGet)
 
-	// Information endpoints (protected via X-VLA-KEY header)
-	api.Handle("/information/investment", http.HandlerFunc(controllers.GetInvestmentInformationHandler)).Methods(http.MethodGet)
-	api.Handle("/information/withdrawal", http.HandlerFunc(controllers.GetWithdrawalInformationHandler)).Methods(http.MethodGet)
+	// Information endpoints (partner key with info:read)
+	api.Handle("/information/investment", middleware.PartnerAuth(utils.ScopeInfoRead)(http.HandlerFunc(controllers.GetInvestmentInformationHandler))).Methods(http.MethodGet)
+	api.Handle("/information/withdrawal", middleware.PartnerAuth(utils.ScopeInfoRead)(http.HandlerFunc(controllers.GetWithdrawalInformationHandler))).Methods(http.MethodGet)
 
-	// Management transactions endpoint (protected via X-VLA-KEY header)
-	api.Handle("/management-transactions", http.HandlerFunc(controllers.ManagementTransactionsHandler)).Methods(http.MethodPost)
+	// Management transactions endpoint (partner key with management:write)
+	api.Handle("/management-transactions", middleware.PartnerAuth(utils.ScopeManagementWrite)(http.HandlerFunc(controllers.ManagementTransactionsHandler))).Methods(http.MethodPost)
 
 	// News endpoints
 	api.Handle("/news/login", http.HandlerFunc(controllers.NewsLoginHandler)).Methods(http.MethodPost)
@@ -123,9 +136,9 @@ func InitRouter() *mux.Router {
 		})
 	})).Methods(http.MethodGet)
 
-	// Payment settings endpoints (protected by static header)
-	api.Handle("/payment_info", http.HandlerFunc(controllers.GetPaymentInfo)).Methods(http.MethodGet)
-	api.Handle("/payment_info", http.HandlerFunc(controllers.PutPaymentInfo)).Methods(http.MethodPut)
+	// Payment settings endpoints (partner key with payment_settings scopes)
+	api.Handle("/payment_info", middleware.PartnerAuth(utils.ScopePaymentSettingsRead)(http.HandlerFunc(controllers.GetPaymentInfo))).Methods(http.MethodGet)
+	api.Handle("/payment_info", middleware.PartnerAuth(utils.ScopePaymentSettingsWrite)(http.HandlerFunc(controllers.PutPaymentInfo))).Methods(http.MethodPut)
 
 	// Delegasi semua route users ke file users.go
 	UsersRoutes(api)
@@ -45,6 +45,21 @@ func UsersRoutes(api *mux.Router) {
 	api.Handle("/users/profile", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UpdateProfileHandler)))).Methods(http.MethodPut)
 	api.Handle("/users/profile", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.DeleteProfileHandler)))).Methods(http.MethodDelete)
 
+	// Personal data export and account closure
+	api.Handle("/users/account/export", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ExportAccountDataHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/account/close", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CloseAccountHandler)))).Methods(http.MethodPost)
+
+	// Identity verification (KYC)
+	api.Handle("/users/kyc", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.KYCStatusHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/kyc", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SubmitKYCHandler)))).Methods(http.MethodPost)
+
+	// Transaction PIN (required for transfer, gift, withdrawal and bank account changes)
+	api.Handle("/users/pin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.PINStatusHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/pin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SetPINHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/pin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ChangePINHandler)))).Methods(http.MethodPut)
+	api.Handle("/users/pin/reset/request-otp", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ResetPINRequestOTPHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/pin/reset", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ResetPINHandler)))).Methods(http.MethodPost)
+
 	// Get Bank List, Add, Edit, Delete
 	api.Handle("/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(controllers.BankListHandler)))).Methods(http.MethodGet)
 	api.Handle("/users/bank", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.AddBankAccountHandler)))).Methods(http.MethodPost)
@@ -73,6 +88,8 @@ func UsersRoutes(api *mux.Router) {
 	api.Handle("/transfer/inquiry", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TransferInquiryHandler)))).Methods(http.MethodPost)
 	api.Handle("/transfer", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TransferHandler)))).Methods(http.MethodPost)
 	api.Handle("/transfer/contact", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TransferContactHandler)))).Methods(http.MethodGet)
+	api.Handle("/transfer/dispute", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CreateTransferDisputeHandler)))).Methods(http.MethodPost)
+	api.Handle("/transfer/dispute", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.ListTransferDisputesHandler)))).Methods(http.MethodGet)
 
 	// Gift (dana kaget)
 	api.Handle("/gift", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CreateGiftHandler)))).Methods(http.MethodPost)
@@ -85,6 +102,10 @@ func UsersRoutes(api *mux.Router) {
 	// Spin endpoints
 	api.Handle("/spin-prize-list", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SpinPrizeListHandler)))).Methods(http.MethodGet)
 	api.Handle("/users/spin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UserSpinHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/spin/seed", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SpinSeedHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/spin/seed/rotate", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.RotateSpinSeedHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/spin/{id:[0-9]+}/verify", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.VerifySpinHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/spin/tickets", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.SpinTicketHistoryHandler)))).Methods(http.MethodGet)
 	//api.Handle("/users/spin-v2", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.UserSpinHandler)))).Methods(http.MethodGet)
 
 	api.Handle("/users/transaction", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.GetTransactionHistory)))).Methods(http.MethodGet)
@@ -100,6 +121,8 @@ func UsersRoutes(api *mux.Router) {
 
 	api.Handle("/users/task", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TaskListHandler)))).Methods(http.MethodGet)
 	api.Handle("/users/task/submit", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.TaskSubmitHandler)))).Methods(http.MethodPost)
+	api.Handle("/users/checkin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CheckinStatusHandler)))).Methods(http.MethodGet)
+	api.Handle("/users/checkin", userLimiter.Middleware(middleware.AuthMiddleware(http.HandlerFunc(users.CheckinHandler)))).Methods(http.MethodPost)
 
 	// Live Chat AI endpoints
 	// Start chat (public - no auth required, but can be used with auth)
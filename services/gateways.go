@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/utils"
+)
+
+// PaymentInstruction is what the user needs to pay a pending investment. ExpiresAt is
+// zero when the gateway did not report an expiry.
+type PaymentInstruction struct {
+	Code      string
+	ExpiresAt time.Time
+}
+
+// PaymentGateway collects investment payments by QRIS or bank virtual account.
+type PaymentGateway interface {
+	CreateQRIS(ctx context.Context, orderID string, amount float64) (*PaymentInstruction, error)
+	CreateVA(ctx context.Context, orderID, bankCode, customerNo, name string, amount float64) (*PaymentInstruction, error)
+	// IsPaid asks the gateway whether a QRIS or BANK payment has been settled.
+	IsPaid(ctx context.Context, method, orderID string) (bool, error)
+}
+
+// PayoutRequest sends FinalAmount of a withdrawal to a bank account or e-wallet.
+type PayoutRequest struct {
+	OrderID       string
+	AccountNumber string
+	Code          string // bank or e-wallet product code
+	EWallet       bool
+	Amount        int64
+}
+
+// PayoutGateway disburses approved withdrawals. An accepted request stays Pending until
+// the gateway calls back.
+type PayoutGateway interface {
+	Payout(ctx context.Context, req PayoutRequest) error
+}
+
+// PakailinkPayments is the PaymentGateway backed by Pakailink SNAP.
+type PakailinkPayments struct {
+	Client *http.Client
+}
+
+func NewPakailinkPayments() *PakailinkPayments {
+	return &PakailinkPayments{Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (p *PakailinkPayments) CreateQRIS(ctx context.Context, orderID string, amount float64) (*PaymentInstruction, error) {
+	token, err := utils.GetPakailinkAccessToken(ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+	res, err := utils.CreatePakailinkQRIS(ctx, p.Client, token, orderID, amount)
+	if err != nil {
+		return nil, err
+	}
+	out := &PaymentInstruction{Code: res.QRContent}
+	if t, err := time.Parse(time.RFC3339, res.ValidityPeriod); err == nil {
+		out.ExpiresAt = t
+	}
+	return out, nil
+}
+
+func (p *PakailinkPayments) CreateVA(ctx context.Context, orderID, bankCode, customerNo, name string, amount float64) (*PaymentInstruction, error) {
+	token, err := utils.GetPakailinkAccessToken(ctx, p.Client)
+	if err != nil {
+		return nil, err
+	}
+	res, err := utils.CreatePakailinkVA(ctx, p.Client, token, orderID, customerNo, name, amount, bankCode)
+	if err != nil {
+		return nil, err
+	}
+	out := &PaymentInstruction{Code: res.VirtualAccountData.VirtualAccountNo}
+	if t, err := time.Parse("2006-01-02T15:04:05-07:00", res.VirtualAccountData.ExpiredDate); err == nil {
+		out.ExpiresAt = t
+	}
+	return out, nil
+}
+
+func (p *PakailinkPayments) IsPaid(ctx context.Context, method, orderID string) (bool, error) {
+	token, err := utils.GetPakailinkAccessToken(ctx, p.Client)
+	if err != nil {
+		return false, err
+	}
+	switch method {
+	case "BANK":
+		res, err := utils.InquiryPakailinkVAStatus(ctx, p.Client, token, orderID)
+		if err != nil {
+			return false, err
+		}
+		return utils.IsPakailinkSuccessStatus(res.LatestTransactionStatus), nil
+	case "QRIS":
+		res, err := utils.InquiryPakailinkQRStatus(ctx, p.Client, token, orderID)
+		if err != nil {
+			return false, err
+		}
+		return utils.IsPakailinkSuccessStatus(res.LatestTransactionStatus), nil
+	}
+	return false, nil
+}
+
+// PakailinkPayouts is the PayoutGateway backed by Pakailink bank transfer and e-wallet
+// top-up.
+type PakailinkPayouts struct {
+	Client *http.Client
+}
+
+func NewPakailinkPayouts() *PakailinkPayouts {
+	return &PakailinkPayouts{Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (p *PakailinkPayouts) Payout(ctx context.Context, req PayoutRequest) error {
+	token, err := utils.GetPakailinkAccessToken(ctx, p.Client)
+	if err != nil {
+		return err
+	}
+	callbackURL := utils.GetPakailinkPayoutCallbackURL()
+	if req.EWallet {
+		_, err = utils.PakailinkEwalletTopup(ctx, p.Client, token, req.OrderID, req.AccountNumber, req.Code, "", req.Amount, callbackURL)
+	} else {
+		_, err = utils.PakailinkBankTransfer(ctx, p.Client, token, req.OrderID, req.AccountNumber, req.Code, "", req.Amount, callbackURL)
+	}
+	return err
+}
+
+// isEWallet reports whether a bank row of the given type is paid out as an e-wallet top-up.
+func isEWallet(bankType string) bool {
+	return strings.EqualFold(strings.TrimSpace(bankType), "ewallet")
+}
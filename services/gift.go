@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	minGiftAmount   float64 = 1000
+	maxGiftAmount   float64 = 10000000
+	minGiftWinners  int     = 1
+	maxGiftWinners  int     = 100
+	minRandomAmount float64 = 100 // minimum per winner for random mode
+)
+
+var (
+	ErrGiftCodeRequired   = errors.New("gift code required")
+	ErrGiftNotEligible    = errors.New("gift requires vip level 1")
+	ErrGiftPromotorSender = errors.New("promotor cannot create gifts")
+	ErrGiftCode           = errors.New("could not generate unique gift code")
+)
+
+// CreateGiftRequest describes a new gift. For random distribution Amount is the total
+// split among WinnerCount winners; for equal distribution it is the amount per winner.
+type CreateGiftRequest struct {
+	UserID           uint
+	Amount           float64
+	WinnerCount      int
+	DistributionType string // "random" | "equal"
+	RecipientType    string // "all" | "referral_only"
+	ExpiresInHours   int    // 0 = default from settings
+	PIN              string
+}
+
+type GiftService interface {
+	// Create debits the total from the sender and opens the gift for claims until it
+	// expires; unclaimed amounts are refunded by the expiry cron.
+	Create(ctx context.Context, req CreateGiftRequest) (*models.Gift, error)
+	// Redeem claims the next slot of the gift with the given code.
+	Redeem(ctx context.Context, userID uint, code string) (*models.GiftClaim, error)
+}
+
+type giftService struct {
+	Deps
+}
+
+func NewGiftService(d Deps) GiftService {
+	return &giftService{Deps: d.withDefaults()}
+}
+
+// giftTotal validates the shape of a gift request and returns the amount to deduct from
+// the sender. Violations carry the message shown to the user.
+func giftTotal(req *CreateGiftRequest) (float64, error) {
+	req.DistributionType = strings.ToLower(strings.TrimSpace(req.DistributionType))
+	req.RecipientType = strings.ToLower(strings.TrimSpace(req.RecipientType))
+
+	if req.DistributionType != "random" && req.DistributionType != "equal" {
+		return 0, &utils.PolicyViolation{Rule: "distribution_type", Message: "Tipe distribusi harus random atau equal"}
+	}
+	if req.RecipientType != "all" && req.RecipientType != "referral_only" {
+		return 0, &utils.PolicyViolation{Rule: "recipient_type", Message: "Penerima harus all atau referral_only"}
+	}
+	if req.WinnerCount < minGiftWinners || req.WinnerCount > maxGiftWinners {
+		return 0, &utils.PolicyViolation{Rule: "winner_count", Message: fmt.Sprintf("Jumlah pemenang harus antara %d dan %d", minGiftWinners, maxGiftWinners)}
+	}
+	if req.Amount < minGiftAmount || req.Amount > maxGiftAmount {
+		return 0, &utils.PolicyViolation{Rule: "amount", Message: fmt.Sprintf("Jumlah harus antara Rp %.0f dan Rp %.0f", minGiftAmount, maxGiftAmount)}
+	}
+	if req.DistributionType == "equal" {
+		return req.Amount * float64(req.WinnerCount), nil
+	}
+	if req.Amount < float64(req.WinnerCount)*minRandomAmount {
+		return 0, &utils.PolicyViolation{Rule: "amount", Message: fmt.Sprintf("Total minimal Rp %.0f untuk %d pemenang (Rp %.0f per pemenang)", float64(req.WinnerCount)*minRandomAmount, req.WinnerCount, minRandomAmount)}
+	}
+	return req.Amount, nil
+}
+
+func (s *giftService) Create(ctx context.Context, req CreateGiftRequest) (*models.Gift, error) {
+	totalDeducted, err := giftTotal(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	setting, err := s.Settings.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Clock.Now()
+	expiresAt, err := utils.GiftExpiry(setting, req.ExpiresInHours, now)
+	if err != nil {
+		return nil, &utils.PolicyViolation{Rule: "expiry", Message: err.Error()}
+	}
+
+	if err := verifyPIN(ctx, s.PIN, req.UserID, req.PIN, now); err != nil {
+		return nil, err
+	}
+
+	var gift models.Gift
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sender models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sender, req.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if sender.Balance < totalDeducted {
+			return ErrInsufficientBalance
+		}
+		if userLevel(&sender) < 1 {
+			return ErrGiftNotEligible
+		}
+		if strings.ToLower(sender.UserMode) == "promotor" {
+			return ErrGiftPromotorSender
+		}
+
+		code, err := generateGiftCode(tx)
+		if err != nil {
+			return err
+		}
+		gift = models.Gift{
+			UserID:           req.UserID,
+			Code:             code,
+			Amount:           req.Amount,
+			WinnerCount:      req.WinnerCount,
+			DistributionType: req.DistributionType,
+			RecipientType:    req.RecipientType,
+			Status:           "active",
+			TotalDeducted:    totalDeducted,
+			ExpiresAt:        &expiresAt,
+		}
+
+		if err := tx.Model(&sender).Update("balance", gorm.Expr("balance - ?", totalDeducted)).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&gift).Error; err != nil {
+			return err
+		}
+		if req.DistributionType == "random" {
+			slots := generateRandomAmounts(req.Amount, req.WinnerCount)
+			for i := range slots {
+				slots[i].GiftID = gift.ID
+				if err := tx.Create(&slots[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		msg := fmt.Sprintf("Hadiah %s - %s", code, req.DistributionType)
+		return tx.Create(&models.Transaction{
+			UserID:          req.UserID,
+			Amount:          totalDeducted,
+			OrderID:         utils.GenerateOrderID(req.UserID),
+			TransactionFlow: "credit",
+			TransactionType: "gift",
+			Message:         &msg,
+			Status:          "Success",
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &gift, nil
+}
+
+func (s *giftService) Redeem(ctx context.Context, userID uint, code string) (*models.GiftClaim, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, ErrGiftCodeRequired
+	}
+	db := s.DB.WithContext(ctx)
+	var claimant models.User
+	if err := db.Select("id, reff_by, user_mode, bonus_frozen").First(&claimant, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return utils.RedeemGift(db, code, claimant, s.Clock.Now())
+}
+
+// generateRandomAmounts splits total into winnerCount random amounts. Every winner gets
+// minRandomAmount plus a random share of the rest, floored to whole rupiah, and the last
+// slot takes the remainder so the slots add up to total exactly.
+func generateRandomAmounts(total float64, winnerCount int) []models.GiftAmountSlot {
+	slots := make([]models.GiftAmountSlot, winnerCount)
+	weights := make([]float64, winnerCount)
+	var sum float64
+	for i := range weights {
+		b := make([]byte, 4)
+		rand.Read(b)
+		weights[i] = float64(uint32(b[0])<<24|uint32(b[1])<<16|uint32(b[2])<<8|uint32(b[3])) + 1
+		sum += weights[i]
+	}
+
+	spare := total - float64(winnerCount)*minRandomAmount
+	remaining := total
+	for i := 0; i < winnerCount-1; i++ {
+		amount := minRandomAmount + math.Floor(spare*weights[i]/sum)
+		slots[i] = models.GiftAmountSlot{SlotIndex: i, Amount: amount}
+		remaining -= amount
+	}
+	slots[winnerCount-1] = models.GiftAmountSlot{SlotIndex: winnerCount - 1, Amount: round2(remaining)}
+	return slots
+}
+
+func generateGiftCode(db *gorm.DB) (string, error) {
+	const chars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	const length = 8
+	for attempt := 0; attempt < 20; attempt++ {
+		b := make([]byte, length)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrGiftCode, err)
+		}
+		var code strings.Builder
+		for _, v := range b {
+			code.WriteByte(chars[int(v)%len(chars)])
+		}
+		c := code.String()
+		var count int64
+		if db.Model(&models.Gift{}).Where("code = ?", c).Count(&count).Error == nil && count == 0 {
+			return c, nil
+		}
+	}
+	return "", ErrGiftCode
+}
@@ -0,0 +1,515 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"project/metrics"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidPaymentMethod = errors.New("invalid payment method")
+	ErrInvalidBank          = errors.New("invalid bank channel")
+	ErrProductNotFound      = errors.New("product not found")
+	ErrInvalidCategory      = errors.New("product has no category")
+	ErrPaymentGateway       = errors.New("payment gateway error")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrInvestmentNotFound   = errors.New("investment not found")
+)
+
+// vaChannels are the banks that can issue a virtual account.
+var vaChannels = map[string]struct{}{
+	"BCA": {}, "BNI": {}, "BRI": {}, "BSI": {}, "CIMB": {}, "DANAMON": {},
+	"MANDIRI": {}, "BMI": {}, "BNC": {}, "OCBC": {}, "PERMATA": {}, "SINARMAS": {},
+}
+
+// CreateInvestmentRequest buys ProductID paying by QRIS or BANK (virtual account at
+// PaymentChannel).
+type CreateInvestmentRequest struct {
+	UserID         uint
+	ProductID      uint
+	PaymentMethod  string
+	PaymentChannel string
+}
+
+// InvestmentResult is the created investment. Promotor purchases are paid from the
+// balance and are already Running.
+type InvestmentResult struct {
+	Investment models.Investment
+	Product    models.Product
+	Promotor   bool
+}
+
+// DailyReturnsResult summarizes one daily-returns run. Holiday is set when the run was
+// skipped.
+type DailyReturnsResult struct {
+	Processed int
+	Holiday   string
+}
+
+type InvestmentService interface {
+	Create(ctx context.Context, req CreateInvestmentRequest) (*InvestmentResult, error)
+	// ConfirmPayment applies a gateway callback for the payment with orderID. Callbacks
+	// for investments that are no longer Pending are ignored.
+	ConfirmPayment(ctx context.Context, orderID string, success bool) error
+	// RefreshPayment asks the gateway about a Pending, unexpired payment and settles it
+	// when paid. It reports whether the payment was settled.
+	RefreshPayment(ctx context.Context, payment *models.Payment) (bool, error)
+	// ProcessDailyReturns pays every investment whose next return is due.
+	ProcessDailyReturns(ctx context.Context) (*DailyReturnsResult, error)
+}
+
+type investmentService struct {
+	Deps
+}
+
+func NewInvestmentService(d Deps) InvestmentService {
+	return &investmentService{Deps: d.withDefaults()}
+}
+
+// normalizePayment validates the payment method and, for BANK, the VA channel.
+func normalizePayment(method, channel string) (string, string, error) {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	channel = strings.ToUpper(strings.TrimSpace(channel))
+	if method != "QRIS" && method != "BANK" {
+		return "", "", ErrInvalidPaymentMethod
+	}
+	if method == "BANK" {
+		if _, ok := vaChannels[channel]; !ok {
+			return "", "", ErrInvalidBank
+		}
+	}
+	return method, channel, nil
+}
+
+// checkPaymentAmount applies the gateway limits of each method.
+func checkPaymentAmount(method string, amount float64) error {
+	if method == "QRIS" && amount > 10000000 {
+		return &utils.PolicyViolation{Rule: "qris_max", Message: "Jumlah pembayaran maksimal menggunakan QRIS adalah Rp 10.000.000, Silahkan gunakan metode pembayaran lain"}
+	}
+	if method == "BANK" && amount < 10000 {
+		return &utils.PolicyViolation{Rule: "bank_min", Message: "Jumlah pembayaran minimal menggunakan BANK adalah Rp 10.000, Silahkan gunakan metode pembayaran lain"}
+	}
+	return nil
+}
+
+// CalculateVIPLevel determines VIP level based on total locked category investments
+// VIP1: 50k, VIP2: 1.2M, VIP3: 10M, VIP4: 30M, VIP5: 150M
+func CalculateVIPLevel(totalInvestVIP float64) uint {
+	if totalInvestVIP >= 150000000 {
+		return 5
+	} else if totalInvestVIP >= 30000000 {
+		return 4
+	} else if totalInvestVIP >= 10000000 {
+		return 3
+	} else if totalInvestVIP >= 1200000 {
+		return 2
+	} else if totalInvestVIP >= 50000 {
+		return 1
+	}
+	return 0
+}
+
+func (s *investmentService) Create(ctx context.Context, req CreateInvestmentRequest) (*InvestmentResult, error) {
+	method, channel, err := normalizePayment(req.PaymentMethod, req.PaymentChannel)
+	if err != nil {
+		return nil, err
+	}
+	db := s.DB.WithContext(ctx)
+
+	var product models.Product
+	if err := db.Preload("Category").Where("id = ? AND status = 'Active'", req.ProductID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	if product.Category == nil {
+		return nil, ErrInvalidCategory
+	}
+
+	var user models.User
+	if err := db.Select("level, user_mode, balance, name").Where("id = ?", req.UserID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if level := userLevel(&user); level < uint(product.RequiredVIP) {
+		return nil, &utils.PolicyViolation{Rule: "required_vip", Message: fmt.Sprintf("Produk %s memerlukan VIP level %d. Level VIP Anda saat ini: %d", product.Name, product.RequiredVIP, level)}
+	}
+	if product.PurchaseLimit > 0 {
+		var purchases int64
+		if err := db.Model(&models.Investment{}).
+			Where("user_id = ? AND product_id = ? AND status IN ?", req.UserID, product.ID, []string{"Running", "Completed", "Suspended"}).
+			Count(&purchases).Error; err != nil {
+			return nil, err
+		}
+		if purchases >= int64(product.PurchaseLimit) {
+			return nil, &utils.PolicyViolation{Rule: "purchase_limit", Message: fmt.Sprintf("Anda telah mencapai batas pembelian untuk produk %s (maksimal %dx)", product.Name, product.PurchaseLimit)}
+		}
+	}
+
+	inv := models.Investment{
+		UserID:      req.UserID,
+		ProductID:   product.ID,
+		CategoryID:  product.CategoryID,
+		Amount:      product.Amount,
+		DailyProfit: product.DailyProfit,
+		Duration:    product.Duration,
+		OrderID:     utils.GenerateOrderID(req.UserID),
+		Status:      "Pending",
+	}
+	referenceID := inv.OrderID
+	payment := models.Payment{
+		ReferenceID:   &referenceID,
+		OrderID:       inv.OrderID,
+		PaymentMethod: &method,
+	}
+	if method == "BANK" {
+		payment.PaymentChannel = &channel
+	}
+	msg := fmt.Sprintf("Investasi %s", product.Name)
+	trx := models.Transaction{
+		UserID:          req.UserID,
+		Amount:          inv.Amount,
+		OrderID:         inv.OrderID,
+		TransactionFlow: "credit",
+		TransactionType: "investment",
+		Message:         &msg,
+	}
+
+	// Promotor accounts pay from their balance without the payment gateway
+	if user.UserMode == "promotor" {
+		if user.Balance < inv.Amount {
+			return nil, ErrInsufficientBalance
+		}
+		payment.Status, trx.Status = "Success", "Success"
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var locked models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, req.UserID).Error; err != nil {
+				return err
+			}
+			if locked.Balance < inv.Amount {
+				return ErrInsufficientBalance
+			}
+			if err := tx.Model(&locked).Update("balance", round2(locked.Balance-inv.Amount)).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&inv).Error; err != nil {
+				return err
+			}
+			payment.InvestmentID = inv.ID
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&trx).Error; err != nil {
+				return err
+			}
+			// No referral bonus or spin ticket for promotor purchases
+			_, err := s.activate(tx, &inv)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		db.First(&inv, inv.ID)
+		return &InvestmentResult{Investment: inv, Product: product, Promotor: true}, nil
+	}
+
+	if err := checkPaymentAmount(method, inv.Amount); err != nil {
+		return nil, err
+	}
+	var instr *PaymentInstruction
+	if method == "QRIS" {
+		instr, err = s.Payments.CreateQRIS(ctx, inv.OrderID, inv.Amount)
+	} else {
+		customerNo := fmt.Sprintf("%d%010d", req.UserID, s.Clock.Now().UnixNano()%10000000000)
+		name := strings.TrimSpace(user.Name)
+		if name == "" {
+			name = "Pelanggan"
+		}
+		instr, err = s.Payments.CreateVA(ctx, inv.OrderID, utils.GetVABankCode(channel), customerNo, fmt.Sprintf("%s - NovaVant", name), inv.Amount)
+	}
+	if err != nil {
+		log.Printf("[Pakailink] create %s payment error: %v", method, err)
+		return nil, fmt.Errorf("%w: %v", ErrPaymentGateway, err)
+	}
+	expiredAt := instr.ExpiresAt
+	if expiredAt.IsZero() {
+		expiredAt = s.Clock.Now().Add(24 * time.Hour)
+	}
+	if code := strings.TrimSpace(instr.Code); code != "" {
+		payment.PaymentCode = &code
+	}
+	payment.ExpiredAt = &expiredAt
+	payment.Status, trx.Status = "Pending", "Pending"
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&inv).Error; err != nil {
+			return err
+		}
+		payment.InvestmentID = inv.ID
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return tx.Create(&trx).Error
+	}); err != nil {
+		return nil, err
+	}
+	metrics.PaymentsCreated.WithLabelValues(method).Inc()
+	return &InvestmentResult{Investment: inv, Product: product}, nil
+}
+
+// activate starts the investment and adds it to the user's totals. Locked (Monitor)
+// categories also count towards the VIP level. It reports whether the category is locked.
+func (s *investmentService) activate(tx *gorm.DB, inv *models.Investment) (bool, error) {
+	next := s.Clock.Now().Add(24 * time.Hour)
+	if err := tx.Model(inv).Updates(map[string]interface{}{"status": "Running", "last_return_at": nil, "next_return_at": next}).Error; err != nil {
+		return false, err
+	}
+	var category models.Category
+	isMonitor := tx.Where("id = ?", inv.CategoryID).First(&category).Error == nil && category.ProfitType == "locked"
+
+	userUpdates := map[string]interface{}{
+		"total_invest":      gorm.Expr("total_invest + ?", inv.Amount),
+		"investment_status": "Active",
+	}
+	if isMonitor {
+		userUpdates["total_invest_vip"] = gorm.Expr("total_invest_vip + ?", inv.Amount)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Updates(userUpdates).Error; err != nil {
+		return false, err
+	}
+	if isMonitor {
+		var user models.User
+		if err := tx.Model(&models.User{}).Select("total_invest_vip").Where("id = ?", inv.UserID).First(&user).Error; err == nil {
+			if err := tx.Model(&models.User{}).Where("id = ?", inv.UserID).Update("level", CalculateVIPLevel(user.TotalInvestVIP)).Error; err != nil {
+				return false, err
+			}
+		}
+	}
+	return isMonitor, nil
+}
+
+// settle completes a paid investment: transaction, activation, task progress and the
+// referrer's bonus and spin ticket. The investment row is locked so a callback and a
+// status refresh cannot both settle it.
+func (s *investmentService) settle(tx *gorm.DB, investmentID uint) error {
+	var inv models.Investment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv, investmentID).Error; err != nil {
+		return err
+	}
+	if inv.Status != "Pending" {
+		return nil
+	}
+	if err := tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Updates(map[string]interface{}{"status": "Success"}).Error; err != nil {
+		return err
+	}
+	isMonitor, err := s.activate(tx, &inv)
+	if err != nil {
+		return err
+	}
+	if err := utils.InvalidateInvestmentTaskProgress(tx, inv.UserID); err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := tx.Select("id, reff_by").Where("id = ?", inv.UserID).First(&user).Error; err != nil || user.ReffBy == nil || !isMonitor {
+		return nil
+	}
+	var level1 models.User
+	if err := tx.Select("id, spin_ticket").Where("id = ?", *user.ReffBy).First(&level1).Error; err != nil {
+		return nil
+	}
+	if inv.Amount >= 100000 {
+		orderID := inv.OrderID
+		if _, err := utils.GrantSpinTicket(tx, level1.ID, utils.SpinTicketSourceReferralInvestment, &orderID, s.Clock.Now()); err != nil {
+			return err
+		}
+	}
+	// Held as Pending while the referrer's bonuses are frozen for review
+	bonus := round2(inv.Amount * 0.30)
+	_, err = utils.CreditBonus(tx, level1.ID, bonus, "team", "Bonus rekomendasi investor")
+	return err
+}
+
+func (s *investmentService) ConfirmPayment(ctx context.Context, orderID string, success bool) error {
+	db := s.DB.WithContext(ctx)
+	var payment models.Payment
+	if err := db.Where("order_id = ?", orderID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPaymentNotFound
+		}
+		return err
+	}
+	status := "Failed"
+	if success {
+		status = "Success"
+	}
+	// Gateways repeat callbacks; only the first success is counted
+	if success && payment.Status != "Success" {
+		metrics.PaymentsSucceeded.WithLabelValues(metrics.PaymentMethod(payment.PaymentMethod)).Inc()
+	}
+	_ = db.Model(&payment).Update("status", status).Error
+
+	var inv models.Investment
+	if err := db.Where("id = ?", payment.InvestmentID).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvestmentNotFound
+		}
+		return err
+	}
+	if inv.Status != "Pending" {
+		return nil
+	}
+	if success {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return s.settle(tx, inv.ID)
+		})
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		_ = tx.Model(&models.Transaction{}).Where("order_id = ?", inv.OrderID).Update("status", "Failed").Error
+		_ = tx.Model(&models.Investment{}).Where("id = ? AND status = ?", inv.ID, "Pending").Update("status", "Cancelled").Error
+		return nil
+	})
+}
+
+func (s *investmentService) RefreshPayment(ctx context.Context, payment *models.Payment) (bool, error) {
+	if payment.Status != "Pending" || payment.ExpiredAt == nil || !payment.ExpiredAt.After(s.Clock.Now()) {
+		return false, nil
+	}
+	method := ""
+	if payment.PaymentMethod != nil {
+		method = *payment.PaymentMethod
+	}
+	paid, err := s.Payments.IsPaid(ctx, method, payment.OrderID)
+	if err != nil || !paid {
+		return false, err
+	}
+	db := s.DB.WithContext(ctx)
+	if err := db.Model(payment).Update("status", "Success").Error; err != nil {
+		return false, err
+	}
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return s.settle(tx, payment.InvestmentID)
+	}); err != nil {
+		return false, err
+	}
+	metrics.PaymentsSucceeded.WithLabelValues(metrics.PaymentMethod(payment.PaymentMethod)).Inc()
+	return true, nil
+}
+
+// returnCredit is one balance credit of a daily-returns run.
+type returnCredit struct {
+	Amount  float64
+	Message string
+}
+
+// dailyReturn is what one due investment earns today.
+type dailyReturn struct {
+	Credits   []returnCredit
+	TotalPaid int
+	Returned  float64
+	Completed bool
+}
+
+// planDailyReturn pays unlocked categories every day. Locked (Monitor) categories only
+// accumulate, and pay the whole profit at completion. The principal is returned when
+// the last day is paid.
+func planDailyReturn(inv models.Investment, profitType, productName string) dailyReturn {
+	plan := dailyReturn{
+		TotalPaid: inv.TotalPaid + 1,
+		Returned:  round2(inv.TotalReturned + inv.DailyProfit),
+	}
+	plan.Completed = plan.TotalPaid >= inv.Duration
+	if profitType == "unlocked" {
+		plan.Credits = append(plan.Credits, returnCredit{inv.DailyProfit, fmt.Sprintf("Profit investasi produk %s", productName)})
+	}
+	if profitType == "locked" && plan.Completed {
+		plan.Credits = append(plan.Credits, returnCredit{round2(inv.DailyProfit * float64(inv.Duration)), fmt.Sprintf("Total profit investasi produk %s selesai", productName)})
+	}
+	if plan.Completed {
+		plan.Credits = append(plan.Credits, returnCredit{inv.Amount, fmt.Sprintf("Pengembalian modal investasi produk %s", productName)})
+	}
+	return plan
+}
+
+func (s *investmentService) ProcessDailyReturns(ctx context.Context) (*DailyReturnsResult, error) {
+	db := s.DB.WithContext(ctx)
+	now := s.Clock.Now()
+
+	// Returns are not credited on national holidays. Due investments stay due, so the next
+	// run pays them and no return is lost; each payout moves the schedule on by a day.
+	holidays, err := utils.LoadHolidayCalendarAround(db, now)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := holidays.HolidayOn(now); ok {
+		return &DailyReturnsResult{Holiday: name}, nil
+	}
+
+	var due []models.Investment
+	if err := db.Where("status = 'Running' AND next_return_at IS NOT NULL AND next_return_at <= ? AND total_paid < duration", now).Find(&due).Error; err != nil {
+		return nil, err
+	}
+	result := &DailyReturnsResult{}
+	for i := range due {
+		inv := due[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, inv.UserID).Error; err != nil {
+				return err
+			}
+			var category models.Category
+			if err := tx.Where("id = ?", inv.CategoryID).First(&category).Error; err != nil {
+				return err
+			}
+			var product models.Product
+			if err := tx.Where("id = ?", inv.ProductID).First(&product).Error; err != nil {
+				return err
+			}
+
+			plan := planDailyReturn(inv, category.ProfitType, product.Name)
+			balance := user.Balance
+			for _, c := range plan.Credits {
+				balance = round2(balance + c.Amount)
+				msg := c.Message
+				if err := tx.Create(&models.Transaction{
+					UserID:          inv.UserID,
+					Amount:          c.Amount,
+					OrderID:         utils.GenerateOrderID(inv.UserID),
+					TransactionFlow: "debit",
+					TransactionType: "return",
+					Message:         &msg,
+					Status:          "Success",
+				}).Error; err != nil {
+					return err
+				}
+			}
+			if balance != user.Balance {
+				if err := tx.Model(&user).Update("balance", balance).Error; err != nil {
+					return err
+				}
+			}
+
+			paidAt := s.Clock.Now()
+			updates := map[string]interface{}{"total_paid": plan.TotalPaid, "total_returned": plan.Returned, "last_return_at": paidAt, "next_return_at": paidAt.Add(24 * time.Hour)}
+			if plan.Completed {
+				updates["status"] = "Completed"
+			}
+			return tx.Model(&inv).Updates(updates).Error
+		})
+		if err != nil {
+			log.Printf("[cron/daily-returns] investment %d: %v", inv.ID, err)
+			continue
+		}
+		result.Processed++
+	}
+	return result, nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+func createTestUser(t *testing.T, db *gorm.DB, number string, level uint, balance float64) models.User {
+	t.Helper()
+	u := models.User{Name: "User " + number, Number: number, Password: "x", ReffCode: "R" + number, Level: &level, Balance: balance, Status: "Active"}
+	if err := db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func balanceOf(t *testing.T, db *gorm.DB, id uint) float64 {
+	t.Helper()
+	var u models.User
+	if err := db.Select("balance").First(&u, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return u.Balance
+}
+
+func TestTransferAndWithdrawalMySQL(t *testing.T) {
+	// Monday 10:00 WIB, inside the default withdrawal window
+	clock := testdb.NewClock(time.Date(2026, 10, 19, 10, 0, 0, 0, utils.JakartaLocation()))
+	db := testdb.OpenMySQL(t, clock.Now)
+	payouts := &fakePayouts{}
+	svc := New(Deps{
+		DB:       db,
+		Clock:    clock,
+		Settings: &fakeSettings{setting: models.Setting{MinWithdraw: 50000, MaxWithdraw: 10000000, WithdrawCharge: 10, AutoWithdraw: true}},
+		Payments: &fakePayments{},
+		Payouts:  payouts,
+	})
+	ctx := context.Background()
+
+	alice := createTestUser(t, db, "81100000001", 3, 1000000)
+	bob := createTestUser(t, db, "81100000002", 3, 0)
+	hash, _ := utils.HashPIN("135790")
+	db.Create(&models.UserPIN{UserID: alice.ID, PINHash: hash})
+	bank := models.Bank{Name: "BCA", Code: "014", Type: "bank", Status: "Active"}
+	db.Create(&bank)
+	acc := models.BankAccount{UserID: alice.ID, BankID: bank.ID, AccountName: "Alice", AccountNumber: "1234567890"}
+	db.Create(&acc)
+
+	res, err := svc.Transfers.Transfer(ctx, TransferRequest{SenderID: alice.ID, Number: "0" + bob.Number, Amount: 100000, PIN: "135790"})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if res.Receiver.ID != bob.ID || balanceOf(t, db, alice.ID) != 900000 || balanceOf(t, db, bob.ID) != 100000 {
+		t.Fatalf("unexpected balances after transfer")
+	}
+	if contacts, _ := svc.Transfers.Contacts(ctx, alice.ID); len(contacts) != 1 || contacts[0].ID != bob.ID {
+		t.Fatalf("contacts = %+v", contacts)
+	}
+
+	// Bob never set a PIN
+	var pinErr *PINError
+	if _, err := svc.Transfers.Transfer(ctx, TransferRequest{SenderID: bob.ID, Number: alice.Number, Amount: 10000, PIN: "135790"}); !errors.As(err, &pinErr) || !errors.Is(err, utils.ErrPINNotSet) {
+		t.Fatalf("transfer without pin set gave %v", err)
+	}
+
+	wd, err := svc.Withdrawals.Request(ctx, WithdrawalRequest{UserID: alice.ID, BankAccountID: acc.ID, Amount: 100000, PIN: "135790"})
+	if err != nil {
+		t.Fatalf("withdrawal: %v", err)
+	}
+	if wd.Withdrawal.Status != "Pending" || wd.Withdrawal.FinalAmount != 90000 || balanceOf(t, db, alice.ID) != 800000 {
+		t.Fatalf("withdrawal = %+v", wd.Withdrawal)
+	}
+	if len(payouts.requests) != 1 || payouts.requests[0].Amount != 90000 || payouts.requests[0].Code != "014" || payouts.requests[0].EWallet {
+		t.Fatalf("payouts = %+v", payouts.requests)
+	}
+	// Already sent to the gateway: no partner may claim and pay it a second time
+	if wd.Withdrawal.GatewayDispatchedAt == nil {
+		t.Fatal("auto-withdrawn request not marked as dispatched")
+	}
+	if claimed, err := utils.ClaimPartnerWithdrawals(db, 1, 10, clock.Now()); err != nil || len(claimed) != 0 {
+		t.Fatalf("partner claimed an auto-withdrawn request: %+v, %v", claimed, err)
+	}
+
+	// The default rules allow one withdrawal per day
+	var v *utils.PolicyViolation
+	if _, err := svc.Withdrawals.Request(ctx, WithdrawalRequest{UserID: alice.ID, BankAccountID: acc.ID, Amount: 100000, PIN: "135790"}); !errors.As(err, &v) {
+		t.Fatalf("second withdrawal gave %v", err)
+	}
+	clock.Advance(24 * time.Hour)
+	if _, err := svc.Withdrawals.Request(ctx, WithdrawalRequest{UserID: alice.ID, BankAccountID: acc.ID, Amount: 900000, PIN: "135790"}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("overdraft gave %v", err)
+	}
+}
+
+func TestWithdrawalDailyCapConcurrentMySQL(t *testing.T) {
+	clock := testdb.NewClock(time.Date(2026, 10, 19, 10, 0, 0, 0, utils.JakartaLocation()))
+	db := testdb.OpenMySQL(t, clock.Now)
+	svc := New(Deps{
+		DB:       db,
+		Clock:    clock,
+		Settings: &fakeSettings{setting: models.Setting{MinWithdraw: 50000, MaxWithdraw: 10000000, WithdrawCharge: 10}},
+		PIN:      &fakePIN{},
+		Payments: &fakePayments{},
+		Payouts:  &fakePayouts{},
+	})
+	user := createTestUser(t, db, "81100000004", 3, 1000000)
+	bank := models.Bank{Name: "BCA", Code: "014", Type: "bank", Status: "Active"}
+	db.Create(&bank)
+	acc := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "User", AccountNumber: "1234567890"}
+	db.Create(&acc)
+
+	// The default rules allow one withdrawal per day, however many arrive at once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Withdrawals.Request(context.Background(), WithdrawalRequest{UserID: user.ID, BankAccountID: acc.ID, Amount: 100000, PIN: "135790"})
+			var v *utils.PolicyViolation
+			if err != nil && !errors.As(err, &v) {
+				t.Errorf("withdrawal: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 1 || balanceOf(t, db, user.ID) != 900000 {
+		t.Fatalf("%d withdrawals accepted, balance %.2f", ok, balanceOf(t, db, user.ID))
+	}
+}
+
+func TestInvestmentLifecycleMySQL(t *testing.T) {
+	clock := testdb.NewClock(time.Date(2026, 10, 19, 8, 0, 0, 0, utils.JakartaLocation()))
+	db := testdb.OpenMySQL(t, clock.Now)
+	payments := &fakePayments{paid: map[string]bool{}}
+	svc := New(Deps{DB: db, Clock: clock, Settings: &fakeSettings{}, PIN: &fakePIN{}, Payments: payments, Payouts: &fakePayouts{}})
+	ctx := context.Background()
+
+	category := models.Category{Name: "Insight", ProfitType: "unlocked", Status: "Active"}
+	db.Create(&category)
+	product := models.Product{CategoryID: category.ID, Name: "Insight 1", Amount: 100000, DailyProfit: 10000, Duration: 2, Status: "Active"}
+	db.Create(&product)
+	user := createTestUser(t, db, "81100000003", 0, 0)
+
+	res, err := svc.Investments.Create(ctx, CreateInvestmentRequest{UserID: user.ID, ProductID: product.ID, PaymentMethod: "bank", PaymentChannel: "bca"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.Investment.Status != "Pending" || len(payments.created) != 1 {
+		t.Fatalf("investment %+v, gateway calls %v", res.Investment, payments.created)
+	}
+
+	var payment models.Payment
+	db.Where("order_id = ?", res.Investment.OrderID).First(&payment)
+	if settled, err := svc.Investments.RefreshPayment(ctx, &payment); err != nil || settled {
+		t.Fatalf("unpaid refresh: %v %v", settled, err)
+	}
+	payments.paid[payment.OrderID] = true
+	if settled, err := svc.Investments.RefreshPayment(ctx, &payment); err != nil || !settled {
+		t.Fatalf("paid refresh: %v %v", settled, err)
+	}
+	// A late callback must not settle twice
+	if err := svc.Investments.ConfirmPayment(ctx, payment.OrderID, true); err != nil {
+		t.Fatal(err)
+	}
+	var u models.User
+	db.First(&u, user.ID)
+	if u.TotalInvest != 100000 || u.InvestmentStatus != "Active" {
+		t.Fatalf("user totals %.2f %s", u.TotalInvest, u.InvestmentStatus)
+	}
+
+	for day := 1; day <= 3; day++ {
+		clock.Advance(24*time.Hour + time.Minute)
+		out, err := svc.Investments.ProcessDailyReturns(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 1
+		if day == 3 {
+			want = 0
+		}
+		if out.Processed != want {
+			t.Fatalf("day %d processed %d", day, out.Processed)
+		}
+	}
+	var inv models.Investment
+	db.First(&inv, res.Investment.ID)
+	if inv.Status != "Completed" || inv.TotalPaid != 2 || balanceOf(t, db, user.ID) != 120000 {
+		t.Fatalf("investment %+v balance %.2f", inv, balanceOf(t, db, user.ID))
+	}
+
+	if err := svc.Investments.ConfirmPayment(ctx, "NOPE", true); !errors.Is(err, ErrPaymentNotFound) {
+		t.Fatalf("unknown order gave %v", err)
+	}
+}
+
+func TestDailyReturnsHolidayMySQL(t *testing.T) {
+	// 17 August, Independence Day
+	clock := testdb.NewClock(time.Date(2026, 8, 17, 8, 0, 0, 0, utils.JakartaLocation()))
+	db := testdb.OpenMySQL(t, clock.Now)
+	svc := New(Deps{DB: db, Clock: clock, Settings: &fakeSettings{}, PIN: &fakePIN{}, Payments: &fakePayments{paid: map[string]bool{}}, Payouts: &fakePayouts{}})
+	ctx := context.Background()
+
+	db.Create(&models.Holiday{Date: time.Date(2026, 8, 17, 0, 0, 0, 0, time.Local), Name: "Hari Kemerdekaan"})
+	category := models.Category{Name: "Insight", ProfitType: "unlocked", Status: "Active"}
+	db.Create(&category)
+	product := models.Product{CategoryID: category.ID, Name: "Insight 1", Amount: 100000, DailyProfit: 10000, Duration: 2, Status: "Active"}
+	db.Create(&product)
+	user := createTestUser(t, db, "81100000004", 0, 0)
+	due := clock.Now().Add(-time.Minute)
+	inv := models.Investment{UserID: user.ID, ProductID: product.ID, CategoryID: category.ID, Amount: 100000, DailyProfit: 10000,
+		Duration: 2, NextReturnAt: &due, OrderID: "INV-HOLIDAY", Status: "Running"}
+	if err := db.Create(&inv).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := svc.Investments.ProcessDailyReturns(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Holiday != "Hari Kemerdekaan" || out.Processed != 0 || balanceOf(t, db, user.ID) != 0 {
+		t.Fatalf("holiday run %+v, balance %.2f", out, balanceOf(t, db, user.ID))
+	}
+
+	// The return skipped on the holiday is paid by the next run
+	clock.Advance(24 * time.Hour)
+	if out, err = svc.Investments.ProcessDailyReturns(ctx); err != nil || out.Processed != 1 {
+		t.Fatalf("day after holiday: %+v, %v", out, err)
+	}
+	db.First(&inv, inv.ID)
+	if inv.TotalPaid != 1 || balanceOf(t, db, user.ID) != 10000 {
+		t.Fatalf("investment %+v balance %.2f", inv, balanceOf(t, db, user.ID))
+	}
+}
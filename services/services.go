@@ -0,0 +1,144 @@
+// Package services holds the money-moving business rules (investments, withdrawals,
+// transfers, gifts). Handlers decode the request, call a service and map its errors to
+// responses. Everything a service needs besides the database — clock, settings, PIN
+// check, payment and payout gateways — comes in through Deps, so the rules can be
+// exercised with fakes.
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+)
+
+// Deps are the collaborators shared by all services. Zero fields are filled with the
+// production implementations by New.
+type Deps struct {
+	DB       *gorm.DB
+	Clock    utils.Clock
+	Settings SettingsSource
+	PIN      PINVerifier
+	Payments PaymentGateway
+	Payouts  PayoutGateway
+}
+
+// SettingsSource loads the application settings row.
+type SettingsSource interface {
+	Load(ctx context.Context) (*models.Setting, error)
+}
+
+// PINVerifier checks a transaction PIN and records the attempt.
+type PINVerifier interface {
+	Verify(ctx context.Context, userID uint, pin string, now time.Time) (utils.PINAttempt, error)
+}
+
+// Services bundles one instance of every service built from the same Deps.
+type Services struct {
+	Investments InvestmentService
+	Withdrawals WithdrawalService
+	Transfers   TransferService
+	Gifts       GiftService
+}
+
+// New builds all services from d.
+func New(d Deps) *Services {
+	d = d.withDefaults()
+	return &Services{
+		Investments: NewInvestmentService(d),
+		Withdrawals: NewWithdrawalService(d),
+		Transfers:   NewTransferService(d),
+		Gifts:       NewGiftService(d),
+	}
+}
+
+func (d Deps) withDefaults() Deps {
+	if d.Clock == nil {
+		d.Clock = utils.SystemClock
+	}
+	if d.Settings == nil {
+		d.Settings = DBSettings{DB: d.DB}
+	}
+	if d.PIN == nil {
+		d.PIN = DBPINVerifier{DB: d.DB}
+	}
+	if d.Payments == nil {
+		d.Payments = NewPakailinkPayments()
+	}
+	if d.Payouts == nil {
+		d.Payouts = NewPakailinkPayouts()
+	}
+	return d
+}
+
+// DBSettings reads settings with models.GetSetting.
+type DBSettings struct {
+	DB *gorm.DB
+}
+
+func (s DBSettings) Load(ctx context.Context) (*models.Setting, error) {
+	sqlDB, err := s.DB.DB()
+	if err != nil {
+		return nil, err
+	}
+	return models.GetSetting(sqlDB)
+}
+
+// DBPINVerifier checks PINs against the user_pins table.
+type DBPINVerifier struct {
+	DB *gorm.DB
+}
+
+func (v DBPINVerifier) Verify(ctx context.Context, userID uint, pin string, now time.Time) (utils.PINAttempt, error) {
+	return utils.VerifyTransactionPIN(v.DB.WithContext(ctx), userID, pin, now)
+}
+
+// PINError is returned when the transaction PIN was rejected. Attempt carries the lock
+// or remaining-attempts detail shown to the user.
+type PINError struct {
+	Attempt utils.PINAttempt
+}
+
+func (e *PINError) Error() string { return e.Attempt.Err.Error() }
+func (e *PINError) Unwrap() error { return e.Attempt.Err }
+
+// verifyPIN runs the PIN check shared by every money movement. It must run outside the
+// money transaction so a failed attempt is recorded even though the request aborts.
+func verifyPIN(ctx context.Context, v PINVerifier, userID uint, pin string, now time.Time) error {
+	if pin == "" {
+		return ErrPINRequired
+	}
+	attempt, err := v.Verify(ctx, userID, pin, now)
+	if err != nil {
+		return err
+	}
+	if attempt.Err != nil {
+		return &PINError{Attempt: attempt}
+	}
+	return nil
+}
+
+var (
+	ErrPINRequired         = errors.New("transaction pin required")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrAccountInactive     = errors.New("account inactive")
+	ErrAccountSuspended    = errors.New("account suspended")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrFundsOnHold         = errors.New("funds on hold")
+)
+
+// userLevel returns the VIP level, treating NULL as level 0.
+func userLevel(u *models.User) uint {
+	if u.Level == nil {
+		return 0
+	}
+	return *u.Level
+}
+
+func round2(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/utils"
+)
+
+type fakeSettings struct{ setting models.Setting }
+
+func (f *fakeSettings) Load(ctx context.Context) (*models.Setting, error) {
+	s := f.setting
+	return &s, nil
+}
+
+// fakePIN accepts the PIN "135790" and otherwise reports a mismatch.
+type fakePIN struct{ calls int }
+
+func (f *fakePIN) Verify(ctx context.Context, userID uint, pin string, now time.Time) (utils.PINAttempt, error) {
+	f.calls++
+	if pin == "135790" {
+		return utils.PINAttempt{RemainingAttempts: utils.PINMaxAttempts}, nil
+	}
+	return utils.PINAttempt{Err: utils.ErrPINMismatch, RemainingAttempts: 2}, nil
+}
+
+type fakePayments struct {
+	paid    map[string]bool
+	created []string
+	err     error
+}
+
+func (f *fakePayments) CreateQRIS(ctx context.Context, orderID string, amount float64) (*PaymentInstruction, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.created = append(f.created, "QRIS:"+orderID)
+	return &PaymentInstruction{Code: "00020101021226"}, nil
+}
+
+func (f *fakePayments) CreateVA(ctx context.Context, orderID, bankCode, customerNo, name string, amount float64) (*PaymentInstruction, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.created = append(f.created, "VA:"+bankCode+":"+orderID)
+	return &PaymentInstruction{Code: "8808" + customerNo}, nil
+}
+
+func (f *fakePayments) IsPaid(ctx context.Context, method, orderID string) (bool, error) {
+	return f.paid[orderID], nil
+}
+
+type fakePayouts struct{ requests []PayoutRequest }
+
+func (f *fakePayouts) Payout(ctx context.Context, req PayoutRequest) error {
+	f.requests = append(f.requests, req)
+	return nil
+}
+
+func TestVerifyPIN(t *testing.T) {
+	pin := &fakePIN{}
+	if err := verifyPIN(context.Background(), pin, 1, "", time.Now()); !errors.Is(err, ErrPINRequired) {
+		t.Fatalf("empty pin gave %v", err)
+	}
+	if pin.calls != 0 {
+		t.Fatal("empty pin must not count as an attempt")
+	}
+	err := verifyPIN(context.Background(), pin, 1, "000000", time.Now())
+	var pinErr *PINError
+	if !errors.As(err, &pinErr) || !errors.Is(err, utils.ErrPINMismatch) || pinErr.Attempt.RemainingAttempts != 2 {
+		t.Fatalf("wrong pin gave %v", err)
+	}
+	if err := verifyPIN(context.Background(), pin, 1, "135790", time.Now()); err != nil {
+		t.Fatalf("correct pin gave %v", err)
+	}
+}
+
+func TestCheckAccountStatus(t *testing.T) {
+	cases := map[string]error{"Active": nil, "active": nil, "Suspend": ErrAccountSuspended, "Inactive": ErrAccountInactive, "": ErrAccountInactive}
+	for status, want := range cases {
+		if got := checkAccountStatus(&models.User{Status: status}); got != want {
+			t.Errorf("%q: got %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestWithdrawalCharge(t *testing.T) {
+	charge, final := withdrawalCharge(123457, 10)
+	if charge != 12345.7 || final != 111111.3 {
+		t.Fatalf("charge %.2f final %.2f", charge, final)
+	}
+}
+
+func TestTransferHold(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	until, reason, v := transferHold(models.TransferLimit{}, nil, now)
+	if until != nil || reason != nil || v != nil {
+		t.Fatal("no hold expected without limit hold or velocity hit")
+	}
+
+	until, reason, _ = transferHold(models.TransferLimit{HoldHours: 24}, nil, now)
+	if until == nil || !until.Equal(now.Add(24*time.Hour)) || *reason != "transfer_hold" {
+		t.Fatalf("limit hold: %v %v", until, reason)
+	}
+
+	hit := &utils.VelocityHit{Rule: models.TransferVelocityRule{Name: "fan-in", Action: "hold", HoldHours: 72}}
+	until, reason, _ = transferHold(models.TransferLimit{HoldHours: 24}, hit, now)
+	if !until.Equal(now.Add(72*time.Hour)) || *reason != "velocity:fan-in" {
+		t.Fatalf("velocity hold: %v %v", until, *reason)
+	}
+
+	hit.Rule.Action = "block"
+	if _, _, v := transferHold(models.TransferLimit{}, hit, now); v == nil || v.Rule != "velocity" {
+		t.Fatalf("blocking rule gave %v", v)
+	}
+}
+
+func TestPlanDailyReturn(t *testing.T) {
+	inv := models.Investment{Amount: 100000, DailyProfit: 5000, Duration: 3, TotalPaid: 1, TotalReturned: 5000}
+
+	plan := planDailyReturn(inv, "unlocked", "Insight")
+	if plan.Completed || plan.TotalPaid != 2 || plan.Returned != 10000 || len(plan.Credits) != 1 || plan.Credits[0].Amount != 5000 {
+		t.Fatalf("unlocked mid-term: %+v", plan)
+	}
+	if plan := planDailyReturn(inv, "locked", "Monitor"); len(plan.Credits) != 0 {
+		t.Fatalf("locked mid-term must only accumulate: %+v", plan)
+	}
+
+	inv.TotalPaid = 2
+	plan = planDailyReturn(inv, "locked", "Monitor")
+	if !plan.Completed || len(plan.Credits) != 2 || plan.Credits[0].Amount != 15000 || plan.Credits[1].Amount != 100000 {
+		t.Fatalf("locked completion: %+v", plan)
+	}
+	plan = planDailyReturn(inv, "unlocked", "Insight")
+	if !plan.Completed || len(plan.Credits) != 2 || plan.Credits[0].Amount != 5000 || plan.Credits[1].Amount != 100000 {
+		t.Fatalf("unlocked completion: %+v", plan)
+	}
+}
+
+func TestGiftTotal(t *testing.T) {
+	req := CreateGiftRequest{Amount: 5000, WinnerCount: 4, DistributionType: " Equal ", RecipientType: "ALL"}
+	if total, err := giftTotal(&req); err != nil || total != 20000 || req.DistributionType != "equal" {
+		t.Fatalf("equal: %v %v", total, err)
+	}
+	req = CreateGiftRequest{Amount: 1000, WinnerCount: 20, DistributionType: "random", RecipientType: "all"}
+	var v *utils.PolicyViolation
+	if _, err := giftTotal(&req); !errors.As(err, &v) || v.Rule != "amount" {
+		t.Fatalf("random below minimum per winner gave %v", err)
+	}
+	req = CreateGiftRequest{Amount: 5000, WinnerCount: 1, DistributionType: "random", RecipientType: "friends"}
+	if _, err := giftTotal(&req); !errors.As(err, &v) || v.Rule != "recipient_type" {
+		t.Fatalf("bad recipient gave %v", err)
+	}
+}
+
+func TestGenerateRandomAmounts(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		slots := generateRandomAmounts(1000, 7)
+		sum := 0.0
+		for _, s := range slots {
+			if s.Amount < minRandomAmount {
+				t.Fatalf("slot below minimum: %+v", slots)
+			}
+			sum += s.Amount
+		}
+		if sum != 1000 {
+			t.Fatalf("slots sum to %.2f", sum)
+		}
+	}
+}
+
+func TestPaymentRules(t *testing.T) {
+	if _, _, err := normalizePayment("ovo", ""); !errors.Is(err, ErrInvalidPaymentMethod) {
+		t.Errorf("ovo gave %v", err)
+	}
+	if _, _, err := normalizePayment("bank", "xyz"); !errors.Is(err, ErrInvalidBank) {
+		t.Errorf("unknown bank gave %v", err)
+	}
+	if m, c, err := normalizePayment(" bank ", "bca"); err != nil || m != "BANK" || c != "BCA" {
+		t.Errorf("bank bca gave %s %s %v", m, c, err)
+	}
+	if err := checkPaymentAmount("QRIS", 10000001); err == nil {
+		t.Error("QRIS above 10M must be rejected")
+	}
+	if err := checkPaymentAmount("BANK", 9999); err == nil {
+		t.Error("BANK below 10k must be rejected")
+	}
+	if CalculateVIPLevel(49999) != 0 || CalculateVIPLevel(50000) != 1 || CalculateVIPLevel(10000000) != 3 || CalculateVIPLevel(2e8) != 5 {
+		t.Error("unexpected VIP levels")
+	}
+}
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	for _, in := range []string{"0812241231", "+62812241231", "62 812-241-231", "812241231"} {
+		if got := NormalizePhoneNumber(in); got != "812241231" {
+			t.Errorf("%q -> %q", in, got)
+		}
+	}
+}
+
+// The rules that run before the database is touched can be exercised without one.
+func TestServicesRejectBeforeDatabase(t *testing.T) {
+	pin := &fakePIN{}
+	d := Deps{
+		Clock:    utils.FixedClock(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)),
+		Settings: &fakeSettings{setting: models.Setting{GiftDefaultExpiryHours: 24, GiftMaxExpiryHours: 168}},
+		PIN:      pin,
+		Payments: &fakePayments{},
+		Payouts:  &fakePayouts{},
+	}
+	svc := New(d)
+	ctx := context.Background()
+
+	if _, err := svc.Investments.Create(ctx, CreateInvestmentRequest{UserID: 1, ProductID: 1, PaymentMethod: "CASH"}); !errors.Is(err, ErrInvalidPaymentMethod) {
+		t.Errorf("investment with bad method gave %v", err)
+	}
+
+	gift := CreateGiftRequest{UserID: 1, Amount: 5000, WinnerCount: 2, DistributionType: "equal", RecipientType: "all", PIN: "000000"}
+	if _, err := svc.Gifts.Create(ctx, gift); !errors.Is(err, utils.ErrPINMismatch) {
+		t.Errorf("gift with wrong pin gave %v", err)
+	}
+	gift.ExpiresInHours = 1000
+	var v *utils.PolicyViolation
+	if _, err := svc.Gifts.Create(ctx, gift); !errors.As(err, &v) || v.Rule != "expiry" {
+		t.Errorf("gift beyond max expiry gave %v", err)
+	}
+	if pin.calls != 1 {
+		t.Errorf("pin checked %d times, want 1", pin.calls)
+	}
+
+	if _, err := svc.Gifts.Redeem(ctx, 1, "  "); !errors.Is(err, ErrGiftCodeRequired) {
+		t.Errorf("empty code gave %v", err)
+	}
+	if _, err := svc.Transfers.Transfer(ctx, TransferRequest{SenderID: 1, Number: "abc", Amount: 1}); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("bad number gave %v", err)
+	}
+	if _, err := svc.Transfers.Transfer(ctx, TransferRequest{SenderID: 1, Number: "0812", Amount: -5}); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("negative amount gave %v", err)
+	}
+}
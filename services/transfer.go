@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransferMinVIP is the VIP level required to send or look up transfers.
+const TransferMinVIP = 3
+
+var (
+	ErrTransferNotEligible = errors.New("transfer requires higher vip level")
+	ErrInvalidNumber       = errors.New("invalid phone number")
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrReceiverNotFound    = errors.New("receiver not found")
+	ErrSelfTransfer        = errors.New("cannot transfer to self")
+)
+
+// TransferRequest moves Amount from SenderID to the user registered with Number.
+type TransferRequest struct {
+	SenderID uint
+	Number   string
+	Amount   float64
+	PIN      string
+}
+
+// TransferResult is the completed transfer. HoldUntil is set when the receiver cannot
+// withdraw the funds yet.
+type TransferResult struct {
+	Receiver  models.User
+	Amount    float64
+	HoldUntil *time.Time
+}
+
+type TransferService interface {
+	// Inquiry resolves a phone number to the receiving user.
+	Inquiry(ctx context.Context, senderID uint, number string) (*models.User, error)
+	Transfer(ctx context.Context, req TransferRequest) (*TransferResult, error)
+	// Contacts lists the users the sender has transferred to, most recent first.
+	Contacts(ctx context.Context, senderID uint) ([]models.User, error)
+}
+
+type transferService struct {
+	Deps
+}
+
+func NewTransferService(d Deps) TransferService {
+	return &transferService{Deps: d.withDefaults()}
+}
+
+// NormalizePhoneNumber converts 0812241231, +62812241231, 62812241231 to 812241231
+func NormalizePhoneNumber(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "+", "")
+	s = strings.ReplaceAll(s, " ", "")
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	s = b.String()
+	s = strings.TrimPrefix(s, "0")
+	s = strings.TrimPrefix(s, "62")
+	return s
+}
+
+func (s *transferService) eligibleSender(db *gorm.DB, senderID uint, columns ...string) (*models.User, error) {
+	var user models.User
+	q := db
+	if len(columns) > 0 {
+		q = q.Select(columns)
+	}
+	if err := q.First(&user, senderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if userLevel(&user) < TransferMinVIP {
+		return nil, ErrTransferNotEligible
+	}
+	return &user, nil
+}
+
+func (s *transferService) Inquiry(ctx context.Context, senderID uint, number string) (*models.User, error) {
+	db := s.DB.WithContext(ctx)
+	if _, err := s.eligibleSender(db, senderID, "level"); err != nil {
+		return nil, err
+	}
+	normalized := NormalizePhoneNumber(number)
+	if normalized == "" {
+		return nil, ErrInvalidNumber
+	}
+	var receiver models.User
+	if err := db.Select("id, name, number, profile").Where("number = ?", normalized).First(&receiver).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReceiverNotFound
+		}
+		return nil, err
+	}
+	if receiver.ID == senderID {
+		return nil, ErrSelfTransfer
+	}
+	return &receiver, nil
+}
+
+// transferHold decides how long the receiver's funds stay on hold. The receiver's VIP
+// limit sets the base hold; a matched velocity rule either blocks the transfer or
+// extends the hold.
+func transferHold(limit models.TransferLimit, hit *utils.VelocityHit, now time.Time) (holdUntil *time.Time, reason *string, violation *utils.PolicyViolation) {
+	holdHours := limit.HoldHours
+	if holdHours > 0 {
+		r := "transfer_hold"
+		reason = &r
+	}
+	if hit != nil {
+		if hit.Rule.Action != "hold" {
+			return nil, nil, &utils.PolicyViolation{Rule: "velocity", Message: "Transfer ke penerima ini sementara tidak dapat diproses, silakan coba lagi nanti"}
+		}
+		if hit.Rule.HoldHours > holdHours {
+			holdHours = hit.Rule.HoldHours
+		}
+		r := "velocity:" + hit.Rule.Name
+		reason = &r
+	}
+	if holdHours > 0 {
+		until := now.Add(time.Duration(holdHours) * time.Hour)
+		holdUntil = &until
+	}
+	return holdUntil, reason, nil
+}
+
+func (s *transferService) Transfer(ctx context.Context, req TransferRequest) (*TransferResult, error) {
+	normalized := NormalizePhoneNumber(req.Number)
+	if normalized == "" {
+		return nil, ErrInvalidNumber
+	}
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	db := s.DB.WithContext(ctx)
+	sender, err := s.eligibleSender(db, req.SenderID)
+	if err != nil {
+		return nil, err
+	}
+	var receiver models.User
+	if err := db.First(&receiver, "number = ?", normalized).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReceiverNotFound
+		}
+		return nil, err
+	}
+	if receiver.ID == sender.ID {
+		return nil, ErrSelfTransfer
+	}
+
+	if err := verifyPIN(ctx, s.PIN, sender.ID, req.PIN, s.Clock.Now()); err != nil {
+		return nil, err
+	}
+
+	uid := sender.ID
+	var holdUntil *time.Time
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Lock sender and receiver in id order so opposite transfers cannot deadlock
+		var lockedSender, lockedReceiver models.User
+		first, second := &lockedSender, &lockedReceiver
+		firstID, secondID := uid, receiver.ID
+		if receiver.ID < uid {
+			first, second = second, first
+			firstID, secondID = secondID, firstID
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(first, firstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(second, secondID).Error; err != nil {
+			return err
+		}
+
+		now := s.Clock.Now()
+
+		// Limits are evaluated under the row locks so concurrent transfers see each other's totals
+		senderLimit, err := utils.LoadTransferLimit(tx, userLevel(sender))
+		if err != nil {
+			return err
+		}
+		receiverLimit, err := utils.LoadTransferLimit(tx, userLevel(&lockedReceiver))
+		if err != nil {
+			return err
+		}
+		usage, err := utils.LoadTransferUsage(tx, uid, receiver.ID, now)
+		if err != nil {
+			return err
+		}
+		if v := utils.CheckTransferLimits(utils.TransferCheck{
+			Amount:        req.Amount,
+			SenderLimit:   senderLimit,
+			ReceiverLimit: receiverLimit,
+			Usage:         usage,
+		}); v != nil {
+			return v
+		}
+
+		var velocityRules []models.TransferVelocityRule
+		if err := tx.Where("status = ?", "Active").Find(&velocityRules).Error; err != nil {
+			return err
+		}
+		hit, err := utils.CheckTransferVelocity(velocityRules, req.Amount, now, func(since time.Time, smallMax float64) (int64, error) {
+			q := tx.Model(&models.Transfer{}).
+				Where("receiver_id = ? AND sender_id <> ? AND created_at >= ?", receiver.ID, uid, since)
+			if smallMax > 0 {
+				q = q.Where("amount <= ?", smallMax)
+			}
+			var n int64
+			err := q.Distinct("sender_id").Count(&n).Error
+			return n, err
+		})
+		if err != nil {
+			return err
+		}
+		if hit != nil {
+			log.Printf("[transfer] velocity rule %q matched: sender=%d receiver=%d amount=%.2f action=%s", hit.Rule.Name, uid, receiver.ID, req.Amount, hit.Rule.Action)
+		}
+		var holdReason *string
+		var v *utils.PolicyViolation
+		holdUntil, holdReason, v = transferHold(receiverLimit, hit, now)
+		if v != nil {
+			return v
+		}
+
+		if lockedSender.Balance < req.Amount {
+			return ErrInsufficientBalance
+		}
+		held, err := utils.HeldTransferAmount(tx, uid, now)
+		if err != nil {
+			return err
+		}
+		if lockedSender.Balance-held < req.Amount {
+			return ErrFundsOnHold
+		}
+
+		if err := tx.Model(&lockedSender).Update("balance", round2(lockedSender.Balance-req.Amount)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&lockedReceiver).Update("balance", round2(lockedReceiver.Balance+req.Amount)).Error; err != nil {
+			return err
+		}
+
+		// Sender side: credit, type transfer
+		orderID := utils.GenerateOrderID(uid)
+		msgSender := fmt.Sprintf("Transfer Uang ke %s", lockedReceiver.Name)
+		if err := tx.Create(&models.Transaction{
+			UserID:          uid,
+			Amount:          req.Amount,
+			OrderID:         orderID,
+			TransactionFlow: "credit",
+			TransactionType: "transfer",
+			Message:         &msgSender,
+			Status:          "Success",
+		}).Error; err != nil {
+			return err
+		}
+
+		// Receiver side: debit, type receive
+		orderIDReceiver := utils.GenerateOrderID(receiver.ID)
+		msgReceiver := fmt.Sprintf("Terima Uang dari %s", lockedSender.Name)
+		if err := tx.Create(&models.Transaction{
+			UserID:          receiver.ID,
+			Amount:          req.Amount,
+			OrderID:         orderIDReceiver,
+			TransactionFlow: "debit",
+			TransactionType: "receive",
+			Message:         &msgReceiver,
+			Status:          "Success",
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.Transfer{
+			SenderID:        uid,
+			ReceiverID:      receiver.ID,
+			Amount:          req.Amount,
+			SenderOrderID:   orderID,
+			ReceiverOrderID: orderIDReceiver,
+			HoldUntil:       holdUntil,
+			HoldReason:      holdReason,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Save transfer contact for history
+		var contact models.TransferContact
+		if err := tx.Where("sender_id = ? AND receiver_id = ?", uid, receiver.ID).First(&contact).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				tx.Create(&models.TransferContact{SenderID: uid, ReceiverID: receiver.ID})
+			}
+		} else {
+			tx.Model(&contact).Updates(map[string]interface{}{"updated_at": now})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TransferResult{Receiver: receiver, Amount: req.Amount, HoldUntil: holdUntil}, nil
+}
+
+func (s *transferService) Contacts(ctx context.Context, senderID uint) ([]models.User, error) {
+	db := s.DB.WithContext(ctx)
+	if _, err := s.eligibleSender(db, senderID, "level"); err != nil {
+		return nil, err
+	}
+
+	var contacts []models.TransferContact
+	if err := db.Where("sender_id = ?", senderID).Order("updated_at DESC").Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return nil, nil
+	}
+	receiverIDs := make([]uint, 0, len(contacts))
+	seen := make(map[uint]struct{})
+	for _, c := range contacts {
+		if _, ok := seen[c.ReceiverID]; !ok {
+			seen[c.ReceiverID] = struct{}{}
+			receiverIDs = append(receiverIDs, c.ReceiverID)
+		}
+	}
+
+	var users []models.User
+	if err := db.Select("id, name, number, profile").Where("id IN ?", receiverIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	// Keep the most-recent-first order of the contacts
+	out := make([]models.User, 0, len(receiverIDs))
+	for _, id := range receiverIDs {
+		if u, ok := byID[id]; ok {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"project/metrics"
+	"project/models"
+	"project/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBankAccountNotFound = errors.New("bank account not found")
+	ErrBankUnavailable     = errors.New("bank under maintenance")
+)
+
+// WithdrawalRequest asks to move Amount from the user's balance to one of their bank
+// accounts.
+type WithdrawalRequest struct {
+	UserID        uint
+	BankAccountID uint
+	Amount        float64
+	PIN           string
+}
+
+// WithdrawalResult is the created withdrawal and the account it is paid to.
+type WithdrawalResult struct {
+	Withdrawal   models.Withdrawal
+	Account      models.BankAccount
+	Promotor     bool
+	AutoWithdraw bool
+}
+
+type WithdrawalService interface {
+	// Request debits the balance and records a Pending withdrawal (Success for promotor
+	// accounts). With auto_withdraw enabled the payout is sent right away.
+	Request(ctx context.Context, req WithdrawalRequest) (*WithdrawalResult, error)
+}
+
+type withdrawalService struct {
+	Deps
+}
+
+func NewWithdrawalService(d Deps) WithdrawalService {
+	return &withdrawalService{Deps: d.withDefaults()}
+}
+
+// checkAccountStatus allows only active accounts to move money out.
+func checkAccountStatus(u *models.User) error {
+	switch strings.ToLower(u.Status) {
+	case "active":
+		return nil
+	case "suspend":
+		return ErrAccountSuspended
+	}
+	return ErrAccountInactive
+}
+
+// withdrawalCharge returns the fee and the amount actually paid out.
+func withdrawalCharge(amount, chargePercent float64) (charge, final float64) {
+	charge = round2(amount * (chargePercent / 100.0))
+	return charge, amount - charge
+}
+
+func (s *withdrawalService) Request(ctx context.Context, req WithdrawalRequest) (*WithdrawalResult, error) {
+	db := s.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := checkAccountStatus(&user); err != nil {
+		return nil, err
+	}
+	isPromotor := user.UserMode == "promotor"
+
+	setting, err := s.Settings.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acc models.BankAccount
+	if err := db.Preload("Bank").Where("id = ? AND user_id = ?", req.BankAccountID, req.UserID).First(&acc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBankAccountNotFound
+		}
+		return nil, err
+	}
+	if acc.Bank == nil || acc.Bank.Status != "Active" {
+		return nil, ErrBankUnavailable
+	}
+
+	policy, err := utils.LoadWithdrawalPolicy(db, setting)
+	if err != nil {
+		return nil, err
+	}
+	policy.Clock = s.Clock
+	startOfDay, endOfDay := policy.DayBounds()
+	bankChangedAt := acc.UpdatedAt
+	if acc.CreatedAt.After(bankChangedAt) {
+		bankChangedAt = acc.CreatedAt
+	}
+
+	if err := verifyPIN(ctx, s.PIN, req.UserID, req.PIN, s.Clock.Now()); err != nil {
+		return nil, err
+	}
+
+	charge, finalAmount := withdrawalCharge(req.Amount, setting.WithdrawCharge)
+	orderID := utils.GenerateOrderID(req.UserID)
+	// Promotor accounts are not paid out, so they complete immediately
+	status := "Pending"
+	if isPromotor {
+		status = "Success"
+	}
+	// Sent to the gateway below; marked up front so no partner can claim it meanwhile
+	autoPayout := !isPromotor && setting.AutoWithdraw
+	var dispatchedAt *time.Time
+	if autoPayout {
+		now := s.Clock.Now()
+		dispatchedAt = &now
+	}
+
+	var wd models.Withdrawal
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, req.UserID).Error; err != nil {
+			return err
+		}
+
+		// Amount limits, operating hours, closed days, daily caps, cooling-off. Today's
+		// usage is read under the user lock so concurrent requests cannot all pass the caps.
+		var usage utils.WithdrawalUsage
+		if err := tx.Model(&models.Withdrawal{}).
+			Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+			Where("user_id = ? AND created_at >= ? AND created_at < ?", req.UserID, startOfDay, endOfDay).
+			Scan(&usage).Error; err != nil {
+			return err
+		}
+		if v := policy.Evaluate(utils.WithdrawalCheck{
+			VIPLevel:             userLevel(&locked),
+			Amount:               req.Amount,
+			Today:                usage,
+			BankAccountChangedAt: bankChangedAt,
+			KYCVerified:          locked.KYCStatus == utils.KYCStatusVerified,
+		}); v != nil {
+			return v
+		}
+
+		if locked.Balance < req.Amount {
+			return ErrInsufficientBalance
+		}
+		// Funds received by transfer stay on hold for the configured period
+		held, err := utils.HeldTransferAmount(tx, req.UserID, s.Clock.Now())
+		if err != nil {
+			return err
+		}
+		if locked.Balance-held < req.Amount {
+			return ErrFundsOnHold
+		}
+		if err := tx.Model(&locked).Update("balance", round2(locked.Balance-req.Amount)).Error; err != nil {
+			return err
+		}
+
+		wd = models.Withdrawal{
+			UserID:        req.UserID,
+			BankAccountID: acc.ID,
+			Amount:        req.Amount,
+			Charge:        charge,
+			FinalAmount:   finalAmount,
+			OrderID:       orderID,
+			Status:        status,
+
+			GatewayDispatchedAt: dispatchedAt,
+		}
+		if err := tx.Create(&wd).Error; err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Penarikan ke %s %s", acc.Bank.Name, MaskAccountNumber(acc.AccountNumber))
+		return tx.Create(&models.Transaction{
+			UserID:          req.UserID,
+			Amount:          req.Amount,
+			Charge:          charge,
+			OrderID:         orderID,
+			TransactionFlow: "credit",
+			TransactionType: "withdrawal",
+			Message:         &msg,
+			Status:          status,
+		}).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	if autoPayout {
+		// An accepted payout stays Pending; the gateway callback settles it
+		if err := s.Payouts.Payout(ctx, PayoutRequest{
+			OrderID:       wd.OrderID,
+			AccountNumber: acc.AccountNumber,
+			Code:          acc.Bank.Code,
+			EWallet:       isEWallet(acc.Bank.Type),
+			Amount:        int64(wd.FinalAmount),
+		}); err != nil {
+			log.Printf("[Pakailink] User payout error: %v", err)
+			metrics.PayoutFailures.WithLabelValues("pakailink", "request").Inc()
+			if err := utils.ClearGatewayDispatch(db, wd.ID); err != nil {
+				log.Printf("[Pakailink] Clear gateway dispatch for %s: %v", wd.OrderID, err)
+			}
+		}
+	}
+
+	// The payout callback may already have settled it
+	db.First(&wd, wd.ID)
+	return &WithdrawalResult{Withdrawal: wd, Account: acc, Promotor: isPromotor, AutoWithdraw: setting.AutoWithdraw}, nil
+}
+
+// MaskAccountNumber keeps the first and last four digits of an account number.
+func MaskAccountNumber(accountNumber string) string {
+	if len(accountNumber) <= 6 {
+		return accountNumber
+	}
+	return accountNumber[:4] + "****" + accountNumber[len(accountNumber)-4:]
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAccountClosed             = errors.New("account already closed")
+	ErrAccountBalanceNotZero     = errors.New("account balance is not zero")
+	ErrAccountRunningInvestments = errors.New("account has running investments")
+	ErrAccountPendingWithdrawals = errors.New("account has pending withdrawals")
+	ErrAccountActiveGifts        = errors.New("account has active gifts")
+	ErrAccountInactive           = errors.New("account is inactive")
+)
+
+// CheckUserAccess reports whether userID may still use its access tokens. App tokens
+// live up to 30 days, so the auth middleware asks on every request instead of trusting
+// the token: closed (ErrAccountClosed) and deactivated (ErrAccountInactive) accounts
+// are refused. An unknown user gives gorm.ErrRecordNotFound.
+func CheckUserAccess(db *gorm.DB, userID uint) error {
+	var u struct {
+		Status   string
+		ClosedAt *time.Time
+	}
+	if err := db.Model(&models.User{}).Select("status, closed_at").Where("id = ?", userID).Take(&u).Error; err != nil {
+		return err
+	}
+	return userAccessError(u.Status, u.ClosedAt)
+}
+
+func userAccessError(status string, closedAt *time.Time) error {
+	if closedAt != nil {
+		return ErrAccountClosed
+	}
+	if strings.EqualFold(status, "inactive") {
+		return ErrAccountInactive
+	}
+	return nil
+}
+
+// ClosedUserName replaces the name of a closed account.
+const ClosedUserName = "Pengguna Terhapus"
+
+// ChatSessionExport is a live chat session with its messages.
+type ChatSessionExport struct {
+	models.ChatSession
+	Messages []models.ChatMessage `json:"messages"`
+}
+
+// UserDataExport is everything we hold about a user, as handed out on a data request.
+type UserDataExport struct {
+	GeneratedAt    time.Time                  `json:"generated_at"`
+	Profile        models.User                `json:"profile"`
+	BankAccounts   []models.BankAccount       `json:"bank_accounts"`
+	Investments    []models.Investment        `json:"investments"`
+	Transactions   []models.Transaction       `json:"transactions"`
+	Withdrawals    []models.Withdrawal        `json:"withdrawals"`
+	Transfers      []models.Transfer          `json:"transfers"`
+	GiftsSent      []models.Gift              `json:"gifts_sent"`
+	GiftsClaimed   []models.GiftClaim         `json:"gifts_claimed"`
+	Spins          []models.UserSpin          `json:"spins"`
+	ForumPosts     []models.Forum             `json:"forum_posts"`
+	KYCSubmissions []models.KYCSubmission     `json:"kyc_submissions"`
+	ChatHistory    []ChatSessionExport        `json:"chat_history"`
+	Registration   *models.RegistrationSignal `json:"registration,omitempty"`
+}
+
+// BuildUserDataExport collects the user's data from every table that references them.
+func BuildUserDataExport(db *gorm.DB, userID uint, now time.Time) (*UserDataExport, error) {
+	out := &UserDataExport{GeneratedAt: now}
+	if err := db.First(&out.Profile, userID).Error; err != nil {
+		return nil, err
+	}
+
+	byUser := []struct {
+		dst   interface{}
+		query *gorm.DB
+	}{
+		{&out.BankAccounts, db.Preload("Bank").Where("user_id = ?", userID)},
+		{&out.Investments, db.Where("user_id = ?", userID)},
+		{&out.Transactions, db.Where("user_id = ?", userID)},
+		{&out.Withdrawals, db.Where("user_id = ?", userID)},
+		{&out.Transfers, db.Where("sender_id = ? OR receiver_id = ?", userID, userID)},
+		{&out.GiftsSent, db.Where("user_id = ?", userID)},
+		{&out.GiftsClaimed, db.Where("user_id = ?", userID)},
+		{&out.Spins, db.Where("user_id = ?", userID)},
+		{&out.ForumPosts, db.Where("user_id = ?", userID)},
+		{&out.KYCSubmissions, db.Where("user_id = ?", userID)},
+	}
+	for _, q := range byUser {
+		if err := q.query.Order("id ASC").Find(q.dst).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var sessions []models.ChatSession
+	if err := db.Preload("Messages", func(tx *gorm.DB) *gorm.DB { return tx.Order("id ASC") }).
+		Where("user_id = ?", userID).Order("id ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	out.ChatHistory = make([]ChatSessionExport, 0, len(sessions))
+	for _, s := range sessions {
+		messages := s.Messages
+		s.Messages = nil
+		out.ChatHistory = append(out.ChatHistory, ChatSessionExport{ChatSession: s, Messages: messages})
+	}
+
+	var signal models.RegistrationSignal
+	if err := db.Where("user_id = ?", userID).First(&signal).Error; err == nil {
+		out.Registration = &signal
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WriteUserDataZip writes the export as a ZIP with one JSON file per section.
+func WriteUserDataZip(w io.Writer, export *UserDataExport) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"bank_accounts.json", export.BankAccounts},
+		{"investments.json", export.Investments},
+		{"transactions.json", export.Transactions},
+		{"withdrawals.json", export.Withdrawals},
+		{"transfers.json", export.Transfers},
+		{"gifts_sent.json", export.GiftsSent},
+		{"gifts_claimed.json", export.GiftsClaimed},
+		{"spins.json", export.Spins},
+		{"forum_posts.json", export.ForumPosts},
+		{"kyc_submissions.json", export.KYCSubmissions},
+		{"chat_history.json", export.ChatHistory},
+		{"registration.json", export.Registration},
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: export.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// CloseAccount closes an account once nothing of value is left in it. Financial records
+// (transactions, withdrawals, investments, transfers, gifts, spins) are kept for
+// accounting; personal data is anonymized or deleted. Returns the private object keys
+// (profile picture, forum images, KYC documents) to delete after the commit.
+func CloseAccount(tx *gorm.DB, userID uint, now time.Time) ([]string, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.ClosedAt != nil {
+		return nil, ErrAccountClosed
+	}
+	if math.Abs(user.Balance) >= 0.01 {
+		return nil, ErrAccountBalanceNotZero
+	}
+
+	blockers := []struct {
+		model interface{}
+		where string
+		err   error
+	}{
+		{&models.Investment{}, "user_id = ? AND status IN ('Pending','Running')", ErrAccountRunningInvestments},
+		{&models.Withdrawal{}, "user_id = ? AND status = 'Pending'", ErrAccountPendingWithdrawals},
+		{&models.Gift{}, "user_id = ? AND status = 'active'", ErrAccountActiveGifts},
+	}
+	for _, b := range blockers {
+		var count int64
+		if err := tx.Model(b.model).Where(b.where, userID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, b.err
+		}
+	}
+
+	var objects []string
+	if user.Profile != nil && *user.Profile != "" {
+		objects = append(objects, *user.Profile)
+	}
+	var forumImages []string
+	if err := tx.Model(&models.Forum{}).Where("user_id = ? AND image <> ''", userID).Pluck("image", &forumImages).Error; err != nil {
+		return nil, err
+	}
+	objects = append(objects, forumImages...)
+	var kyc []models.KYCSubmission
+	if err := tx.Where("user_id = ?", userID).Find(&kyc).Error; err != nil {
+		return nil, err
+	}
+	for _, k := range kyc {
+		for _, key := range []string{k.IDCardKey, k.SelfieKey} {
+			if key != "" {
+				objects = append(objects, key)
+			}
+		}
+	}
+
+	// The password hash is replaced by a value bcrypt never matches
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"name":             ClosedUserName,
+		"number":           fmt.Sprintf("closed-%d", userID),
+		"password":         "!",
+		"reff_code":        fmt.Sprintf("CLOSED%d", userID),
+		"profile":          nil,
+		"status":           "Inactive",
+		"status_publisher": "Inactive",
+		"closed_at":        now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.BankAccount{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"account_name":   ClosedUserName,
+		"account_number": gorm.Expr("CONCAT('****', RIGHT(account_number, 4))"),
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.KYCSubmission{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"full_name":   ClosedUserName,
+		"id_number":   gorm.Expr("CONCAT(LEFT(id_number, 6), '******', RIGHT(id_number, 4))"),
+		"id_card_key": "",
+		"selfie_key":  "",
+	}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Forum{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"description": "[dihapus]",
+		"image":       "",
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	var sessionIDs []uint
+	if err := tx.Model(&models.ChatSession{}).Where("user_id = ?", userID).Pluck("id", &sessionIDs).Error; err != nil {
+		return nil, err
+	}
+	if len(sessionIDs) > 0 {
+		if err := tx.Where("session_id IN ?", sessionIDs).Delete(&models.ChatMessage{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.ChatSession{}).Where("id IN ?", sessionIDs).Update("user_name", ClosedUserName).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	deletes := []struct {
+		model interface{}
+		where string
+		args  []interface{}
+	}{
+		{&models.RegistrationSignal{}, "user_id = ?", []interface{}{userID}},
+		{&models.UserPIN{}, "user_id = ?", []interface{}{userID}},
+		{&models.RefreshToken{}, "user_id = ?", []interface{}{userID}},
+		{&models.TransferContact{}, "sender_id = ? OR receiver_id = ?", []interface{}{userID, userID}},
+	}
+	for _, d := range deletes {
+		if err := tx.Where(d.where, d.args...).Delete(d.model).Error; err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestWriteUserDataZip(t *testing.T) {
+	export := &UserDataExport{
+		GeneratedAt:  time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC),
+		Profile:      models.User{ID: 7, Name: "Budi", Number: "81234567890", Password: "hash"},
+		Transactions: []models.Transaction{{ID: 1, UserID: 7, Amount: 2000, Status: "Success"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteUserDataZip(&buf, export); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"profile.json", "transactions.json", "chat_history.json", "forum_posts.json"} {
+		if files[name] == nil {
+			t.Fatalf("missing %s in export", name)
+		}
+	}
+
+	rc, err := files["profile.json"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	var profile map[string]interface{}
+	if err := json.NewDecoder(rc).Decode(&profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["name"] != "Budi" {
+		t.Fatalf("unexpected profile %v", profile)
+	}
+	if _, leaked := profile["password"]; leaked {
+		t.Fatal("password hash must not be exported")
+	}
+}
+
+func TestUserAccessError(t *testing.T) {
+	closed := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		status   string
+		closedAt *time.Time
+		want     error
+	}{
+		{"Active", nil, nil},
+		{"Suspend", nil, nil},
+		{"Inactive", nil, ErrAccountInactive},
+		{"Inactive", &closed, ErrAccountClosed},
+	}
+	for _, c := range cases {
+		if got := userAccessError(c.status, c.closedAt); got != c.want {
+			t.Errorf("%s closed=%v: got %v, want %v", c.status, c.closedAt != nil, got, c.want)
+		}
+	}
+}
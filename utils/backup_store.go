@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"io"
+
+	"project/database"
+)
+
+// R2BackupStore keeps database backups in the R2 bucket used for uploads.
+type R2BackupStore struct{}
+
+func (R2BackupStore) Put(key string, r io.ReadSeeker, size int64) error {
+	return UploadToS3(key, r, size)
+}
+
+func (R2BackupStore) Get(key string) (io.ReadCloser, error) {
+	return DownloadFromS3(key)
+}
+
+func (R2BackupStore) List(prefix string) ([]database.StoredObject, error) {
+	objects, err := ListS3Objects(prefix)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]database.StoredObject, len(objects))
+	for i, o := range objects {
+		out[i] = database.StoredObject{Key: o.Key, Size: o.Size, LastModified: o.LastModified}
+	}
+	return out, nil
+}
+
+func (R2BackupStore) Delete(key string) error {
+	return DeleteFromS3(key)
+}
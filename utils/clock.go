@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// Clock abstracts time.Now so time-dependent business rules can be tested
+// with a fixed or controllable time source.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the clock the application reads: the wall clock in production.
+// End-to-end tests replace it with a clock they can move forward.
+var SystemClock Clock = systemClock{}
+
+// FixedClock always returns the same instant.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+var jakartaLoc = loadJakarta()
+
+func loadJakarta() *time.Location {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		// tzdata may be missing in minimal containers; WIB has no DST
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return loc
+}
+
+// JakartaLocation returns the Asia/Jakarta (WIB) location used for business-day rules.
+func JakartaLocation() *time.Location {
+	return jakartaLoc
+}
+
+// StartOfDay returns midnight of t's calendar day in loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// ParseDate parses a YYYY-MM-DD calendar date for DATE columns. The value is
+// anchored at midnight in time.Local, which is the zone the MySQL driver uses
+// (loc=Local), so the stored date is not shifted by a zone conversion.
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", strings.TrimSpace(s), time.Local)
+}
+
+// DateKey formats the calendar date of t as YYYY-MM-DD.
+func DateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
@@ -6,8 +6,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
+
+	"project/config"
 )
 
 const FazpassBaseURL = "https://api.fazpass.com"
@@ -189,8 +190,8 @@ type FazpassOTPVerifyResponse struct {
 
 // RequestOTP requests OTP from Fazpass API
 func RequestOTP(phone string) (*FazpassOTPResponse, error) {
-	merchantKey := os.Getenv("FAZPASS_MERCHANT_KEY")
-	gatewayKey := os.Getenv("FAZPASS_GATEWAY_KEY")
+	fazpass := config.Get().Fazpass
+	merchantKey, gatewayKey := fazpass.MerchantKey, fazpass.GatewayKey
 
 	if merchantKey == "" || gatewayKey == "" {
 		return nil, fmt.Errorf("FAZPASS_MERCHANT_KEY or FAZPASS_GATEWAY_KEY not set")
@@ -248,7 +249,7 @@ func RequestOTP(phone string) (*FazpassOTPResponse, error) {
 
 // VerifyOTP verifies OTP with Fazpass API
 func VerifyOTP(otpID, otp string) (*FazpassOTPVerifyResponse, error) {
-	merchantKey := os.Getenv("FAZPASS_MERCHANT_KEY")
+	merchantKey := config.Get().Fazpass.MerchantKey
 
 	if merchantKey == "" {
 		return nil, fmt.Errorf("FAZPASS_MERCHANT_KEY not set")
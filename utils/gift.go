@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	GiftMinExpiryHours = 1
+
+	defaultGiftExpiryHours = 24
+	defaultGiftMaxHours    = 168
+)
+
+var ErrGiftNotActive = errors.New("gift is not active")
+
+// GiftExpiry resolves the expiry for a new gift. requestedHours 0 means the settings default;
+// anything outside [GiftMinExpiryHours, max] is rejected.
+func GiftExpiry(setting *models.Setting, requestedHours int, now time.Time) (time.Time, error) {
+	def, max := defaultGiftExpiryHours, defaultGiftMaxHours
+	if setting != nil {
+		if setting.GiftMaxExpiryHours > 0 {
+			max = setting.GiftMaxExpiryHours
+		}
+		if setting.GiftDefaultExpiryHours > 0 {
+			def = setting.GiftDefaultExpiryHours
+		}
+	}
+	if def > max {
+		def = max
+	}
+
+	hours := requestedHours
+	if hours == 0 {
+		hours = def
+	}
+	if hours < GiftMinExpiryHours || hours > max {
+		return time.Time{}, fmt.Errorf("Masa berlaku hadiah harus antara %d dan %d jam", GiftMinExpiryHours, max)
+	}
+	return now.Add(time.Duration(hours) * time.Hour), nil
+}
+
+// GiftRefund is the result of closing a gift and returning its unclaimed amount.
+type GiftRefund struct {
+	Amount  float64
+	OrderID string
+}
+
+// RefundGift closes an active gift with the given status ("expired" or "cancelled") and
+// credits the unclaimed amount back to the sender with a linked refund transaction.
+// It locks the gift row, so it must run inside the caller's transaction.
+func RefundGift(tx *gorm.DB, giftID uint, status string) (*GiftRefund, error) {
+	var gift models.Gift
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&gift, giftID).Error; err != nil {
+		return nil, err
+	}
+	if gift.Status != "active" {
+		return nil, ErrGiftNotActive
+	}
+
+	var claims []models.GiftClaim
+	if err := tx.Where("gift_id = ?", gift.ID).Find(&claims).Error; err != nil {
+		return nil, err
+	}
+
+	var unclaimed float64
+	if gift.DistributionType == "random" {
+		claimed := make(map[int]bool, len(claims))
+		for _, c := range claims {
+			claimed[c.SlotIndex] = true
+		}
+		var slots []models.GiftAmountSlot
+		if err := tx.Where("gift_id = ?", gift.ID).Find(&slots).Error; err != nil {
+			return nil, err
+		}
+		for _, s := range slots {
+			if !claimed[s.SlotIndex] {
+				unclaimed += s.Amount
+			}
+		}
+	} else {
+		remaining := gift.WinnerCount - len(claims)
+		if remaining > 0 {
+			unclaimed = gift.Amount * float64(remaining)
+		}
+	}
+	unclaimed = RoundFloat(unclaimed, 2)
+
+	updates := map[string]interface{}{"status": status}
+	refund := &GiftRefund{Amount: unclaimed}
+	if unclaimed > 0 {
+		if err := tx.Model(&models.User{}).Where("id = ?", gift.UserID).
+			Update("balance", gorm.Expr("balance + ?", unclaimed)).Error; err != nil {
+			return nil, err
+		}
+
+		refund.OrderID = GenerateOrderID(gift.UserID)
+		msg := "Refund hadiah kedaluwarsa - " + gift.Code
+		if status == "cancelled" {
+			msg = "Refund hadiah dibatalkan - " + gift.Code
+		}
+		trx := models.Transaction{
+			UserID:          gift.UserID,
+			Amount:          unclaimed,
+			Charge:          0,
+			OrderID:         refund.OrderID,
+			TransactionFlow: "debit",
+			TransactionType: "refund",
+			Message:         &msg,
+			Status:          "Success",
+		}
+		if err := tx.Create(&trx).Error; err != nil {
+			return nil, err
+		}
+		updates["refunded_amount"] = unclaimed
+		updates["refund_order_id"] = refund.OrderID
+	}
+
+	if err := tx.Model(&gift).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"project/models"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGiftNotFound       = errors.New("gift not found")
+	ErrGiftExpired        = errors.New("gift expired")
+	ErrGiftOwn            = errors.New("cannot claim own gift")
+	ErrGiftReferralOnly   = errors.New("gift is for the sender's referrals only")
+	ErrGiftPromotor       = errors.New("promotor cannot claim gifts")
+	ErrGiftBonusFrozen    = errors.New("claimant bonuses are frozen")
+	ErrGiftAlreadyClaimed = errors.New("gift already claimed by user")
+	ErrGiftExhausted      = errors.New("all gift slots claimed")
+)
+
+// RedeemGift claims the next slot of the gift with the given code for claimant.
+//
+// Everything is decided under a row lock on the gift, and gift_claims carries unique keys
+// on (gift_id, user_id) and (gift_id, slot_index), so concurrent redemptions serialize per
+// gift and can never double-claim a slot or exceed WinnerCount.
+func RedeemGift(db *gorm.DB, code string, claimant models.User, now time.Time) (*models.GiftClaim, error) {
+	var claim models.GiftClaim
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var gift models.Gift
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&gift).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGiftNotFound
+			}
+			return err
+		}
+		if gift.Status != "active" {
+			return ErrGiftNotActive
+		}
+		if gift.ExpiresAt != nil && !now.Before(*gift.ExpiresAt) {
+			return ErrGiftExpired
+		}
+		if gift.UserID == claimant.ID {
+			return ErrGiftOwn
+		}
+		if gift.RecipientType == "referral_only" && (claimant.ReffBy == nil || *claimant.ReffBy != gift.UserID) {
+			return ErrGiftReferralOnly
+		}
+		if strings.ToLower(claimant.UserMode) == "promotor" {
+			return ErrGiftPromotor
+		}
+		if claimant.BonusFrozen {
+			return ErrGiftBonusFrozen
+		}
+
+		var claims []models.GiftClaim
+		if err := tx.Select("user_id", "slot_index").Where("gift_id = ?", gift.ID).Find(&claims).Error; err != nil {
+			return err
+		}
+		taken := make(map[int]bool, len(claims))
+		for _, c := range claims {
+			if c.UserID == claimant.ID {
+				return ErrGiftAlreadyClaimed
+			}
+			taken[c.SlotIndex] = true
+		}
+		if len(claims) >= gift.WinnerCount {
+			return ErrGiftExhausted
+		}
+
+		// Pick the lowest free slot
+		slotIndex := -1
+		amount := gift.Amount
+		if gift.DistributionType == "random" {
+			var slots []models.GiftAmountSlot
+			if err := tx.Where("gift_id = ?", gift.ID).Order("slot_index").Find(&slots).Error; err != nil {
+				return err
+			}
+			for _, s := range slots {
+				if !taken[s.SlotIndex] {
+					slotIndex, amount = s.SlotIndex, s.Amount
+					break
+				}
+			}
+		} else {
+			for i := 0; i < gift.WinnerCount; i++ {
+				if !taken[i] {
+					slotIndex = i
+					break
+				}
+			}
+		}
+		if slotIndex < 0 {
+			return ErrGiftExhausted
+		}
+
+		claim = models.GiftClaim{GiftID: gift.ID, UserID: claimant.ID, Amount: amount, SlotIndex: slotIndex}
+		if err := tx.Create(&claim).Error; err != nil {
+			if isDuplicateKey(err) {
+				return ErrGiftAlreadyClaimed
+			}
+			return err
+		}
+		// Held as Pending while the claimant's bonuses are frozen (gift farming review)
+		if _, err := CreditBonus(tx, claimant.ID, amount, "bonus", fmt.Sprintf("Hadiah dari %s", gift.Code)); err != nil {
+			return err
+		}
+		if len(claims)+1 >= gift.WinnerCount {
+			return tx.Model(&gift).Update("status", "completed").Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+func isDuplicateKey(err error) bool {
+	var myErr *mysqldriver.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == 1062
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+)
+
+func TestRedeemGift_ConcurrentClaimsMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+
+	const winners = 5
+	const claimants = 40
+
+	sender := models.User{Name: "Sender", Number: "81000000000", Password: "x", ReffCode: "SENDER"}
+	if err := db.Create(&sender).Error; err != nil {
+		t.Fatal(err)
+	}
+	users := make([]models.User, claimants)
+	for i := range users {
+		users[i] = models.User{Name: fmt.Sprintf("User %d", i), Number: fmt.Sprintf("8100000%04d", i+1), Password: "x", ReffCode: fmt.Sprintf("U%04d", i)}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gift := models.Gift{UserID: sender.ID, Code: "RACE0001", Amount: 50000, WinnerCount: winners,
+		DistributionType: "random", RecipientType: "all", Status: "active", TotalDeducted: 50000}
+	if err := db.Create(&gift).Error; err != nil {
+		t.Fatal(err)
+	}
+	slotAmounts := []float64{5000, 15000, 10000, 12000, 8000}
+	for i, a := range slotAmounts {
+		if err := db.Create(&models.GiftAmountSlot{GiftID: gift.ID, SlotIndex: i, Amount: a}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every user tries twice at the same time
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := map[string]int{}
+	start := make(chan struct{})
+	for i := 0; i < claimants*2; i++ {
+		wg.Add(1)
+		go func(u models.User) {
+			defer wg.Done()
+			<-start
+			_, err := RedeemGift(db, gift.Code, u, time.Now())
+			key := "ok"
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrGiftExhausted), errors.Is(err, ErrGiftNotActive):
+				key = "exhausted"
+			case errors.Is(err, ErrGiftAlreadyClaimed):
+				key = "duplicate"
+			default:
+				key = "error: " + err.Error()
+			}
+			mu.Lock()
+			results[key]++
+			mu.Unlock()
+		}(users[i%claimants])
+	}
+	close(start)
+	wg.Wait()
+
+	if results["ok"] != winners {
+		t.Fatalf("expected %d successful claims, got %v", winners, results)
+	}
+	for k, n := range results {
+		if strings.HasPrefix(k, "error") {
+			t.Fatalf("unexpected error x%d: %s", n, k)
+		}
+	}
+
+	var claims []models.GiftClaim
+	db.Where("gift_id = ?", gift.ID).Find(&claims)
+	if len(claims) != winners {
+		t.Fatalf("expected %d claim rows, got %d", winners, len(claims))
+	}
+	seenUser, seenSlot := map[uint]bool{}, map[int]bool{}
+	total := 0.0
+	for _, c := range claims {
+		if seenUser[c.UserID] || seenSlot[c.SlotIndex] {
+			t.Fatalf("duplicate claim %+v", c)
+		}
+		seenUser[c.UserID], seenSlot[c.SlotIndex] = true, true
+		total += c.Amount
+	}
+	if total != gift.TotalDeducted {
+		t.Fatalf("claimed %.2f, expected %.2f", total, gift.TotalDeducted)
+	}
+
+	var balances float64
+	db.Model(&models.User{}).Where("id <> ?", sender.ID).Select("COALESCE(SUM(balance), 0)").Scan(&balances)
+	if balances != gift.TotalDeducted {
+		t.Fatalf("credited %.2f to claimants, expected %.2f", balances, gift.TotalDeducted)
+	}
+
+	var reloaded models.Gift
+	db.First(&reloaded, gift.ID)
+	if reloaded.Status != "completed" {
+		t.Fatalf("expected gift to be completed, got %s", reloaded.Status)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestGiftExpiry(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	setting := &models.Setting{GiftDefaultExpiryHours: 12, GiftMaxExpiryHours: 72}
+
+	got, err := GiftExpiry(setting, 0, now)
+	if err != nil || !got.Equal(now.Add(12*time.Hour)) {
+		t.Fatalf("expected settings default, got %v (err %v)", got, err)
+	}
+	got, err = GiftExpiry(setting, 48, now)
+	if err != nil || !got.Equal(now.Add(48*time.Hour)) {
+		t.Fatalf("expected requested expiry, got %v (err %v)", got, err)
+	}
+	if _, err := GiftExpiry(setting, 73, now); err == nil {
+		t.Fatalf("expected expiry above the maximum to be rejected")
+	}
+	if _, err := GiftExpiry(setting, -1, now); err == nil {
+		t.Fatalf("expected negative expiry to be rejected")
+	}
+
+	// Missing settings fall back to built-in bounds
+	got, err = GiftExpiry(&models.Setting{}, 0, now)
+	if err != nil || !got.Equal(now.Add(defaultGiftExpiryHours*time.Hour)) {
+		t.Fatalf("expected built-in default, got %v (err %v)", got, err)
+	}
+}
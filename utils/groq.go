@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"time"
+
+	"project/config"
+	"project/metrics"
 )
 
 type GroqMessage struct {
@@ -44,7 +46,8 @@ type GroqResponse struct {
 
 // CallGroqAPI calls Groq API with the given messages and returns the response
 func CallGroqAPI(messages []GroqMessage, systemPrompt string) (string, error) {
-	apiKey := os.Getenv("GROQ_API_KEY")
+	cfg := config.Get().Groq
+	apiKey := cfg.APIKey
 	if apiKey == "" {
 		return "", fmt.Errorf("GROQ_API_KEY not set")
 	}
@@ -59,10 +62,7 @@ func CallGroqAPI(messages []GroqMessage, systemPrompt string) (string, error) {
 	}
 	allMessages = append(allMessages, messages...)
 
-	model := os.Getenv("GROQ_MODEL")
-	if model == "" {
-		model = "llama-3.1-70b-versatile" // Default model
-	}
+	model := cfg.Model
 
 	reqBody := GroqRequest{
 		Model:       model,
@@ -88,23 +88,30 @@ func CallGroqAPI(messages []GroqMessage, systemPrompt string) (string, error) {
 		Timeout: 30 * time.Second,
 	}
 
+	start := time.Now()
+	defer func() { metrics.GroqRequestDuration.Observe(time.Since(start).Seconds()) }()
+
 	resp, err := client.Do(req)
 	if err != nil {
+		metrics.GroqErrors.WithLabelValues("transport").Inc()
 		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.GroqErrors.WithLabelValues("status").Inc()
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return "", fmt.Errorf("groq API error: status %d, body: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var groqResp GroqResponse
 	if err := json.NewDecoder(resp.Body).Decode(&groqResp); err != nil {
+		metrics.GroqErrors.WithLabelValues("decode").Inc()
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(groqResp.Choices) == 0 {
+		metrics.GroqErrors.WithLabelValues("empty").Inc()
 		return "", fmt.Errorf("no choices in response")
 	}
 
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// HolidayCalendar answers whether a calendar day is a holiday.
+type HolidayCalendar interface {
+	// HolidayOn returns the holiday name when t's date (in WIB) is a holiday.
+	HolidayOn(t time.Time) (string, bool)
+}
+
+// HolidaySet is an in-memory HolidayCalendar keyed by YYYY-MM-DD.
+type HolidaySet map[string]string
+
+func (s HolidaySet) HolidayOn(t time.Time) (string, bool) {
+	name, ok := s[DateKey(t.In(JakartaLocation()))]
+	return name, ok
+}
+
+// LoadHolidayCalendar loads holidays between from and to (inclusive dates).
+func LoadHolidayCalendar(db *gorm.DB, from, to time.Time) (HolidaySet, error) {
+	var rows []models.Holiday
+	if err := db.Where("date BETWEEN ? AND ?", DateKey(from), DateKey(to)).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	set := make(HolidaySet, len(rows))
+	for _, h := range rows {
+		// DATE columns come back as midnight in the driver location; only the calendar fields matter
+		set[DateKey(h.Date)] = h.Name
+	}
+	return set, nil
+}
+
+// LoadHolidayCalendarAround loads the holidays relevant for business-day checks around t.
+func LoadHolidayCalendarAround(db *gorm.DB, t time.Time) (HolidaySet, error) {
+	t = t.In(JakartaLocation())
+	return LoadHolidayCalendar(db, t.AddDate(0, 0, -7), t.AddDate(0, 0, 31))
+}
+
+// IsBusinessDay reports whether banks settle transfers on t's date: Monday to Saturday
+// and not a holiday. cal may be nil.
+func IsBusinessDay(t time.Time, cal HolidayCalendar) bool {
+	t = t.In(JakartaLocation())
+	if t.Weekday() == time.Sunday {
+		return false
+	}
+	if cal != nil {
+		if _, ok := cal.HolidayOn(t); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsedHoliday is a holiday read from an import file.
+type ParsedHoliday struct {
+	Date time.Time
+	Name string
+}
+
+var csvDateLayouts = []string{"2006-01-02", "02/01/2006", "02-01-2006", "20060102"}
+
+// ParseHolidayCSV reads "date,name" rows. A header row and blank lines are skipped.
+// Dates may be YYYY-MM-DD, DD/MM/YYYY, DD-MM-YYYY or YYYYMMDD.
+func ParseHolidayCSV(r io.Reader) ([]ParsedHoliday, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var out []ParsedHoliday
+	line := 0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if len(rec) == 0 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		date, err := parseHolidayDate(strings.TrimSpace(rec[0]))
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("baris %d: tanggal tidak valid %q", line, rec[0])
+		}
+		name := "Hari Libur"
+		if len(rec) > 1 && strings.TrimSpace(rec[1]) != "" {
+			name = strings.TrimSpace(rec[1])
+		}
+		out = append(out, ParsedHoliday{Date: date, Name: name})
+	}
+	return out, nil
+}
+
+// ParseHolidayICal reads all-day VEVENTs from an iCalendar (.ics) file. Multi-day
+// events are expanded using the exclusive DTEND.
+func ParseHolidayICal(r io.Reader) ([]ParsedHoliday, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ParsedHoliday
+	var inEvent bool
+	var start, end time.Time
+	var summary string
+	for _, l := range lines {
+		switch {
+		case l == "BEGIN:VEVENT":
+			inEvent = true
+			start, end, summary = time.Time{}, time.Time{}, ""
+		case l == "END:VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			if summary == "" {
+				summary = "Hari Libur"
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				out = append(out, ParsedHoliday{Date: d, Name: summary})
+			}
+		case inEvent:
+			name, params, value, ok := splitICalProperty(l)
+			if !ok {
+				continue
+			}
+			switch name {
+			case "DTSTART":
+				if start, err = parseICalDate(value, params); err != nil {
+					return nil, err
+				}
+			case "DTEND":
+				if end, err = parseICalDate(value, params); err != nil {
+					return nil, err
+				}
+			case "SUMMARY":
+				summary = unescapeICalText(value)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("tidak ada event tanggal pada file iCal")
+	}
+	return out, nil
+}
+
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var lines []string
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICalProperty splits "DTSTART;TZID=Asia/Jakarta:20260817T000000" into
+// ("DTSTART", ["TZID=Asia/Jakarta"], "20260817T000000").
+func splitICalProperty(l string) (string, []string, string, bool) {
+	idx := strings.Index(l, ":")
+	if idx < 0 {
+		return "", nil, "", false
+	}
+	parts := strings.Split(l[:idx], ";")
+	return strings.ToUpper(parts[0]), parts[1:], l[idx+1:], true
+}
+
+// parseICalDate returns the WIB calendar day of a DATE (20260817) or DATE-TIME value.
+// DATE-TIME is UTC with a Z suffix, in the TZID parameter's zone, or otherwise floating
+// and read as WIB. The day is anchored at midnight in time.Local like ParseDate, so it
+// is stored in the DATE column unshifted.
+func parseICalDate(v string, params []string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	loc := JakartaLocation()
+	for _, p := range params {
+		if k, tz, ok := strings.Cut(p, "="); ok && strings.EqualFold(k, "TZID") {
+			var err error
+			if loc, err = time.LoadLocation(strings.Trim(tz, `"`)); err != nil {
+				return time.Time{}, fmt.Errorf("zona waktu iCal tidak dikenal %q", tz)
+			}
+		}
+	}
+	var t time.Time
+	var err error
+	switch {
+	case len(v) == 8:
+		t, err = time.ParseInLocation("20060102", v, JakartaLocation())
+	case strings.HasSuffix(v, "Z"):
+		t, err = time.Parse("20060102T150405Z", v)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", v, loc)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("tanggal iCal tidak valid %q", v)
+	}
+	t = t.In(JakartaLocation())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+}
+
+func unescapeICalText(v string) string {
+	r := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(r.Replace(v))
+}
+
+func parseHolidayDate(s string) (time.Time, error) {
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHolidayCSV(t *testing.T) {
+	in := "date,name\n2026-08-17,Hari Kemerdekaan\n\n25/12/2026, Hari Raya Natal\n"
+	got, err := ParseHolidayCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 holidays, got %d", len(got))
+	}
+	if DateKey(got[0].Date) != "2026-08-17" || got[0].Name != "Hari Kemerdekaan" {
+		t.Fatalf("unexpected first row: %+v", got[0])
+	}
+	if DateKey(got[1].Date) != "2026-12-25" || got[1].Name != "Hari Raya Natal" {
+		t.Fatalf("unexpected second row: %+v", got[1])
+	}
+}
+
+func TestParseHolidayCSV_InvalidDate(t *testing.T) {
+	in := "2026-08-17,Hari Kemerdekaan\nbesok,Libur\n"
+	if _, err := ParseHolidayCSV(strings.NewReader(in)); err == nil {
+		t.Fatalf("expected error for invalid date")
+	}
+}
+
+func TestParseHolidayICal(t *testing.T) {
+	in := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260320",
+		"DTEND;VALUE=DATE:20260322",
+		"SUMMARY:Hari Raya Idul Fitri\\, 1447 H",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260817",
+		"SUMMARY:Hari Kemerdekaan",
+		"  Republik Indonesia",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	got, err := ParseHolidayICal(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"2026-03-20", "2026-03-21", "2026-08-17"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d days, got %d", len(want), len(got))
+	}
+	for i, d := range want {
+		if DateKey(got[i].Date) != d {
+			t.Fatalf("day %d: expected %s, got %s", i, d, DateKey(got[i].Date))
+		}
+	}
+	if got[0].Name != "Hari Raya Idul Fitri, 1447 H" {
+		t.Fatalf("unexpected summary %q", got[0].Name)
+	}
+	if got[2].Name != "Hari Kemerdekaan Republik Indonesia" {
+		t.Fatalf("expected folded summary, got %q", got[2].Name)
+	}
+}
+
+func TestParseHolidayICal_DateTime(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skip("tzdata not available")
+	}
+	in := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART:20260816T170000Z",
+		"DTEND:20260817T170000Z",
+		"SUMMARY:Hari Kemerdekaan",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Asia/Tokyo:20261225T010000",
+		"SUMMARY:Hari Raya Natal",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	got, err := ParseHolidayICal(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 17:00 UTC is midnight WIB; 01:00 in Tokyo is still the previous day in WIB
+	want := []string{"2026-08-17", "2026-12-24"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d days, got %d", len(want), len(got))
+	}
+	for i, d := range want {
+		if DateKey(got[i].Date) != d {
+			t.Fatalf("day %d: expected %s, got %s", i, d, DateKey(got[i].Date))
+		}
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	cal := HolidaySet{"2026-08-17": "Hari Kemerdekaan"}
+	if IsBusinessDay(wib(2026, time.August, 17, 10, 0), cal) {
+		t.Fatalf("expected holiday to be a non-business day")
+	}
+	if IsBusinessDay(wib(2026, time.August, 16, 10, 0), cal) {
+		t.Fatalf("expected Sunday to be a non-business day")
+	}
+	if !IsBusinessDay(wib(2026, time.August, 18, 10, 0), cal) {
+		t.Fatalf("expected Tuesday to be a business day")
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"project/config"
 	"project/database"
 	"project/models"
 
@@ -18,35 +18,20 @@ import (
 	"gorm.io/gorm"
 )
 
-func init() {
-	if os.Getenv("JWT_SECRET") == "supersecretjwtkey" {
-		panic("JWT_SECRET environment variable is not set")
-	}
-}
-
 // RedisClient is an optional shared Redis client used for token revocation and other
 // cross-process coordination (lockout, blacklists). It will be nil when REDIS_ADDR
 // is not configured.
 var RedisClient *redis.Client
 
-func init() {
-	// Initialize Redis client if configured (optional revocation store)
-	addr := strings.TrimSpace(os.Getenv("REDIS_ADDR"))
+// ConnectRedis sets RedisClient from the Redis settings. It is called from main once
+// the configuration is loaded; a Redis that does not answer is logged and skipped.
+func ConnectRedis(cfg config.Redis) {
+	// If someone accidentally put a trailing colon or space, sanitize common mistakes
+	addr := strings.ReplaceAll(cfg.Addr, " ", "")
 	if addr == "" {
 		return
 	}
-	// If someone accidentally put a trailing colon or space, sanitize common mistakes
-	addr = strings.ReplaceAll(addr, " ", "")
-	opts := &redis.Options{Addr: addr}
-	if p := os.Getenv("REDIS_PASS"); p != "" {
-		opts.Password = p
-	}
-	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
-		var dbn int
-		_, _ = fmt.Sscanf(dbStr, "%d", &dbn)
-		opts.DB = dbn
-	}
-	rc := redis.NewClient(opts)
+	rc := redis.NewClient(&redis.Options{Addr: addr, Password: cfg.Pass, DB: cfg.DB})
 	ctx := context.Background()
 	if err := rc.Ping(ctx).Err(); err != nil {
 		fmt.Printf("warning: redis ping failed: %v\n", err)
@@ -56,15 +41,22 @@ func init() {
 	RedisClient = rc
 }
 
+// jwtTimeFunc makes the jwt library check exp/nbf against SystemClock like the
+// rest of the token code.
+var jwtTimeFunc = jwt.WithTimeFunc(func() time.Time { return SystemClock.Now() })
+
 type contextKey string
 
 const UserIDKey = contextKey("userID")
+
+// AdminIDKey holds the authenticated admin ID set by AdminAuthMiddleware.
+const AdminIDKey = contextKey("adminID")
 const UserRoleKey = contextKey("userRole")
 const RequestIDKey = contextKey("requestID")
 
 // ValidateToken validates a JWT token and returns the parsed token if valid
 func ValidateToken(tokenString string) (*jwt.Token, error) {
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		return nil, errors.New("JWT_SECRET is not set")
 	}
@@ -75,7 +67,7 @@ func ValidateToken(tokenString string) (*jwt.Token, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, jwtTimeFunc)
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
@@ -86,7 +78,7 @@ func ValidateToken(tokenString string) (*jwt.Token, error) {
 
 // GenerateJWT generates a new JWT token for the given user ID, username and role
 func GenerateJWT(id int64, username, role string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		return "", errors.New("JWT_SECRET is not set")
 	}
@@ -99,7 +91,7 @@ func GenerateJWT(id int64, username, role string) (string, error) {
 		expTime = time.Hour * 24
 	}
 
-	now := time.Now()
+	now := SystemClock.Now()
 	jti, err := generateJTI(32)
 	if err != nil {
 		return "", err
@@ -113,8 +105,8 @@ func GenerateJWT(id int64, username, role string) (string, error) {
 		"iat":      now.Unix(),
 		"nbf":      now.Unix(),
 		"jti":      jti,
-		"aud":      os.Getenv("JWT_AUD"),
-		"iss":      os.Getenv("JWT_ISS"),
+		"aud":      config.Get().JWT.Audience,
+		"iss":      config.Get().JWT.Issuer,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -134,11 +126,11 @@ func GenerateAccessToken(userID uint, role string) (string, error) {
 
 // GenerateAccessTokenWithExpiry issues an access token with custom expiry duration
 func GenerateAccessTokenWithExpiry(userID uint, role string, expiry time.Duration) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		return "", errors.New("JWT_SECRET is not set")
 	}
-	now := time.Now()
+	now := SystemClock.Now()
 	exp := now.Add(expiry)
 	jti, err := generateJTI(32)
 	if err != nil {
@@ -160,8 +152,8 @@ func GenerateAccessTokenWithExpiry(userID uint, role string, expiry time.Duratio
 		"iat":  rc.IssuedAt.Unix(),
 		"nbf":  rc.NotBefore.Unix(),
 		"jti":  rc.ID,
-		"aud":  os.Getenv("JWT_AUD"),
-		"iss":  os.Getenv("JWT_ISS"),
+		"aud":  config.Get().JWT.Audience,
+		"iss":  config.Get().JWT.Issuer,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -180,6 +172,9 @@ func GenerateRefreshToken(userID uint) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
+	now := SystemClock.Now()
+	rt.CreatedAt = now
+	rt.ExpiresAt = now.Add(7 * 24 * time.Hour)
 	// override generated ID with jti for consistency
 	rt.ID = jti
 	if database.DB == nil {
@@ -193,7 +188,7 @@ func GenerateRefreshToken(userID uint) (string, string, error) {
 
 // ValidateAccessToken parses and validates the access token and optionally checks jti revocation store in DB (not implemented here)
 func ValidateAccessToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		return nil, nil, errors.New("JWT_SECRET is not set")
 	}
@@ -204,7 +199,7 @@ func ValidateAccessToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, jwtTimeFunc)
 	if err != nil || !token.Valid {
 		return nil, nil, errors.New("invalid token")
 	}
@@ -216,7 +211,7 @@ func ValidateAccessToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
 	}
 
 	// Validate registered claims: exp, nbf, aud, iss, jti
-	now := time.Now()
+	now := SystemClock.Now()
 	// exp
 	if expRaw, ok := claims["exp"]; ok {
 		switch v := expRaw.(type) {
@@ -246,7 +241,7 @@ func ValidateAccessToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
 	}
 
 	// aud
-	audEnv := os.Getenv("JWT_AUD")
+	audEnv := config.Get().JWT.Audience
 	if audEnv != "" {
 		audRaw, ok := claims["aud"]
 		if !ok {
@@ -274,7 +269,7 @@ func ValidateAccessToken(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
 	}
 
 	// iss
-	issEnv := os.Getenv("JWT_ISS")
+	issEnv := config.Get().JWT.Issuer
 	if issEnv != "" {
 		if issRaw, ok := claims["iss"].(string); !ok || issRaw != issEnv {
 			return token, nil, errors.New("invalid issuer")
@@ -319,7 +314,7 @@ func ValidateRefreshToken(jti string) (*models.RefreshToken, error) {
 	if rt.Revoked {
 		return nil, errors.New("refresh token revoked")
 	}
-	if time.Now().After(rt.ExpiresAt) {
+	if SystemClock.Now().After(rt.ExpiresAt) {
 		return nil, errors.New("refresh token expired")
 	}
 	return &rt, nil
@@ -332,7 +327,7 @@ func ExtractUserIDFromRequest(r *http.Request) (uint, error) {
 		return 0, errors.New("missing or invalid Authorization header")
 	}
 	tokenStr := strings.TrimSpace(strings.TrimPrefix(authz, "Bearer "))
-	secret := os.Getenv("JWT_SECRET")
+	secret := config.Get().JWT.Secret
 	if secret == "" {
 		return 0, errors.New("server misconfiguration")
 	}
@@ -341,7 +336,7 @@ func ExtractUserIDFromRequest(r *http.Request) (uint, error) {
 			return nil, errors.New("unexpected signing method")
 		}
 		return []byte(secret), nil
-	})
+	}, jwtTimeFunc)
 	if err != nil || !token.Valid {
 		return 0, errors.New("invalid token")
 	}
@@ -384,12 +379,35 @@ func RevokeJTI(jti string, ttl time.Duration) error {
 	}
 	if database.DB != nil {
 		// Upsert into revoked_tokens (MySQL ON DUPLICATE KEY). If DB is unavailable, return error.
-		res := database.DB.Exec("INSERT INTO revoked_tokens (id, revoked_at) VALUES (?, ?) ON DUPLICATE KEY UPDATE revoked_at = VALUES(revoked_at)", jti, time.Now())
+		res := database.DB.Exec("INSERT INTO revoked_tokens (id, revoked_at) VALUES (?, ?) ON DUPLICATE KEY UPDATE revoked_at = VALUES(revoked_at)", jti, SystemClock.Now())
 		return res.Error
 	}
 	return errors.New("no revocation store configured")
 }
 
+// RevokeRequestToken revokes the access token the request was authenticated with until
+// it would have expired.
+func RevokeRequestToken(r *http.Request) error {
+	authz := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authz, "Bearer ") {
+		return errors.New("missing or invalid Authorization header")
+	}
+	_, claims, err := ValidateAccessToken(strings.TrimSpace(strings.TrimPrefix(authz, "Bearer ")))
+	if err != nil {
+		return err
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("token has no expiry")
+	}
+	ttl := exp.Sub(SystemClock.Now())
+	if ttl <= 0 {
+		return nil
+	}
+	return RevokeJTI(jti, ttl)
+}
+
 // generateJTI creates a URL-safe random identifier used as JWT ID
 func generateJTI(n int) (string, error) {
 	b := make([]byte, n)
@@ -424,3 +442,9 @@ func GetUserID(r *http.Request) (uint, bool) {
 	id, ok := v.(uint)
 	return id, ok
 }
+
+func GetAdminID(r *http.Request) (uint, bool) {
+	v := r.Context().Value(AdminIDKey)
+	id, ok := v.(uint)
+	return id, ok
+}
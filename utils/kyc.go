@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	KYCStatusUnverified = "Unverified"
+	KYCStatusPending    = "Pending"
+	KYCStatusVerified   = "Verified"
+	KYCStatusRejected   = "Rejected"
+
+	KYCDocumentIDCard = "id_card"
+	KYCDocumentSelfie = "selfie"
+
+	// KYCSignedURLExpiry keeps document links short-lived; they are fetched per review
+	KYCSignedURLExpiry = 300
+)
+
+var (
+	ErrKYCAlreadyVerified = errors.New("kyc already verified")
+	ErrKYCPending         = errors.New("kyc submission already pending")
+	ErrKYCIDNumberUsed    = errors.New("id number verified on another account")
+	ErrKYCNotPending      = errors.New("kyc submission is not pending")
+	ErrKYCReasonRequired  = errors.New("reject reason required")
+)
+
+// ValidateIDNumber checks an Indonesian NIK: 16 digits.
+func ValidateIDNumber(nik string) error {
+	if len(nik) != 16 {
+		return fmt.Errorf("NIK harus 16 digit")
+	}
+	for _, c := range nik {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("NIK hanya boleh berisi angka")
+		}
+	}
+	return nil
+}
+
+// KYCObjectKey is the private object key for a user's KYC document.
+func KYCObjectKey(userID uint, document, ext string, now time.Time) string {
+	return fmt.Sprintf("kyc/%d/%s_%d%s", userID, document, now.UnixNano(), ext)
+}
+
+// KYCRequired reports whether a withdrawal of amount, on top of what was already
+// withdrawn today, crosses one of the KYC thresholds. A zero threshold is disabled.
+func KYCRequired(amount, withdrawnToday, threshold, dailyThreshold float64) bool {
+	if threshold > 0 && amount > threshold {
+		return true
+	}
+	return dailyThreshold > 0 && withdrawnToday+amount > dailyThreshold
+}
+
+// SubmitKYC records a new submission with already uploaded documents and marks the
+// user as Pending. A user can resubmit only after a rejection.
+func SubmitKYC(tx *gorm.DB, userID uint, fullName, idNumber, idCardKey, selfieKey string) (*models.KYCSubmission, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "kyc_status").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	switch user.KYCStatus {
+	case KYCStatusVerified:
+		return nil, ErrKYCAlreadyVerified
+	case KYCStatusPending:
+		return nil, ErrKYCPending
+	}
+	if err := checkIDNumberUnused(tx, userID, idNumber); err != nil {
+		return nil, err
+	}
+
+	sub := models.KYCSubmission{
+		UserID:    userID,
+		FullName:  strings.TrimSpace(fullName),
+		IDNumber:  idNumber,
+		IDCardKey: idCardKey,
+		SelfieKey: selfieKey,
+		Status:    "Pending",
+	}
+	if err := tx.Create(&sub).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("kyc_status", KYCStatusPending).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ReviewKYC approves or rejects a pending submission and updates the user's status.
+func ReviewKYC(tx *gorm.DB, submissionID uint, approve bool, adminID uint, reason string, now time.Time) (*models.KYCSubmission, error) {
+	var sub models.KYCSubmission
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sub, submissionID).Error; err != nil {
+		return nil, err
+	}
+	if sub.Status != "Pending" {
+		return nil, ErrKYCNotPending
+	}
+	reason = strings.TrimSpace(reason)
+
+	userStatus := KYCStatusVerified
+	if approve {
+		if err := checkIDNumberUnused(tx, sub.UserID, sub.IDNumber); err != nil {
+			return nil, err
+		}
+		sub.Status = "Approved"
+	} else {
+		if reason == "" {
+			return nil, ErrKYCReasonRequired
+		}
+		sub.Status = "Rejected"
+		sub.RejectReason = &reason
+		userStatus = KYCStatusRejected
+	}
+	sub.ReviewedBy = &adminID
+	sub.ReviewedAt = &now
+	if err := tx.Save(&sub).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", sub.UserID).Update("kyc_status", userStatus).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// checkIDNumberUnused rejects an NIK that is already approved for a different account.
+func checkIDNumberUnused(tx *gorm.DB, userID uint, idNumber string) error {
+	var count int64
+	if err := tx.Model(&models.KYCSubmission{}).
+		Where("id_number = ? AND status = ? AND user_id <> ?", idNumber, "Approved", userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrKYCIDNumberUsed
+	}
+	return nil
+}
+
+// KYCDocumentURLs returns short-lived signed URLs for a submission's documents.
+func KYCDocumentURLs(sub models.KYCSubmission) (map[string]string, error) {
+	idCard, err := GenerateSignedURL(sub.IDCardKey, KYCSignedURLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	selfie, err := GenerateSignedURL(sub.SelfieKey, KYCSignedURLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{KYCDocumentIDCard: idCard, KYCDocumentSelfie: selfie}, nil
+}
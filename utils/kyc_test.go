@@ -0,0 +1,17 @@
+package utils
+
+import "testing"
+
+func TestValidateIDNumber(t *testing.T) {
+	for nik, ok := range map[string]bool{
+		"3174012345678901":  true,
+		"317401234567890":   false,
+		"31740123456789012": false,
+		"31740123456789O1":  false,
+		"":                  false,
+	} {
+		if err := ValidateIDNumber(nik); (err == nil) != ok {
+			t.Errorf("ValidateIDNumber(%q) = %v, want ok=%v", nik, err, ok)
+		}
+	}
+}
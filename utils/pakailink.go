@@ -22,6 +22,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"project/config"
 )
 
 const (
@@ -29,19 +31,11 @@ const (
 )
 
 func getPakailinkConfig() (baseURL, clientKey, clientSecret, partnerID, privateKeyPath, callbackURL, merchantID, storeID, terminalID string, err error) {
-	baseURL = os.Getenv("PAKAILINK_BASE_URL")
-	clientKey = os.Getenv("PAKAILINK_CLIENT_KEY")
-	clientSecret = os.Getenv("PAKAILINK_CLIENT_SECRET")
-	partnerID = os.Getenv("PAKAILINK_PARTNER_ID")
-	privateKeyPath = os.Getenv("PAKAILINK_PRIVATE_KEY_PATH")
-	callbackURL = os.Getenv("PAKAILINK_PAYMENT_CALLBACK_URL")
-	merchantID = os.Getenv("PAKAILINK_MERCHANT_ID")
-	storeID = os.Getenv("PAKAILINK_STORE_ID")
-	terminalID = os.Getenv("PAKAILINK_TERMINAL_ID")
-
-	if baseURL == "" {
-		baseURL = "https://api.pakailink.id"
-	}
+	cfg := config.Get().Pakailink
+	baseURL, clientKey, clientSecret, partnerID = cfg.BaseURL, cfg.ClientKey, cfg.ClientSecret, cfg.PartnerID
+	privateKeyPath, callbackURL = cfg.PrivateKeyPath, cfg.PaymentCallbackURL
+	merchantID, storeID, terminalID = cfg.MerchantID, cfg.StoreID, cfg.TerminalID
+
 	if clientKey == "" || clientSecret == "" || partnerID == "" || privateKeyPath == "" || callbackURL == "" {
 		return "", "", "", "", "", "", "", "", "", fmt.Errorf("PAKAILINK config wajib")
 	}
@@ -49,9 +43,8 @@ func getPakailinkConfig() (baseURL, clientKey, clientSecret, partnerID, privateK
 }
 
 func getPakailinkQRISConfig() (merchantID, storeID, terminalID string, err error) {
-	merchantID = os.Getenv("PAKAILINK_MERCHANT_ID")
-	storeID = os.Getenv("PAKAILINK_STORE_ID")
-	terminalID = os.Getenv("PAKAILINK_TERMINAL_ID")
+	cfg := config.Get().Pakailink
+	merchantID, storeID, terminalID = cfg.MerchantID, cfg.StoreID, cfg.TerminalID
 	if merchantID == "" || storeID == "" || terminalID == "" {
 		return "", "", "", fmt.Errorf("PAKAILINK_MERCHANT_ID, PAKAILINK_STORE_ID, PAKAILINK_TERMINAL_ID wajib untuk QRIS")
 	}
@@ -141,14 +134,10 @@ type PakailinkAccessTokenResponse struct {
 
 // GetPakailinkAccessToken obtains B2B token using asymmetric signature
 func GetPakailinkAccessToken(ctx context.Context, client *http.Client) (string, error) {
-	_, clientKey, _, _, privateKeyPath, _, _, _, _, err := getPakailinkConfig()
+	baseURL, clientKey, _, _, privateKeyPath, _, _, _, _, err := getPakailinkConfig()
 	if err != nil {
 		return "", err
 	}
-	baseURL := os.Getenv("PAKAILINK_BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://api.pakailink.id"
-	}
 	path := "/snap/v1.0/access-token/b2b"
 	url := strings.TrimRight(baseURL, "/") + path
 
@@ -470,13 +459,14 @@ func IsPakailinkSuccessStatus(status string) bool {
 	return strings.TrimSpace(status) == "00"
 }
 
-// GetPakailinkPayoutCallbackURL returns payout callback URL from env
+// GetPakailinkPayoutCallbackURL returns the payout callback URL, falling back to the
+// payment callback URL
 func GetPakailinkPayoutCallbackURL() string {
-	u := os.Getenv("PAKAILINK_PAYOUT_CALLBACK_URL")
-	if u == "" {
-		return os.Getenv("PAKAILINK_PAYMENT_CALLBACK_URL") // fallback
+	cfg := config.Get().Pakailink
+	if cfg.PayoutCallbackURL == "" {
+		return cfg.PaymentCallbackURL
 	}
-	return u
+	return cfg.PayoutCallbackURL
 }
 
 // PakailinkBankInquiryResponse from bank-account-inquiry
@@ -0,0 +1,359 @@
+package utils
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/config"
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// Partner scopes. Each partner route requires one; a key only reaches the routes its
+// scopes name.
+const (
+	ScopeInfoRead             = "info:read"              // aggregate investment/withdrawal figures
+	ScopeUserBalancesRead     = "users:balance:read"     // users with a balance of at least 50.000
+	ScopeManagementWrite      = "management:write"       // management transactions
+	ScopePaymentSettingsRead  = "payment_settings:read"  // GET /payment_info
+	ScopePaymentSettingsWrite = "payment_settings:write" // PUT /payment_info
+	ScopeWithdrawalsRead      = "withdrawals:read"       // StoneForm pending withdrawals, masked
+	ScopeWithdrawalsClaim     = "withdrawals:claim"      // StoneForm claim/release withdrawals to pay
+	ScopeWithdrawalsReport    = "withdrawals:report"     // StoneForm withdrawal results
+)
+
+// PartnerScopes lists every scope a key can be granted.
+var PartnerScopes = []string{
+	ScopeInfoRead,
+	ScopeUserBalancesRead,
+	ScopeManagementWrite,
+	ScopePaymentSettingsRead,
+	ScopePaymentSettingsWrite,
+	ScopeWithdrawalsRead,
+	ScopeWithdrawalsClaim,
+	ScopeWithdrawalsReport,
+}
+
+const (
+	partnerKeyTag = "pk_"
+	// PartnerSignatureMaxSkew is how far X-Timestamp may be from the server clock. A
+	// signature is also refused a second time within twice this window.
+	PartnerSignatureMaxSkew = 5 * time.Minute
+	// partnerLastUsedEvery limits last_used_at writes to one per key per minute.
+	partnerLastUsedEvery = time.Minute
+)
+
+const (
+	PartnerIDKey    = contextKey("partnerID")
+	PartnerKeyIDKey = contextKey("partnerKeyID")
+)
+
+var (
+	ErrPartnerKeyInvalid   = errors.New("invalid partner key")
+	ErrPartnerKeyExpired   = errors.New("partner key expired")
+	ErrPartnerKeyRevoked   = errors.New("partner key revoked")
+	ErrPartnerInactive     = errors.New("partner inactive")
+	ErrPartnerSignature    = errors.New("invalid request signature")
+	ErrPartnerReplay       = errors.New("request signature already used")
+	ErrPartnerReplayCheck  = errors.New("partner replay protection unavailable")
+	ErrPartnerScopeUnknown = errors.New("unknown partner scope")
+	ErrPartnerNoEncryption = errors.New("PARTNER_SECRET_ENCRYPTION_KEY is not set")
+)
+
+// PartnerKeyOptions describes a key to issue.
+type PartnerKeyOptions struct {
+	Scopes           []string
+	ExpiresAt        *time.Time // nil never expires
+	RequireSignature bool
+	CreatedBy        *uint
+	RotatedFromID    *uint
+}
+
+// IssuedPartnerKey carries the plaintext credentials, which are shown to the admin
+// once and never stored.
+type IssuedPartnerKey struct {
+	Key           *models.PartnerKey
+	APIKey        string
+	SigningSecret string // empty unless the key requires signatures
+}
+
+// NormalizePartnerScopes checks scopes against PartnerScopes and returns them sorted,
+// deduplicated and comma separated for storage.
+func NormalizePartnerScopes(scopes []string) (string, error) {
+	seen := map[string]bool{}
+	var out []string
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		known := false
+		for _, k := range PartnerScopes {
+			if k == s {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("%w: %s", ErrPartnerScopeUnknown, s)
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	if len(out) == 0 {
+		return "", fmt.Errorf("%w: at least one scope is required", ErrPartnerScopeUnknown)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ","), nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashPartnerSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitPartnerKey parses "pk_<prefix>.<secret>".
+func splitPartnerKey(raw string) (prefix, secret string, ok bool) {
+	if !strings.HasPrefix(raw, partnerKeyTag) {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(strings.TrimPrefix(raw, partnerKeyTag), ".")
+	if !ok || prefix == "" || len(prefix) > 16 || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+// IssuePartnerKey creates a key for the partner and returns its plaintext once.
+func IssuePartnerKey(db *gorm.DB, partnerID uint, opts PartnerKeyOptions) (*IssuedPartnerKey, error) {
+	scopes, err := NormalizePartnerScopes(opts.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	pb := make([]byte, 6)
+	if _, err := rand.Read(pb); err != nil {
+		return nil, err
+	}
+	prefix := hex.EncodeToString(pb)
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.PartnerKey{
+		PartnerID:        partnerID,
+		Prefix:           prefix,
+		SecretHash:       hashPartnerSecret(secret),
+		Scopes:           scopes,
+		RequireSignature: opts.RequireSignature,
+		ExpiresAt:        opts.ExpiresAt,
+		RotatedFromID:    opts.RotatedFromID,
+		CreatedBy:        opts.CreatedBy,
+	}
+	issued := &IssuedPartnerKey{Key: key, APIKey: partnerKeyTag + prefix + "." + secret}
+	if opts.RequireSignature {
+		if issued.SigningSecret, err = randomToken(32); err != nil {
+			return nil, err
+		}
+		if key.SigningSecret, err = encryptPartnerSecret(issued.SigningSecret); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Create(key).Error; err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// RotatePartnerKey issues a replacement with the same scopes and signing requirement
+// and lets the old key work for grace longer so the partner can switch without
+// downtime.
+func RotatePartnerKey(db *gorm.DB, old *models.PartnerKey, grace time.Duration, expiresAt *time.Time, createdBy *uint, now time.Time) (*IssuedPartnerKey, error) {
+	var issued *IssuedPartnerKey
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		issued, err = IssuePartnerKey(tx, old.PartnerID, PartnerKeyOptions{
+			Scopes:           old.ScopeList(),
+			ExpiresAt:        expiresAt,
+			RequireSignature: old.RequireSignature,
+			CreatedBy:        createdBy,
+			RotatedFromID:    &old.ID,
+		})
+		if err != nil {
+			return err
+		}
+		until := now.Add(grace)
+		if old.ExpiresAt != nil && old.ExpiresAt.Before(until) {
+			return nil
+		}
+		old.ExpiresAt = &until
+		return tx.Model(old).Update("expires_at", until).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// AuthenticatePartnerKey looks up a presented key and checks that it, and its partner,
+// may be used at now.
+func AuthenticatePartnerKey(db *gorm.DB, raw string, now time.Time) (*models.PartnerKey, error) {
+	prefix, secret, ok := splitPartnerKey(strings.TrimSpace(raw))
+	if !ok {
+		return nil, ErrPartnerKeyInvalid
+	}
+	var key models.PartnerKey
+	if err := db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerKeyInvalid
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashPartnerSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrPartnerKeyInvalid
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrPartnerKeyRevoked
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrPartnerKeyExpired
+	}
+	var partner models.Partner
+	if err := db.Select("id, status").First(&partner, key.PartnerID).Error; err != nil {
+		return nil, err
+	}
+	if partner.Status != "Active" {
+		return nil, ErrPartnerInactive
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= partnerLastUsedEvery {
+		db.Model(&key).UpdateColumn("last_used_at", now)
+	}
+	return &key, nil
+}
+
+// PartnerStringToSign is what X-Signature covers: method, request URI, timestamp and
+// the hex SHA-256 of the body, one per line.
+func PartnerStringToSign(method, requestURI, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{strings.ToUpper(method), requestURI, timestamp, hex.EncodeToString(sum[:])}, "\n")
+}
+
+// SignPartnerRequest returns the hex HMAC-SHA256 a partner sends in X-Signature.
+func SignPartnerRequest(signingSecret, method, requestURI, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(PartnerStringToSign(method, requestURI, timestamp, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPartnerSignature checks X-Timestamp (unix seconds) and X-Signature for a key
+// that requires signing. Each signature is recorded in Redis to refuse replays; when
+// that is not possible the request is refused with ErrPartnerReplayCheck.
+func VerifyPartnerSignature(key *models.PartnerKey, method, requestURI, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrPartnerSignature
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > PartnerSignatureMaxSkew || skew < -PartnerSignatureMaxSkew {
+		return ErrPartnerSignature
+	}
+	secret, err := decryptPartnerSecret(key.SigningSecret)
+	if err != nil {
+		return err
+	}
+	want := SignPartnerRequest(secret, method, requestURI, timestamp, body)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(signature))) {
+		return ErrPartnerSignature
+	}
+	// Fail closed: without a record of used signatures a captured request could be replayed
+	if RedisClient == nil {
+		return ErrPartnerReplayCheck
+	}
+	fresh, err := RedisClient.SetNX(context.Background(), "partner:sig:"+want, key.ID, 2*PartnerSignatureMaxSkew).Result()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPartnerReplayCheck, err)
+	}
+	if !fresh {
+		return ErrPartnerReplay
+	}
+	return nil
+}
+
+// GetPartnerID returns the partner authenticated by PartnerAuth.
+func GetPartnerID(r *http.Request) (uint, bool) {
+	id, ok := r.Context().Value(PartnerIDKey).(uint)
+	return id, ok
+}
+
+// GetPartnerKeyID returns the key PartnerAuth accepted.
+func GetPartnerKeyID(r *http.Request) (uint, bool) {
+	id, ok := r.Context().Value(PartnerKeyIDKey).(uint)
+	return id, ok
+}
+
+func partnerCipher() (cipher.AEAD, error) {
+	k := config.Get().Partner.SecretEncryptionKey
+	if k == "" {
+		return nil, ErrPartnerNoEncryption
+	}
+	key, err := base64.StdEncoding.DecodeString(k)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptPartnerSecret seals s with AES-256-GCM as base64(nonce || ciphertext).
+func encryptPartnerSecret(s string) (string, error) {
+	gcm, err := partnerCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(s), nil)), nil
+}
+
+func decryptPartnerSecret(s string) (string, error) {
+	gcm, err := partnerCipher()
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(b) < gcm.NonceSize() {
+		return "", ErrPartnerSignature
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt signing secret: %w", err)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"project/models"
+
+	"github.com/alicebob/miniredis/v2"
+	redis "github.com/redis/go-redis/v9"
+)
+
+const testPartnerEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+func TestNormalizePartnerScopes(t *testing.T) {
+	got, err := NormalizePartnerScopes([]string{ScopeWithdrawalsRead, " " + ScopeInfoRead, ScopeWithdrawalsRead, ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ScopeInfoRead + "," + ScopeWithdrawalsRead; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if _, err := NormalizePartnerScopes([]string{"admin"}); !errors.Is(err, ErrPartnerScopeUnknown) {
+		t.Fatalf("unknown scope: got %v", err)
+	}
+	if _, err := NormalizePartnerScopes(nil); !errors.Is(err, ErrPartnerScopeUnknown) {
+		t.Fatalf("empty scopes: got %v", err)
+	}
+}
+
+func TestSplitPartnerKey(t *testing.T) {
+	cases := map[string]bool{
+		"pk_a1b2c3.secret":             true,
+		"a1b2c3.secret":                false,
+		"pk_a1b2c3":                    false,
+		"pk_.secret":                   false,
+		"pk_a1b2c3.":                   false,
+		"pk_0123456789abcdef01.secret": false, // prefix longer than the column
+		"VLA010124":                    false,
+		"Bearer pk_a1b2c3.secret":      false,
+	}
+	for raw, want := range cases {
+		if _, _, ok := splitPartnerKey(raw); ok != want {
+			t.Errorf("splitPartnerKey(%q) ok = %v, want %v", raw, ok, want)
+		}
+	}
+}
+
+func TestPartnerKeyHasScope(t *testing.T) {
+	k := &models.PartnerKey{Scopes: ScopeInfoRead + "," + ScopeWithdrawalsRead}
+	if !k.HasScope(ScopeWithdrawalsRead) || k.HasScope(ScopeWithdrawalsReport) {
+		t.Fatalf("scopes %v", k.ScopeList())
+	}
+	if (&models.PartnerKey{}).HasScope("") {
+		t.Fatal("a key without scopes must not match the empty scope")
+	}
+}
+
+func TestVerifyPartnerSignature(t *testing.T) {
+	t.Setenv("PARTNER_SECRET_ENCRYPTION_KEY", testPartnerEncryptionKey)
+
+	sealed, err := encryptPartnerSecret("signing-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &models.PartnerKey{ID: 1, RequireSignature: true, SigningSecret: sealed}
+	now := time.Unix(1767225600, 0)
+	ts := "1767225600"
+	body := []byte(`{"order_id":"WD1","status":"Success"}`)
+	sig := SignPartnerRequest("signing-secret", "POST", "/v3/sfxcr/withdrawals/callback", ts, body)
+
+	m := miniredis.RunT(t)
+	prevRedis := RedisClient
+	RedisClient = redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = RedisClient.Close(); RedisClient = prevRedis })
+
+	if err := VerifyPartnerSignature(key, "POST", "/v3/sfxcr/withdrawals/callback", ts, sig, body, now); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if err := VerifyPartnerSignature(key, "POST", "/v3/sfxcr/withdrawals/callback", ts, sig, body, now); !errors.Is(err, ErrPartnerReplay) {
+		t.Fatalf("replayed signature: got %v, want ErrPartnerReplay", err)
+	}
+
+	bad := []struct {
+		name         string
+		uri, ts, sig string
+		body         []byte
+		now          time.Time
+	}{
+		{"tampered body", "/v3/sfxcr/withdrawals/callback", ts, sig, []byte(`{"order_id":"WD2","status":"Success"}`), now},
+		{"other path", "/v3/payment_info", ts, sig, body, now},
+		{"stale timestamp", "/v3/sfxcr/withdrawals/callback", ts, sig, body, now.Add(PartnerSignatureMaxSkew + time.Second)},
+		{"bad timestamp", "/v3/sfxcr/withdrawals/callback", "yesterday", sig, body, now},
+		{"wrong secret", "/v3/sfxcr/withdrawals/callback", ts, SignPartnerRequest("other", "POST", "/v3/sfxcr/withdrawals/callback", ts, body), body, now},
+	}
+	for _, c := range bad {
+		if err := VerifyPartnerSignature(key, "POST", c.uri, c.ts, c.sig, c.body, c.now); !errors.Is(err, ErrPartnerSignature) {
+			t.Errorf("%s: got %v, want ErrPartnerSignature", c.name, err)
+		}
+	}
+}
+
+func TestVerifyPartnerSignature_FailsClosedWithoutRedis(t *testing.T) {
+	t.Setenv("PARTNER_SECRET_ENCRYPTION_KEY", testPartnerEncryptionKey)
+
+	sealed, err := encryptPartnerSecret("signing-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &models.PartnerKey{ID: 1, RequireSignature: true, SigningSecret: sealed}
+	now := time.Unix(1767225600, 0)
+	body := []byte(`{"order_id":"WD1","status":"Success"}`)
+	sig := SignPartnerRequest("signing-secret", "POST", "/v3/sfxcr/withdrawals/callback", "1767225600", body)
+
+	prevRedis := RedisClient
+	t.Cleanup(func() { RedisClient = prevRedis })
+
+	RedisClient = nil
+	if err := VerifyPartnerSignature(key, "POST", "/v3/sfxcr/withdrawals/callback", "1767225600", sig, body, now); !errors.Is(err, ErrPartnerReplayCheck) {
+		t.Fatalf("without redis: got %v, want ErrPartnerReplayCheck", err)
+	}
+
+	m := miniredis.RunT(t)
+	RedisClient = redis.NewClient(&redis.Options{Addr: m.Addr(), MaxRetries: -1})
+	defer RedisClient.Close()
+	m.Close()
+	if err := VerifyPartnerSignature(key, "POST", "/v3/sfxcr/withdrawals/callback", "1767225600", sig, body, now); !errors.Is(err, ErrPartnerReplayCheck) {
+		t.Fatalf("redis down: got %v, want ErrPartnerReplayCheck", err)
+	}
+}
+
+func TestEncryptPartnerSecretRequiresKey(t *testing.T) {
+	t.Setenv("PARTNER_SECRET_ENCRYPTION_KEY", "")
+	if _, err := encryptPartnerSecret("x"); !errors.Is(err, ErrPartnerNoEncryption) {
+		t.Fatalf("got %v, want ErrPartnerNoEncryption", err)
+	}
+}
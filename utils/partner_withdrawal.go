@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// PartnerClaimLease is how long a claimed withdrawal is reserved for the partner
+	// that claimed it. An unreported claim returns to the pool afterwards.
+	PartnerClaimLease = 15 * time.Minute
+	// PartnerClaimMaxBatch caps how many withdrawals one claim call may take.
+	PartnerClaimMaxBatch = 50
+	// PartnerMaxFailures is how many failed partner payouts a withdrawal may have before
+	// it is no longer offered to partners and waits for an admin.
+	PartnerMaxFailures = 3
+)
+
+var (
+	ErrPartnerWithdrawalNotFound   = errors.New("withdrawal not found")
+	ErrPartnerWithdrawalNotClaimed = errors.New("withdrawal is not claimed by this partner")
+	ErrPartnerWithdrawalSettled    = errors.New("withdrawal already settled")
+	ErrPartnerWithdrawalClaimed    = errors.New("withdrawal is claimed by a partner")
+)
+
+// PartnerPayout is what a partner needs to pay a claimed withdrawal. It carries no user
+// id, name or phone number.
+type PartnerPayout struct {
+	OrderID        string    `json:"order_id"`
+	BankName       string    `json:"bank_name"`
+	BankCode       string    `json:"bank_code"`
+	AccountName    string    `json:"account_name"`
+	AccountNumber  string    `json:"account_number"`
+	FinalAmount    float64   `json:"final_amount"`
+	ClaimExpiresAt time.Time `json:"claim_expires_at"`
+}
+
+// PartnerClaimable restricts a withdrawals query to those a partner may claim at now:
+// pending, not sent to the payout gateway, not held by an unexpired claim and not
+// failed too often by partners.
+func PartnerClaimable(now time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("withdrawals.status = ?", "Pending").
+			Where("withdrawals.gateway_dispatched_at IS NULL").
+			Where("withdrawals.claim_expires_at IS NULL OR withdrawals.claim_expires_at <= ?", now).
+			Where("withdrawals.partner_failures < ?", PartnerMaxFailures)
+	}
+}
+
+// PartnerClaimActive reports whether w is reserved for a partner at now.
+func PartnerClaimActive(w *models.Withdrawal, now time.Time) bool {
+	return w.Status == "Pending" && w.ClaimedByPartnerID != nil && w.ClaimExpiresAt != nil && now.Before(*w.ClaimExpiresAt)
+}
+
+// ClaimPartnerWithdrawals leases up to limit claimable withdrawals, oldest first, to the
+// partner. Rows another transaction is claiming are skipped rather than waited for, so
+// concurrent processors each get a disjoint batch.
+func ClaimPartnerWithdrawals(db *gorm.DB, partnerID uint, limit int, now time.Time) ([]PartnerPayout, error) {
+	if limit < 1 || limit > PartnerClaimMaxBatch {
+		limit = PartnerClaimMaxBatch
+	}
+	expires := now.Add(PartnerClaimLease)
+
+	var ids []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []models.Withdrawal
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id").Scopes(PartnerClaimable(now)).
+			Order("created_at ASC, id ASC").Limit(limit).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		for _, w := range rows {
+			ids = append(ids, w.ID)
+		}
+		return tx.Model(&models.Withdrawal{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"claimed_by_partner_id": partnerID,
+			"claim_expires_at":      expires,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	payouts := []PartnerPayout{}
+	if len(ids) == 0 {
+		return payouts, nil
+	}
+
+	if err := db.Table("withdrawals").
+		Select("withdrawals.order_id, banks.name AS bank_name, banks.code AS bank_code, "+
+			"bank_accounts.account_name, bank_accounts.account_number, withdrawals.final_amount, withdrawals.claim_expires_at").
+		Joins("JOIN bank_accounts ON withdrawals.bank_account_id = bank_accounts.id").
+		Joins("JOIN banks ON bank_accounts.bank_id = banks.id").
+		Where("withdrawals.id IN ?", ids).
+		Order("withdrawals.created_at ASC, withdrawals.id ASC").
+		Scan(&payouts).Error; err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+// lockPartnerWithdrawal loads the withdrawal by order id FOR UPDATE.
+func lockPartnerWithdrawal(tx *gorm.DB, orderID string) (*models.Withdrawal, error) {
+	var w models.Withdrawal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&w).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerWithdrawalNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// LockWithdrawalForAdmin loads withdrawal id FOR UPDATE inside tx before an admin
+// approves or rejects it. While a partner's claim is active it returns the row with
+// ErrPartnerWithdrawalClaimed: the partner may be paying it right now. Holding the lock
+// until tx ends keeps a partner from claiming it between the check and the decision.
+func LockWithdrawalForAdmin(tx *gorm.DB, id uint, now time.Time) (*models.Withdrawal, error) {
+	var w models.Withdrawal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&w, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartnerWithdrawalNotFound
+		}
+		return nil, err
+	}
+	if PartnerClaimActive(&w, now) {
+		return &w, ErrPartnerWithdrawalClaimed
+	}
+	return &w, nil
+}
+
+// ClearGatewayDispatch returns a pending withdrawal to the partner claim pool after the
+// payout gateway request for it failed. No callback will arrive for a request the
+// gateway never accepted, so nothing else would clear the mark.
+func ClearGatewayDispatch(db *gorm.DB, id uint) error {
+	return db.Model(&models.Withdrawal{}).
+		Where("id = ? AND status = ?", id, "Pending").
+		Update("gateway_dispatched_at", nil).Error
+}
+
+// ReleasePartnerWithdrawal gives back a claim the partner will not pay.
+func ReleasePartnerWithdrawal(db *gorm.DB, partnerID uint, orderID string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		w, err := lockPartnerWithdrawal(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if w.Status != "Pending" {
+			return ErrPartnerWithdrawalSettled
+		}
+		if !PartnerClaimActive(w, now) || *w.ClaimedByPartnerID != partnerID {
+			return ErrPartnerWithdrawalNotClaimed
+		}
+		return tx.Model(w).Updates(map[string]interface{}{
+			"claimed_by_partner_id": nil,
+			"claim_expires_at":      nil,
+		}).Error
+	})
+}
+
+// ReportPartnerWithdrawal records the result of a partner payout. Only the partner that
+// holds the claim may report; an expired claim still counts as long as no other partner
+// has claimed the withdrawal since. Success settles the withdrawal and its transaction.
+// Failure returns it to the pool, or to the admin after PartnerMaxFailures. Repeating a
+// Success report returns the settled withdrawal with replay set.
+func ReportPartnerWithdrawal(db *gorm.DB, partnerID uint, orderID string, success bool, reference string, now time.Time) (w *models.Withdrawal, replay bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if w, err = lockPartnerWithdrawal(tx, orderID); err != nil {
+			return err
+		}
+		claimedByPartner := w.ClaimedByPartnerID != nil && *w.ClaimedByPartnerID == partnerID
+		if w.Status != "Pending" {
+			if w.Status == "Success" && success && claimedByPartner {
+				replay = true
+				return nil
+			}
+			return ErrPartnerWithdrawalSettled
+		}
+		if !claimedByPartner {
+			return ErrPartnerWithdrawalNotClaimed
+		}
+
+		var ref *string
+		if reference != "" {
+			ref = &reference
+		}
+		if !success {
+			w.ClaimedByPartnerID = nil
+			w.ClaimExpiresAt = nil
+			w.PartnerFailures++
+			return tx.Model(w).Updates(map[string]interface{}{
+				"claimed_by_partner_id": nil,
+				"claim_expires_at":      nil,
+				"partner_failures":      w.PartnerFailures,
+			}).Error
+		}
+
+		w.Status = "Success"
+		w.ClaimExpiresAt = nil
+		w.PartnerReference = ref
+		if err := tx.Model(w).Updates(map[string]interface{}{
+			"status":            "Success",
+			"claim_expires_at":  nil,
+			"partner_reference": ref,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Transaction{}).Where("order_id = ?", w.OrderID).Update("status", "Success").Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return w, replay, nil
+}
+
+// RecordPartnerAction appends to the partner audit trail. Failures are returned but
+// callers only log them: the audit must not block a payout that already happened.
+func RecordPartnerAction(db *gorm.DB, entry models.PartnerAuditLog) error {
+	if len(entry.Detail) > 255 {
+		entry.Detail = entry.Detail[:255]
+	}
+	if len(entry.IP) > 45 {
+		entry.IP = entry.IP[:45]
+	}
+	return db.Create(&entry).Error
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+func seedPartnerWithdrawals(t *testing.T, db *gorm.DB, n int) []models.Withdrawal {
+	t.Helper()
+	user := models.User{Name: "Withdrawer", Number: "81200000000", Password: "x", ReffCode: "WDUSER"}
+	bank := models.Bank{Name: "Bank Central Asia", Code: "014"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&bank).Error; err != nil {
+		t.Fatal(err)
+	}
+	account := models.BankAccount{UserID: user.ID, BankID: bank.ID, AccountName: "Withdrawer", AccountNumber: "1234567890"}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := make([]models.Withdrawal, n)
+	for i := range out {
+		orderID := fmt.Sprintf("WD%04d", i+1)
+		out[i] = models.Withdrawal{UserID: user.ID, BankAccountID: account.ID, Amount: 100000, Charge: 10000, FinalAmount: 90000, OrderID: orderID, Status: "Pending"}
+		if err := db.Create(&out[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+		tx := models.Transaction{UserID: user.ID, Amount: 100000, OrderID: orderID, TransactionFlow: "debit", TransactionType: "withdrawal", Status: "Pending"}
+		if err := db.Create(&tx).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out
+}
+
+func TestClaimPartnerWithdrawals_DisjointBatchesMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	const total = 30
+	seedPartnerWithdrawals(t, db, total)
+	now := time.Now().Truncate(time.Second)
+
+	// Six processors claim at once; every withdrawal must go to exactly one of them
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	owner := map[string]uint{}
+	start := make(chan struct{})
+	for p := uint(1); p <= 6; p++ {
+		wg.Add(1)
+		go func(partnerID uint) {
+			defer wg.Done()
+			<-start
+			payouts, err := ClaimPartnerWithdrawals(db, partnerID, 10, now)
+			if err != nil {
+				t.Errorf("partner %d: %v", partnerID, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range payouts {
+				if prev, ok := owner[p.OrderID]; ok {
+					t.Errorf("%s claimed by partners %d and %d", p.OrderID, prev, partnerID)
+				}
+				owner[p.OrderID] = partnerID
+			}
+		}(p)
+	}
+	close(start)
+	wg.Wait()
+
+	if len(owner) != total {
+		t.Fatalf("claimed %d of %d withdrawals", len(owner), total)
+	}
+	if more, err := ClaimPartnerWithdrawals(db, 7, 10, now.Add(time.Minute)); err != nil || len(more) != 0 {
+		t.Fatalf("leased withdrawals must not be claimable again: %d, %v", len(more), err)
+	}
+	// After the lease runs out they return to the pool
+	if again, err := ClaimPartnerWithdrawals(db, 7, 50, now.Add(PartnerClaimLease)); err != nil || len(again) != total {
+		t.Fatalf("expired leases: got %d, %v", len(again), err)
+	}
+}
+
+func TestClaimPartnerWithdrawals_SkipsGatewayDispatchedMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	seeded := seedPartnerWithdrawals(t, db, 2)
+	now := time.Now().Truncate(time.Second)
+
+	// The first one was auto-withdrawn through the payout gateway
+	if err := db.Model(&seeded[0]).Update("gateway_dispatched_at", now).Error; err != nil {
+		t.Fatal(err)
+	}
+	payouts, err := ClaimPartnerWithdrawals(db, 1, 10, now)
+	if err != nil || len(payouts) != 1 || payouts[0].OrderID != seeded[1].OrderID {
+		t.Fatalf("claim: %+v, %v", payouts, err)
+	}
+}
+
+func TestClaimPartnerWithdrawals_FailedDispatchReturnsToPoolMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	seeded := seedPartnerWithdrawals(t, db, 1)
+	now := time.Now().Truncate(time.Second)
+
+	if err := db.Model(&seeded[0]).Update("gateway_dispatched_at", now).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payouts, err := ClaimPartnerWithdrawals(db, 1, 10, now); err != nil || len(payouts) != 0 {
+		t.Fatalf("dispatched withdrawal claimed: %+v, %v", payouts, err)
+	}
+
+	// The gateway request failed, so no callback will settle it
+	if err := ClearGatewayDispatch(db, seeded[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	payouts, err := ClaimPartnerWithdrawals(db, 1, 10, now)
+	if err != nil || len(payouts) != 1 || payouts[0].OrderID != seeded[0].OrderID {
+		t.Fatalf("claim after failed dispatch: %+v, %v", payouts, err)
+	}
+}
+
+func TestLockWithdrawalForAdminMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	seeded := seedPartnerWithdrawals(t, db, 2)
+	now := time.Now().Truncate(time.Second)
+
+	if _, err := ClaimPartnerWithdrawals(db, 1, 1, now); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := LockWithdrawalForAdmin(tx, seeded[0].ID, now)
+		return err
+	})
+	if !errors.Is(err, ErrPartnerWithdrawalClaimed) {
+		t.Fatalf("claimed withdrawal: got %v", err)
+	}
+
+	// While the admin holds the row lock, a partner cannot claim it
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if _, err := LockWithdrawalForAdmin(tx, seeded[1].ID, now); err != nil {
+			return err
+		}
+		payouts, err := ClaimPartnerWithdrawals(db, 2, 10, now)
+		if err != nil {
+			return err
+		}
+		if len(payouts) != 0 {
+			return fmt.Errorf("partner claimed %d withdrawals locked by the admin", len(payouts))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReportPartnerWithdrawalMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	seedPartnerWithdrawals(t, db, 2)
+	now := time.Now().Truncate(time.Second)
+
+	payouts, err := ClaimPartnerWithdrawals(db, 1, 2, now)
+	if err != nil || len(payouts) != 2 {
+		t.Fatalf("claim: %d, %v", len(payouts), err)
+	}
+	if payouts[0].AccountNumber != "1234567890" || payouts[0].BankCode != "014" || payouts[0].FinalAmount != 90000 {
+		t.Fatalf("payout details: %+v", payouts[0])
+	}
+
+	if _, _, err := ReportPartnerWithdrawal(db, 2, "WD0001", true, "", now); !errors.Is(err, ErrPartnerWithdrawalNotClaimed) {
+		t.Fatalf("other partner report: got %v", err)
+	}
+	w, replay, err := ReportPartnerWithdrawal(db, 1, "WD0001", true, "SF-123", now)
+	if err != nil || replay || w.Status != "Success" {
+		t.Fatalf("report success: %+v, %v, %v", w, replay, err)
+	}
+	var txStatus string
+	db.Model(&models.Transaction{}).Where("order_id = ?", "WD0001").Select("status").Scan(&txStatus)
+	if txStatus != "Success" {
+		t.Fatalf("transaction status %s", txStatus)
+	}
+	if _, replay, err := ReportPartnerWithdrawal(db, 1, "WD0001", true, "SF-123", now); err != nil || !replay {
+		t.Fatalf("repeated success: replay=%v, %v", replay, err)
+	}
+	if _, _, err := ReportPartnerWithdrawal(db, 1, "WD0001", false, "", now); !errors.Is(err, ErrPartnerWithdrawalSettled) {
+		t.Fatalf("failure after success: got %v", err)
+	}
+
+	// A failed payout goes back to the pool until it has failed PartnerMaxFailures times
+	for i := 1; i <= PartnerMaxFailures; i++ {
+		if i > 1 {
+			if got, err := ClaimPartnerWithdrawals(db, 1, 1, now); err != nil || len(got) != 1 {
+				t.Fatalf("reclaim %d: %d, %v", i, len(got), err)
+			}
+		}
+		w, _, err := ReportPartnerWithdrawal(db, 1, "WD0002", false, "", now)
+		if err != nil || w.Status != "Pending" || int(w.PartnerFailures) != i {
+			t.Fatalf("failure %d: %+v, %v", i, w, err)
+		}
+	}
+	if got, err := ClaimPartnerWithdrawals(db, 1, 1, now); err != nil || len(got) != 0 {
+		t.Fatalf("withdrawal failed %d times must wait for an admin: %d, %v", PartnerMaxFailures, len(got), err)
+	}
+}
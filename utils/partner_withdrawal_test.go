@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestPartnerClaimActive(t *testing.T) {
+	now := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC)
+	partner := uint(3)
+	until := now.Add(time.Minute)
+	past := now.Add(-time.Second)
+
+	cases := []struct {
+		name string
+		w    models.Withdrawal
+		want bool
+	}{
+		{"unclaimed", models.Withdrawal{Status: "Pending"}, false},
+		{"held", models.Withdrawal{Status: "Pending", ClaimedByPartnerID: &partner, ClaimExpiresAt: &until}, true},
+		{"lease expired", models.Withdrawal{Status: "Pending", ClaimedByPartnerID: &partner, ClaimExpiresAt: &past}, false},
+		{"settled", models.Withdrawal{Status: "Success", ClaimedByPartnerID: &partner, ClaimExpiresAt: &until}, false},
+	}
+	for _, c := range cases {
+		if got := PartnerClaimActive(&c.w, now); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
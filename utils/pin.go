@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"project/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PINLength       = 6
+	PINMaxAttempts  = 5
+	PINLockDuration = 30 * time.Minute
+)
+
+var (
+	ErrPINFormat   = errors.New("PIN harus terdiri dari 6 digit angka")
+	ErrPINTooWeak  = errors.New("PIN terlalu mudah ditebak, hindari angka berulang atau berurutan")
+	ErrPINNotSet   = errors.New("transaction pin not set")
+	ErrPINLocked   = errors.New("transaction pin locked")
+	ErrPINMismatch = errors.New("transaction pin mismatch")
+)
+
+// ValidatePINFormat accepts exactly six digits that are not all the same or a
+// straight ascending/descending run.
+func ValidatePINFormat(pin string) error {
+	if len(pin) != PINLength {
+		return ErrPINFormat
+	}
+	for i := 0; i < len(pin); i++ {
+		if pin[i] < '0' || pin[i] > '9' {
+			return ErrPINFormat
+		}
+	}
+	same, asc, desc := true, true, true
+	for i := 1; i < len(pin); i++ {
+		d := int(pin[i]) - int(pin[i-1])
+		same = same && d == 0
+		asc = asc && d == 1
+		desc = desc && d == -1
+	}
+	if same || asc || desc {
+		return ErrPINTooWeak
+	}
+	return nil
+}
+
+func HashPIN(pin string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// PINAttempt is the outcome of a PIN check. LockedUntil is set when the PIN is (now) locked.
+type PINAttempt struct {
+	Err               error
+	RemainingAttempts int
+	LockedUntil       *time.Time
+}
+
+// ApplyPINAttempt updates the failure counter and lock on p for one attempt.
+func ApplyPINAttempt(p *models.UserPIN, matched bool, now time.Time) PINAttempt {
+	if p.LockedUntil != nil && now.Before(*p.LockedUntil) {
+		return PINAttempt{Err: ErrPINLocked, LockedUntil: p.LockedUntil}
+	}
+	if matched {
+		p.FailedAttempts = 0
+		p.LockedUntil = nil
+		return PINAttempt{RemainingAttempts: PINMaxAttempts}
+	}
+	p.FailedAttempts++
+	if p.FailedAttempts >= PINMaxAttempts {
+		until := now.Add(PINLockDuration)
+		p.FailedAttempts = 0
+		p.LockedUntil = &until
+		return PINAttempt{Err: ErrPINLocked, LockedUntil: &until}
+	}
+	p.LockedUntil = nil
+	return PINAttempt{Err: ErrPINMismatch, RemainingAttempts: PINMaxAttempts - p.FailedAttempts}
+}
+
+// VerifyTransactionPIN checks pin for userID and persists the attempt counter. Run it
+// outside the money transaction so a failed attempt is recorded even when the caller aborts.
+func VerifyTransactionPIN(db *gorm.DB, userID uint, pin string, now time.Time) (PINAttempt, error) {
+	var result PINAttempt
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var p models.UserPIN
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&p).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result = PINAttempt{Err: ErrPINNotSet}
+				return nil
+			}
+			return err
+		}
+		before := p.FailedAttempts
+		locked := p.LockedUntil != nil && now.Before(*p.LockedUntil)
+		matched := !locked && bcrypt.CompareHashAndPassword([]byte(p.PINHash), []byte(pin)) == nil
+		result = ApplyPINAttempt(&p, matched, now)
+		if locked || (matched && before == 0 && p.LockedUntil == nil) {
+			return nil
+		}
+		return tx.Model(&p).Updates(map[string]interface{}{
+			"failed_attempts": p.FailedAttempts,
+			"locked_until":    p.LockedUntil,
+		}).Error
+	})
+	return result, err
+}
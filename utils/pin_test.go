@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestValidatePINFormat(t *testing.T) {
+	cases := map[string]error{
+		"135790":  nil,
+		"12345":   ErrPINFormat,
+		"12a456":  ErrPINFormat,
+		"1234567": ErrPINFormat,
+		"111111":  ErrPINTooWeak,
+		"123456":  ErrPINTooWeak,
+		"987654":  ErrPINTooWeak,
+	}
+	for pin, want := range cases {
+		if got := ValidatePINFormat(pin); got != want {
+			t.Errorf("ValidatePINFormat(%q) = %v, want %v", pin, got, want)
+		}
+	}
+}
+
+func TestApplyPINAttempt_LocksAfterMaxFailures(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	p := &models.UserPIN{}
+
+	for i := 1; i < PINMaxAttempts; i++ {
+		a := ApplyPINAttempt(p, false, now)
+		if a.Err != ErrPINMismatch || a.RemainingAttempts != PINMaxAttempts-i {
+			t.Fatalf("attempt %d: got %+v", i, a)
+		}
+	}
+	a := ApplyPINAttempt(p, false, now)
+	if a.Err != ErrPINLocked || a.LockedUntil == nil || !a.LockedUntil.Equal(now.Add(PINLockDuration)) {
+		t.Fatalf("expected lock after %d failures, got %+v", PINMaxAttempts, a)
+	}
+
+	// The correct PIN is still refused while locked
+	if a := ApplyPINAttempt(p, true, now.Add(time.Minute)); a.Err != ErrPINLocked {
+		t.Fatalf("expected locked PIN to be refused, got %+v", a)
+	}
+
+	// After the lock expires the counter starts over
+	if a := ApplyPINAttempt(p, true, now.Add(PINLockDuration+time.Second)); a.Err != nil {
+		t.Fatalf("expected PIN to be accepted after lock expiry, got %+v", a)
+	}
+	if p.FailedAttempts != 0 || p.LockedUntil != nil {
+		t.Fatalf("expected counters to be reset, got %+v", p)
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxTeamDepth is how many referral levels count as a user's team.
+const MaxTeamDepth = 3
+
+// AddReferralClosure inserts the closure rows of a newly registered user: the self row
+// plus one row per ancestor of the referrer. Run it in the same transaction as the insert
+// into users.
+func AddReferralClosure(tx *gorm.DB, userID uint, reffBy *uint) error {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ReferralClosure{AncestorID: userID, DescendantID: userID, Depth: 0}).Error; err != nil {
+		return err
+	}
+	if reffBy == nil {
+		return nil
+	}
+	return tx.Exec(`INSERT IGNORE INTO referral_closure (ancestor_id, descendant_id, depth)
+		SELECT ancestor_id, ?, depth + 1 FROM referral_closure WHERE descendant_id = ?`, userID, *reffBy).Error
+}
+
+// ReferralClosureRows computes the closure of a parent map (user id -> reff_by). Chains
+// are cut at a repeated id, so a corrupt cycle cannot loop forever.
+func ReferralClosureRows(parents map[uint]*uint) []models.ReferralClosure {
+	rows := make([]models.ReferralClosure, 0, len(parents)*2)
+	for id := range parents {
+		rows = append(rows, models.ReferralClosure{AncestorID: id, DescendantID: id, Depth: 0})
+		seen := map[uint]bool{id: true}
+		depth := 1
+		for p := parents[id]; p != nil && !seen[*p]; p = parents[*p] {
+			if _, ok := parents[*p]; !ok {
+				break // referrer no longer exists
+			}
+			rows = append(rows, models.ReferralClosure{AncestorID: *p, DescendantID: id, Depth: depth})
+			seen[*p] = true
+			depth++
+		}
+	}
+	return rows
+}
+
+// BackfillReferralClosure builds closure rows for every user from users.reff_by. Existing
+// rows are kept unless rebuild is set, in which case the table is emptied first.
+// Returns the number of rows written.
+func BackfillReferralClosure(db *gorm.DB, rebuild bool) (int, error) {
+	var users []models.User
+	if err := db.Select("id", "reff_by").Find(&users).Error; err != nil {
+		return 0, err
+	}
+	parents := make(map[uint]*uint, len(users))
+	for _, u := range users {
+		parents[u.ID] = u.ReffBy
+	}
+	rows := ReferralClosureRows(parents)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if rebuild {
+			if err := tx.Exec("DELETE FROM referral_closure").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 1000).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// UplineIDs returns the referrers of userID up to depth levels, nearest first.
+func UplineIDs(db *gorm.DB, userID uint, depth int) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&models.ReferralClosure{}).
+		Where("descendant_id = ? AND depth BETWEEN 1 AND ?", userID, depth).
+		Order("depth ASC").
+		Pluck("ancestor_id", &ids).Error
+	return ids, err
+}
+
+// TeamLevelStat summarizes the members at one referral depth below a user.
+type TeamLevelStat struct {
+	Depth       int     `json:"-"`
+	Count       int64   `json:"count"`
+	Active      int64   `json:"active"`
+	Inactive    int64   `json:"inactive"`
+	TotalInvest float64 `json:"total_invest"`
+}
+
+// TeamStats returns the stats for depths 1..maxDepth; depths without members are zeroed.
+func TeamStats(db *gorm.DB, userID uint, maxDepth int) (map[int]TeamLevelStat, error) {
+	var rows []TeamLevelStat
+	if err := db.Table("referral_closure AS c").
+		Joins("JOIN users u ON u.id = c.descendant_id").
+		Select(`c.depth,
+			COUNT(*) AS count,
+			COALESCE(SUM(CASE WHEN u.investment_status = 'Active' THEN 1 ELSE 0 END), 0) AS active,
+			COALESCE(SUM(CASE WHEN u.investment_status = 'Inactive' THEN 1 ELSE 0 END), 0) AS inactive,
+			COALESCE(SUM(u.total_invest), 0) AS total_invest`).
+		Where("c.ancestor_id = ? AND c.depth BETWEEN 1 AND ?", userID, maxDepth).
+		Group("c.depth").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := make(map[int]TeamLevelStat, maxDepth)
+	for d := 1; d <= maxDepth; d++ {
+		stats[d] = TeamLevelStat{Depth: d}
+	}
+	for _, r := range rows {
+		stats[r.Depth] = r
+	}
+	return stats, nil
+}
+
+// TeamMembersQuery selects the users exactly depth levels below userID, aliased as u.
+func TeamMembersQuery(db *gorm.DB, userID uint, depth int) *gorm.DB {
+	return db.Table("users AS u").
+		Joins("JOIN referral_closure c ON c.descendant_id = u.id").
+		Where("c.ancestor_id = ? AND c.depth = ?", userID, depth)
+}
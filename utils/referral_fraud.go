@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceFingerprintHeader carries the client's device fingerprint on registration.
+const DeviceFingerprintHeader = "X-Device-Fingerprint"
+
+const (
+	ReferralFlagDeviceCluster = "device_cluster"
+	ReferralFlagIPCluster     = "ip_cluster"
+	ReferralFlagSelfReferral  = "self_referral"
+	ReferralFlagGiftFarm      = "gift_farm"
+
+	// Accounts on one device under one referrer, including the new one
+	FraudDeviceClusterSize = 2
+	// Accounts from one IP under one referrer within FraudIPClusterWindow, including the new one
+	FraudIPClusterSize   = 3
+	FraudIPClusterWindow = 24 * time.Hour
+	// Accounts this old with at least this many gift claims and nothing invested
+	FraudGiftFarmMinAge    = 3 * 24 * time.Hour
+	FraudGiftFarmMinClaims = 3
+)
+
+// HeldBonusTypes are the transaction types held as Pending while bonuses are frozen
+var HeldBonusTypes = []string{"bonus", "team"}
+
+var ErrReferralFlagNotPending = errors.New("referral flag is not pending")
+
+// FraudFinding is one reason to flag an account.
+type FraudFinding struct {
+	Kind          string
+	RelatedUserID *uint
+	Details       string
+}
+
+// RegistrationEvidence is what the detector knows about a registration before it is saved.
+type RegistrationEvidence struct {
+	Signal models.RegistrationSignal
+	// Accounts under the same referrer with the same device / recent same IP
+	SameDeviceUnderReferrer int64
+	SameIPUnderReferrer     int64
+	// Registration signals of the referrer and its own uplines, nearest first
+	AncestorSignals []models.RegistrationSignal
+}
+
+// EvaluateRegistration turns registration evidence into findings.
+func EvaluateRegistration(ev RegistrationEvidence) []FraudFinding {
+	var findings []FraudFinding
+	sig := ev.Signal
+	if sig.DeviceID != "" && ev.SameDeviceUnderReferrer+1 >= FraudDeviceClusterSize {
+		findings = append(findings, FraudFinding{
+			Kind:          ReferralFlagDeviceCluster,
+			RelatedUserID: sig.ReffBy,
+			Details:       fmt.Sprintf("%d akun lain dengan perangkat yang sama di bawah referrer yang sama", ev.SameDeviceUnderReferrer),
+		})
+	}
+	if ev.SameIPUnderReferrer+1 >= FraudIPClusterSize {
+		findings = append(findings, FraudFinding{
+			Kind:          ReferralFlagIPCluster,
+			RelatedUserID: sig.ReffBy,
+			Details:       fmt.Sprintf("%d akun lain dari IP %s di bawah referrer yang sama dalam 24 jam", ev.SameIPUnderReferrer, sig.IP),
+		})
+	}
+	for i, a := range ev.AncestorSignals {
+		sameDevice := sig.DeviceID != "" && a.DeviceID == sig.DeviceID
+		sameIP := i == 0 && a.IP != "" && a.IP == sig.IP // IP only for the direct referrer
+		if sameDevice || sameIP {
+			match := "perangkat"
+			if !sameDevice {
+				match = "IP"
+			}
+			uid := a.UserID
+			findings = append(findings, FraudFinding{
+				Kind:          ReferralFlagSelfReferral,
+				RelatedUserID: &uid,
+				Details:       fmt.Sprintf("%s sama dengan upline level %d", match, i+1),
+			})
+			break
+		}
+	}
+	return findings
+}
+
+// CollectRegistrationEvidence queries the signals the detector needs for a new registration.
+func CollectRegistrationEvidence(db *gorm.DB, sig models.RegistrationSignal, now time.Time) (RegistrationEvidence, error) {
+	ev := RegistrationEvidence{Signal: sig}
+	if sig.ReffBy == nil {
+		return ev, nil
+	}
+	if sig.DeviceID != "" {
+		if err := db.Model(&models.RegistrationSignal{}).
+			Where("reff_by = ? AND device_id = ?", *sig.ReffBy, sig.DeviceID).
+			Count(&ev.SameDeviceUnderReferrer).Error; err != nil {
+			return ev, err
+		}
+	}
+	if err := db.Model(&models.RegistrationSignal{}).
+		Where("reff_by = ? AND ip = ? AND created_at >= ?", *sig.ReffBy, sig.IP, now.Add(-FraudIPClusterWindow)).
+		Count(&ev.SameIPUnderReferrer).Error; err != nil {
+		return ev, err
+	}
+
+	// The referrer itself is depth 0 in its own closure rows
+	var ancestors []uint
+	if err := db.Model(&models.ReferralClosure{}).
+		Where("descendant_id = ? AND depth < ?", *sig.ReffBy, MaxTeamDepth).
+		Order("depth ASC").
+		Pluck("ancestor_id", &ancestors).Error; err != nil {
+		return ev, err
+	}
+	if len(ancestors) == 0 {
+		ancestors = []uint{*sig.ReffBy}
+	}
+	var signals []models.RegistrationSignal
+	if err := db.Where("user_id IN ?", ancestors).Find(&signals).Error; err != nil {
+		return ev, err
+	}
+	byUser := make(map[uint]models.RegistrationSignal, len(signals))
+	for _, s := range signals {
+		byUser[s.UserID] = s
+	}
+	for _, id := range ancestors {
+		if s, ok := byUser[id]; ok {
+			ev.AncestorSignals = append(ev.AncestorSignals, s)
+		} else {
+			ev.AncestorSignals = append(ev.AncestorSignals, models.RegistrationSignal{UserID: id})
+		}
+	}
+	return ev, nil
+}
+
+// freezesRelated reports whether a finding of this kind also implicates the related
+// account; a gift farm is the claimant's doing, not the referrer's.
+func freezesRelated(kind string) bool {
+	return kind != ReferralFlagGiftFarm
+}
+
+// RecordReferralFlags stores findings against userID and freezes the bonuses of the
+// account and, for cluster and self-referral findings, of the related account.
+func RecordReferralFlags(tx *gorm.DB, userID uint, findings []FraudFinding) error {
+	for _, f := range findings {
+		flag := models.ReferralFlag{UserID: userID, RelatedUserID: f.RelatedUserID, Kind: f.Kind, Details: f.Details, Status: "Pending"}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&flag).Error; err != nil {
+			return err
+		}
+		ids := []uint{userID}
+		if f.RelatedUserID != nil && freezesRelated(f.Kind) {
+			ids = append(ids, *f.RelatedUserID)
+		}
+		if err := tx.Model(&models.User{}).Where("id IN ?", ids).Update("bonus_frozen", true).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreditBonus pays a bonus to userID, or records it as a Pending transaction without
+// touching the balance when the user's bonuses are frozen. Returns the transaction status.
+func CreditBonus(tx *gorm.DB, userID uint, amount float64, txType, message string) (string, error) {
+	var user models.User
+	if err := tx.Select("id", "bonus_frozen").First(&user, userID).Error; err != nil {
+		return "", err
+	}
+	status := "Success"
+	if user.BonusFrozen {
+		status = "Pending"
+	} else if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
+		return "", err
+	}
+	msg := message
+	err := tx.Create(&models.Transaction{
+		UserID:          userID,
+		Amount:          amount,
+		Charge:          0,
+		OrderID:         GenerateOrderID(userID),
+		TransactionFlow: "debit",
+		TransactionType: txType,
+		Message:         &msg,
+		Status:          status,
+	}).Error
+	return status, err
+}
+
+// ReleaseHeldBonuses credits the user's held bonuses and unfreezes the account.
+func ReleaseHeldBonuses(tx *gorm.DB, userID uint) (float64, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+		return 0, err
+	}
+	held := tx.Model(&models.Transaction{}).
+		Where("user_id = ? AND status = ? AND transaction_flow = ? AND transaction_type IN ?", userID, "Pending", "debit", HeldBonusTypes)
+	var total float64
+	if err := held.Session(&gorm.Session{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	if err := held.Session(&gorm.Session{}).Update("status", "Success").Error; err != nil {
+		return 0, err
+	}
+	updates := map[string]interface{}{"bonus_frozen": false}
+	if total > 0 {
+		updates["balance"] = gorm.Expr("balance + ?", total)
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	return RoundFloat(total, 2), nil
+}
+
+// VoidHeldBonuses fails the user's held bonuses; the account stays frozen.
+func VoidHeldBonuses(tx *gorm.DB, userID uint) (float64, error) {
+	held := tx.Model(&models.Transaction{}).
+		Where("user_id = ? AND status = ? AND transaction_flow = ? AND transaction_type IN ?", userID, "Pending", "debit", HeldBonusTypes)
+	var total float64
+	if err := held.Session(&gorm.Session{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		return 0, err
+	}
+	if err := held.Session(&gorm.Session{}).Update("status", "Failed").Error; err != nil {
+		return 0, err
+	}
+	return RoundFloat(total, 2), nil
+}
+
+// ResolveReferralFlag closes a pending flag. Clearing releases the held bonuses of every
+// involved account that has no other pending flag. Confirming voids the flagged
+// account's held bonuses and keeps both accounts frozen until an admin unfreezes them.
+func ResolveReferralFlag(tx *gorm.DB, flagID uint, confirm bool, adminID uint, note string, now time.Time) (*models.ReferralFlag, error) {
+	var flag models.ReferralFlag
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&flag, flagID).Error; err != nil {
+		return nil, err
+	}
+	if flag.Status != "Pending" {
+		return nil, ErrReferralFlagNotPending
+	}
+	flag.Status = "Cleared"
+	if confirm {
+		flag.Status = "Confirmed"
+	}
+	flag.ReviewedBy = &adminID
+	flag.ReviewedAt = &now
+	if note != "" {
+		flag.AdminNote = &note
+	}
+	if err := tx.Save(&flag).Error; err != nil {
+		return nil, err
+	}
+
+	if confirm {
+		if _, err := VoidHeldBonuses(tx, flag.UserID); err != nil {
+			return nil, err
+		}
+		return &flag, nil
+	}
+
+	involved := []uint{flag.UserID}
+	if flag.RelatedUserID != nil && freezesRelated(flag.Kind) {
+		involved = append(involved, *flag.RelatedUserID)
+	}
+	for _, id := range involved {
+		var open int64
+		if err := tx.Model(&models.ReferralFlag{}).
+			Where("status IN ? AND (user_id = ? OR related_user_id = ?)", []string{"Pending", "Confirmed"}, id, id).
+			Count(&open).Error; err != nil {
+			return nil, err
+		}
+		if open == 0 {
+			if _, err := ReleaseHeldBonuses(tx, id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &flag, nil
+}
+
+// ScanGiftFarms flags accounts that are old enough, have invested nothing and live off
+// gift claims. Returns the number of new flags.
+func ScanGiftFarms(db *gorm.DB, now time.Time) (int, error) {
+	var rows []struct {
+		UserID uint
+		ReffBy *uint
+		Claims int64
+		Amount float64
+	}
+	if err := db.Table("gift_claims AS gc").
+		Joins("JOIN users u ON u.id = gc.user_id").
+		Joins("LEFT JOIN referral_flags f ON f.user_id = gc.user_id AND f.kind = ?", ReferralFlagGiftFarm).
+		Select("gc.user_id, u.reff_by, COUNT(*) AS claims, COALESCE(SUM(gc.amount), 0) AS amount").
+		Where("u.total_invest = 0 AND u.created_at <= ? AND f.id IS NULL", now.Add(-FraudGiftFarmMinAge)).
+		Group("gc.user_id, u.reff_by").
+		Having("COUNT(*) >= ?", FraudGiftFarmMinClaims).
+		Limit(500).
+		Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+	flagged := 0
+	for _, r := range rows {
+		finding := FraudFinding{
+			Kind:          ReferralFlagGiftFarm,
+			RelatedUserID: r.ReffBy,
+			Details:       fmt.Sprintf("%d klaim hadiah (Rp%.0f) tanpa investasi", r.Claims, r.Amount),
+		}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return RecordReferralFlags(tx, r.UserID, []FraudFinding{finding})
+		}); err != nil {
+			return flagged, err
+		}
+		flagged++
+	}
+	return flagged, nil
+}
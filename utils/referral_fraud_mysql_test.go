@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+)
+
+func TestCreditBonus_FrozenUserMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	sender := models.User{Name: "Sender", Number: "81000000001", Password: "x", ReffCode: "FRZSEND"}
+	frozen := models.User{Name: "Frozen", Number: "81000000002", Password: "x", ReffCode: "FRZUSER", BonusFrozen: true}
+	for _, u := range []*models.User{&sender, &frozen} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if status, err := CreditBonus(db, frozen.ID, 5000, "bonus", "Hadiah Spin Wheel"); err != nil || status != "Pending" {
+		t.Fatalf("credit to frozen user: %s, %v", status, err)
+	}
+	// Gift claims are bonuses too
+	gift := models.Gift{UserID: sender.ID, Code: "FRZ00001", Amount: 10000, WinnerCount: 1,
+		DistributionType: "equal", RecipientType: "all", Status: "active", TotalDeducted: 10000}
+	if err := db.Create(&gift).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.GiftAmountSlot{GiftID: gift.ID, SlotIndex: 0, Amount: 10000}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RedeemGift(db, gift.Code, frozen, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var u models.User
+	db.First(&u, frozen.ID)
+	if u.Balance != 0 {
+		t.Fatalf("frozen user balance %.2f, want 0", u.Balance)
+	}
+	released, err := ReleaseHeldBonuses(db, frozen.ID)
+	if err != nil || released != 15000 {
+		t.Fatalf("released %.2f, %v", released, err)
+	}
+	db.First(&u, frozen.ID)
+	if u.Balance != 15000 || u.BonusFrozen {
+		t.Fatalf("after release: balance %.2f frozen %v", u.Balance, u.BonusFrozen)
+	}
+}
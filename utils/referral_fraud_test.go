@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+
+	"project/models"
+)
+
+func TestEvaluateRegistration(t *testing.T) {
+	ref := uint(10)
+	sig := models.RegistrationSignal{ReffBy: &ref, IP: "10.0.0.1", DeviceID: "dev-a"}
+	kinds := func(fs []FraudFinding) []string {
+		out := make([]string, 0, len(fs))
+		for _, f := range fs {
+			out = append(out, f.Kind)
+		}
+		return out
+	}
+
+	cases := []struct {
+		name string
+		ev   RegistrationEvidence
+		want []string
+	}{
+		{"clean", RegistrationEvidence{Signal: sig, SameIPUnderReferrer: 1}, []string{}},
+		{"device cluster", RegistrationEvidence{Signal: sig, SameDeviceUnderReferrer: 1}, []string{ReferralFlagDeviceCluster}},
+		{"no fingerprint never clusters", RegistrationEvidence{Signal: models.RegistrationSignal{ReffBy: &ref, IP: "10.0.0.1"}, SameDeviceUnderReferrer: 5}, []string{}},
+		{"ip cluster", RegistrationEvidence{Signal: sig, SameIPUnderReferrer: 2}, []string{ReferralFlagIPCluster}},
+		{"same device as upline", RegistrationEvidence{Signal: sig, AncestorSignals: []models.RegistrationSignal{
+			{UserID: 10, IP: "10.9.9.9", DeviceID: "dev-x"},
+			{UserID: 7, IP: "10.8.8.8", DeviceID: "dev-a"},
+		}}, []string{ReferralFlagSelfReferral}},
+		{"same ip as referrer", RegistrationEvidence{Signal: sig, AncestorSignals: []models.RegistrationSignal{
+			{UserID: 10, IP: "10.0.0.1", DeviceID: "dev-x"},
+		}}, []string{ReferralFlagSelfReferral}},
+		{"same ip as higher upline is ignored", RegistrationEvidence{Signal: sig, AncestorSignals: []models.RegistrationSignal{
+			{UserID: 10, IP: "10.9.9.9"},
+			{UserID: 7, IP: "10.0.0.1"},
+		}}, []string{}},
+	}
+	for _, c := range cases {
+		got := kinds(EvaluateRegistration(c.ev))
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+			}
+		}
+	}
+
+	self := EvaluateRegistration(RegistrationEvidence{Signal: sig, AncestorSignals: []models.RegistrationSignal{{UserID: 7, DeviceID: "dev-a"}}})
+	if len(self) != 1 || self[0].RelatedUserID == nil || *self[0].RelatedUserID != 7 {
+		t.Fatalf("self-referral should point at the matching upline, got %+v", self)
+	}
+}
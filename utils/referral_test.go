@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestReferralClosureRows(t *testing.T) {
+	u := func(id uint) *uint { return &id }
+	parents := map[uint]*uint{
+		1: nil,
+		2: u(1),
+		3: u(2),
+		4: u(3),
+		5: u(99), // referrer was deleted
+		6: u(7),  // corrupt cycle
+		7: u(6),
+	}
+
+	var got []string
+	for _, r := range ReferralClosureRows(parents) {
+		got = append(got, fmt.Sprintf("%d>%d@%d", r.AncestorID, r.DescendantID, r.Depth))
+	}
+	sort.Strings(got)
+	want := []string{
+		"1>1@0", "1>2@1", "1>3@2", "1>4@3",
+		"2>2@0", "2>3@1", "2>4@2",
+		"3>3@0", "3>4@1",
+		"4>4@0",
+		"5>5@0",
+		"6>6@0", "6>7@1",
+		"7>6@1", "7>7@0",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got  %v\nwant %v", got, want)
+	}
+}
@@ -5,29 +5,29 @@ import (
 	"fmt"
 	"io"
 	"mime"
-	"os"
 	"path"
 	"time"
 
+	"project/config"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 // getR2Config returns AWS config for Cloudflare R2 (S3-compatible)
 func getR2Config() (aws.Config, error) {
-	accountID := os.Getenv("R2_ACCOUNT_ID")
-	accessKey := os.Getenv("R2_ACCESS_KEY_ID")
-	secretKey := os.Getenv("R2_SECRET_ACCESS_KEY")
+	r2 := config.Get().R2
+	accountID, accessKey, secretKey := r2.AccountID, r2.AccessKeyID, r2.SecretAccessKey
 
 	if accountID == "" || accessKey == "" || secretKey == "" {
 		return aws.Config{}, fmt.Errorf("R2_ACCOUNT_ID, R2_ACCESS_KEY_ID, atau R2_SECRET_ACCESS_KEY belum diatur")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("auto"), // Required by SDK, R2 ignores this
-		config.WithCredentialsProvider(
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
+		awsconfig.WithRegion("auto"), // Required by SDK, R2 ignores this
+		awsconfig.WithCredentialsProvider(
 			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
 		),
 	)
@@ -40,7 +40,7 @@ func getR2Config() (aws.Config, error) {
 
 // getR2Client returns S3 client configured for Cloudflare R2
 func getR2Client() (*s3.Client, error) {
-	accountID := os.Getenv("R2_ACCOUNT_ID")
+	accountID := config.Get().R2.AccountID
 	if accountID == "" {
 		return nil, fmt.Errorf("R2_ACCOUNT_ID belum diatur")
 	}
@@ -60,7 +60,7 @@ func getR2Client() (*s3.Client, error) {
 
 // getR2Bucket returns the R2 bucket name from env
 func getR2Bucket() (string, error) {
-	bucket := os.Getenv("R2_BUCKET_NAME")
+	bucket := config.Get().R2.BucketName
 	if bucket == "" {
 		return "", fmt.Errorf("R2_BUCKET_NAME belum diatur")
 	}
@@ -157,3 +157,67 @@ func DeleteFromS3(objectName string) error {
 
 	return nil
 }
+
+// DownloadFromS3 opens an object from Cloudflare R2. The caller closes the body.
+func DownloadFromS3(objectName string) (io.ReadCloser, error) {
+	bucket, err := getR2Bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := getR2Client()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("R2 download gagal: %w", err)
+	}
+
+	return out.Body, nil
+}
+
+// S3Object is an entry returned by ListS3Objects
+type S3Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ListS3Objects lists all objects under prefix in Cloudflare R2
+func ListS3Objects(prefix string) ([]S3Object, error) {
+	bucket, err := getR2Bucket()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := getR2Client()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []S3Object
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("R2 list gagal: %w", err)
+		}
+		for _, o := range page.Contents {
+			obj := S3Object{Key: aws.ToString(o.Key), Size: aws.ToInt64(o.Size)}
+			if o.LastModified != nil {
+				obj.LastModified = *o.LastModified
+			}
+			objects = append(objects, obj)
+		}
+	}
+
+	return objects, nil
+}
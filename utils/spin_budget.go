@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Reasons recorded on UserSpin.FallbackReason
+const (
+	SpinFallbackDailyBudget   = "daily_budget"
+	SpinFallbackTotalBudget   = "total_budget"
+	SpinFallbackDailyQuantity = "daily_quantity"
+	SpinFallbackTotalQuantity = "total_quantity"
+)
+
+// SpinBudget holds the payout caps from settings; 0 means unlimited.
+type SpinBudget struct {
+	Daily float64 `json:"daily"`
+	Total float64 `json:"total"`
+}
+
+func SpinBudgetFromSetting(setting *models.Setting) SpinBudget {
+	if setting == nil {
+		return SpinBudget{}
+	}
+	return SpinBudget{Daily: setting.SpinDailyBudget, Total: setting.SpinTotalBudget}
+}
+
+type SpinPrizeUsage struct {
+	WinsToday int64   `json:"wins_today"`
+	WinsTotal int64   `json:"wins_total"`
+	PaidToday float64 `json:"paid_today"`
+	PaidTotal float64 `json:"paid_total"`
+}
+
+// SpinBudgetUsage is what has been paid out so far, overall and per prize.
+type SpinBudgetUsage struct {
+	PaidToday      float64                 `json:"paid_today"`
+	PaidTotal      float64                 `json:"paid_total"`
+	FallbacksToday int64                   `json:"fallbacks_today"`
+	Prizes         map[uint]SpinPrizeUsage `json:"prizes"`
+}
+
+// spinBudgetTotalPeriod is the spin_budget_counters period holding all-time totals
+const spinBudgetTotalPeriod = "total"
+
+// LoadSpinBudgetUsage reads the spin_budget_counters rows for the all-time total and for
+// the WIB day of now.
+func LoadSpinBudgetUsage(db *gorm.DB, now time.Time) (SpinBudgetUsage, error) {
+	today := DateKey(now.In(JakartaLocation()))
+	usage := SpinBudgetUsage{Prizes: map[uint]SpinPrizeUsage{}}
+
+	var counters []models.SpinBudgetCounter
+	if err := db.Where("period IN ?", []string{spinBudgetTotalPeriod, today}).Find(&counters).Error; err != nil {
+		return usage, err
+	}
+	for _, c := range counters {
+		pu := usage.Prizes[c.PrizeID]
+		if c.Period == spinBudgetTotalPeriod {
+			pu.WinsTotal, pu.PaidTotal = c.Wins, c.Paid
+			usage.PaidTotal += c.Paid
+		} else {
+			pu.WinsToday, pu.PaidToday = c.Wins, c.Paid
+			usage.PaidToday += c.Paid
+			usage.FallbacksToday += c.Fallbacks
+		}
+		usage.Prizes[c.PrizeID] = pu
+	}
+	usage.PaidToday = RoundFloat(usage.PaidToday, 2)
+	usage.PaidTotal = RoundFloat(usage.PaidTotal, 2)
+	return usage, nil
+}
+
+// RecordSpinWin adds an awarded prize to the total and daily counters. Call it in the
+// transaction that creates the UserSpin.
+func RecordSpinWin(tx *gorm.DB, prizeID uint, amount float64, fallback bool, now time.Time) error {
+	var fallbacks int64
+	if fallback {
+		fallbacks = 1
+	}
+	for _, period := range []string{spinBudgetTotalPeriod, DateKey(now.In(JakartaLocation()))} {
+		counter := models.SpinBudgetCounter{Period: period, PrizeID: prizeID, Wins: 1, Paid: amount, Fallbacks: fallbacks}
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"wins":      gorm.Expr("wins + 1"),
+				"paid":      gorm.Expr("paid + ?", amount),
+				"fallbacks": gorm.Expr("fallbacks + ?", fallbacks),
+			}),
+		}).Create(&counter).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SpinBudgetExceeded reports why awarding prize would break a cap, or "" if it fits.
+func SpinBudgetExceeded(prize models.SpinPrize, budget SpinBudget, usage SpinBudgetUsage) string {
+	pu := usage.Prizes[prize.ID]
+	switch {
+	case prize.TotalQuantityLimit > 0 && pu.WinsTotal >= int64(prize.TotalQuantityLimit):
+		return SpinFallbackTotalQuantity
+	case prize.DailyQuantityLimit > 0 && pu.WinsToday >= int64(prize.DailyQuantityLimit):
+		return SpinFallbackDailyQuantity
+	case budget.Total > 0 && usage.PaidTotal+prize.Amount > budget.Total:
+		return SpinFallbackTotalBudget
+	case budget.Daily > 0 && usage.PaidToday+prize.Amount > budget.Daily:
+		return SpinFallbackDailyBudget
+	}
+	return ""
+}
+
+// ResolveSpinPrize returns the prize to award for a draw. When the drawn prize is over a
+// cap, the lowest prize is awarded instead; the lowest prize itself is never capped so a
+// spent ticket always pays something.
+func ResolveSpinPrize(drawn, lowest models.SpinPrize, budget SpinBudget, usage SpinBudgetUsage) (models.SpinPrize, string) {
+	if drawn.ID == lowest.ID {
+		return drawn, ""
+	}
+	if reason := SpinBudgetExceeded(drawn, budget, usage); reason != "" {
+		return lowest, reason
+	}
+	return drawn, ""
+}
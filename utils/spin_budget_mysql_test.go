@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+)
+
+func TestSpinBudgetCountersMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+
+	yesterday := time.Date(2026, 3, 9, 23, 30, 0, 0, JakartaLocation())
+	today := yesterday.Add(time.Hour)
+	if err := RecordSpinWin(db, 8, 100000, false, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := RecordSpinWin(db, 8, 100000, false, today); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordSpinWin(db, 1, 1000, true, today); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := LoadSpinBudgetUsage(db, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.PaidToday != 201000 || usage.PaidTotal != 301000 || usage.FallbacksToday != 1 {
+		t.Fatalf("usage = %+v", usage)
+	}
+	if pu := usage.Prizes[8]; pu.WinsToday != 2 || pu.WinsTotal != 3 || pu.PaidToday != 200000 {
+		t.Fatalf("prize 8 usage = %+v", pu)
+	}
+
+	var rows int64
+	db.Model(&models.SpinBudgetCounter{}).Count(&rows)
+	if rows != 5 { // total and two days for prize 8, total and today for prize 1
+		t.Fatalf("counter rows = %d, want 5", rows)
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"project/models"
+)
+
+func TestResolveSpinPrize(t *testing.T) {
+	lowest := models.SpinPrize{ID: 1, Amount: 1000, TotalQuantityLimit: 1}
+	big := models.SpinPrize{ID: 8, Amount: 1000000, DailyQuantityLimit: 1, TotalQuantityLimit: 3}
+
+	cases := []struct {
+		name   string
+		budget SpinBudget
+		usage  SpinBudgetUsage
+		want   uint
+		reason string
+	}{
+		{"within caps", SpinBudget{Daily: 2000000}, SpinBudgetUsage{PaidToday: 500000}, 8, ""},
+		{"unlimited budgets", SpinBudget{}, SpinBudgetUsage{PaidToday: 9e9, PaidTotal: 9e9}, 8, ""},
+		{"daily budget", SpinBudget{Daily: 1200000}, SpinBudgetUsage{PaidToday: 300000}, 1, SpinFallbackDailyBudget},
+		{"total budget", SpinBudget{Total: 5000000}, SpinBudgetUsage{PaidTotal: 4500000}, 1, SpinFallbackTotalBudget},
+		{"daily quantity", SpinBudget{}, SpinBudgetUsage{Prizes: map[uint]SpinPrizeUsage{8: {WinsToday: 1, WinsTotal: 1}}}, 1, SpinFallbackDailyQuantity},
+		{"total quantity", SpinBudget{}, SpinBudgetUsage{Prizes: map[uint]SpinPrizeUsage{8: {WinsTotal: 3}}}, 1, SpinFallbackTotalQuantity},
+	}
+	for _, c := range cases {
+		got, reason := ResolveSpinPrize(big, lowest, c.budget, c.usage)
+		if got.ID != c.want || reason != c.reason {
+			t.Errorf("%s: got prize %d (%q), want %d (%q)", c.name, got.ID, reason, c.want, c.reason)
+		}
+	}
+
+	// The lowest prize is the floor and is never capped
+	usage := SpinBudgetUsage{PaidToday: 1e9, Prizes: map[uint]SpinPrizeUsage{1: {WinsTotal: 10}}}
+	if got, reason := ResolveSpinPrize(lowest, lowest, SpinBudget{Daily: 1}, usage); got.ID != 1 || reason != "" {
+		t.Errorf("lowest prize: got %d (%q)", got.ID, reason)
+	}
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const SpinClientSeedMaxLength = 64
+
+var (
+	ErrSpinNoPrizes   = errors.New("no active spin prizes")
+	ErrSpinClientSeed = errors.New("Client seed harus 1-64 karakter huruf, angka, '-' atau '_'")
+	ErrSpinNotFair    = errors.New("spin has no provably fair data")
+	ErrNoSpinSeed     = errors.New("no published spin seed")
+)
+
+// SpinWeight is one row of the prize weight table used for a spin. The table is stored as
+// JSON on every UserSpin so the draw can be replayed after prizes are edited.
+type SpinWeight struct {
+	PrizeID uint    `json:"prize_id"`
+	Code    string  `json:"code"`
+	Amount  float64 `json:"amount"`
+	Weight  int     `json:"weight"`
+}
+
+// SpinWeightTable builds the weight table from active prizes, skipping zero weights.
+// prizes must be in a stable order (amount, id) because the roll walks the table in order.
+func SpinWeightTable(prizes []models.SpinPrize) ([]SpinWeight, int) {
+	table := make([]SpinWeight, 0, len(prizes))
+	total := 0
+	for _, p := range prizes {
+		if p.ChanceWeight <= 0 {
+			continue
+		}
+		table = append(table, SpinWeight{PrizeID: p.ID, Code: p.Code, Amount: p.Amount, Weight: p.ChanceWeight})
+		total += p.ChanceWeight
+	}
+	return table, total
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewServerSeed returns a fresh 32-byte server seed from crypto/rand and its SHA-256 hash.
+func NewServerSeed() (seed, hash string, err error) {
+	seed, err = randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return seed, HashServerSeed(seed), nil
+}
+
+func HashServerSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateClientSeed accepts 1-64 characters of [A-Za-z0-9_-].
+func ValidateClientSeed(seed string) error {
+	if len(seed) == 0 || len(seed) > SpinClientSeedMaxLength {
+		return ErrSpinClientSeed
+	}
+	for _, c := range seed {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return ErrSpinClientSeed
+		}
+	}
+	return nil
+}
+
+// SpinRoll derives the roll in [1, totalWeight] from HMAC-SHA256(serverSeed, "clientSeed:nonce").
+// The first 8 bytes are read as a big-endian uint64; the modulo bias is below 2^-40 for any
+// realistic weight total.
+func SpinRoll(serverSeed, clientSeed string, nonce uint64, totalWeight int) int {
+	if totalWeight <= 0 {
+		return 0
+	}
+	mac := hmac.New(sha256.New, []byte(serverSeed))
+	mac.Write([]byte(clientSeed + ":" + strconv.FormatUint(nonce, 10)))
+	v := binary.BigEndian.Uint64(mac.Sum(nil)[:8])
+	return int(v%uint64(totalWeight)) + 1
+}
+
+// PickSpinPrize walks the table in order and returns the row whose cumulative weight
+// first reaches roll.
+func PickSpinPrize(table []SpinWeight, roll int) (SpinWeight, bool) {
+	acc := 0
+	for _, w := range table {
+		acc += w.Weight
+		if roll <= acc {
+			return w, true
+		}
+	}
+	return SpinWeight{}, false
+}
+
+// ActiveSpinSeed locks the user's active seed for a spin. It must run inside the caller's
+// transaction. A seed is only created by PublishSpinSeed or RotateSpinSeed, which show its
+// hash to the user, so a spin never uses a seed whose hash was not published first;
+// without one it returns ErrNoSpinSeed.
+func ActiveSpinSeed(tx *gorm.DB, userID uint) (*models.SpinSeed, error) {
+	var seed models.SpinSeed
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ?", userID, "Active").
+		Order("id DESC").
+		First(&seed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoSpinSeed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &seed, nil
+}
+
+// PublishSpinSeed returns the user's active seed, creating one with a random client seed
+// when none exists. Callers must show the returned hash to the user.
+func PublishSpinSeed(tx *gorm.DB, userID uint) (*models.SpinSeed, error) {
+	seed, err := ActiveSpinSeed(tx, userID)
+	if !errors.Is(err, ErrNoSpinSeed) {
+		return seed, err
+	}
+	clientSeed, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	return createSpinSeed(tx, userID, clientSeed)
+}
+
+func createSpinSeed(tx *gorm.DB, userID uint, clientSeed string) (*models.SpinSeed, error) {
+	serverSeed, hash, err := NewServerSeed()
+	if err != nil {
+		return nil, err
+	}
+	seed := models.SpinSeed{
+		UserID:         userID,
+		ServerSeed:     serverSeed,
+		ServerSeedHash: hash,
+		ClientSeed:     clientSeed,
+		Status:         "Active",
+	}
+	if err := tx.Create(&seed).Error; err != nil {
+		return nil, err
+	}
+	return &seed, nil
+}
+
+// RotateSpinSeed reveals the user's active server seed and commits to a new one. An empty
+// clientSeed keeps the previous client seed. Returns the revealed seed (nil if the user had
+// none) and the new active seed.
+func RotateSpinSeed(tx *gorm.DB, userID uint, clientSeed string, now time.Time) (*models.SpinSeed, *models.SpinSeed, error) {
+	var old models.SpinSeed
+	var revealed *models.SpinSeed
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND status = ?", userID, "Active").
+		Order("id DESC").
+		First(&old).Error
+	switch {
+	case err == nil:
+		if err := tx.Model(&old).Updates(map[string]interface{}{"status": "Revealed", "revealed_at": now}).Error; err != nil {
+			return nil, nil, err
+		}
+		old.Status, old.RevealedAt = "Revealed", &now
+		revealed = &old
+		if clientSeed == "" {
+			clientSeed = old.ClientSeed
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, nil, err
+	}
+
+	if clientSeed == "" {
+		if clientSeed, err = randomHex(8); err != nil {
+			return nil, nil, err
+		}
+	}
+	next, err := createSpinSeed(tx, userID, clientSeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return revealed, next, nil
+}
+
+// SpinVerification is the replay of a past spin from its revealed seed and weight snapshot.
+type SpinVerification struct {
+	ServerSeed     string       `json:"server_seed"`
+	ServerSeedHash string       `json:"server_seed_hash"`
+	ClientSeed     string       `json:"client_seed"`
+	Nonce          uint64       `json:"nonce"`
+	TotalWeight    int          `json:"total_weight"`
+	WeightTable    []SpinWeight `json:"weight_table"`
+	Roll           int          `json:"roll"`
+	PrizeID        uint         `json:"prize_id"`
+	PrizeCode      string       `json:"prize_code"`
+	HashMatches    bool         `json:"hash_matches"`
+	RollMatches    bool         `json:"roll_matches"`
+	PrizeMatches   bool         `json:"prize_matches"`
+	Valid          bool         `json:"valid"`
+}
+
+// VerifySpin recomputes a spin from the revealed server seed and the weight table stored
+// on it, and reports whether the hash, roll and awarded prize all match.
+func VerifySpin(spin models.UserSpin, serverSeed string) (*SpinVerification, error) {
+	if spin.SeedID == nil || spin.WeightSnapshot == "" {
+		return nil, ErrSpinNotFair
+	}
+	var table []SpinWeight
+	if err := json.Unmarshal([]byte(spin.WeightSnapshot), &table); err != nil {
+		return nil, fmt.Errorf("invalid weight snapshot: %w", err)
+	}
+	total := 0
+	for _, w := range table {
+		total += w.Weight
+	}
+
+	v := &SpinVerification{
+		ServerSeed:     serverSeed,
+		ServerSeedHash: spin.ServerSeedHash,
+		ClientSeed:     spin.ClientSeed,
+		Nonce:          spin.Nonce,
+		TotalWeight:    total,
+		WeightTable:    table,
+		HashMatches:    HashServerSeed(serverSeed) == spin.ServerSeedHash,
+	}
+	v.Roll = SpinRoll(serverSeed, spin.ClientSeed, spin.Nonce, total)
+	v.RollMatches = v.Roll == spin.Roll && total == spin.TotalWeight
+	if prize, ok := PickSpinPrize(table, v.Roll); ok {
+		v.PrizeID, v.PrizeCode = prize.PrizeID, prize.Code
+	}
+	// A budget fallback changes the award, not the draw
+	drawn := spin.PrizeID
+	if spin.FallbackFromPrizeID != nil {
+		drawn = *spin.FallbackFromPrizeID
+	}
+	v.PrizeMatches = v.PrizeID == drawn
+	v.Valid = v.HashMatches && v.RollMatches && v.PrizeMatches
+	return v, nil
+}
+
+// LoadSpinVerification loads a spin and its seed and replays it. The returned verification
+// is nil while the seed is still active, since the server seed must stay secret until then.
+func LoadSpinVerification(db *gorm.DB, spinID uint) (*models.UserSpin, *models.SpinSeed, *SpinVerification, error) {
+	var spin models.UserSpin
+	if err := db.First(&spin, spinID).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if spin.SeedID == nil {
+		return &spin, nil, nil, ErrSpinNotFair
+	}
+	var seed models.SpinSeed
+	if err := db.First(&seed, *spin.SeedID).Error; err != nil {
+		return &spin, nil, nil, err
+	}
+	if seed.Status != "Revealed" {
+		return &spin, &seed, nil, nil
+	}
+	v, err := VerifySpin(spin, seed.ServerSeed)
+	if err != nil {
+		return &spin, &seed, nil, err
+	}
+	return &spin, &seed, v, nil
+}
+
+// SpinVerificationResponse builds the API payload shared by the user and admin verify endpoints.
+func SpinVerificationResponse(spin *models.UserSpin, seed *models.SpinSeed, v *SpinVerification) APIResponse {
+	if v == nil {
+		return APIResponse{
+			Success: true,
+			Message: "Server seed belum dibuka. Ganti server seed untuk memverifikasi spin ini",
+			Data: map[string]interface{}{
+				"spin":             spin,
+				"revealed":         false,
+				"server_seed_hash": seed.ServerSeedHash,
+			},
+		}
+	}
+	message := "Spin terverifikasi"
+	if !v.Valid {
+		message = "Verifikasi spin gagal"
+	}
+	return APIResponse{
+		Success: true,
+		Message: message,
+		Data: map[string]interface{}{
+			"spin":         spin,
+			"revealed":     true,
+			"verification": v,
+		},
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"project/internal/testdb"
+)
+
+func TestActiveSpinSeedRequiresPublishedSeedMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+
+	// A spin must not create the seed it is drawn from
+	if _, err := ActiveSpinSeed(db, 7); !errors.Is(err, ErrNoSpinSeed) {
+		t.Fatalf("unpublished seed: got %v", err)
+	}
+
+	published, err := PublishSpinSeed(db, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := PublishSpinSeed(db, 7)
+	if err != nil || again.ID != published.ID {
+		t.Fatalf("second publish: %+v, %v", again, err)
+	}
+	active, err := ActiveSpinSeed(db, 7)
+	if err != nil || active.ID != published.ID || active.ServerSeedHash != published.ServerSeedHash {
+		t.Fatalf("active seed: %+v, %v", active, err)
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Spin ticket grant sources
+const (
+	SpinTicketSourceReferralInvestment = "referral_investment"
+	SpinTicketSourceLegacy             = "legacy"
+)
+
+// Spin ticket statuses derived from the grant timestamps
+const (
+	SpinTicketAvailable = "available"
+	SpinTicketConsumed  = "consumed"
+	SpinTicketExpired   = "expired"
+)
+
+var ErrNoSpinTicket = errors.New("no spin ticket available")
+
+// SpinTicketExpiry returns the expiry for a ticket granted at now, or nil when tickets
+// do not expire.
+func SpinTicketExpiry(setting *models.Setting, now time.Time) *time.Time {
+	if setting == nil || setting.SpinTicketExpiryDays <= 0 {
+		return nil
+	}
+	exp := now.AddDate(0, 0, setting.SpinTicketExpiryDays)
+	return &exp
+}
+
+// SpinTicketStatus reports the grant's status at now. A ticket past its expiry counts as
+// expired even before the expiry cron has marked it.
+func SpinTicketStatus(g models.SpinTicketGrant, now time.Time) string {
+	switch {
+	case g.ConsumedAt != nil:
+		return SpinTicketConsumed
+	case g.ExpiredAt != nil, g.ExpiresAt != nil && !now.Before(*g.ExpiresAt):
+		return SpinTicketExpired
+	}
+	return SpinTicketAvailable
+}
+
+// GrantSpinTicket records one ticket for userID and bumps the cached counter. The expiry
+// comes from settings. It must run inside the caller's transaction.
+func GrantSpinTicket(tx *gorm.DB, userID uint, source string, referenceOrderID *string, now time.Time) (*models.SpinTicketGrant, error) {
+	var setting models.Setting
+	if err := tx.First(&setting).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	grant := models.SpinTicketGrant{
+		UserID:           userID,
+		Source:           source,
+		ReferenceOrderID: referenceOrderID,
+		GrantedAt:        now,
+		ExpiresAt:        SpinTicketExpiry(&setting, now),
+	}
+	if err := tx.Create(&grant).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("spin_ticket", gorm.Expr("COALESCE(spin_ticket, 0) + 1")).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// ConsumeSpinTicket marks the user's ticket that expires soonest as consumed and
+// decrements the cached counter. It must run inside the caller's transaction.
+func ConsumeSpinTicket(tx *gorm.DB, userID uint, now time.Time) (*models.SpinTicketGrant, error) {
+	var grant models.SpinTicketGrant
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND consumed_at IS NULL AND expired_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Order("expires_at IS NULL, expires_at ASC, id ASC").
+		First(&grant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoSpinTicket
+		}
+		return nil, err
+	}
+	if err := tx.Model(&grant).Update("consumed_at", now).Error; err != nil {
+		return nil, err
+	}
+	grant.ConsumedAt = &now
+	if err := tx.Model(&models.User{}).Where("id = ? AND spin_ticket > 0", userID).
+		UpdateColumn("spin_ticket", gorm.Expr("spin_ticket - 1")).Error; err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// ExpireSpinTickets marks the user's overdue tickets as expired and resyncs the cached
+// counter. Returns the number of tickets expired.
+func ExpireSpinTickets(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+		return 0, err
+	}
+	res := tx.Model(&models.SpinTicketGrant{}).
+		Where("user_id = ? AND consumed_at IS NULL AND expired_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", userID, now).
+		Update("expired_at", now)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	var available int64
+	if err := tx.Model(&models.SpinTicketGrant{}).
+		Where("user_id = ? AND consumed_at IS NULL AND expired_at IS NULL", userID).
+		Count(&available).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).UpdateColumn("spin_ticket", available).Error; err != nil {
+		return 0, err
+	}
+	return res.RowsAffected, nil
+}
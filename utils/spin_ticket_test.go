@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestSpinTicketExpiry(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	if SpinTicketExpiry(&models.Setting{}, now) != nil {
+		t.Fatal("expected no expiry when disabled")
+	}
+	exp := SpinTicketExpiry(&models.Setting{SpinTicketExpiryDays: 7}, now)
+	if exp == nil || !exp.Equal(wib(2026, time.March, 9, 10, 0)) {
+		t.Fatalf("unexpected expiry %v", exp)
+	}
+}
+
+func TestSpinTicketStatus(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	cases := []struct {
+		name  string
+		grant models.SpinTicketGrant
+		want  string
+	}{
+		{"no expiry", models.SpinTicketGrant{}, SpinTicketAvailable},
+		{"not yet expired", models.SpinTicketGrant{ExpiresAt: &future}, SpinTicketAvailable},
+		{"overdue", models.SpinTicketGrant{ExpiresAt: &past}, SpinTicketExpired},
+		{"marked expired", models.SpinTicketGrant{ExpiredAt: &past}, SpinTicketExpired},
+		{"consumed before expiry", models.SpinTicketGrant{ExpiresAt: &past, ConsumedAt: &past}, SpinTicketConsumed},
+	}
+	for _, c := range cases {
+		if got := SpinTicketStatus(c.grant, now); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
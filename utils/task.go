@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TaskTypeOneTime = "one_time"
+	TaskTypeDaily   = "daily"
+	TaskTypeWeekly  = "weekly"
+
+	TaskCriterionActiveMembers  = "active_members"
+	TaskCriterionInvestedAmount = "invested_amount"
+	TaskCriterionCheckinStreak  = "checkin_streak"
+	TaskCriterionForumAccepted  = "forum_accepted"
+
+	taskPeriodOnce = "once"
+
+	// TaskProgressTTL is how long cached progress is trusted by task lists. Claims always
+	// recompute, and the events that move a criterion clear the cache straight away.
+	TaskProgressTTL = 5 * time.Minute
+)
+
+var (
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrTaskAlreadyClaimed = errors.New("task already claimed for this period")
+	ErrTaskIncomplete     = errors.New("task requirements not met")
+)
+
+// ValidateTask checks the type/criterion combination and target of a task before it is saved.
+func ValidateTask(t models.Task) error {
+	if t.Name == "" || t.Reward <= 0 {
+		return errors.New("Nama dan hadiah tugas wajib diisi")
+	}
+	switch t.Type {
+	case TaskTypeOneTime, TaskTypeDaily, TaskTypeWeekly:
+	default:
+		return errors.New("Tipe tugas harus one_time, daily atau weekly")
+	}
+	switch t.Criterion {
+	case TaskCriterionActiveMembers:
+		if t.RequiredLevel < 1 || t.RequiredLevel > 3 || t.RequiredActiveMembers < 1 {
+			return errors.New("Tugas anggota aktif membutuhkan level 1-3 dan jumlah anggota minimal 1")
+		}
+	case TaskCriterionInvestedAmount, TaskCriterionCheckinStreak, TaskCriterionForumAccepted:
+		if t.TargetValue <= 0 {
+			return errors.New("Target tugas harus lebih dari 0")
+		}
+	default:
+		return fmt.Errorf("Kriteria tugas tidak dikenal: %s", t.Criterion)
+	}
+	if t.Status != "Active" && t.Status != "Inactive" {
+		return errors.New("Status tugas harus Active atau Inactive")
+	}
+	return nil
+}
+
+// TaskTarget is the progress value needed to claim the task.
+func TaskTarget(t models.Task) float64 {
+	if t.Criterion == TaskCriterionActiveMembers || t.Criterion == "" {
+		return float64(t.RequiredActiveMembers)
+	}
+	return t.TargetValue
+}
+
+// TaskPeriod returns the claim period containing now: its key and [start, end) in WIB.
+// One-time tasks have a single unbounded period.
+func TaskPeriod(taskType string, now time.Time) (string, time.Time, time.Time) {
+	day := StartOfDay(now, JakartaLocation())
+	switch taskType {
+	case TaskTypeDaily:
+		return DateKey(day), day, day.AddDate(0, 0, 1)
+	case TaskTypeWeekly:
+		offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+		start := day.AddDate(0, 0, -offset)
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 7)
+	}
+	return taskPeriodOnce, time.Time{}, time.Time{}
+}
+
+// NextCheckinStreak is the streak for a check-in on today's WIB date given the user's
+// previous check-in (nil if none).
+func NextCheckinStreak(last *models.UserCheckin, now time.Time) int {
+	if last == nil {
+		return 1
+	}
+	today := StartOfDay(now, JakartaLocation())
+	switch last.CheckinDate {
+	case DateKey(today):
+		return last.Streak
+	case DateKey(today.AddDate(0, 0, -1)):
+		return last.Streak + 1
+	}
+	return 1
+}
+
+// CurrentCheckinStreak is the streak still alive at now: it survives until the end of
+// the day after the last check-in.
+func CurrentCheckinStreak(last *models.UserCheckin, now time.Time) int {
+	if last == nil {
+		return 0
+	}
+	today := StartOfDay(now, JakartaLocation())
+	if last.CheckinDate == DateKey(today) || last.CheckinDate == DateKey(today.AddDate(0, 0, -1)) {
+		return last.Streak
+	}
+	return 0
+}
+
+// TaskProgressValue computes the user's current progress on task for the period containing now.
+func TaskProgressValue(db *gorm.DB, t models.Task, userID uint, now time.Time) (float64, error) {
+	_, start, end := TaskPeriod(t.Type, now)
+	switch t.Criterion {
+	case TaskCriterionInvestedAmount:
+		var sum float64
+		q := db.Model(&models.Investment{}).
+			Where("user_id = ? AND status IN ?", userID, []string{"Running", "Completed"})
+		if !start.IsZero() {
+			q = q.Where("created_at >= ? AND created_at < ?", start, end)
+		}
+		err := q.Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+		return sum, err
+
+	case TaskCriterionCheckinStreak:
+		var last models.UserCheckin
+		err := db.Where("user_id = ?", userID).Order("checkin_date DESC").First(&last).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return float64(CurrentCheckinStreak(&last, now)), nil
+
+	case TaskCriterionForumAccepted:
+		var n int64
+		q := db.Model(&models.Forum{}).Where("user_id = ? AND status = ?", userID, "Accepted")
+		if !start.IsZero() {
+			q = q.Where("accepted_at >= ? AND accepted_at < ?", start, end)
+		}
+		err := q.Count(&n).Error
+		return float64(n), err
+	}
+
+	// active_members: downlines at exactly RequiredLevel with an active investment
+	level := t.RequiredLevel
+	if level < 1 {
+		level = 1
+	}
+	if level > MaxTeamDepth {
+		level = MaxTeamDepth
+	}
+	var n int64
+	err := TeamMembersQuery(db, userID, level).Where("u.investment_status = ?", "Active").Count(&n).Error
+	return float64(n), err
+}
+
+func saveTaskProgress(db *gorm.DB, userID uint, t models.Task, periodKey string, value float64, now time.Time) (models.UserTaskProgress, error) {
+	p := models.UserTaskProgress{
+		UserID:    userID,
+		TaskID:    t.ID,
+		PeriodKey: periodKey,
+		Progress:  RoundFloat(value, 2),
+		Target:    TaskTarget(t),
+		UpdatedAt: now,
+	}
+	if p.Target > 0 && p.Progress >= p.Target {
+		p.CompletedAt = &now
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "task_id"}, {Name: "period_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"progress", "target", "completed_at", "updated_at"}),
+	}).Create(&p).Error
+	return p, err
+}
+
+// LoadTaskProgress returns the user's progress on each task for the current period, using
+// cached rows younger than TaskProgressTTL and recomputing the rest.
+func LoadTaskProgress(db *gorm.DB, userID uint, tasks []models.Task, now time.Time) (map[uint]models.UserTaskProgress, error) {
+	result := make(map[uint]models.UserTaskProgress, len(tasks))
+	if len(tasks) == 0 {
+		return result, nil
+	}
+	taskIDs := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	var cached []models.UserTaskProgress
+	if err := db.Where("user_id = ? AND task_id IN ?", userID, taskIDs).Find(&cached).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.UserTaskProgress, len(cached))
+	for _, p := range cached {
+		byKey[fmt.Sprintf("%d/%s", p.TaskID, p.PeriodKey)] = p
+	}
+
+	for _, t := range tasks {
+		key, _, _ := TaskPeriod(t.Type, now)
+		if p, ok := byKey[fmt.Sprintf("%d/%s", t.ID, key)]; ok && p.Target == TaskTarget(t) && now.Sub(p.UpdatedAt) < TaskProgressTTL {
+			result[t.ID] = p
+			continue
+		}
+		value, err := TaskProgressValue(db, t, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		p, err := saveTaskProgress(db, userID, t, key, value, now)
+		if err != nil {
+			return nil, err
+		}
+		result[t.ID] = p
+	}
+	return result, nil
+}
+
+// InvalidateTaskProgress drops cached progress of the users on tasks with the given
+// criterion, so the next task list recomputes it.
+func InvalidateTaskProgress(db *gorm.DB, userIDs []uint, criterion string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return db.Where("user_id IN ? AND task_id IN (?)", userIDs,
+		db.Model(&models.Task{}).Select("id").Where("criterion = ?", criterion)).
+		Delete(&models.UserTaskProgress{}).Error
+}
+
+// ClaimTask pays the task reward for the current period if the requirement is met.
+// Progress is recomputed inside the transaction, and uk_user_tasks_period stops a
+// concurrent second claim for the same period.
+func ClaimTask(db *gorm.DB, userID, taskID uint, now time.Time) (*models.UserTask, *models.Task, error) {
+	var claim models.UserTask
+	var task models.Task
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND status = ?", taskID, "Active").First(&task).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		key, _, _ := TaskPeriod(task.Type, now)
+		var count int64
+		if err := tx.Model(&models.UserTask{}).Where("user_id = ? AND task_id = ? AND period_key = ?", userID, task.ID, key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTaskAlreadyClaimed
+		}
+
+		value, err := TaskProgressValue(tx, task, userID, now)
+		if err != nil {
+			return err
+		}
+		if _, err := saveTaskProgress(tx, userID, task, key, value, now); err != nil {
+			return err
+		}
+		if value < TaskTarget(task) {
+			return ErrTaskIncomplete
+		}
+
+		claim = models.UserTask{UserID: userID, TaskID: task.ID, ClaimedAt: now, PeriodKey: key}
+		if err := tx.Create(&claim).Error; err != nil {
+			if isDuplicateKey(err) {
+				return ErrTaskAlreadyClaimed
+			}
+			return err
+		}
+		_, err = CreditBonus(tx, userID, task.Reward, "bonus", "Reward tugas: "+task.Name)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &claim, &task, nil
+}
+
+// InvalidateInvestmentTaskProgress clears cached progress that an investment by userID
+// moves: the investor's invested amount and the active member counts of three upline levels.
+func InvalidateInvestmentTaskProgress(db *gorm.DB, userID uint) error {
+	if err := InvalidateTaskProgress(db, []uint{userID}, TaskCriterionInvestedAmount); err != nil {
+		return err
+	}
+	uplines, err := UplineIDs(db, userID, MaxTeamDepth)
+	if err != nil {
+		return err
+	}
+	return InvalidateTaskProgress(db, uplines, TaskCriterionActiveMembers)
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/internal/testdb"
+	"project/models"
+)
+
+func TestTaskProgress_ForumAcceptedByAcceptanceDateMySQL(t *testing.T) {
+	db := testdb.OpenMySQL(t, nil)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, JakartaLocation())
+	yesterday := now.AddDate(0, 0, -1)
+
+	// Accepted yesterday and edited today: it belongs to yesterday's period
+	old := models.Forum{UserID: 7, Description: "kemarin", Image: "a.jpg", Status: "Accepted", AcceptedAt: &yesterday}
+	fresh := models.Forum{UserID: 7, Description: "hari ini", Image: "b.jpg", Status: "Accepted", AcceptedAt: &now}
+	for _, f := range []*models.Forum{&old, &fresh} {
+		if err := db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&old).Update("description", "diubah").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	task := models.Task{Type: TaskTypeDaily, Criterion: TaskCriterionForumAccepted, TargetValue: 1}
+	if got, err := TaskProgressValue(db, task, 7, now); err != nil || got != 1 {
+		t.Fatalf("today's progress = %v, %v; want 1", got, err)
+	}
+	if got, err := TaskProgressValue(db, task, 7, yesterday); err != nil || got != 1 {
+		t.Fatalf("yesterday's progress = %v, %v; want 1", got, err)
+	}
+}
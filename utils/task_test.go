@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestTaskPeriod(t *testing.T) {
+	// Wednesday 4 March 2026, 23:30 WIB
+	now := wib(2026, time.March, 4, 23, 30)
+
+	if key, start, _ := TaskPeriod(TaskTypeOneTime, now); key != "once" || !start.IsZero() {
+		t.Fatalf("one_time: got %s %v", key, start)
+	}
+	key, start, end := TaskPeriod(TaskTypeDaily, now)
+	if key != "2026-03-04" || !start.Equal(wib(2026, time.March, 4, 0, 0)) || !end.Equal(wib(2026, time.March, 5, 0, 0)) {
+		t.Fatalf("daily: got %s [%v, %v)", key, start, end)
+	}
+	key, start, end = TaskPeriod(TaskTypeWeekly, now)
+	if key != "2026-W10" || !start.Equal(wib(2026, time.March, 2, 0, 0)) || !end.Equal(wib(2026, time.March, 9, 0, 0)) {
+		t.Fatalf("weekly: got %s [%v, %v)", key, start, end)
+	}
+	// Sunday still belongs to the week that started on Monday
+	if key, _, _ := TaskPeriod(TaskTypeWeekly, wib(2026, time.March, 8, 12, 0)); key != "2026-W10" {
+		t.Fatalf("weekly sunday: got %s", key)
+	}
+}
+
+func TestCheckinStreak(t *testing.T) {
+	now := wib(2026, time.March, 4, 8, 0)
+
+	if NextCheckinStreak(nil, now) != 1 || CurrentCheckinStreak(nil, now) != 0 {
+		t.Fatal("expected a fresh streak without check-ins")
+	}
+	yesterday := &models.UserCheckin{CheckinDate: "2026-03-03", Streak: 4}
+	if got := NextCheckinStreak(yesterday, now); got != 5 {
+		t.Fatalf("expected streak to continue, got %d", got)
+	}
+	if got := CurrentCheckinStreak(yesterday, now); got != 4 {
+		t.Fatalf("expected streak alive until tonight, got %d", got)
+	}
+	gap := &models.UserCheckin{CheckinDate: "2026-03-01", Streak: 9}
+	if NextCheckinStreak(gap, now) != 1 || CurrentCheckinStreak(gap, now) != 0 {
+		t.Fatal("expected a missed day to reset the streak")
+	}
+}
+
+func TestValidateTask(t *testing.T) {
+	valid := []models.Task{
+		{Name: "Ajak 5 anggota", Reward: 10000, Type: TaskTypeOneTime, Criterion: TaskCriterionActiveMembers, RequiredLevel: 1, RequiredActiveMembers: 5, Status: "Active"},
+		{Name: "Check-in 7 hari", Reward: 5000, Type: TaskTypeWeekly, Criterion: TaskCriterionCheckinStreak, TargetValue: 7, Status: "Active"},
+		{Name: "Investasi harian", Reward: 2000, Type: TaskTypeDaily, Criterion: TaskCriterionInvestedAmount, TargetValue: 100000, Status: "Inactive"},
+	}
+	for _, task := range valid {
+		if err := ValidateTask(task); err != nil {
+			t.Errorf("%s: unexpected error %v", task.Name, err)
+		}
+	}
+	invalid := []models.Task{
+		{Name: "x", Reward: 1, Type: "monthly", Criterion: TaskCriterionForumAccepted, TargetValue: 1, Status: "Active"},
+		{Name: "x", Reward: 1, Type: TaskTypeDaily, Criterion: "deposits", TargetValue: 1, Status: "Active"},
+		{Name: "x", Reward: 1, Type: TaskTypeDaily, Criterion: TaskCriterionForumAccepted, Status: "Active"},
+		{Name: "x", Reward: 1, Type: TaskTypeOneTime, Criterion: TaskCriterionActiveMembers, RequiredLevel: 4, RequiredActiveMembers: 1, Status: "Active"},
+	}
+	for i, task := range invalid {
+		if ValidateTask(task) == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTransferLimit applies to VIP levels without a transfer_limits row.
+// It keeps the original global bounds and no caps.
+func DefaultTransferLimit(level uint) models.TransferLimit {
+	return models.TransferLimit{VIPLevel: level, MinAmount: 10000, MaxAmount: 10000000}
+}
+
+// TransferUsage is the amount already moved in the current day/month, read inside
+// the locked transfer transaction.
+type TransferUsage struct {
+	SenderDailyOut    float64
+	SenderMonthlyOut  float64
+	SenderToReceiver  float64 // today, same sender and receiver
+	ReceiverDailyIn   float64
+	ReceiverMonthlyIn float64
+}
+
+// TransferCheck is the input evaluated by CheckTransferLimits.
+type TransferCheck struct {
+	Amount        float64
+	SenderLimit   models.TransferLimit
+	ReceiverLimit models.TransferLimit
+	Usage         TransferUsage
+}
+
+// CheckTransferLimits returns the first violated limit, or nil.
+func CheckTransferLimits(c TransferCheck) *PolicyViolation {
+	s, r := c.SenderLimit, c.ReceiverLimit
+	switch {
+	case s.MinAmount > 0 && c.Amount < s.MinAmount:
+		return &PolicyViolation{Rule: "min_amount", Message: fmt.Sprintf("Minimal transfer Rp %.0f", s.MinAmount)}
+	case s.MaxAmount > 0 && c.Amount > s.MaxAmount:
+		return &PolicyViolation{Rule: "max_amount", Message: fmt.Sprintf("Maksimal transfer Rp %.0f", s.MaxAmount)}
+	case s.DailyOutLimit > 0 && c.Usage.SenderDailyOut+c.Amount > s.DailyOutLimit:
+		return &PolicyViolation{Rule: "daily_out_limit", Message: fmt.Sprintf("Melebihi batas transfer harian Rp %.0f", s.DailyOutLimit)}
+	case s.MonthlyOutLimit > 0 && c.Usage.SenderMonthlyOut+c.Amount > s.MonthlyOutLimit:
+		return &PolicyViolation{Rule: "monthly_out_limit", Message: fmt.Sprintf("Melebihi batas transfer bulanan Rp %.0f", s.MonthlyOutLimit)}
+	case s.PerRecipientDailyLimit > 0 && c.Usage.SenderToReceiver+c.Amount > s.PerRecipientDailyLimit:
+		return &PolicyViolation{Rule: "per_recipient_daily_limit", Message: fmt.Sprintf("Melebihi batas transfer harian ke penerima yang sama Rp %.0f", s.PerRecipientDailyLimit)}
+	case r.DailyInLimit > 0 && c.Usage.ReceiverDailyIn+c.Amount > r.DailyInLimit:
+		return &PolicyViolation{Rule: "daily_in_limit", Message: "Penerima telah mencapai batas penerimaan transfer harian"}
+	case r.MonthlyInLimit > 0 && c.Usage.ReceiverMonthlyIn+c.Amount > r.MonthlyInLimit:
+		return &PolicyViolation{Rule: "monthly_in_limit", Message: "Penerima telah mencapai batas penerimaan transfer bulanan"}
+	}
+	return nil
+}
+
+// VelocityHit is returned when an active velocity rule matches the incoming transfer.
+type VelocityHit struct {
+	Rule models.TransferVelocityRule
+}
+
+// CheckTransferVelocity evaluates fan-in rules for a receiver. recentSenders returns the
+// distinct senders (excluding the current one) that sent at most smallMax (0 = any) to the
+// receiver since the given time.
+func CheckTransferVelocity(rules []models.TransferVelocityRule, amount float64, now time.Time, recentSenders func(since time.Time, smallMax float64) (int64, error)) (*VelocityHit, error) {
+	for _, rule := range rules {
+		if rule.Status == "Inactive" || rule.WindowMinutes <= 0 || rule.MaxDistinctSenders <= 0 {
+			continue
+		}
+		if rule.SmallAmountMax > 0 && amount > rule.SmallAmountMax {
+			continue
+		}
+		n, err := recentSenders(now.Add(-time.Duration(rule.WindowMinutes)*time.Minute), rule.SmallAmountMax)
+		if err != nil {
+			return nil, err
+		}
+		// +1 for the transfer being evaluated
+		if n+1 > int64(rule.MaxDistinctSenders) {
+			return &VelocityHit{Rule: rule}, nil
+		}
+	}
+	return nil, nil
+}
+
+// LoadTransferLimit returns the limits for a VIP level, falling back to DefaultTransferLimit.
+func LoadTransferLimit(db *gorm.DB, level uint) (models.TransferLimit, error) {
+	var limits []models.TransferLimit
+	if err := db.Where("vip_level = ?", level).Limit(1).Find(&limits).Error; err != nil {
+		return models.TransferLimit{}, err
+	}
+	if len(limits) == 0 {
+		return DefaultTransferLimit(level), nil
+	}
+	return limits[0], nil
+}
+
+// LoadTransferUsage sums transfers for the current WIB day and month. Call it inside the
+// transaction that holds the sender and receiver row locks so the totals cannot race.
+func LoadTransferUsage(tx *gorm.DB, senderID, receiverID uint, now time.Time) (TransferUsage, error) {
+	loc := JakartaLocation()
+	dayStart := StartOfDay(now, loc)
+	n := now.In(loc)
+	monthStart := time.Date(n.Year(), n.Month(), 1, 0, 0, 0, 0, loc)
+
+	var u TransferUsage
+	sum := func(dst *float64, where string, args ...interface{}) error {
+		return tx.Model(&models.Transfer{}).Select("COALESCE(SUM(amount), 0)").Where(where, args...).Scan(dst).Error
+	}
+	if err := sum(&u.SenderDailyOut, "sender_id = ? AND created_at >= ?", senderID, dayStart); err != nil {
+		return u, err
+	}
+	if err := sum(&u.SenderMonthlyOut, "sender_id = ? AND created_at >= ?", senderID, monthStart); err != nil {
+		return u, err
+	}
+	if err := sum(&u.SenderToReceiver, "sender_id = ? AND receiver_id = ? AND created_at >= ?", senderID, receiverID, dayStart); err != nil {
+		return u, err
+	}
+	if err := sum(&u.ReceiverDailyIn, "receiver_id = ? AND created_at >= ?", receiverID, dayStart); err != nil {
+		return u, err
+	}
+	if err := sum(&u.ReceiverMonthlyIn, "receiver_id = ? AND created_at >= ?", receiverID, monthStart); err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+// HeldTransferAmount is the part of a user's balance received by transfer that is still on hold.
+func HeldTransferAmount(tx *gorm.DB, userID uint, now time.Time) (float64, error) {
+	var held float64
+	err := tx.Model(&models.Transfer{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("receiver_id = ? AND hold_until IS NOT NULL AND hold_until > ? AND reversed_at IS NULL", userID, now).
+		Scan(&held).Error
+	return held, err
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestCheckTransferLimits(t *testing.T) {
+	sender := models.TransferLimit{MinAmount: 10000, MaxAmount: 1000000, DailyOutLimit: 2000000, PerRecipientDailyLimit: 500000}
+	receiver := models.TransferLimit{DailyInLimit: 3000000}
+
+	cases := []struct {
+		name   string
+		amount float64
+		usage  TransferUsage
+		rule   string
+	}{
+		{"allowed", 100000, TransferUsage{}, ""},
+		{"below minimum", 5000, TransferUsage{}, "min_amount"},
+		{"above maximum", 2000000, TransferUsage{}, "max_amount"},
+		{"daily out", 300000, TransferUsage{SenderDailyOut: 1800000}, "daily_out_limit"},
+		{"per recipient", 300000, TransferUsage{SenderToReceiver: 300000}, "per_recipient_daily_limit"},
+		{"daily in", 300000, TransferUsage{ReceiverDailyIn: 2900000}, "daily_in_limit"},
+	}
+	for _, c := range cases {
+		v := CheckTransferLimits(TransferCheck{Amount: c.amount, SenderLimit: sender, ReceiverLimit: receiver, Usage: c.usage})
+		switch {
+		case c.rule == "" && v != nil:
+			t.Errorf("%s: expected no violation, got %s", c.name, v.Rule)
+		case c.rule != "" && (v == nil || v.Rule != c.rule):
+			t.Errorf("%s: expected %s violation, got %+v", c.name, c.rule, v)
+		}
+	}
+}
+
+func TestCheckTransferVelocity(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	rules := []models.TransferVelocityRule{
+		{Name: "inactive", WindowMinutes: 60, MaxDistinctSenders: 1, Action: "block", Status: "Inactive"},
+		{Name: "fan-in", WindowMinutes: 60, MaxDistinctSenders: 5, SmallAmountMax: 50000, Action: "hold", HoldHours: 24, Status: "Active"},
+	}
+
+	var gotSince time.Time
+	senders := func(n int64) func(time.Time, float64) (int64, error) {
+		return func(since time.Time, _ float64) (int64, error) {
+			gotSince = since
+			return n, nil
+		}
+	}
+
+	hit, err := CheckTransferVelocity(rules, 20000, now, senders(5))
+	if err != nil || hit == nil || hit.Rule.Name != "fan-in" {
+		t.Fatalf("expected fan-in hit, got %+v (err %v)", hit, err)
+	}
+	if !gotSince.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected window start %v, got %v", now.Add(-time.Hour), gotSince)
+	}
+
+	if hit, _ := CheckTransferVelocity(rules, 20000, now, senders(3)); hit != nil {
+		t.Fatalf("expected no hit below the sender threshold, got %+v", hit)
+	}
+	if hit, _ := CheckTransferVelocity(rules, 100000, now, senders(10)); hit != nil {
+		t.Fatalf("expected large transfers to be ignored by the small-amount rule, got %+v", hit)
+	}
+}
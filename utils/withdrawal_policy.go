@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/models"
+
+	"gorm.io/gorm"
+)
+
+// WithdrawalUsage is what a user has already withdrawn in the current business day.
+type WithdrawalUsage struct {
+	Count  int64
+	Amount float64
+}
+
+// WithdrawalCheck is the input evaluated against a WithdrawalPolicy.
+type WithdrawalCheck struct {
+	VIPLevel uint
+	Amount   float64
+	Today    WithdrawalUsage
+	// BankAccountChangedAt is when the destination account was added or last edited.
+	// Zero means unknown and never triggers the cooling_off rule.
+	BankAccountChangedAt time.Time
+	KYCVerified          bool
+}
+
+// PolicyViolation describes why a withdrawal was rejected.
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v *PolicyViolation) Error() string {
+	return v.Message
+}
+
+// WithdrawalPolicy evaluates withdrawal requests against admin-defined rules.
+// The global min/max from settings always apply; everything else comes from Rules.
+type WithdrawalPolicy struct {
+	Rules       []models.WithdrawalRule
+	MinWithdraw float64
+	MaxWithdraw float64
+	// Holidays closes withdrawals on days banks do not settle; nil disables the check.
+	Holidays HolidayCalendar
+	Clock    Clock
+	Location *time.Location
+	// Amounts above which an unverified user is asked to complete KYC; 0 disables
+	KYCThreshold      float64
+	KYCDailyThreshold float64
+}
+
+// DefaultWithdrawalRules mirrors the rules that were hardcoded before withdrawal_rules
+// existed: 09:00-17:00 WIB, closed on Sunday, one withdrawal per day.
+func DefaultWithdrawalRules() []models.WithdrawalRule {
+	sunday := int(time.Sunday)
+	return []models.WithdrawalRule{
+		{RuleType: models.WithdrawalRuleOperatingWindow, StartTime: "09:00", EndTime: "17:00", Status: "Active"},
+		{RuleType: models.WithdrawalRuleClosedWeekday, Weekday: &sunday, Status: "Active"},
+		{RuleType: models.WithdrawalRuleDailyCount, LimitCount: 1, Status: "Active"},
+	}
+}
+
+// LoadWithdrawalPolicy builds the policy from the withdrawal_rules table, the holiday
+// calendar and settings. When no rule has ever been defined the defaults are used.
+func LoadWithdrawalPolicy(db *gorm.DB, setting *models.Setting) (*WithdrawalPolicy, error) {
+	var rules []models.WithdrawalRule
+	if err := db.Order("id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		rules = DefaultWithdrawalRules()
+	}
+	holidays, err := LoadHolidayCalendarAround(db, SystemClock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &WithdrawalPolicy{
+		Rules:       rules,
+		MinWithdraw: setting.MinWithdraw,
+		MaxWithdraw: setting.MaxWithdraw,
+		Holidays:    holidays,
+		Clock:       SystemClock,
+		Location:    JakartaLocation(),
+
+		KYCThreshold:      setting.KYCWithdrawalThreshold,
+		KYCDailyThreshold: setting.KYCDailyWithdrawalThreshold,
+	}, nil
+}
+
+// Now returns the current time in the policy location.
+func (p *WithdrawalPolicy) Now() time.Time {
+	clock := p.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	return clock.Now().In(p.location())
+}
+
+// DayBounds returns the [start, end) range of the current business day.
+func (p *WithdrawalPolicy) DayBounds() (time.Time, time.Time) {
+	start := StartOfDay(p.Now(), p.location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Evaluate returns the first violated rule, or nil if the withdrawal is allowed.
+func (p *WithdrawalPolicy) Evaluate(c WithdrawalCheck) *PolicyViolation {
+	now := p.Now()
+
+	if c.Amount < p.MinWithdraw {
+		return &PolicyViolation{Rule: "min_withdraw", Message: fmt.Sprintf("Minimal penarikan adalah Rp%.0f", p.MinWithdraw)}
+	}
+	if p.MaxWithdraw > 0 && c.Amount > p.MaxWithdraw {
+		return &PolicyViolation{Rule: "max_withdraw", Message: fmt.Sprintf("Maksimal penarikan adalah Rp%.0f", p.MaxWithdraw)}
+	}
+
+	for _, r := range p.rulesFor(models.WithdrawalRuleAmountRange, c.VIPLevel) {
+		if r.MinAmount > 0 && c.Amount < r.MinAmount {
+			return violation(r, fmt.Sprintf("Minimal penarikan untuk level Anda adalah Rp%.0f", r.MinAmount))
+		}
+		if r.MaxAmount > 0 && c.Amount > r.MaxAmount {
+			return violation(r, fmt.Sprintf("Maksimal penarikan untuk level Anda adalah Rp%.0f", r.MaxAmount))
+		}
+	}
+
+	if !c.KYCVerified && KYCRequired(c.Amount, c.Today.Amount, p.KYCThreshold, p.KYCDailyThreshold) {
+		return &PolicyViolation{Rule: "kyc_required", Message: "Verifikasi identitas (KYC) diperlukan untuk penarikan sebesar ini"}
+	}
+
+	if v := p.checkOperatingWindow(now, c.VIPLevel); v != nil {
+		return v
+	}
+
+	for _, r := range p.rulesFor(models.WithdrawalRuleClosedWeekday, c.VIPLevel) {
+		if r.Weekday != nil && time.Weekday(*r.Weekday) == now.Weekday() {
+			return violation(r, fmt.Sprintf("Penarikan tidak dapat dilakukan pada hari %s", IndonesianWeekday(now.Weekday())))
+		}
+	}
+
+	if p.Holidays != nil {
+		if name, ok := p.Holidays.HolidayOn(now); ok {
+			return &PolicyViolation{Rule: "holiday", Message: fmt.Sprintf("Penarikan tidak dapat dilakukan pada hari libur nasional (%s)", name)}
+		}
+	}
+
+	for _, r := range p.rulesFor(models.WithdrawalRuleClosedDate, c.VIPLevel) {
+		if r.Date != nil && sameDate(*r.Date, now) {
+			return violation(r, fmt.Sprintf("Penarikan tidak dapat dilakukan pada tanggal %s", now.Format("02-01-2006")))
+		}
+	}
+
+	for _, r := range p.rulesFor(models.WithdrawalRuleDailyCount, c.VIPLevel) {
+		if r.LimitCount > 0 && c.Today.Count >= int64(r.LimitCount) {
+			return violation(r, fmt.Sprintf("Anda hanya dapat melakukan %d kali penarikan dalam sehari", r.LimitCount))
+		}
+	}
+
+	for _, r := range p.rulesFor(models.WithdrawalRuleDailyAmount, c.VIPLevel) {
+		if r.LimitAmount > 0 && c.Today.Amount+c.Amount > r.LimitAmount {
+			return violation(r, fmt.Sprintf("Batas total penarikan harian adalah Rp%.0f", r.LimitAmount))
+		}
+	}
+
+	if !c.BankAccountChangedAt.IsZero() {
+		for _, r := range p.rulesFor(models.WithdrawalRuleCoolingOff, c.VIPLevel) {
+			if r.CooldownHours <= 0 {
+				continue
+			}
+			if now.Before(c.BankAccountChangedAt.Add(time.Duration(r.CooldownHours) * time.Hour)) {
+				return violation(r, fmt.Sprintf("Penarikan ke rekening yang baru ditambahkan atau diubah dapat dilakukan setelah %d jam", r.CooldownHours))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkOperatingWindow allows the request if it falls inside any window that applies today.
+// Without any operating_window rule there is no time restriction.
+func (p *WithdrawalPolicy) checkOperatingWindow(now time.Time, level uint) *PolicyViolation {
+	windows := p.rulesFor(models.WithdrawalRuleOperatingWindow, level)
+	if len(windows) == 0 {
+		return nil
+	}
+	minute := now.Hour()*60 + now.Minute()
+	var today []models.WithdrawalRule
+	for _, r := range windows {
+		if r.Weekday != nil && time.Weekday(*r.Weekday) != now.Weekday() {
+			continue
+		}
+		start, err1 := parseClock(r.StartTime)
+		end, err2 := parseClock(r.EndTime)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		today = append(today, r)
+		if start <= end && minute >= start && minute < end {
+			return nil
+		}
+		// window that wraps past midnight, e.g. 22:00-02:00
+		if start > end && (minute >= start || minute < end) {
+			return nil
+		}
+	}
+	if len(today) == 0 {
+		return violation(windows[0], fmt.Sprintf("Penarikan tidak dapat dilakukan pada hari %s", IndonesianWeekday(now.Weekday())))
+	}
+	ranges := make([]string, 0, len(today))
+	for _, r := range today {
+		ranges = append(ranges, r.StartTime+" - "+r.EndTime)
+	}
+	return violation(today[0], fmt.Sprintf("Penarikan hanya dapat dilakukan pada pukul %s WIB", strings.Join(ranges, ", ")))
+}
+
+// rulesFor returns the active rules of ruleType for a VIP level. Level-specific
+// rules replace the general ones when at least one exists.
+func (p *WithdrawalPolicy) rulesFor(ruleType string, level uint) []models.WithdrawalRule {
+	var general, specific []models.WithdrawalRule
+	for _, r := range p.Rules {
+		if r.RuleType != ruleType || r.Status == "Inactive" {
+			continue
+		}
+		if r.VIPLevel == nil {
+			general = append(general, r)
+		} else if *r.VIPLevel == level {
+			specific = append(specific, r)
+		}
+	}
+	if len(specific) > 0 {
+		return specific
+	}
+	return general
+}
+
+func (p *WithdrawalPolicy) location() *time.Location {
+	if p.Location == nil {
+		return JakartaLocation()
+	}
+	return p.Location
+}
+
+func violation(r models.WithdrawalRule, fallback string) *PolicyViolation {
+	msg := strings.TrimSpace(r.Message)
+	if msg == "" {
+		msg = fallback
+	}
+	return &PolicyViolation{Rule: r.RuleType, Message: msg}
+}
+
+// ValidateWithdrawalRule checks that the fields required by the rule type are present.
+func ValidateWithdrawalRule(r *models.WithdrawalRule) error {
+	switch r.RuleType {
+	case models.WithdrawalRuleOperatingWindow:
+		if _, err := parseClock(r.StartTime); err != nil {
+			return fmt.Errorf("start_time harus berformat HH:MM")
+		}
+		if _, err := parseClock(r.EndTime); err != nil {
+			return fmt.Errorf("end_time harus berformat HH:MM")
+		}
+		if r.Weekday != nil && (*r.Weekday < 0 || *r.Weekday > 6) {
+			return fmt.Errorf("weekday harus 0 (Minggu) sampai 6 (Sabtu)")
+		}
+	case models.WithdrawalRuleClosedWeekday:
+		if r.Weekday == nil || *r.Weekday < 0 || *r.Weekday > 6 {
+			return fmt.Errorf("weekday harus 0 (Minggu) sampai 6 (Sabtu)")
+		}
+	case models.WithdrawalRuleClosedDate:
+		if r.Date == nil {
+			return fmt.Errorf("date wajib diisi")
+		}
+	case models.WithdrawalRuleDailyCount:
+		if r.LimitCount <= 0 {
+			return fmt.Errorf("limit_count harus lebih dari 0")
+		}
+	case models.WithdrawalRuleDailyAmount:
+		if r.LimitAmount <= 0 {
+			return fmt.Errorf("limit_amount harus lebih dari 0")
+		}
+	case models.WithdrawalRuleAmountRange:
+		if r.MinAmount < 0 || r.MaxAmount < 0 || (r.MinAmount == 0 && r.MaxAmount == 0) {
+			return fmt.Errorf("min_amount atau max_amount wajib diisi")
+		}
+		if r.MaxAmount > 0 && r.MinAmount > r.MaxAmount {
+			return fmt.Errorf("min_amount tidak boleh lebih besar dari max_amount")
+		}
+	case models.WithdrawalRuleCoolingOff:
+		if r.CooldownHours <= 0 {
+			return fmt.Errorf("cooldown_hours harus lebih dari 0")
+		}
+	default:
+		return fmt.Errorf("rule_type tidak valid")
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight. "24:00" is accepted as end of day.
+func parseClock(s string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+func sameDate(a, b time.Time) bool {
+	// DATE columns carry no zone; compare calendar fields only
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+var indonesianWeekdays = [...]string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+
+// IndonesianWeekday returns the Indonesian name of a weekday.
+func IndonesianWeekday(d time.Weekday) string {
+	return indonesianWeekdays[d]
+}
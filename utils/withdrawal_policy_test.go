@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func wib(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, JakartaLocation())
+}
+
+func newTestPolicy(now time.Time, rules ...models.WithdrawalRule) *WithdrawalPolicy {
+	if rules == nil {
+		rules = DefaultWithdrawalRules()
+	}
+	return &WithdrawalPolicy{
+		Rules:       rules,
+		MinWithdraw: 50000,
+		MaxWithdraw: 10000000,
+		Clock:       FixedClock(now),
+		Location:    JakartaLocation(),
+	}
+}
+
+func TestWithdrawalPolicy_DefaultsAllowWeekdayBusinessHours(t *testing.T) {
+	// Monday 10:00 WIB
+	p := newTestPolicy(wib(2026, time.March, 2, 10, 0))
+	if v := p.Evaluate(WithdrawalCheck{Amount: 100000}); v != nil {
+		t.Fatalf("expected withdrawal to be allowed, got %s (%s)", v.Rule, v.Message)
+	}
+}
+
+func TestWithdrawalPolicy_DefaultsRejectOutsideHours(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.March, 2, 17, 0))
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000})
+	if v == nil || v.Rule != models.WithdrawalRuleOperatingWindow {
+		t.Fatalf("expected operating_window violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_DefaultsRejectSunday(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.March, 1, 10, 0))
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000})
+	if v == nil || v.Rule != models.WithdrawalRuleClosedWeekday {
+		t.Fatalf("expected closed_weekday violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_DefaultsRejectSecondWithdrawal(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.March, 2, 10, 0))
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000, Today: WithdrawalUsage{Count: 1, Amount: 100000}})
+	if v == nil || v.Rule != models.WithdrawalRuleDailyCount {
+		t.Fatalf("expected daily_count violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_SettingsMinMax(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.March, 2, 10, 0))
+	if v := p.Evaluate(WithdrawalCheck{Amount: 10000}); v == nil || v.Rule != "min_withdraw" {
+		t.Fatalf("expected min_withdraw violation, got %+v", v)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 20000000}); v == nil || v.Rule != "max_withdraw" {
+		t.Fatalf("expected max_withdraw violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_VIPRulesOverrideGeneral(t *testing.T) {
+	vip3 := uint(3)
+	rules := []models.WithdrawalRule{
+		{RuleType: models.WithdrawalRuleDailyCount, LimitCount: 1, Status: "Active"},
+		{RuleType: models.WithdrawalRuleDailyCount, VIPLevel: &vip3, LimitCount: 3, Status: "Active"},
+		{RuleType: models.WithdrawalRuleDailyAmount, VIPLevel: &vip3, LimitAmount: 500000, Status: "Active"},
+	}
+	p := newTestPolicy(wib(2026, time.March, 2, 10, 0), rules...)
+
+	if v := p.Evaluate(WithdrawalCheck{VIPLevel: 1, Amount: 100000, Today: WithdrawalUsage{Count: 1}}); v == nil {
+		t.Fatalf("expected VIP1 to be limited by the general daily_count rule")
+	}
+	if v := p.Evaluate(WithdrawalCheck{VIPLevel: 3, Amount: 100000, Today: WithdrawalUsage{Count: 2, Amount: 200000}}); v != nil {
+		t.Fatalf("expected VIP3 second withdrawal to be allowed, got %s", v.Message)
+	}
+	v := p.Evaluate(WithdrawalCheck{VIPLevel: 3, Amount: 400000, Today: WithdrawalUsage{Count: 1, Amount: 200000}})
+	if v == nil || v.Rule != models.WithdrawalRuleDailyAmount {
+		t.Fatalf("expected daily_amount violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_ClosedDateAndCustomMessage(t *testing.T) {
+	holiday := time.Date(2026, time.August, 17, 0, 0, 0, 0, time.UTC)
+	rules := []models.WithdrawalRule{
+		{RuleType: models.WithdrawalRuleClosedDate, Date: &holiday, Message: "Libur Hari Kemerdekaan", Status: "Active"},
+	}
+	p := newTestPolicy(wib(2026, time.August, 17, 11, 0), rules...)
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000})
+	if v == nil || v.Message != "Libur Hari Kemerdekaan" {
+		t.Fatalf("expected custom closed_date message, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_CoolingOff(t *testing.T) {
+	now := wib(2026, time.March, 2, 10, 0)
+	rules := []models.WithdrawalRule{
+		{RuleType: models.WithdrawalRuleCoolingOff, CooldownHours: 24, Status: "Active"},
+	}
+	p := newTestPolicy(now, rules...)
+
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000, BankAccountChangedAt: now.Add(-2 * time.Hour)})
+	if v == nil || v.Rule != models.WithdrawalRuleCoolingOff {
+		t.Fatalf("expected cooling_off violation, got %+v", v)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 100000, BankAccountChangedAt: now.Add(-25 * time.Hour)}); v != nil {
+		t.Fatalf("expected withdrawal after cooldown to be allowed, got %s", v.Message)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 100000}); v != nil {
+		t.Fatalf("expected unknown change time to be ignored, got %s", v.Message)
+	}
+}
+
+func TestWithdrawalPolicy_InactiveRulesIgnored(t *testing.T) {
+	rules := DefaultWithdrawalRules()
+	for i := range rules {
+		rules[i].Status = "Inactive"
+	}
+	p := newTestPolicy(wib(2026, time.March, 1, 23, 0), rules...)
+	if v := p.Evaluate(WithdrawalCheck{Amount: 100000, Today: WithdrawalUsage{Count: 5}}); v != nil {
+		t.Fatalf("expected inactive rules to be skipped, got %s", v.Message)
+	}
+}
+
+func TestWithdrawalPolicy_RejectsHoliday(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.August, 17, 10, 0))
+	p.Holidays = HolidaySet{"2026-08-17": "Hari Kemerdekaan"}
+	v := p.Evaluate(WithdrawalCheck{Amount: 100000})
+	if v == nil || v.Rule != "holiday" {
+		t.Fatalf("expected holiday violation, got %+v", v)
+	}
+}
+
+func TestWithdrawalPolicy_KYCThresholds(t *testing.T) {
+	p := newTestPolicy(wib(2026, time.March, 2, 10, 0))
+	p.KYCThreshold = 1000000
+	p.KYCDailyThreshold = 2000000
+
+	if v := p.Evaluate(WithdrawalCheck{Amount: 1000000}); v != nil {
+		t.Fatalf("amount at the threshold should pass, got %+v", v)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 1500000}); v == nil || v.Rule != "kyc_required" {
+		t.Fatalf("expected kyc_required for a single large withdrawal, got %+v", v)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 600000, Today: WithdrawalUsage{Count: 2, Amount: 1500000}}); v == nil || v.Rule != "kyc_required" {
+		t.Fatalf("expected kyc_required once the daily total is crossed, got %+v", v)
+	}
+	if v := p.Evaluate(WithdrawalCheck{Amount: 1500000, KYCVerified: true}); v != nil {
+		t.Fatalf("verified users are not gated, got %+v", v)
+	}
+}